@@ -17,10 +17,28 @@
 package main
 
 import (
+	"alfred/internal/chaos"
+	"alfred/internal/cli"
 	"alfred/internal/conf"
+	"alfred/internal/cron"
+	"alfred/internal/database"
+	"alfred/internal/faker"
+	"alfred/internal/function"
+	"alfred/internal/health"
+	"alfred/internal/jwt"
+	"alfred/internal/latency"
 	"alfred/internal/log"
+	"alfred/internal/messaging"
 	"alfred/internal/mock"
+	"alfred/internal/oidc"
+	"alfred/internal/sandbox"
 	"alfred/internal/server"
+	"alfred/internal/smtp"
+	"alfred/internal/socket"
+	"alfred/internal/store"
+	"alfred/internal/watcher"
+	"alfred/internal/wiremock"
+	"alfred/pkg/request"
 	"context"
 	"encoding/json"
 	"errors"
@@ -30,16 +48,30 @@ import (
 	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// Delay without file change before mocks are reloaded.
+const HOT_RELOAD_DEBOUNCE = 300 * time.Millisecond
+
 // Afred is a mock, written in Go (Golang), for performance testing. Alfred
 // manages a mock list, offers helpers, permits to trigger asynchronous
 // actions, and offers the ability to wrap users' javascript functions;
 // users have infinite creatives possibilities.
 func main() {
 
+	//Command line
+	args, err := cli.ParseProfileFlags(os.Args[1:])
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	if len(args) > 0 {
+		os.Exit(cli.Run(args))
+	}
+
 	configuration, err := conf.GetConfiguration()
 	if err != nil {
 
@@ -55,11 +87,133 @@ func main() {
 		panic(fmt.Errorf("fatal error, config file: %w", err))
 	}
 
+	//Access log
+	if configuration.Alfred.AccessLog.Enable {
+
+		err = log.InitAccessLogger(log.AccessLogConfig{
+			Format:        configuration.Alfred.AccessLog.Format,
+			Output:        configuration.Alfred.AccessLog.Output,
+			FilePath:      configuration.Alfred.AccessLog.FilePath,
+			MaxSizeMb:     configuration.Alfred.AccessLog.MaxSizeMb,
+			MaxBackups:    configuration.Alfred.AccessLog.MaxBackups,
+			MaxAgeDays:    configuration.Alfred.AccessLog.MaxAgeDays,
+			SyslogAddress: configuration.Alfred.AccessLog.SyslogAddress,
+			SyslogTag:     configuration.Alfred.AccessLog.SyslogTag,
+		})
+		if err != nil {
+
+			panic(fmt.Errorf("fatal error, config file: %w", err))
+		}
+	}
+
+	//Chaos, jwt keys, faker seed, js modules and identity provider
+	err = configureModules(configuration, nil)
+	if err != nil {
+
+		panic(fmt.Errorf("fatal error, config file: %w", err))
+	}
+
+	_, err = time.ParseDuration(configuration.Alfred.Core.ShutdownTimeout)
+	if err != nil {
+
+		panic(fmt.Errorf("fatal error, config file: %w", err))
+	}
+
+	//State of the js functions
+	err = store.Configure(store.Config{
+		Type: configuration.Alfred.Store.Type,
+		Path: configuration.Alfred.Store.Path,
+		Redis: store.RedisConfig{
+			Address:  configuration.Alfred.Store.Redis.Address,
+			Password: configuration.Alfred.Store.Redis.Password,
+			Db:       configuration.Alfred.Store.Redis.Db,
+			Prefix:   configuration.Alfred.Store.Redis.Prefix,
+		},
+	})
+	if err != nil {
+
+		panic(fmt.Errorf("fatal error, config file: %w", err))
+	}
+	defer store.Close()
+
+	//Test database of the js functions
+	err = database.Configure(database.Config{
+		Driver:       configuration.Alfred.Database.Driver,
+		Dsn:          configuration.Alfred.Database.Dsn,
+		MaxOpenConns: configuration.Alfred.Database.MaxOpenConns,
+		QueryTimeout: configuration.Alfred.Database.QueryTimeout,
+	})
+	if err != nil {
+
+		panic(fmt.Errorf("fatal error, config file: %w", err))
+	}
+	defer database.Close()
+
+	//Brokers of the mocks messages
+	if len(configuration.Alfred.Kafka.Brokers) > 0 {
+
+		broker, err := messaging.NewKafkaBroker(messaging.KafkaConfig{
+			Brokers:  configuration.Alfred.Kafka.Brokers,
+			ClientId: configuration.Alfred.Kafka.ClientId,
+		})
+		if err == nil {
+			err = messaging.Register(messaging.KAFKA, broker)
+		}
+		if err != nil {
+
+			panic(fmt.Errorf("fatal error, config file: %w", err))
+		}
+	}
+
+	if configuration.Alfred.Mqtt.Url != "" {
+
+		broker, err := messaging.NewMqttBroker(messaging.MqttConfig{
+			Url:      configuration.Alfred.Mqtt.Url,
+			ClientId: configuration.Alfred.Mqtt.ClientId,
+			Username: configuration.Alfred.Mqtt.Username,
+			Password: configuration.Alfred.Mqtt.Password,
+			Qos:      byte(configuration.Alfred.Mqtt.Qos),
+		})
+		if err == nil {
+			err = messaging.Register(messaging.MQTT, broker)
+		}
+		if err != nil {
+
+			panic(fmt.Errorf("fatal error, config file: %w", err))
+		}
+	}
+
+	if configuration.Alfred.Amqp.Url != "" {
+
+		broker, err := messaging.NewAmqpBroker(messaging.AmqpConfig{
+			Url:      configuration.Alfred.Amqp.Url,
+			Exchange: configuration.Alfred.Amqp.Exchange,
+		})
+		if err == nil {
+			err = messaging.Register(messaging.AMQP, broker)
+		}
+		if err != nil {
+
+			panic(fmt.Errorf("fatal error, config file: %w", err))
+		}
+	}
+	defer messaging.Close()
+
 	//Core context
 	ctx := context.Background()
 	log.Debug(ctx, "alfred configuration initialized with: "+string(configurationJson))
 
-	mockCollection := mock.CreateMockCollectionFromFolder(configuration.Alfred.Core.MocksDir)
+	//Not ready until the mocks are served
+	health.Starting(health.CHECK_MOCKS, health.CHECK_LISTENERS, health.CHECK_SOCKETS)
+	if configuration.Alfred.Smtp.Enable {
+		health.Starting(health.CHECK_SMTP)
+	}
+
+	mockCollection, err := loadMockCollection(ctx, &configuration)
+	if err != nil {
+		log.Error(ctx, "Application Panic", errors.New("error during mocks load..."+err.Error()))
+		panic("error during mocks load..." + err.Error())
+	}
 	mocksNbStr := strconv.Itoa(len(mockCollection.Mocks))
 
 	//Add mocks nb to context
@@ -69,18 +223,90 @@ func main() {
 	log.Info(ctx, "mock files loaded - "+mocksNbStr+" mock(s) created",
 		zap.String("mocks", mockCollection.GetJsonStrMockList()))
 
+	services, err := loadServices(ctx, &configuration)
+	if err != nil {
+		log.Error(ctx, "Application Panic", errors.New("error during services mocks load..."+err.Error()))
+		panic("error during services mocks load..." + err.Error())
+	}
+	health.Set(health.CHECK_MOCKS, nil, getMocksCount(mockCollection, services))
+
 	//------------------
 	// Server Management
 	//------------------
 	var asyncRunningJobsCount sync.WaitGroup //Use to count process to wait before shutdowning
-	classicServer, err := server.BuildServer(&configuration, &asyncRunningJobsCount, mockCollection)
+	servers, err := server.BuildServers(&configuration, &asyncRunningJobsCount, mockCollection, services)
 	if err != nil {
 		log.Error(ctx, "Server Panic", errors.New("error during preparing controller..."+err.Error()))
 		panic("Error during preparing controller..." + err.Error())
 	}
 
 	// Let's go !!!
-	server.Serve(ctx, &configuration, classicServer)
+	server.Serve(ctx, &configuration, servers)
+	health.Set(health.CHECK_LISTENERS, nil, strconv.Itoa(len(servers))+" listener(s)")
+
+	mocks := &mocksServer{configuration: &configuration, servers: servers, jobs: &asyncRunningJobsCount}
+
+	//TCP and UDP mocks
+	mocks.sockets, err = startSocketMocks(&configuration, mockCollection, services)
+	if err != nil {
+		log.Error(ctx, "Application Panic", errors.New("error during socket mocks start..."+err.Error()))
+		panic("error during socket mocks start..." + err.Error())
+	}
+	health.Set(health.CHECK_SOCKETS, nil, mocks.sockets.String())
+
+	//SMTP mock server
+	var smtpServer *smtp.Server
+	if configuration.Alfred.Smtp.Enable {
+
+		smtp.SetMaxEmails(configuration.Alfred.Smtp.MaxEmails)
+
+		listen := configuration.Alfred.Smtp.Listen
+		smtpServer, err = smtp.Start(listen.Ip + ":" + listen.Port)
+		if err != nil {
+			log.Error(ctx, "Application Panic", errors.New("error during smtp server start..."+err.Error()))
+			panic("error during smtp server start..." + err.Error())
+		}
+		health.Set(health.CHECK_SMTP, nil, smtpServer.Addr().String())
+	}
+
+	//Stops the files watcher and the consumers at shutdown
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	//Messages consumers, subscribed again on reload
+	mocks.consumersCtx = watchCtx
+	err = mocks.subscribeConsumers()
+	if err != nil {
+		log.Error(ctx, "Application Panic", errors.New("error during consumers subscription..."+err.Error()))
+		panic("error during consumers subscription..." + err.Error())
+	}
+
+	//Function files jobs
+	scheduleJobs(ctx, &configuration)
+
+	//Hot reload
+	if configuration.Alfred.Core.HotReload {
+
+		dirs := []string{
+			configuration.Alfred.Core.MocksDir,
+			configuration.Alfred.Core.FunctionsDir,
+			configuration.Alfred.Core.BodiesDir,
+			configuration.Alfred.Core.SchemasDir,
+			configuration.Alfred.Core.WiremockDir,
+		}
+		for _, service := range configuration.Alfred.Services {
+			dirs = append(dirs, service.MocksDir)
+		}
+
+		err = watcher.Watch(watchCtx, dirs, HOT_RELOAD_DEBOUNCE, func() {
+			mocks.reload(ctx, "hot reload")
+		})
+		if err != nil {
+			log.Error(ctx, "hot reload disabled, files watcher creation failed", err)
+		} else {
+			log.Info(ctx, "hot reload enabled")
+		}
+	}
 
 	//---------------------
 	// Wait for a kill
@@ -89,14 +315,315 @@ func main() {
 	// We must use a buffered channel or risk missing the signal
 	// if we're not ready to receive when the signal is sent.
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	// Block until a kill signal is received, the configuration is reloaded
+	// on SIGHUP
+	for sig := range c {
 
-	// Block until a signal is received.
-	<-c
+		if sig != syscall.SIGHUP {
+			break
+		}
+
+		mocks.reloadConfiguration(ctx)
+	}
 
 	//---------------------
 	// Shutdown Management
 	//---------------------
-	// Stop externalApiServer
-	server.Stop(ctx, classicServer, &asyncRunningJobsCount)
+	// Not ready anymore, the load balancers stop sending requests
+	health.Set(health.CHECK_LISTENERS, errors.New("shutting down"), "")
+	cancel()
+
+	// Requests in progress are served before the VMs are released
+	mocks.stop(ctx)
+	if smtpServer != nil {
+		smtpServer.Stop()
+	}
+	function.ShutdownPools()
+}
+
+// configureModules applies the configuration of the modules used by the
+// mocks, at startup and when the configuration is reloaded. On reload, the
+// previous configuration is given: the sections also changed with the admin
+// api are applied only if they changed in the file, so the runtime changes
+// are kept otherwise.
+func configureModules(configuration conf.Config, previous *conf.AlfredConfig) error {
+
+	var err error
+
+	//Log level, can be changed later with the admin api
+	if previous == nil || runtimeSectionChanged("log-level", configuration.Alfred.LogLevel, previous.LogLevel) {
+
+		err = log.SetLevel(configuration.Alfred.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+
+	//Latency profiles, can be changed later with the admin api
+	if previous == nil || runtimeSectionChanged("latency-profiles", configuration.Alfred.LatencyProfiles, previous.LatencyProfiles) {
+
+		err = latency.Configure(latencyProfiles(configuration.Alfred.LatencyProfiles))
+		if err != nil {
+
+			return err
+		}
+	}
+
+	//Sandbox policies of the function files, applied from their next call
+	err = sandbox.Configure(sandboxPolicies(configuration.Alfred.Sandbox.Policies), configuration.Alfred.Sandbox.DefaultPolicy)
+	if err != nil {
+
+		return err
+	}
+
+	//Chaos, can be toggled later with the admin api
+	if previous == nil || runtimeSectionChanged("chaos", configuration.Alfred.Chaos, previous.Chaos) {
+
+		err = chaos.SetProfile(chaos.Profile{
+			Enable:       configuration.Alfred.Chaos.Enable,
+			LatencyRate:  configuration.Alfred.Chaos.LatencyRate,
+			MinLatencyMs: configuration.Alfred.Chaos.MinLatencyMs,
+			MaxLatencyMs: configuration.Alfred.Chaos.MaxLatencyMs,
+			ErrorRate:    configuration.Alfred.Chaos.ErrorRate,
+			ErrorStatus:  configuration.Alfred.Chaos.ErrorStatus,
+			DropRate:     configuration.Alfred.Chaos.DropRate,
+		})
+		if err != nil {
+
+			return err
+		}
+	}
+
+	//Keys of the js functions jwt helpers
+	err = jwt.Configure(jwt.Config{
+		Algorithm:      configuration.Alfred.Jwt.Algorithm,
+		Secret:         configuration.Alfred.Jwt.Secret,
+		PrivateKeyPath: configuration.Alfred.Jwt.PrivateKeyPath,
+		PublicKeyPath:  configuration.Alfred.Jwt.PublicKeyPath,
+		Issuer:         configuration.Alfred.Jwt.Issuer,
+		ExpiresIn:      configuration.Alfred.Jwt.ExpiresIn,
+	})
+	if err != nil {
+
+		return err
+	}
+
+	//Random data of the faker helpers and js api
+	faker.SetSeed(configuration.Alfred.Faker.Seed)
+
+	//Tags of the mocks served, can be changed later with the admin api
+	if previous == nil || runtimeSectionChanged("core.profiles", configuration.Alfred.Core.Profiles, previous.Core.Profiles) {
+		mock.SetProfiles(configuration.Alfred.Core.Profiles)
+	}
+
+	//Proxies trusted to forward the client ip of the connection matchers
+	err = request.SetTrustedProxies(configuration.Alfred.Core.TrustedProxies)
+	if err != nil {
+
+		return errors.New("trusted proxy " + err.Error())
+	}
+
+	//Modules required by js functions
+	modulesDir := configuration.Alfred.Core.ModulesDir
+	if modulesDir == "" {
+		modulesDir = configuration.Alfred.Core.FunctionsDir
+	}
+	function.SetModulesRoot(modulesDir)
+
+	//Identity provider
+	if configuration.Alfred.Oidc.Enable {
+
+		err = oidc.Configure(oidcConfig(configuration.Alfred.Oidc))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startSocketMocks listens on the ports of the core and services socket mocks.
+func startSocketMocks(configuration *conf.Config, mockCollection mock.MockCollection, services []server.Service) (*socket.Listeners, error) {
+
+	mocks := mockCollection.GetSocketMocks()
+	for _, service := range services {
+		mocks = append(mocks, service.MockCollection.GetSocketMocks()...)
+	}
+
+	if len(mocks) == 0 {
+		return &socket.Listeners{}, nil
+	}
+
+	functions, err := function.CreateFunctionCollectionFromFolder(configuration.Alfred.Core.FunctionsDir)
+	if err != nil {
+		log.Debug(context.Background(), "function files loader error: "+err.Error())
+	}
+
+	return socket.Start(mocks, functions)
+}
+
+// scheduleJobs runs the exports.jobs of the function files on their cron
+// schedule, the previous jobs are unscheduled.
+func scheduleJobs(ctx context.Context, configuration *conf.Config) {
+
+	functions, err := function.CreateFunctionCollectionFromFolder(configuration.Alfred.Core.FunctionsDir)
+	if err != nil && !errors.Is(err, function.ErrNoFunctionFile) {
+		log.Error(ctx, "jobs not scheduled, function files load failed", err)
+		return
+	}
+
+	jobs := function.NewJobs(functions)
+
+	err = cron.Start(jobs)
+	if err != nil {
+		log.Error(ctx, "jobs not scheduled", err)
+		return
+	}
+
+	if len(jobs) > 0 {
+		log.Info(ctx, strconv.Itoa(len(jobs))+" job(s) scheduled")
+	}
+}
+
+// subscribeConsumers runs the onMessage functions of the consumed topics.
+func subscribeConsumers(ctx context.Context, configuration *conf.Config) error {
+
+	consumers := map[string][]conf.ConsumerConfig{
+		messaging.KAFKA: configuration.Alfred.Kafka.Consumers,
+		messaging.MQTT:  configuration.Alfred.Mqtt.Consumers,
+		messaging.AMQP:  configuration.Alfred.Amqp.Consumers,
+	}
+
+	var functions function.FunctionCollection
+
+	for broker, brokerConsumers := range consumers {
+
+		for _, consumer := range brokerConsumers {
+
+			if functions == nil {
+
+				var err error
+				functions, err = function.CreateFunctionCollectionFromFolder(configuration.Alfred.Core.FunctionsDir)
+				if err != nil {
+					return err
+				}
+			}
+
+			handler, err := function.NewMessageHandler(functions, consumer.FunctionFile)
+			if err != nil {
+				return errors.New(broker + " topic " + consumer.Topic + ": " + err.Error())
+			}
+
+			err = messaging.Subscribe(ctx, broker, consumer.Topic, consumer.GroupId, handler)
+			if err != nil {
+				return errors.New(broker + " topic " + consumer.Topic + ": " + err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadMockCollection reads the mock files and the WireMock stub mappings.
+func loadMockCollection(ctx context.Context, configuration *conf.Config) (mock.MockCollection, error) {
+
+	mockCollection, err := mock.LoadMockCollectionFromFolder(configuration.Alfred.Core.MocksDir)
+	if err != nil {
+		return mockCollection, err
+	}
+
+	//WireMock stub mappings
+	if configuration.Alfred.Core.WiremockDir != "" {
+
+		wiremockMocks, err := wiremock.CreateMocksFromFolder(configuration.Alfred.Core.WiremockDir)
+		if err != nil {
+			return mockCollection, errors.New("error during wiremock mappings load..." + err.Error())
+		}
+
+		log.Info(ctx, "wiremock mappings loaded - "+strconv.Itoa(len(wiremockMocks))+" mock(s) created")
+		mockCollection.Mocks = append(mockCollection.Mocks, wiremockMocks...)
+	}
+
+	return mockCollection, nil
+}
+
+// Mocks served by the core and the services, for the readiness probe
+func getMocksCount(mockCollection mock.MockCollection, services []server.Service) string {
+
+	count := len(mockCollection.Mocks)
+	for _, service := range services {
+		count += len(service.MockCollection.Mocks)
+	}
+
+	return strconv.Itoa(count) + " mock(s)"
+}
+
+// loadServices reads the mock files of each service.
+func loadServices(ctx context.Context, configuration *conf.Config) ([]server.Service, error) {
+
+	var services []server.Service
+
+	for _, serviceConf := range configuration.Alfred.Services {
+
+		mockCollection, err := mock.LoadMockCollectionFromFolder(serviceConf.MocksDir)
+		if err != nil {
+			return services, errors.New("service " + serviceConf.Name + ": " + err.Error())
+		}
+
+		log.Info(ctx, "service "+serviceConf.Name+" mock files loaded - "+strconv.Itoa(len(mockCollection.Mocks))+" mock(s) created",
+			zap.String("mocks", mockCollection.GetJsonStrMockList()))
+
+		mockCollection.SetService(serviceConf.Name)
+
+		services = append(services, server.Service{Conf: serviceConf, MockCollection: mockCollection})
+	}
+
+	return services, nil
+}
+
+// oidcConfig maps the identity provider configuration section.
+func oidcConfig(c conf.OidcConfig) oidc.Config {
+
+	oidcConf := oidc.Config{
+		Path:                 c.Path,
+		Issuer:               c.Issuer,
+		PrivateKeyPath:       c.PrivateKeyPath,
+		AccessTokenLifetime:  c.AccessTokenLifetime,
+		IdTokenLifetime:      c.IdTokenLifetime,
+		RefreshTokenLifetime: c.RefreshTokenLifetime,
+	}
+
+	for _, client := range c.Clients {
+		oidcConf.Clients = append(oidcConf.Clients, oidc.Client{ClientId: client.ClientId, ClientSecret: client.ClientSecret, RedirectUris: client.RedirectUris})
+	}
+
+	for _, user := range c.Users {
+		oidcConf.Users = append(oidcConf.Users, oidc.User{Username: user.Username, Password: user.Password, Claims: user.Claims})
+	}
+
+	return oidcConf
+}
+
+// latencyProfiles maps the latency profiles configuration section.
+func latencyProfiles(configs []conf.LatencyProfileConfig) []latency.Profile {
+
+	profiles := make([]latency.Profile, 0, len(configs))
+	for _, c := range configs {
+		profiles = append(profiles, latency.Profile{Name: c.Name, Distribution: c.Distribution, Min: c.Min, P50: c.P50, P90: c.P90, P95: c.P95, P99: c.P99, Max: c.Max})
+	}
+
+	return profiles
+}
+
+// sandboxPolicies maps the policies of the sandbox configuration section.
+func sandboxPolicies(configs []conf.SandboxPolicyConfig) []sandbox.Policy {
+
+	policies := make([]sandbox.Policy, 0, len(configs))
+	for _, c := range configs {
+		policies = append(policies, sandbox.Policy{Name: c.Name, Files: c.Files, Allow: c.Allow, MaxStackDepth: c.MaxStackDepth, Timeout: c.Timeout})
+	}
+
+	return policies
 }
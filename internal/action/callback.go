@@ -0,0 +1,149 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"alfred/internal/helper"
+	"alfred/internal/mock"
+	"alfred/pkg/request"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const CALLBACK_DEFAULT_METHOD = "POST"
+
+// Callback with helpers replaced, js functions can update it before it's sent
+type Callback struct {
+	Method  string            `json:"method"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Delay   int               `json:"delay"`
+	Timeout string            `json:"timeout"`
+}
+
+// CreateCallback replaces the helpers of the mock callback. A json body is
+// sent as is, with a json content type if none is set.
+func CreateCallback(cb mock.MockCallback, helpers []helper.Helper) (Callback, error) {
+
+	callback := Callback{
+		Method:  cb.Method,
+		Url:     cb.Url,
+		Headers: map[string]string{},
+		Delay:   cb.Delay,
+		Timeout: cb.Timeout,
+	}
+
+	if callback.Method == "" {
+		callback.Method = CALLBACK_DEFAULT_METHOD
+	}
+
+	var err error
+	var isJsonBody bool
+
+	callback.Body, isJsonBody, err = getRawBody(cb.Body)
+	if err != nil {
+		return callback, errors.New("callback body is not a valid json")
+	}
+
+	callback.Url, err = helper.HelperReplacement(callback.Url, helpers)
+	if err != nil {
+		return callback, err
+	}
+
+	callback.Body, err = helper.HelperReplacement(callback.Body, helpers)
+	if err != nil {
+		return callback, err
+	}
+
+	hasContentType := false
+	for k, v := range cb.Headers {
+
+		value, err := helper.HelperReplacement(v, helpers)
+		if err != nil {
+			return callback, err
+		}
+
+		callback.Headers[k] = value
+		hasContentType = hasContentType || http.CanonicalHeaderKey(k) == "Content-Type"
+	}
+
+	if isJsonBody && !hasContentType {
+		callback.Headers["Content-Type"] = "application/json"
+	}
+
+	return callback, nil
+}
+
+// getRawBody returns a json string body as text, other json bodies compacted.
+func getRawBody(raw json.RawMessage) (string, bool, error) {
+
+	if len(raw) == 0 {
+		return "", false, nil
+	}
+
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text, false, nil
+	}
+
+	body := new(bytes.Buffer)
+	err := json.Compact(body, raw)
+
+	return body.String(), true, err
+}
+
+func (c Callback) GetDelayDuration() time.Duration {
+
+	return time.Duration(c.Delay) * time.Millisecond
+}
+
+// CreateRequest builds the request sent to the callback url.
+func (c Callback) CreateRequest() (request.Request, error) {
+
+	var req request.Request
+
+	if c.Url == "" {
+		return req, errors.New("empty callback url")
+	}
+
+	err := req.SetUrl(c.Url)
+	if err != nil {
+		return req, err
+	}
+
+	err = req.SetMethod(c.Method)
+	if err != nil {
+		return req, err
+	}
+
+	if c.Timeout != "" {
+
+		err = req.SetTimeout(c.Timeout)
+		if err != nil {
+			return req, err
+		}
+	}
+
+	req.Body = []byte(c.Body)
+	req.Headers = c.Headers
+
+	return req, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"alfred/internal/helper"
+	"alfred/internal/messaging"
+	"alfred/internal/mock"
+	"testing"
+)
+
+func TestCreateCallback(t *testing.T) {
+
+	helpers := []helper.Helper{
+		{String: "{{ alfred.req.id }}", Value: "pay-42"},
+		{String: "{{ alfred.req.notify-url }}", Value: "http://localhost:8080/webhook"},
+	}
+
+	cb := mock.MockCallback{
+		Url:     "{{ alfred.req.notify-url }}",
+		Delay:   1500,
+		Headers: map[string]string{"X-Id": "{{ alfred.req.id }}"},
+		Body:    []byte(`{ "id": "{{ alfred.req.id }}", "status": "confirmed" }`),
+	}
+
+	callback, err := CreateCallback(cb, helpers)
+	if err != nil {
+		t.Fatalf("callback creation failed with error: %v", err)
+	}
+
+	if callback.Method != CALLBACK_DEFAULT_METHOD || callback.Url != "http://localhost:8080/webhook" {
+		t.Errorf("callback is: %s %s", callback.Method, callback.Url)
+	}
+
+	if callback.Body != `{"id":"pay-42","status":"confirmed"}` {
+		t.Errorf("callback body is: %s", callback.Body)
+	}
+
+	if callback.Headers["X-Id"] != "pay-42" || callback.Headers["Content-Type"] != "application/json" {
+		t.Errorf("callback headers are: %v", callback.Headers)
+	}
+
+	req, err := callback.CreateRequest()
+	if err != nil {
+		t.Fatalf("request creation failed with error: %v", err)
+	}
+
+	if req.GetMethod() != "POST" || string(req.Body) != callback.Body {
+		t.Errorf("request is: %s %s", req.GetMethod(), string(req.Body))
+	}
+
+	// text body
+	cb.Body = []byte(`"payment {{ alfred.req.id }} confirmed"`)
+	callback, _ = CreateCallback(cb, helpers)
+	if callback.Body != "payment pay-42 confirmed" || callback.Headers["Content-Type"] != "" {
+		t.Errorf("text callback is: %s, %v", callback.Body, callback.Headers)
+	}
+}
+
+func TestCreateMessage(t *testing.T) {
+
+	helpers := []helper.Helper{
+		{String: "{{ alfred.req.id }}", Value: "order-42"},
+	}
+
+	mm := mock.MockMessage{
+		Topic:   "orders",
+		Key:     "{{ alfred.req.id }}",
+		Headers: map[string]string{"X-Event": "order-created"},
+		Body:    []byte(`{ "id": "{{ alfred.req.id }}", "status": "created" }`),
+	}
+
+	msg, err := CreateMessage(mm, helpers)
+	if err != nil {
+		t.Fatalf("message creation failed with error: %v", err)
+	}
+
+	if msg.Broker != messaging.KAFKA || msg.Topic != "orders" || msg.Key != "order-42" {
+		t.Errorf("message is: %+v", msg)
+	}
+
+	if msg.Body != `{"id":"order-42","status":"created"}` || msg.Headers["X-Event"] != "order-created" {
+		t.Errorf("message is: %+v", msg)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package action
+
+import (
+	"alfred/internal/helper"
+	"alfred/internal/messaging"
+	"alfred/internal/mock"
+	"errors"
+	"time"
+)
+
+// CreateMessage replaces the helpers of the mock message, a json body is
+// published as is.
+func CreateMessage(mm mock.MockMessage, helpers []helper.Helper) (messaging.Message, error) {
+
+	msg := messaging.Message{
+		Broker:  mm.Broker,
+		Topic:   mm.Topic,
+		Headers: map[string]string{},
+	}
+
+	if msg.Broker == "" {
+		msg.Broker = messaging.KAFKA
+	}
+
+	var err error
+
+	msg.Body, _, err = getRawBody(mm.Body)
+	if err != nil {
+		return msg, errors.New("message body is not a valid json")
+	}
+
+	msg.Body, err = helper.HelperReplacement(msg.Body, helpers)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.Topic, err = helper.HelperReplacement(msg.Topic, helpers)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.Key, err = helper.HelperReplacement(mm.Key, helpers)
+	if err != nil {
+		return msg, err
+	}
+
+	for k, v := range mm.Headers {
+
+		msg.Headers[k], err = helper.HelperReplacement(v, helpers)
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	return msg, nil
+}
+
+func GetMessageDelayDuration(mm mock.MockMessage) time.Duration {
+
+	return time.Duration(mm.Delay) * time.Millisecond
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// client certificate policies
+const CLIENT_AUTH_NONE = "none"
+const CLIENT_AUTH_REQUEST = "request"
+const CLIENT_AUTH_REQUIRE = "require"
+const CLIENT_AUTH_VERIFY = "verify"
+const CLIENT_AUTH_REQUIRE_AND_VERIFY = "require-and-verify"
+
+const SELF_SIGNED_VALIDITY = 365 * 24 * time.Hour
+
+// GenerateSelfSigned creates an ECDSA certificate valid for the hosts (dns
+// names or ips), localhost included.
+func GenerateSelfSigned(organization string, hosts []string) (tls.Certificate, error) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, errors.New("self-signed key generation failed: " + err.Error())
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, errors.New("self-signed serial number generation failed: " + err.Error())
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{organization}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(SELF_SIGNED_VALIDITY),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, host := range append([]string{"localhost", "127.0.0.1", "::1"}, hosts...) {
+
+		if host == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			if !ip.IsUnspecified() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, errors.New("self-signed certificate creation failed: " + err.Error())
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// ParseClientAuth converts a client certificate policy, an empty policy
+// means none.
+func ParseClientAuth(policy string) (tls.ClientAuthType, error) {
+
+	switch strings.ToLower(policy) {
+	case "", CLIENT_AUTH_NONE:
+		return tls.NoClientCert, nil
+	case CLIENT_AUTH_REQUEST:
+		return tls.RequestClientCert, nil
+	case CLIENT_AUTH_REQUIRE:
+		return tls.RequireAnyClientCert, nil
+	case CLIENT_AUTH_VERIFY:
+		return tls.VerifyClientCertIfGiven, nil
+	case CLIENT_AUTH_REQUIRE_AND_VERIFY:
+		return tls.RequireAndVerifyClientCert, nil
+	}
+
+	return tls.NoClientCert, errors.New("tls client auth '" + policy + "' is not handled by Alfred, use one of: " +
+		strings.Join([]string{CLIENT_AUTH_NONE, CLIENT_AUTH_REQUEST, CLIENT_AUTH_REQUIRE, CLIENT_AUTH_VERIFY, CLIENT_AUTH_REQUIRE_AND_VERIFY}, ", "))
+}
+
+// LoadCertPool reads the PEM certificates of a file.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, errors.New("no PEM certificate found in " + path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certs
+
+import (
+	"alfred/pkg/request"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+
+	cert, err := GenerateSelfSigned("alfred", []string{"0.0.0.0", "mock.local", "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("generation failed with error: %v", err)
+	}
+
+	if err := cert.Leaf.VerifyHostname("mock.local"); err != nil {
+		t.Errorf("certificate not valid for mock.local: %v", err)
+	}
+
+	if err := cert.Leaf.VerifyHostname("10.0.0.1"); err != nil {
+		t.Errorf("certificate not valid for 10.0.0.1: %v", err)
+	}
+
+	if len(cert.Leaf.IPAddresses) != 3 {
+		t.Errorf("ip addresses are: %v, want: 127.0.0.1, ::1, 10.0.0.1", cert.Leaf.IPAddresses)
+	}
+}
+
+func TestParseClientAuth(t *testing.T) {
+
+	tests := map[string]tls.ClientAuthType{
+		"":                   tls.NoClientCert,
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify":             tls.VerifyClientCertIfGiven,
+		"Require-And-Verify": tls.RequireAndVerifyClientCert,
+	}
+
+	for policy, want := range tests {
+
+		got, err := ParseClientAuth(policy)
+		if err != nil || got != want {
+			t.Errorf("policy '%s' is: %v, %v, want: %v", policy, got, err, want)
+		}
+	}
+
+	_, err := ParseClientAuth("always")
+	if err == nil {
+		t.Errorf("unknown policy should fail")
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+
+	serverCert, err := GenerateSelfSigned("alfred", nil)
+	if err != nil {
+		t.Fatalf("generation failed with error: %v", err)
+	}
+
+	clientCert, err := GenerateSelfSigned("client-org", nil)
+	if err != nil {
+		t.Fatalf("generation failed with error: %v", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert.Leaf)
+
+	var got *request.ClientCert
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = request.GetClientCert(r.TLS)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(serverCert.Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed with error: %v", err)
+	}
+	res.Body.Close()
+
+	if got == nil {
+		t.Fatalf("client certificate not found")
+	}
+
+	if !got.Verified || got.Organization[0] != "client-org" || got.CommonName != "localhost" {
+		t.Errorf("client certificate is: %+v", got)
+	}
+}
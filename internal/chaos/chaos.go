@@ -0,0 +1,129 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chaos
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Chaos profile applied to all mocks, rates are request shares between 0 and 1
+type Profile struct {
+	Enable       bool    `json:"enable"`
+	LatencyRate  float64 `json:"latency-rate"`
+	MinLatencyMs int     `json:"min-latency-ms"`
+	MaxLatencyMs int     `json:"max-latency-ms"`
+	ErrorRate    float64 `json:"error-rate"`
+	ErrorStatus  int     `json:"error-status"`
+	DropRate     float64 `json:"drop-rate"`
+}
+
+// Faults to inject in a request
+type Fault struct {
+	Delay  time.Duration
+	Status int
+	Drop   bool
+}
+
+const DEFAULT_ERROR_STATUS = http.StatusServiceUnavailable
+
+var (
+	profile      Profile
+	profileMutex sync.RWMutex
+)
+
+// SetProfile replaces the current profile, it's kept on mocks reload.
+func SetProfile(p Profile) error {
+
+	for name, rate := range map[string]float64{"latency-rate": p.LatencyRate, "error-rate": p.ErrorRate, "drop-rate": p.DropRate} {
+		if rate < 0 || rate > 1 {
+			return errors.New("chaos " + name + " must be between 0 and 1, got " + strconv.FormatFloat(rate, 'f', -1, 64))
+		}
+	}
+
+	if p.MinLatencyMs < 0 || p.MaxLatencyMs < 0 {
+		return errors.New("chaos latencies can't be negative")
+	}
+
+	if p.ErrorStatus == 0 {
+		p.ErrorStatus = DEFAULT_ERROR_STATUS
+	}
+
+	if p.ErrorStatus < 100 || p.ErrorStatus > 599 {
+		return errors.New("chaos error-status " + strconv.Itoa(p.ErrorStatus) + " is not a valid http status")
+	}
+
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	profile = p
+
+	return nil
+}
+
+func GetProfile() Profile {
+
+	profileMutex.RLock()
+	defer profileMutex.RUnlock()
+
+	return profile
+}
+
+// Enable toggles the current profile.
+func Enable(enable bool) {
+
+	profileMutex.Lock()
+	defer profileMutex.Unlock()
+	profile.Enable = enable
+}
+
+// GetFault draws the faults of a request, none if chaos is disabled. A
+// dropped request is neither delayed nor answered.
+func GetFault() Fault {
+
+	p := GetProfile()
+
+	var fault Fault
+
+	if !p.Enable {
+		return fault
+	}
+
+	if p.DropRate > 0 && rand.Float64() < p.DropRate {
+		fault.Drop = true
+		return fault
+	}
+
+	if p.LatencyRate > 0 && rand.Float64() < p.LatencyRate {
+
+		delayMs := p.MinLatencyMs
+		if p.MaxLatencyMs > p.MinLatencyMs {
+			delayMs += rand.Intn(p.MaxLatencyMs - p.MinLatencyMs)
+		}
+
+		fault.Delay = time.Duration(delayMs) * time.Millisecond
+	}
+
+	if p.ErrorRate > 0 && rand.Float64() < p.ErrorRate {
+		fault.Status = p.ErrorStatus
+	}
+
+	return fault
+}
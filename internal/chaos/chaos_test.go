@@ -0,0 +1,71 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chaos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetProfile(t *testing.T) {
+
+	err := SetProfile(Profile{Enable: true, ErrorRate: 1.5})
+	if err == nil {
+		t.Errorf("an error rate above 1 should fail")
+	}
+
+	err = SetProfile(Profile{Enable: true, ErrorStatus: 42})
+	if err == nil {
+		t.Errorf("an invalid error status should fail")
+	}
+
+	err = SetProfile(Profile{Enable: true, ErrorRate: 0.5})
+	if err != nil {
+		t.Fatalf("profile set failed with error: %v", err)
+	}
+
+	if GetProfile().ErrorStatus != DEFAULT_ERROR_STATUS {
+		t.Errorf("error status is: %d, want: %d", GetProfile().ErrorStatus, DEFAULT_ERROR_STATUS)
+	}
+}
+
+func TestGetFault(t *testing.T) {
+
+	err := SetProfile(Profile{Enable: true, LatencyRate: 1, MinLatencyMs: 10, MaxLatencyMs: 20, ErrorRate: 1, ErrorStatus: 500})
+	if err != nil {
+		t.Fatalf("profile set failed with error: %v", err)
+	}
+
+	fault := GetFault()
+	if fault.Drop || fault.Status != 500 || fault.Delay < 10*time.Millisecond || fault.Delay >= 20*time.Millisecond {
+		t.Errorf("fault is: %+v", fault)
+	}
+
+	Enable(false)
+	if fault := GetFault(); fault != (Fault{}) {
+		t.Errorf("disabled chaos fault is: %+v", fault)
+	}
+
+	err = SetProfile(Profile{Enable: true, DropRate: 1, ErrorRate: 1})
+	if err != nil {
+		t.Fatalf("profile set failed with error: %v", err)
+	}
+
+	if fault := GetFault(); !fault.Drop || fault.Status != 0 {
+		t.Errorf("drop fault is: %+v", fault)
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/latency"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"alfred/internal/sandbox"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const USAGE = `Usage: alfred.go [--profile <name>...] [command]
+
+Without command, Alfred starts to serve the mocks, Sir. The mocks tagged with
+a profile are served, before the untagged mocks, only when it's selected.
+
+Commands:
+  import openapi [-o <dir>] <file>   generate mocks from an OpenAPI 3 specification (json or yaml)
+  import postman [-o <dir>] <file>   generate mocks from a Postman collection, using saved examples
+  import har [-o <dir>] <file>       generate mocks from a HAR capture (browser or proxy recording)
+  validate [<dir>...]                check the mock files, of the configured mocks folders by default,
+                                     and the function files
+  list                               print the routes of the mocks served
+  match --request <request.json> [--service <name>]
+                                     show the mock serving the request, and why the others reject it
+  help                               show this help
+
+Generated mocks are written by default in the configured mocks folder, inside
+a sub folder named as the imported file.
+`
+
+// Environment variable of the active profiles
+const PROFILES_ENV = "ALFRED_CORE_PROFILES"
+
+// Run executes an alfred command line, it returns the process exit code.
+func Run(args []string) int {
+
+	configuration, err := conf.GetConfiguration()
+	if err != nil {
+		fmt.Println("fatal error, config file: " + err.Error())
+		return 1
+	}
+
+	//only errors are logged by commands
+	log.InitLogger(configuration.Alfred.Name, false, configuration.Alfred.Version)
+	_ = log.SetLevel(log.LOG_LEVEL_ERROR)
+
+	mock.SetProfiles(configuration.Alfred.Core.Profiles)
+
+	err = latency.Configure(latencyProfiles(configuration.Alfred.LatencyProfiles))
+	if err != nil {
+		fmt.Println("fatal error, config file: " + err.Error())
+		return 1
+	}
+
+	err = sandbox.Configure(sandboxPolicies(configuration.Alfred.Sandbox.Policies), configuration.Alfred.Sandbox.DefaultPolicy)
+	if err != nil {
+		fmt.Println("fatal error, config file: " + err.Error())
+		return 1
+	}
+
+	switch args[0] {
+	case "import":
+		return importCommand(configuration, args[1:])
+	case "validate":
+		return validateCommand(configuration, args[1:])
+	case "list":
+		return listCommand(configuration, args[1:])
+	case "match":
+		return matchCommand(configuration, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(USAGE)
+		return 0
+	}
+
+	fmt.Println("unknown command '" + args[0] + "'")
+	fmt.Print(USAGE)
+	return 2
+}
+
+// ParseProfileFlags removes the leading --profile flags of the arguments.
+// Their profiles are set in the ALFRED_CORE_PROFILES environment variable,
+// so they are kept when the configuration is reloaded.
+func ParseProfileFlags(args []string) ([]string, error) {
+
+	var profiles []string
+
+	for len(args) > 0 {
+
+		name, found := strings.CutPrefix(args[0], "--profile=")
+		if !found && args[0] == "--profile" {
+
+			if len(args) < 2 {
+				return nil, errors.New("--profile needs a profile name")
+			}
+			name, args = args[1], args[1:]
+			found = true
+		}
+
+		if !found {
+			break
+		}
+
+		profiles = append(profiles, strings.Split(name, ",")...)
+		args = args[1:]
+	}
+
+	if len(profiles) > 0 {
+
+		err := os.Setenv(PROFILES_ENV, strings.Join(profiles, ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
+}
+
+// Write mocks as json files. Each mock is built before being written, to be
+// sure Alfred can load it.
+func writeMocks(dir string, mocks []mock.Mock) error {
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	fileNames := map[string]int{}
+
+	for _, m := range mocks {
+
+		data, err := marshalMock(m)
+		if err != nil {
+			return err
+		}
+
+		_, err = mock.BuildMockFromJson(data)
+		if err != nil {
+			return fmt.Errorf("generated mock '%s' not valid: %w", m.GetName(), err)
+		}
+
+		fileName := getMockFileName(m.GetName())
+		fileNames[fileName]++
+		if fileNames[fileName] > 1 {
+			fileName += "-" + strconv.Itoa(fileNames[fileName])
+		}
+
+		filePath := filepath.Join(dir, fileName+".json")
+		err = os.WriteFile(filePath, data, 0644)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("mock '" + m.GetName() + "' written in " + filePath)
+	}
+
+	return nil
+}
+
+// Mock fields used at runtime only, not written
+var runtimeMockFields = []string{"UrlTransformed", "RegexUrl"}
+
+// Mock fields holding user values, an empty or zero value in them is meant
+var verbatimMockFields = map[string]bool{"body": true, "headers": true, "query": true, "fields": true, "variables": true, "claims": true, "compression": true}
+
+// marshalMock writes the mock without its empty and runtime fields, the
+// request and response bodies are kept as is
+func marshalMock(m mock.Mock) ([]byte, error) {
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&fields)
+	if err != nil {
+		return nil, err
+	}
+
+	removeEmptyFields(fields)
+
+	if request, isObject := fields["request"].(map[string]interface{}); isObject {
+		for _, name := range runtimeMockFields {
+			delete(request, name)
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+
+	err = encoder.Encode(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// removeEmptyFields removes the null, empty and zero values of an object and
+// of its nested objects, the verbatim fields are only removed when null
+func removeEmptyFields(fields map[string]interface{}) {
+
+	for name, value := range fields {
+
+		if verbatimMockFields[name] {
+			if value == nil {
+				delete(fields, name)
+			}
+			continue
+		}
+
+		switch v := value.(type) {
+		case nil:
+			delete(fields, name)
+		case string:
+			if v == "" {
+				delete(fields, name)
+			}
+		case json.Number:
+			if v == "0" {
+				delete(fields, name)
+			}
+		case bool:
+			if !v {
+				delete(fields, name)
+			}
+		case []interface{}:
+			for _, item := range v {
+				if object, isObject := item.(map[string]interface{}); isObject {
+					removeEmptyFields(object)
+				}
+			}
+			if len(v) == 0 {
+				delete(fields, name)
+			}
+		case map[string]interface{}:
+			removeEmptyFields(v)
+			if len(v) == 0 {
+				delete(fields, name)
+			}
+		}
+	}
+}
+
+func getMockFileName(mockName string) string {
+
+	fileName := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, mockName)
+
+	return strings.Trim(fileName, "-.")
+}
+
+// Default output folder: the configured mocks folder, inside a sub folder
+// named as the imported file.
+func getDefaultOutputDir(configuration conf.Config, filePath string) string {
+
+	fileName := filepath.Base(filePath)
+
+	return filepath.Join(configuration.Alfred.Core.MocksDir, strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+}
+
+// latencyProfiles maps the latency profiles configuration section.
+func latencyProfiles(configs []conf.LatencyProfileConfig) []latency.Profile {
+
+	profiles := make([]latency.Profile, 0, len(configs))
+	for _, c := range configs {
+		profiles = append(profiles, latency.Profile{Name: c.Name, Distribution: c.Distribution, Min: c.Min, P50: c.P50, P90: c.P90, P95: c.P95, P99: c.P99, Max: c.Max})
+	}
+
+	return profiles
+}
+
+// sandboxPolicies maps the policies of the sandbox configuration section.
+func sandboxPolicies(configs []conf.SandboxPolicyConfig) []sandbox.Policy {
+
+	policies := make([]sandbox.Policy, 0, len(configs))
+	for _, c := range configs {
+		policies = append(policies, sandbox.Policy{Name: c.Name, Files: c.Files, Allow: c.Allow, MaxStackDepth: c.MaxStackDepth, Timeout: c.Timeout})
+	}
+
+	return policies
+}
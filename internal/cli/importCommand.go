@@ -0,0 +1,118 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/har"
+	"alfred/internal/mock"
+	"alfred/internal/openapi"
+	"alfred/internal/postman"
+	"flag"
+	"fmt"
+)
+
+const (
+	IMPORT_OPENAPI = "openapi"
+	IMPORT_POSTMAN = "postman"
+	IMPORT_HAR     = "har"
+)
+
+func importCommand(configuration conf.Config, args []string) int {
+
+	if len(args) < 1 {
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	format := args[0]
+
+	flags := flag.NewFlagSet("import "+format, flag.ContinueOnError)
+	outputDir := flags.String("o", "", "mocks output folder")
+
+	err := flags.Parse(args[1:])
+	if err != nil || flags.NArg() != 1 {
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	filePath := flags.Arg(0)
+
+	var mocks []mock.Mock
+
+	switch format {
+	case IMPORT_OPENAPI:
+		mocks, err = importOpenapi(filePath)
+	case IMPORT_POSTMAN:
+		mocks, err = importPostman(filePath)
+	case IMPORT_HAR:
+		mocks, err = importHar(filePath)
+	default:
+		fmt.Println("unknown import format '" + format + "'")
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Println("import failed: " + err.Error())
+		return 1
+	}
+
+	if *outputDir == "" {
+		*outputDir = getDefaultOutputDir(configuration, filePath)
+	}
+
+	err = writeMocks(*outputDir, mocks)
+	if err != nil {
+		fmt.Println("import failed: " + err.Error())
+		return 1
+	}
+
+	fmt.Println(fmt.Sprint(len(mocks)) + " mock(s) imported from " + filePath + ", restart Alfred to use them, Sir.")
+
+	return 0
+}
+
+func importOpenapi(filePath string) ([]mock.Mock, error) {
+
+	doc, err := openapi.LoadSpec(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return openapi.GenerateMocks(doc)
+}
+
+func importPostman(filePath string) ([]mock.Mock, error) {
+
+	collection, err := postman.LoadCollection(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return postman.GenerateMocks(collection)
+}
+
+func importHar(filePath string) ([]mock.Mock, error) {
+
+	capture, err := har.LoadHar(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return har.GenerateMocks(capture)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cli
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/mock"
+	"alfred/internal/wiremock"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Mocks of the core or of a service, as served by Alfred
+type servedCollection struct {
+	name       string
+	listen     conf.ListenConfig
+	hosts      []string
+	collection mock.MockCollection
+}
+
+// loadCollections loads the core mocks, wiremock mappings included, and the
+// services mocks.
+func loadCollections(configuration conf.Config) ([]servedCollection, error) {
+
+	core, err := mock.LoadMockCollectionFromFolder(configuration.Alfred.Core.MocksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if configuration.Alfred.Core.WiremockDir != "" {
+
+		wiremockMocks, err := wiremock.CreateMocksFromFolder(configuration.Alfred.Core.WiremockDir)
+		if err != nil {
+			return nil, errors.New("wiremock mappings: " + err.Error())
+		}
+		core.Mocks = append(core.Mocks, wiremockMocks...)
+	}
+
+	collections := []servedCollection{{name: "core", listen: configuration.Alfred.Core.Listen, collection: core}}
+
+	for _, service := range configuration.Alfred.Services {
+
+		collection, err := mock.LoadMockCollectionFromFolder(service.MocksDir)
+		if err != nil {
+			return nil, errors.New("service " + service.Name + ": " + err.Error())
+		}
+
+		listen := service.Listen
+		if listen.Port == "" {
+			listen = configuration.Alfred.Core.Listen
+		}
+
+		collections = append(collections, servedCollection{name: service.Name, listen: listen, hosts: service.Hosts, collection: collection})
+	}
+
+	return collections, nil
+}
+
+func listCommand(configuration conf.Config, args []string) int {
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() != 0 {
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	collections, err := loadCollections(configuration)
+	if err != nil {
+		fmt.Println("mocks load failed: " + err.Error())
+		return 1
+	}
+
+	for _, c := range collections {
+
+		title := c.name + " - " + c.listen.Ip + ":" + c.listen.Port
+		if len(c.hosts) > 0 {
+			title += " - hosts " + strings.Join(c.hosts, ", ")
+		}
+		fmt.Println(title)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(writer, "  METHOD\tURL\tMOCKS (first matching)")
+
+		for _, route := range c.collection.GetRoutes() {
+
+			var names []string
+			for _, m := range route.ActiveMocks() {
+				names = append(names, m.GetName())
+			}
+			for _, m := range route.InactiveMocks() {
+				names = append(names, m.GetName()+" (inactive, tags "+strings.Join(m.Tags, ", ")+")")
+			}
+
+			m := route.Mocks[0]
+			fmt.Fprintln(writer, "  "+m.GetRequestMethod()+"\t"+m.GetRequestRoute()+"\t"+strings.Join(names, ", "))
+		}
+
+		for _, m := range c.collection.GetSocketMocks() {
+			fmt.Fprintln(writer, "  "+strings.ToUpper(m.Socket.Protocol)+"\t"+m.Socket.GetAddress()+"\t"+m.GetName())
+		}
+
+		writer.Flush()
+		fmt.Println()
+	}
+
+	return 0
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cli
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/mock"
+	"alfred/pkg/request"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func matchCommand(configuration conf.Config, args []string) int {
+
+	flags := flag.NewFlagSet("match", flag.ContinueOnError)
+	requestFile := flags.String("request", "", "json file of the request: method, url, headers, query and body")
+	service := flags.String("service", "core", "service of the mocks")
+
+	err := flags.Parse(args)
+	if err != nil || *requestFile == "" || flags.NArg() != 0 {
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	r, body, err := readRequestFile(*requestFile)
+	if err != nil {
+		fmt.Println("request file error: " + err.Error())
+		return 1
+	}
+
+	collections, err := loadCollections(configuration)
+	if err != nil {
+		fmt.Println("mocks load failed: " + err.Error())
+		return 1
+	}
+
+	var collection *mock.MockCollection
+	for _, c := range collections {
+		if c.name == *service {
+			collection = &c.collection
+		}
+	}
+
+	if collection == nil {
+		fmt.Println("unknown service '" + *service + "'")
+		return 1
+	}
+
+	fmt.Println("request " + r.Method + " " + r.URL.Path)
+
+	route, ok := collection.FindRoute(r.Method, r.URL.Path)
+	if !ok {
+		fmt.Println("no mock for " + r.Method + " " + r.URL.Path + ", the mock list is answered")
+		return 1
+	}
+
+	fmt.Println("route " + route.Mocks[0].GetRequestMethod() + " " + route.Mocks[0].GetRequestRoute() + ", mocks tried in order:")
+
+	var served *mock.Mock
+
+	for _, m := range route.ActiveMocks() {
+
+		if served != nil {
+			fmt.Println("  - " + m.GetName() + ": not tried")
+			continue
+		}
+
+		err := m.Match(r, body)
+		if err != nil {
+			fmt.Println("  - " + m.GetName() + ": rejected, " + err.Error())
+			continue
+		}
+
+		fmt.Println("  - " + m.GetName() + ": matched")
+		served = m
+	}
+
+	for _, m := range route.InactiveMocks() {
+		fmt.Println("  - " + m.GetName() + ": not served, none of its tags " + strings.Join(m.Tags, ", ") + " is an active profile")
+	}
+
+	if served == nil {
+		fmt.Println("no mock matching the request, the mock list is answered")
+		return 1
+	}
+
+	fmt.Println("served by mock '" + served.GetName() + "'")
+
+	return 0
+}
+
+// readRequestFile builds the request of a json file, in the format of the
+// requests of the js functions.
+func readRequestFile(filePath string) (*http.Request, []byte, error) {
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var req request.Req
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Method == "" || req.Url == "" {
+		return nil, nil, errors.New("request method and url are required")
+	}
+
+	r, err := http.NewRequest(strings.ToUpper(req.Method), req.Url, strings.NewReader(req.Body))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, value := range req.Headers {
+		r.Header.Set(name, value)
+	}
+
+	if host := r.Header.Get("Host"); host != "" {
+		r.Host = host
+	}
+
+	query := r.URL.Query()
+	for name, value := range req.Query {
+		query.Set(name, value)
+	}
+	r.URL.RawQuery = query.Encode()
+
+	return r, []byte(req.Body), nil
+}
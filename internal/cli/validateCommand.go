@@ -0,0 +1,182 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cli
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/function"
+	"alfred/internal/mock"
+	"alfred/pkg/files"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Checks of the mock and function files, every error is reported. Warnings
+// don't fail the validation.
+type validation struct {
+	errors   int
+	warnings int
+}
+
+func (v *validation) report(filePath string, err error) {
+
+	v.errors++
+	fmt.Println("error: " + filePath + ": " + err.Error())
+}
+
+func (v *validation) warn(filePath string, msg string) {
+
+	v.warnings++
+	fmt.Println("warning: " + filePath + ": " + msg)
+}
+
+func validateCommand(configuration conf.Config, args []string) int {
+
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+
+	err := flags.Parse(args)
+	if err != nil {
+		fmt.Print(USAGE)
+		return 2
+	}
+
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = append(dirs, configuration.Alfred.Core.MocksDir)
+		for _, service := range configuration.Alfred.Services {
+			dirs = append(dirs, service.MocksDir)
+		}
+	}
+
+	v := &validation{}
+
+	//modules required by the function files
+	modulesDir := configuration.Alfred.Core.ModulesDir
+	if modulesDir == "" {
+		modulesDir = configuration.Alfred.Core.FunctionsDir
+	}
+	function.SetModulesRoot(modulesDir)
+
+	functionFiles := v.validateFunctions(configuration.Alfred.Core.FunctionsDir)
+
+	mocksCount := 0
+	for _, dir := range dirs {
+		mocksCount += v.validateMocks(dir, functionFiles)
+	}
+
+	fmt.Println(fmt.Sprint(mocksCount) + " mock(s) and " + fmt.Sprint(len(functionFiles)) + " function file(s) checked, " + fmt.Sprint(v.errors) + " error(s), " + fmt.Sprint(v.warnings) + " warning(s).")
+
+	if v.errors > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// validateFunctions compiles the function files, it returns the names of the
+// files found.
+func (v *validation) validateFunctions(dir string) map[string]bool {
+
+	functionFiles := map[string]bool{}
+
+	matches, err := files.FindFiles(dir, "*.js", "*.ts")
+	if err != nil {
+		// no function file
+		return functionFiles
+	}
+
+	for _, filePath := range matches {
+
+		functionFiles[filepath.Base(filePath)] = true
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			v.report(filePath, err)
+			continue
+		}
+
+		_, err = function.CreateFunction(filepath.Base(filePath), data)
+		if err != nil {
+			v.report(filePath, err)
+		}
+	}
+
+	return functionFiles
+}
+
+// validateMocks builds the mock files of the folder of a mock collection, it
+// returns the number of mocks checked.
+func (v *validation) validateMocks(dir string, functionFiles map[string]bool) int {
+
+	matches, err := files.FindAllFiles(dir, "*.json")
+	if err != nil {
+		v.report(dir, err)
+		return 0
+	}
+
+	var collection mock.MockCollection
+	mockFiles := map[*mock.Mock]string{}
+
+	for _, filePath := range matches {
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			v.report(filePath, err)
+			continue
+		}
+
+		m, err := mock.BuildMockFromJsonFile(data, filePath)
+		if err != nil {
+			v.report(filePath, err)
+			continue
+		}
+
+		err = checkUnknownFields(data)
+		if err != nil {
+			v.warn(filePath, err.Error()+", it is ignored")
+		}
+
+		if m.HasFunctionFile() && !functionFiles[m.FunctionFile] {
+			v.report(filePath, errors.New("function file '"+m.FunctionFile+"' not found"))
+		}
+
+		collection.Mocks = append(collection.Mocks, &m)
+		mockFiles[&m] = filePath
+	}
+
+	for _, duplicate := range collection.GetDuplicateMocks() {
+		v.report(mockFiles[duplicate.Shadowed], errors.New("mock '"+duplicate.Shadowed.GetName()+"' is never used, mock '"+duplicate.Mock.GetName()+"' ("+mockFiles[duplicate.Mock]+") has the same method, url and matchers"))
+	}
+
+	return len(collection.Mocks)
+}
+
+// checkUnknownFields reports the attributes ignored by Alfred, misspelled ones
+// for example.
+func checkUnknownFields(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var m mock.Mock
+
+	return decoder.Decode(&m)
+}
@@ -17,6 +17,8 @@
 package conf
 
 import (
+	"alfred/internal/env"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -31,21 +33,82 @@ const (
 	DEFAULT_MOCKS_DIR                    = "user-files/mocks/"
 	DEFAULT_FUNCTIONS_DIR                = "user-files/functions/"
 	DEFAULT_BODIES_DIR                   = "user-files/body-files/"
+	DEFAULT_SCHEMAS_DIR                  = "user-files/schemas/"
+	DEFAULT_SHUTDOWN_TIMEOUT             = "5s"
 	DEFAULT_LISTEN_INTERFACE             = "0.0.0.0"
 	DEFAULT_LISTEN_PORT                  = "8080"
 	DEFAULT_TLS_ENABLED                  = false
 	DEFAULT_TLS_CERT_PATH                = "user-files/tls/cert.pem"
 	DEFAULT_TLS_KEY_PATH                 = "user-files/tls/key.pem"
+	DEFAULT_TLS_SELF_SIGNED              = false
+	DEFAULT_TLS_CLIENT_AUTH              = "none"
+	DEFAULT_TLS_CLIENT_CA_PATH           = ""
 	DEFAULT_LOG_LEVEL                    = "info"
+	DEFAULT_ACCESS_LOG_ENABLE            = false
+	DEFAULT_ACCESS_LOG_FORMAT            = "json"
+	DEFAULT_ACCESS_LOG_OUTPUT            = "stdout"
+	DEFAULT_ACCESS_LOG_FILE_PATH         = "logs/access.log"
+	DEFAULT_ACCESS_LOG_MAX_SIZE_MB       = 100
+	DEFAULT_ACCESS_LOG_MAX_BACKUPS       = 5
+	DEFAULT_ACCESS_LOG_MAX_AGE_DAYS      = 30
+	DEFAULT_ACCESS_LOG_SYSLOG_ADDRESS    = ""
+	DEFAULT_ACCESS_LOG_SYSLOG_TAG        = "alfred"
 	DEFAULT_PROMETHEUS_ENABLE            = false
 	DEFAULT_PROMETHEUS_PATH              = "/metrics"
 	DEFAULT_PROMETHEUS_SLOW_TIME_SECONDS = 10
 	DEFAULT_PROMETHEUS_LISTEN_PORT       = ""
 	DEFAULT_PROMETHEUS_LISTEN_IP         = ""
 	DEFAULT_TRACING_OTLP_ENDPOINT        = ""
+	DEFAULT_TRACING_OTLP_HEADERS         = ""
 	DEFAULT_TRACING_INSECURE             = true
 	DEFAULT_TRACING_SAMPLER              = "parentbased_traceidratio"
 	DEFAULT_TRACING_SAMPLER_ARGS         = "1.0"
+	DEFAULT_OPENAPI_SPEC_FILE            = ""
+	DEFAULT_OPENAPI_VALIDATION_MODE      = "log"
+	DEFAULT_CHAOS_ENABLE                 = false
+	DEFAULT_CHAOS_LATENCY_RATE           = 0.0
+	DEFAULT_CHAOS_MIN_LATENCY_MS         = 0
+	DEFAULT_CHAOS_MAX_LATENCY_MS         = 0
+	DEFAULT_CHAOS_ERROR_RATE             = 0.0
+	DEFAULT_CHAOS_ERROR_STATUS           = 503
+	DEFAULT_CHAOS_DROP_RATE              = 0.0
+	DEFAULT_RATE_LIMIT_ENABLE            = false
+	DEFAULT_RATE_LIMIT_REQUESTS          = 100
+	DEFAULT_RATE_LIMIT_PERIOD            = "1s"
+	DEFAULT_RATE_LIMIT_BURST             = 0
+	DEFAULT_JWT_ALGORITHM                = "HS256"
+	DEFAULT_JWT_SECRET                   = ""
+	DEFAULT_JWT_PRIVATE_KEY_PATH         = ""
+	DEFAULT_JWT_PUBLIC_KEY_PATH          = ""
+	DEFAULT_JWT_ISSUER                   = ""
+	DEFAULT_JWT_EXPIRES_IN               = "1h"
+	DEFAULT_OIDC_ENABLE                  = false
+	DEFAULT_OIDC_PATH                    = "/oidc"
+	DEFAULT_OIDC_ISSUER                  = ""
+	DEFAULT_OIDC_PRIVATE_KEY_PATH        = ""
+	DEFAULT_OIDC_ACCESS_TOKEN_LIFETIME   = "1h"
+	DEFAULT_OIDC_ID_TOKEN_LIFETIME       = "1h"
+	DEFAULT_OIDC_REFRESH_TOKEN_LIFETIME  = "24h"
+	DEFAULT_FAKER_SEED                   = 0
+	DEFAULT_COMPRESSION_ENABLE           = false
+	DEFAULT_COMPRESSION_MIN_SIZE         = 1024
+	DEFAULT_CORS_ENABLE                  = false
+	DEFAULT_CORS_ALLOW_CREDENTIALS       = false
+	DEFAULT_CORS_MAX_AGE                 = 600
+	DEFAULT_UNMATCHED_POLICY             = "mock-list"
+	DEFAULT_STORE_TYPE                   = "memory"
+	DEFAULT_STORE_PATH                   = "user-files/store/alfred.db"
+	DEFAULT_STORE_REDIS_ADDRESS          = "localhost:6379"
+	DEFAULT_STORE_REDIS_PREFIX           = "alfred"
+	DEFAULT_DATABASE_MAX_OPEN_CONNS      = 5
+	DEFAULT_DATABASE_QUERY_TIMEOUT       = "5s"
+	DEFAULT_KAFKA_CLIENT_ID              = "alfred"
+	DEFAULT_MQTT_CLIENT_ID               = "alfred"
+	DEFAULT_MQTT_QOS                     = 0
+	DEFAULT_SMTP_ENABLE                  = false
+	DEFAULT_SMTP_LISTEN_IP               = ""
+	DEFAULT_SMTP_LISTEN_PORT             = "2525"
+	DEFAULT_SMTP_MAX_EMAILS              = 1000
 )
 
 var DefaultConfig = Config{
@@ -59,13 +122,30 @@ var DefaultConfig = Config{
 			MocksDir:     DEFAULT_MOCKS_DIR,
 			FunctionsDir: DEFAULT_FUNCTIONS_DIR,
 			BodiesDir:    DEFAULT_BODIES_DIR,
+			SchemasDir:   DEFAULT_SCHEMAS_DIR,
 			Listen: ListenConfig{
 				Ip:          DEFAULT_LISTEN_INTERFACE,
 				Port:        DEFAULT_LISTEN_PORT,
 				TlsEnabled:  DEFAULT_TLS_ENABLED,
 				TlsCertPath: DEFAULT_TLS_CERT_PATH,
 				TlsKeyPath:  DEFAULT_TLS_KEY_PATH,
+
+				TlsSelfSigned:   DEFAULT_TLS_SELF_SIGNED,
+				TlsClientAuth:   DEFAULT_TLS_CLIENT_AUTH,
+				TlsClientCaPath: DEFAULT_TLS_CLIENT_CA_PATH,
 			},
+			ShutdownTimeout: DEFAULT_SHUTDOWN_TIMEOUT,
+		},
+		AccessLog: AccessLogConfig{
+			Enable:        DEFAULT_ACCESS_LOG_ENABLE,
+			Format:        DEFAULT_ACCESS_LOG_FORMAT,
+			Output:        DEFAULT_ACCESS_LOG_OUTPUT,
+			FilePath:      DEFAULT_ACCESS_LOG_FILE_PATH,
+			MaxSizeMb:     DEFAULT_ACCESS_LOG_MAX_SIZE_MB,
+			MaxBackups:    DEFAULT_ACCESS_LOG_MAX_BACKUPS,
+			MaxAgeDays:    DEFAULT_ACCESS_LOG_MAX_AGE_DAYS,
+			SyslogAddress: DEFAULT_ACCESS_LOG_SYSLOG_ADDRESS,
+			SyslogTag:     DEFAULT_ACCESS_LOG_SYSLOG_TAG,
 		},
 		Prometheus: PrometheusConfig{
 			Enable:          DEFAULT_PROMETHEUS_ENABLE,
@@ -78,10 +158,90 @@ var DefaultConfig = Config{
 		},
 		Tracing: TracingConfig{
 			OtlpEndpoint: DEFAULT_TRACING_OTLP_ENDPOINT,
+			OtlpHeaders:  DEFAULT_TRACING_OTLP_HEADERS,
 			Insecure:     DEFAULT_TRACING_INSECURE,
 			Sampler:      DEFAULT_TRACING_SAMPLER,
 			SamplerArgs:  DEFAULT_TRACING_SAMPLER_ARGS,
 		},
+		Openapi: OpenapiConfig{
+			SpecFile:       DEFAULT_OPENAPI_SPEC_FILE,
+			ValidationMode: DEFAULT_OPENAPI_VALIDATION_MODE,
+		},
+		Chaos: ChaosConfig{
+			Enable:       DEFAULT_CHAOS_ENABLE,
+			LatencyRate:  DEFAULT_CHAOS_LATENCY_RATE,
+			MinLatencyMs: DEFAULT_CHAOS_MIN_LATENCY_MS,
+			MaxLatencyMs: DEFAULT_CHAOS_MAX_LATENCY_MS,
+			ErrorRate:    DEFAULT_CHAOS_ERROR_RATE,
+			ErrorStatus:  DEFAULT_CHAOS_ERROR_STATUS,
+			DropRate:     DEFAULT_CHAOS_DROP_RATE,
+		},
+		RateLimit: RateLimitConfig{
+			Enable:   DEFAULT_RATE_LIMIT_ENABLE,
+			Requests: DEFAULT_RATE_LIMIT_REQUESTS,
+			Period:   DEFAULT_RATE_LIMIT_PERIOD,
+			Burst:    DEFAULT_RATE_LIMIT_BURST,
+		},
+		Jwt: JwtConfig{
+			Algorithm:      DEFAULT_JWT_ALGORITHM,
+			Secret:         DEFAULT_JWT_SECRET,
+			PrivateKeyPath: DEFAULT_JWT_PRIVATE_KEY_PATH,
+			PublicKeyPath:  DEFAULT_JWT_PUBLIC_KEY_PATH,
+			Issuer:         DEFAULT_JWT_ISSUER,
+			ExpiresIn:      DEFAULT_JWT_EXPIRES_IN,
+		},
+		Oidc: OidcConfig{
+			Enable:               DEFAULT_OIDC_ENABLE,
+			Path:                 DEFAULT_OIDC_PATH,
+			Issuer:               DEFAULT_OIDC_ISSUER,
+			PrivateKeyPath:       DEFAULT_OIDC_PRIVATE_KEY_PATH,
+			AccessTokenLifetime:  DEFAULT_OIDC_ACCESS_TOKEN_LIFETIME,
+			IdTokenLifetime:      DEFAULT_OIDC_ID_TOKEN_LIFETIME,
+			RefreshTokenLifetime: DEFAULT_OIDC_REFRESH_TOKEN_LIFETIME,
+		},
+		Faker: FakerConfig{
+			Seed: DEFAULT_FAKER_SEED,
+		},
+		Compression: CompressionConfig{
+			Enable:  DEFAULT_COMPRESSION_ENABLE,
+			MinSize: DEFAULT_COMPRESSION_MIN_SIZE,
+		},
+		Cors: CorsConfig{
+			Enable:           DEFAULT_CORS_ENABLE,
+			AllowedOrigins:   []string{"*"},
+			AllowCredentials: DEFAULT_CORS_ALLOW_CREDENTIALS,
+			MaxAge:           DEFAULT_CORS_MAX_AGE,
+		},
+		Unmatched: UnmatchedConfig{
+			Policy: DEFAULT_UNMATCHED_POLICY,
+		},
+		Store: StoreConfig{
+			Type: DEFAULT_STORE_TYPE,
+			Path: DEFAULT_STORE_PATH,
+			Redis: StoreRedisConfig{
+				Address: DEFAULT_STORE_REDIS_ADDRESS,
+				Prefix:  DEFAULT_STORE_REDIS_PREFIX,
+			},
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns: DEFAULT_DATABASE_MAX_OPEN_CONNS,
+			QueryTimeout: DEFAULT_DATABASE_QUERY_TIMEOUT,
+		},
+		Kafka: KafkaConfig{
+			ClientId: DEFAULT_KAFKA_CLIENT_ID,
+		},
+		Mqtt: MqttConfig{
+			ClientId: DEFAULT_MQTT_CLIENT_ID,
+			Qos:      DEFAULT_MQTT_QOS,
+		},
+		Smtp: SmtpConfig{
+			Enable: DEFAULT_SMTP_ENABLE,
+			Listen: ListenConfig{
+				Ip:   DEFAULT_SMTP_LISTEN_IP,
+				Port: DEFAULT_SMTP_LISTEN_PORT,
+			},
+			MaxEmails: DEFAULT_SMTP_MAX_EMAILS,
+		},
 	},
 }
 
@@ -98,6 +258,9 @@ func mergeConfigs(dst *Config, src Config) error {
 func GetConfiguration() (Config, error) {
 
 	configuration, err := buildConfiguration()
+	if errors.Is(err, env.ErrUnresolved) {
+		return Config{}, err
+	}
 	if err != nil {
 		fmt.Println("{ \"alfred-speaking\" : \"" + strings.Replace(err.Error(), "\"", "\\\"", -1) + ", gona use default configuration, Sir.\" }")
 	}
@@ -118,5 +281,16 @@ func sanitizeConfiguration(configuration Config) Config {
 		configuration.Alfred.Core.MocksDir += "/"
 	}
 
+	for i, service := range configuration.Alfred.Services {
+
+		if service.MocksDir != "" && !strings.HasSuffix(service.MocksDir, "/") {
+			configuration.Alfred.Services[i].MocksDir += "/"
+		}
+
+		if service.Listen.Ip == "" {
+			configuration.Alfred.Services[i].Listen.Ip = configuration.Alfred.Core.Listen.Ip
+		}
+	}
+
 	return configuration
 }
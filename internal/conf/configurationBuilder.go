@@ -17,6 +17,10 @@
 package conf
 
 import (
+	"alfred/internal/env"
+	"bytes"
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -29,9 +33,31 @@ const (
 	//Functions files directory configuration key name.
 	FUNCTIONS_DIR_KEY = "alfred.core.functions-dir"
 
+	//Root of the modules required by functions files key name, the functions
+	//directory if empty.
+	MODULES_DIR_KEY = "alfred.core.modules-dir"
+
 	//Body files directory configuration key name.
 	BODIES_DIR_KEY = "alfred.core.body-files-dir"
 
+	//Schema files directory configuration key name.
+	SCHEMAS_DIR_KEY = "alfred.core.schemas-dir"
+
+	//WireMock root directory (mappings and __files) configuration key name.
+	WIREMOCK_DIR_KEY = "alfred.core.wiremock-dir"
+
+	//Mocks and functions hot reload configuration key name.
+	HOT_RELOAD_KEY = "alfred.core.hot-reload"
+
+	//Active mocks profiles configuration key name.
+	PROFILES_KEY = "alfred.core.profiles"
+
+	//Proxies trusted to forward the client ip configuration key name.
+	TRUSTED_PROXIES_KEY = "alfred.core.trusted-proxies"
+
+	//Time given to the requests in progress at shutdown configuration key name.
+	SHUTDOWN_TIMEOUT_KEY = "alfred.core.shutdown-timeout"
+
 	//Component name configuration key name.
 	NAME_KEY = "alfred.name"
 
@@ -50,9 +76,31 @@ const (
 	LISTEN_TLS_CERT_PATH = "alfred.core.listen.tls-cert-path"
 	LISTEN_TLS_KEY_PATH  = "alfred.core.listen.tls-key-path"
 
+	//Self-signed certificate generated at startup, in place of the certificate files.
+	LISTEN_TLS_SELF_SIGNED = "alfred.core.listen.tls-self-signed"
+
+	//Client certificate (mTLS) policy and authorities key names.
+	LISTEN_TLS_CLIENT_AUTH    = "alfred.core.listen.tls-client-auth"
+	LISTEN_TLS_CLIENT_CA_PATH = "alfred.core.listen.tls-client-ca-path"
+
+	//HTTP/2 over cleartext (h2c), and HTTP/1.1 only listener key names.
+	LISTEN_H2C_ENABLE    = "alfred.core.listen.enable-h2c"
+	LISTEN_HTTP2_DISABLE = "alfred.core.listen.disable-http2"
+
 	//Debug key
 	LOG_LEVEL_KEY = "alfred.log-level"
 
+	//Access log
+	ACCESS_LOG_ENABLE_KEY         = "alfred.access-log.enable"
+	ACCESS_LOG_FORMAT_KEY         = "alfred.access-log.format"
+	ACCESS_LOG_OUTPUT_KEY         = "alfred.access-log.output"
+	ACCESS_LOG_FILE_PATH_KEY      = "alfred.access-log.file-path"
+	ACCESS_LOG_MAX_SIZE_MB_KEY    = "alfred.access-log.max-size-mb"
+	ACCESS_LOG_MAX_BACKUPS_KEY    = "alfred.access-log.max-backups"
+	ACCESS_LOG_MAX_AGE_DAYS_KEY   = "alfred.access-log.max-age-days"
+	ACCESS_LOG_SYSLOG_ADDRESS_KEY = "alfred.access-log.syslog-address"
+	ACCESS_LOG_SYSLOG_TAG_KEY     = "alfred.access-log.syslog-tag"
+
 	//Prometheus
 	PROMETHEUS_ENABLE_KEY            = "alfred.prometheus.enable"
 	PROMETHEUS_PATH_KEY              = "alfred.prometheus.path"
@@ -62,9 +110,124 @@ const (
 
 	//Tracing
 	TRACING_OTLP_ENDPOINT_KEY = "alfred.tracing.otlp-endpoint"
+	TRACING_OTLP_HEADERS_KEY  = "alfred.tracing.otlp-headers"
 	TRACING_INSECURE_KEY      = "alfred.tracing.insecure"
 	TRACING_SAMPLER_KEY       = "alfred.tracing.sampler"
 	TRACING_SAMPLER_ARGS_KEY  = "alfred.tracing.sampler-args"
+
+	//OpenAPI validation
+	OPENAPI_SPEC_FILE_KEY       = "alfred.openapi.spec-file"
+	OPENAPI_VALIDATION_MODE_KEY = "alfred.openapi.validation-mode"
+
+	//Virtual services (own mocks, listener and/or Host headers) key name.
+	SERVICES_KEY = "alfred.services"
+
+	//Chaos
+	CHAOS_ENABLE_KEY         = "alfred.chaos.enable"
+	CHAOS_LATENCY_RATE_KEY   = "alfred.chaos.latency-rate"
+	CHAOS_MIN_LATENCY_MS_KEY = "alfred.chaos.min-latency-ms"
+	CHAOS_MAX_LATENCY_MS_KEY = "alfred.chaos.max-latency-ms"
+	CHAOS_ERROR_RATE_KEY     = "alfred.chaos.error-rate"
+	CHAOS_ERROR_STATUS_KEY   = "alfred.chaos.error-status"
+	CHAOS_DROP_RATE_KEY      = "alfred.chaos.drop-rate"
+
+	//Global rate limit
+	RATE_LIMIT_ENABLE_KEY   = "alfred.rate-limit.enable"
+	RATE_LIMIT_REQUESTS_KEY = "alfred.rate-limit.requests"
+	RATE_LIMIT_PERIOD_KEY   = "alfred.rate-limit.period"
+	RATE_LIMIT_BURST_KEY    = "alfred.rate-limit.burst"
+
+	//JWT helpers of js functions
+	JWT_ALGORITHM_KEY        = "alfred.jwt.algorithm"
+	JWT_SECRET_KEY           = "alfred.jwt.secret"
+	JWT_PRIVATE_KEY_PATH_KEY = "alfred.jwt.private-key-path"
+	JWT_PUBLIC_KEY_PATH_KEY  = "alfred.jwt.public-key-path"
+	JWT_ISSUER_KEY           = "alfred.jwt.issuer"
+	JWT_EXPIRES_IN_KEY       = "alfred.jwt.expires-in"
+
+	//OAuth2/OIDC identity provider
+	OIDC_ENABLE_KEY                 = "alfred.oidc.enable"
+	OIDC_PATH_KEY                   = "alfred.oidc.path"
+	OIDC_ISSUER_KEY                 = "alfred.oidc.issuer"
+	OIDC_PRIVATE_KEY_PATH_KEY       = "alfred.oidc.private-key-path"
+	OIDC_ACCESS_TOKEN_LIFETIME_KEY  = "alfred.oidc.access-token-lifetime"
+	OIDC_ID_TOKEN_LIFETIME_KEY      = "alfred.oidc.id-token-lifetime"
+	OIDC_REFRESH_TOKEN_LIFETIME_KEY = "alfred.oidc.refresh-token-lifetime"
+
+	//Faker seed, 0 for a random one
+	FAKER_SEED_KEY = "alfred.faker.seed"
+
+	//Responses compression negotiated with Accept-Encoding
+	COMPRESSION_ENABLE_KEY   = "alfred.compression.enable"
+	COMPRESSION_MIN_SIZE_KEY = "alfred.compression.min-size"
+
+	//CORS headers and automatic preflight responses
+	CORS_ENABLE_KEY            = "alfred.cors.enable"
+	CORS_ALLOWED_ORIGINS_KEY   = "alfred.cors.allowed-origins"
+	CORS_ALLOWED_METHODS_KEY   = "alfred.cors.allowed-methods"
+	CORS_ALLOWED_HEADERS_KEY   = "alfred.cors.allowed-headers"
+	CORS_EXPOSED_HEADERS_KEY   = "alfred.cors.exposed-headers"
+	CORS_ALLOW_CREDENTIALS_KEY = "alfred.cors.allow-credentials"
+	CORS_MAX_AGE_KEY           = "alfred.cors.max-age"
+
+	//Answer of the requests no mock matched
+	UNMATCHED_POLICY_KEY    = "alfred.unmatched.policy"
+	UNMATCHED_PROXY_URL_KEY = "alfred.unmatched.proxy-url"
+
+	//Request body size, concurrent requests and connections limits
+	LIMITS_MAX_BODY_SIZE_KEY           = "alfred.limits.max-body-size"
+	LIMITS_MAX_CONCURRENT_REQUESTS_KEY = "alfred.limits.max-concurrent-requests"
+	LIMITS_MAX_CONNECTIONS_KEY         = "alfred.limits.max-connections"
+
+	//Headers added to and removed from all responses
+	RESPONSE_HEADERS_DEFAULTS_KEY = "alfred.response-headers.defaults"
+	RESPONSE_HEADERS_REMOVE_KEY   = "alfred.response-headers.remove"
+
+	//Named response time curves used by the mocks
+	LATENCY_PROFILES_KEY = "alfred.latency-profiles"
+
+	//Restrictions of the function files, by file name
+	SANDBOX_DEFAULT_POLICY_KEY = "alfred.sandbox.default-policy"
+	SANDBOX_POLICIES_KEY       = "alfred.sandbox.policies"
+
+	//State of the stateful mocks, memory, bbolt file or redis server
+	STORE_TYPE_KEY = "alfred.store.type"
+	STORE_PATH_KEY = "alfred.store.path"
+
+	STORE_REDIS_ADDRESS_KEY  = "alfred.store.redis.address"
+	STORE_REDIS_PASSWORD_KEY = "alfred.store.redis.password"
+	STORE_REDIS_DB_KEY       = "alfred.store.redis.db"
+	STORE_REDIS_PREFIX_KEY   = "alfred.store.redis.prefix"
+
+	//Test database queried by the js functions: postgres, mysql or sqlite
+	DATABASE_DRIVER_KEY         = "alfred.database.driver"
+	DATABASE_DSN_KEY            = "alfred.database.dsn"
+	DATABASE_MAX_OPEN_CONNS_KEY = "alfred.database.max-open-conns"
+	DATABASE_QUERY_TIMEOUT_KEY  = "alfred.database.query-timeout"
+
+	//Kafka brokers of the mocks messages and consumers
+	KAFKA_BROKERS_KEY   = "alfred.kafka.brokers"
+	KAFKA_CLIENT_ID_KEY = "alfred.kafka.client-id"
+	KAFKA_CONSUMERS_KEY = "alfred.kafka.consumers"
+
+	//MQTT broker of the mocks messages and consumers
+	MQTT_URL_KEY       = "alfred.mqtt.url"
+	MQTT_CLIENT_ID_KEY = "alfred.mqtt.client-id"
+	MQTT_USERNAME_KEY  = "alfred.mqtt.username"
+	MQTT_PASSWORD_KEY  = "alfred.mqtt.password"
+	MQTT_QOS_KEY       = "alfred.mqtt.qos"
+	MQTT_CONSUMERS_KEY = "alfred.mqtt.consumers"
+
+	//AMQP (RabbitMQ) broker of the mocks messages and consumers
+	AMQP_URL_KEY       = "alfred.amqp.url"
+	AMQP_EXCHANGE_KEY  = "alfred.amqp.exchange"
+	AMQP_CONSUMERS_KEY = "alfred.amqp.consumers"
+
+	//SMTP mock server
+	SMTP_ENABLE_KEY      = "alfred.smtp.enable"
+	SMTP_LISTEN_IP_KEY   = "alfred.smtp.listen.ip"
+	SMTP_LISTEN_PORT_KEY = "alfred.smtp.listen.port"
+	SMTP_MAX_EMAILS_KEY  = "alfred.smtp.max-emails"
 )
 
 // Struct where all config keys are stored.
@@ -81,9 +244,43 @@ type AlfredConfig struct {
 	Environment string `mapstructure:"environment"`
 	LogLevel    string `mapstructure:"log-level"`
 	//Core configuration.
-	Core       CoreConfig       `mapstructure:"core"`
-	Prometheus PrometheusConfig `mapstructure:"prometheus"`
-	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Core        CoreConfig        `mapstructure:"core"`
+	AccessLog   AccessLogConfig   `mapstructure:"access-log"`
+	Prometheus  PrometheusConfig  `mapstructure:"prometheus"`
+	Tracing     TracingConfig     `mapstructure:"tracing"`
+	Openapi     OpenapiConfig     `mapstructure:"openapi"`
+	Services    []ServiceConfig   `mapstructure:"services"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate-limit"`
+	Chaos       ChaosConfig       `mapstructure:"chaos"`
+	Jwt         JwtConfig         `mapstructure:"jwt"`
+	Oidc        OidcConfig        `mapstructure:"oidc"`
+	Faker       FakerConfig       `mapstructure:"faker"`
+	Compression CompressionConfig `mapstructure:"compression"`
+	Cors        CorsConfig        `mapstructure:"cors"`
+	Unmatched   UnmatchedConfig   `mapstructure:"unmatched"`
+	Sandbox     SandboxConfig     `mapstructure:"sandbox"`
+	Store       StoreConfig       `mapstructure:"store"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Kafka       KafkaConfig       `mapstructure:"kafka"`
+	Mqtt        MqttConfig        `mapstructure:"mqtt"`
+	Amqp        AmqpConfig        `mapstructure:"amqp"`
+	Smtp        SmtpConfig        `mapstructure:"smtp"`
+
+	ResponseHeaders ResponseHeadersConfig  `mapstructure:"response-headers"`
+	Limits          LimitsConfig           `mapstructure:"limits"`
+	LatencyProfiles []LatencyProfileConfig `mapstructure:"latency-profiles"`
+}
+
+// Response time curve of mocks, percentiles are durations like 120ms
+type LatencyProfileConfig struct {
+	Name         string `mapstructure:"name"`
+	Distribution string `mapstructure:"distribution"`
+	Min          string `mapstructure:"min"`
+	P50          string `mapstructure:"p50"`
+	P90          string `mapstructure:"p90"`
+	P95          string `mapstructure:"p95"`
+	P99          string `mapstructure:"p99"`
+	Max          string `mapstructure:"max"`
 }
 
 type ListenConfig struct {
@@ -92,14 +289,46 @@ type ListenConfig struct {
 	TlsEnabled  bool   `mapstructure:"enable-tls"`
 	TlsCertPath string `mapstructure:"tls-cert-path"`
 	TlsKeyPath  string `mapstructure:"tls-key-path"`
+
+	TlsSelfSigned   bool   `mapstructure:"tls-self-signed"`
+	TlsClientAuth   string `mapstructure:"tls-client-auth"`
+	TlsClientCaPath string `mapstructure:"tls-client-ca-path"`
+
+	// HTTP/2 is served with TLS (h2), and without TLS with enable-h2c
+	H2cEnabled    bool `mapstructure:"enable-h2c"`
+	Http2Disabled bool `mapstructure:"disable-http2"`
 }
 
 // Struct where all core config keys are stored.
 type CoreConfig struct {
 	MocksDir     string       `mapstructure:"mocks-dir"`
 	FunctionsDir string       `mapstructure:"functions-dir"`
+	ModulesDir   string       `mapstructure:"modules-dir"`
 	BodiesDir    string       `mapstructure:"body-files-dir"`
+	SchemasDir   string       `mapstructure:"schemas-dir"`
+	WiremockDir  string       `mapstructure:"wiremock-dir"`
+	HotReload    bool         `mapstructure:"hot-reload"`
 	Listen       ListenConfig `mapstructure:"listen"`
+
+	ShutdownTimeout string `mapstructure:"shutdown-timeout"`
+
+	// Tags of the mocks served, untagged mocks are always served
+	Profiles []string `mapstructure:"profiles"`
+
+	// Addresses and CIDR ranges of the proxies trusted to forward the client ip
+	TrustedProxies []string `mapstructure:"trusted-proxies"`
+}
+
+type AccessLogConfig struct {
+	Enable        bool   `mapstructure:"enable"`
+	Format        string `mapstructure:"format"`
+	Output        string `mapstructure:"output"`
+	FilePath      string `mapstructure:"file-path"`
+	MaxSizeMb     int    `mapstructure:"max-size-mb"`
+	MaxBackups    int    `mapstructure:"max-backups"`
+	MaxAgeDays    int    `mapstructure:"max-age-days"`
+	SyslogAddress string `mapstructure:"syslog-address"`
+	SyslogTag     string `mapstructure:"syslog-tag"`
 }
 
 type PrometheusConfig struct {
@@ -111,11 +340,202 @@ type PrometheusConfig struct {
 
 type TracingConfig struct {
 	OtlpEndpoint string `mapstructure:"otlp-endpoint"`
+	OtlpHeaders  string `mapstructure:"otlp-headers"`
 	Insecure     bool   `mapstructure:"insecure"`
 	Sampler      string `mapstructure:"sampler"`
 	SamplerArgs  string `mapstructure:"sampler-args"`
 }
 
+type OpenapiConfig struct {
+	SpecFile       string `mapstructure:"spec-file"`
+	ValidationMode string `mapstructure:"validation-mode"`
+}
+
+// A service serves its own mocks folder on its own listener, or on the core
+// listener when no port is set. Hosts select the service with the request
+// Host header when several services share a listener.
+type ServiceConfig struct {
+	Name     string       `mapstructure:"name"`
+	MocksDir string       `mapstructure:"mocks-dir"`
+	Hosts    []string     `mapstructure:"hosts"`
+	Listen   ListenConfig `mapstructure:"listen"`
+}
+
+type ChaosConfig struct {
+	Enable       bool    `mapstructure:"enable"`
+	LatencyRate  float64 `mapstructure:"latency-rate"`
+	MinLatencyMs int     `mapstructure:"min-latency-ms"`
+	MaxLatencyMs int     `mapstructure:"max-latency-ms"`
+	ErrorRate    float64 `mapstructure:"error-rate"`
+	ErrorStatus  int     `mapstructure:"error-status"`
+	DropRate     float64 `mapstructure:"drop-rate"`
+}
+
+type RateLimitConfig struct {
+	Enable   bool   `mapstructure:"enable"`
+	Requests int    `mapstructure:"requests"`
+	Period   string `mapstructure:"period"`
+	Burst    int    `mapstructure:"burst"`
+}
+
+type JwtConfig struct {
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyPath string `mapstructure:"private-key-path"`
+	PublicKeyPath  string `mapstructure:"public-key-path"`
+	Issuer         string `mapstructure:"issuer"`
+	ExpiresIn      string `mapstructure:"expires-in"`
+}
+
+type OidcConfig struct {
+	Enable               bool               `mapstructure:"enable"`
+	Path                 string             `mapstructure:"path"`
+	Issuer               string             `mapstructure:"issuer"`
+	PrivateKeyPath       string             `mapstructure:"private-key-path"`
+	AccessTokenLifetime  string             `mapstructure:"access-token-lifetime"`
+	IdTokenLifetime      string             `mapstructure:"id-token-lifetime"`
+	RefreshTokenLifetime string             `mapstructure:"refresh-token-lifetime"`
+	Clients              []OidcClientConfig `mapstructure:"clients"`
+	Users                []OidcUserConfig   `mapstructure:"users"`
+}
+
+type OidcClientConfig struct {
+	ClientId     string   `mapstructure:"client-id"`
+	ClientSecret string   `mapstructure:"client-secret"`
+	RedirectUris []string `mapstructure:"redirect-uris"`
+}
+
+// Users claims are added to their id tokens and userinfo.
+type OidcUserConfig struct {
+	Username string                 `mapstructure:"username"`
+	Password string                 `mapstructure:"password"`
+	Claims   map[string]interface{} `mapstructure:"claims"`
+}
+
+type FakerConfig struct {
+	Seed int64 `mapstructure:"seed"`
+}
+
+// Responses smaller than min-size bytes are not compressed
+type CompressionConfig struct {
+	Enable  bool `mapstructure:"enable"`
+	MinSize int  `mapstructure:"min-size"`
+}
+
+// Empty allowed methods and headers allow the requested ones
+type CorsConfig struct {
+	Enable           bool     `mapstructure:"enable"`
+	AllowedOrigins   []string `mapstructure:"allowed-origins"`
+	AllowedMethods   []string `mapstructure:"allowed-methods"`
+	AllowedHeaders   []string `mapstructure:"allowed-headers"`
+	ExposedHeaders   []string `mapstructure:"exposed-headers"`
+	AllowCredentials bool     `mapstructure:"allow-credentials"`
+	MaxAge           int      `mapstructure:"max-age"`
+}
+
+// Policy of the requests no mock matched: mock-list answers with the mocks
+// list, not-found with a 404 listing the near miss mocks, proxy forwards them
+// to the proxy url, and strict answers a 404 and fails the readiness.
+type UnmatchedConfig struct {
+	Policy   string `mapstructure:"policy"`
+	ProxyUrl string `mapstructure:"proxy-url"`
+}
+
+// Policies of the function files, the files matching none of them get the
+// default policy, or run without restriction if it's empty.
+type SandboxConfig struct {
+	DefaultPolicy string                `mapstructure:"default-policy"`
+	Policies      []SandboxPolicyConfig `mapstructure:"policies"`
+}
+
+type SandboxPolicyConfig struct {
+	Name          string   `mapstructure:"name"`
+	Files         []string `mapstructure:"files"`
+	Allow         []string `mapstructure:"allow"`
+	MaxStackDepth int      `mapstructure:"max-stack-depth"`
+	Timeout       string   `mapstructure:"timeout"`
+}
+
+// Zero values don't limit. Larger bodies are answered with a 413, requests
+// over the concurrent requests with a 503, and connections over the max
+// connections of a listener are closed after a 503.
+type LimitsConfig struct {
+	MaxBodySize           int64 `mapstructure:"max-body-size"`
+	MaxConcurrentRequests int   `mapstructure:"max-concurrent-requests"`
+	MaxConnections        int   `mapstructure:"max-connections"`
+}
+
+// Defaults are set when the response doesn't have the header, their values
+// can use request, date and random helpers. Removed headers are deleted
+// from all responses.
+type ResponseHeadersConfig struct {
+	Defaults map[string]string `mapstructure:"defaults"`
+	Remove   []string          `mapstructure:"remove"`
+}
+
+// The bbolt store persists the state in its path file, the redis store shares
+// it between the replicas of a cluster
+type StoreConfig struct {
+	Type  string           `mapstructure:"type"`
+	Path  string           `mapstructure:"path"`
+	Redis StoreRedisConfig `mapstructure:"redis"`
+}
+
+type StoreRedisConfig struct {
+	Address  string `mapstructure:"address"`
+	Password string `mapstructure:"password"`
+	Db       int    `mapstructure:"db"`
+	Prefix   string `mapstructure:"prefix"`
+}
+
+// The database is disabled without driver
+type DatabaseConfig struct {
+	Driver       string `mapstructure:"driver"`
+	Dsn          string `mapstructure:"dsn"`
+	MaxOpenConns int    `mapstructure:"max-open-conns"`
+	QueryTimeout string `mapstructure:"query-timeout"`
+}
+
+// Kafka is disabled without brokers
+type KafkaConfig struct {
+	Brokers   []string         `mapstructure:"brokers"`
+	ClientId  string           `mapstructure:"client-id"`
+	Consumers []ConsumerConfig `mapstructure:"consumers"`
+}
+
+// MQTT is disabled without url
+type MqttConfig struct {
+	Url       string           `mapstructure:"url"`
+	ClientId  string           `mapstructure:"client-id"`
+	Username  string           `mapstructure:"username"`
+	Password  string           `mapstructure:"password"`
+	Qos       int              `mapstructure:"qos"`
+	Consumers []ConsumerConfig `mapstructure:"consumers"`
+}
+
+// AMQP is disabled without url, messages are published to the exchange
+type AmqpConfig struct {
+	Url       string           `mapstructure:"url"`
+	Exchange  string           `mapstructure:"exchange"`
+	Consumers []ConsumerConfig `mapstructure:"consumers"`
+}
+
+// The smtp server captures any mail, the last max-emails are kept
+type SmtpConfig struct {
+	Enable    bool         `mapstructure:"enable"`
+	Listen    ListenConfig `mapstructure:"listen"`
+	MaxEmails int          `mapstructure:"max-emails"`
+}
+
+// Messages of the topic are handled by the onMessage function of the file.
+// The group is the kafka consumer group, the mqtt shared subscription group
+// or the amqp queue.
+type ConsumerConfig struct {
+	Topic        string `mapstructure:"topic"`
+	GroupId      string `mapstructure:"group-id"`
+	FunctionFile string `mapstructure:"function-file"`
+}
+
 // Configure the Viper instance.
 func configureViper(v *viper.Viper) (*viper.Viper, error) {
 
@@ -138,7 +558,18 @@ func configureViper(v *viper.Viper) (*viper.Viper, error) {
 		return v, err
 	}
 
-	return v, err
+	// Environnement variables and secrets references of the config file
+	fileContent, err := os.ReadFile(v.ConfigFileUsed())
+	if err != nil {
+		return v, err
+	}
+
+	fileContent, err = env.Interpolate(fileContent)
+	if err != nil {
+		return v, fmt.Errorf("config file %s: %w", v.ConfigFileUsed(), err)
+	}
+
+	return v, v.ReadConfig(bytes.NewReader(fileContent))
 }
 
 // Use Viper to get configuration from files and environment variables.
@@ -160,7 +591,14 @@ func buildConfiguration() (Config, error) {
 	v.SetDefault(NAME_KEY, "")
 	v.SetDefault(MOCKS_DIR_KEY, "")
 	v.SetDefault(FUNCTIONS_DIR_KEY, "")
+	v.SetDefault(MODULES_DIR_KEY, "")
 	v.SetDefault(BODIES_DIR_KEY, "")
+	v.SetDefault(SCHEMAS_DIR_KEY, "")
+	v.SetDefault(WIREMOCK_DIR_KEY, "")
+	v.SetDefault(HOT_RELOAD_KEY, false)
+	v.SetDefault(SHUTDOWN_TIMEOUT_KEY, "")
+	v.SetDefault(PROFILES_KEY, []string{})
+	v.SetDefault(TRUSTED_PROXIES_KEY, []string{})
 	v.SetDefault(VERSION_KEY, "")
 	v.SetDefault(NAMESPACE_KEY, "")
 	v.SetDefault(ENVIRONMENT_KEY, "")
@@ -169,16 +607,100 @@ func buildConfiguration() (Config, error) {
 	v.SetDefault(LISTEN_TLS_ENABLE, "")
 	v.SetDefault(LISTEN_TLS_CERT_PATH, "")
 	v.SetDefault(LISTEN_TLS_KEY_PATH, "")
+	v.SetDefault(LISTEN_TLS_SELF_SIGNED, false)
+	v.SetDefault(LISTEN_TLS_CLIENT_AUTH, "")
+	v.SetDefault(LISTEN_TLS_CLIENT_CA_PATH, "")
+	v.SetDefault(LISTEN_H2C_ENABLE, false)
+	v.SetDefault(LISTEN_HTTP2_DISABLE, false)
 	v.SetDefault(LOG_LEVEL_KEY, "")
+	v.SetDefault(ACCESS_LOG_ENABLE_KEY, "")
+	v.SetDefault(ACCESS_LOG_FORMAT_KEY, "")
+	v.SetDefault(ACCESS_LOG_OUTPUT_KEY, "")
+	v.SetDefault(ACCESS_LOG_FILE_PATH_KEY, "")
+	v.SetDefault(ACCESS_LOG_MAX_SIZE_MB_KEY, "")
+	v.SetDefault(ACCESS_LOG_MAX_BACKUPS_KEY, "")
+	v.SetDefault(ACCESS_LOG_MAX_AGE_DAYS_KEY, "")
+	v.SetDefault(ACCESS_LOG_SYSLOG_ADDRESS_KEY, "")
+	v.SetDefault(ACCESS_LOG_SYSLOG_TAG_KEY, "")
 	v.SetDefault(PROMETHEUS_ENABLE_KEY, "")
 	v.SetDefault(PROMETHEUS_PATH_KEY, "")
 	v.SetDefault(PROMETHEUS_SLOW_TIME_SECONDS_KEY, "")
 	v.SetDefault(PROMETHEUS_LISTEN_PORT_KEY, "")
 	v.SetDefault(PROMETHEUS_LISTEN_IP_KEY, "")
 	v.SetDefault(TRACING_OTLP_ENDPOINT_KEY, "")
+	v.SetDefault(TRACING_OTLP_HEADERS_KEY, "")
 	v.SetDefault(TRACING_INSECURE_KEY, "")
 	v.SetDefault(TRACING_SAMPLER_KEY, "")
 	v.SetDefault(TRACING_SAMPLER_ARGS_KEY, "")
+	v.SetDefault(OPENAPI_SPEC_FILE_KEY, "")
+	v.SetDefault(OPENAPI_VALIDATION_MODE_KEY, "")
+	v.SetDefault(CHAOS_ENABLE_KEY, "")
+	v.SetDefault(CHAOS_LATENCY_RATE_KEY, "")
+	v.SetDefault(CHAOS_MIN_LATENCY_MS_KEY, "")
+	v.SetDefault(CHAOS_MAX_LATENCY_MS_KEY, "")
+	v.SetDefault(CHAOS_ERROR_RATE_KEY, "")
+	v.SetDefault(CHAOS_ERROR_STATUS_KEY, "")
+	v.SetDefault(CHAOS_DROP_RATE_KEY, "")
+	v.SetDefault(RATE_LIMIT_ENABLE_KEY, "")
+	v.SetDefault(RATE_LIMIT_REQUESTS_KEY, "")
+	v.SetDefault(RATE_LIMIT_PERIOD_KEY, "")
+	v.SetDefault(RATE_LIMIT_BURST_KEY, "")
+	v.SetDefault(JWT_ALGORITHM_KEY, "")
+	v.SetDefault(JWT_SECRET_KEY, "")
+	v.SetDefault(JWT_PRIVATE_KEY_PATH_KEY, "")
+	v.SetDefault(JWT_PUBLIC_KEY_PATH_KEY, "")
+	v.SetDefault(JWT_ISSUER_KEY, "")
+	v.SetDefault(JWT_EXPIRES_IN_KEY, "")
+	v.SetDefault(OIDC_ENABLE_KEY, false)
+	v.SetDefault(OIDC_PATH_KEY, "")
+	v.SetDefault(OIDC_ISSUER_KEY, "")
+	v.SetDefault(OIDC_PRIVATE_KEY_PATH_KEY, "")
+	v.SetDefault(OIDC_ACCESS_TOKEN_LIFETIME_KEY, "")
+	v.SetDefault(OIDC_ID_TOKEN_LIFETIME_KEY, "")
+	v.SetDefault(OIDC_REFRESH_TOKEN_LIFETIME_KEY, "")
+	v.SetDefault(FAKER_SEED_KEY, "")
+	v.SetDefault(COMPRESSION_ENABLE_KEY, "")
+	v.SetDefault(COMPRESSION_MIN_SIZE_KEY, "")
+	v.SetDefault(CORS_ENABLE_KEY, "")
+	v.SetDefault(CORS_ALLOWED_ORIGINS_KEY, "")
+	v.SetDefault(CORS_ALLOWED_METHODS_KEY, "")
+	v.SetDefault(CORS_ALLOWED_HEADERS_KEY, "")
+	v.SetDefault(CORS_EXPOSED_HEADERS_KEY, "")
+	v.SetDefault(CORS_ALLOW_CREDENTIALS_KEY, "")
+	v.SetDefault(CORS_MAX_AGE_KEY, "")
+	v.SetDefault(UNMATCHED_POLICY_KEY, "")
+	v.SetDefault(UNMATCHED_PROXY_URL_KEY, "")
+	v.SetDefault(LIMITS_MAX_BODY_SIZE_KEY, "")
+	v.SetDefault(LIMITS_MAX_CONCURRENT_REQUESTS_KEY, "")
+	v.SetDefault(LIMITS_MAX_CONNECTIONS_KEY, "")
+	v.SetDefault(RESPONSE_HEADERS_DEFAULTS_KEY, map[string]string{})
+	v.SetDefault(RESPONSE_HEADERS_REMOVE_KEY, "")
+	v.SetDefault(LATENCY_PROFILES_KEY, []LatencyProfileConfig{})
+	v.SetDefault(SANDBOX_DEFAULT_POLICY_KEY, "")
+	v.SetDefault(SANDBOX_POLICIES_KEY, []SandboxPolicyConfig{})
+	v.SetDefault(STORE_TYPE_KEY, "")
+	v.SetDefault(STORE_PATH_KEY, "")
+	v.SetDefault(STORE_REDIS_ADDRESS_KEY, "")
+	v.SetDefault(STORE_REDIS_PASSWORD_KEY, "")
+	v.SetDefault(STORE_REDIS_DB_KEY, "")
+	v.SetDefault(STORE_REDIS_PREFIX_KEY, "")
+	v.SetDefault(DATABASE_DRIVER_KEY, "")
+	v.SetDefault(DATABASE_DSN_KEY, "")
+	v.SetDefault(DATABASE_MAX_OPEN_CONNS_KEY, "")
+	v.SetDefault(DATABASE_QUERY_TIMEOUT_KEY, "")
+	v.SetDefault(KAFKA_BROKERS_KEY, "")
+	v.SetDefault(KAFKA_CLIENT_ID_KEY, "")
+	v.SetDefault(MQTT_URL_KEY, "")
+	v.SetDefault(MQTT_CLIENT_ID_KEY, "")
+	v.SetDefault(MQTT_USERNAME_KEY, "")
+	v.SetDefault(MQTT_PASSWORD_KEY, "")
+	v.SetDefault(MQTT_QOS_KEY, "")
+	v.SetDefault(AMQP_URL_KEY, "")
+	v.SetDefault(AMQP_EXCHANGE_KEY, "")
+	v.SetDefault(SMTP_ENABLE_KEY, "")
+	v.SetDefault(SMTP_LISTEN_IP_KEY, "")
+	v.SetDefault(SMTP_LISTEN_PORT_KEY, "")
+	v.SetDefault(SMTP_MAX_EMAILS_KEY, "")
 
 	//Unmarshall loaded config into our struct
 	err = v.Unmarshal(&configuration)
@@ -0,0 +1,231 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cron
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Descriptors replacing the 5 fields expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+const EVERY_PREFIX = "@every "
+
+type field struct {
+	min, max int
+	names    []string
+}
+
+var (
+	minutes = field{min: 0, max: 59}
+	hours   = field{min: 0, max: 23}
+	days    = field{min: 1, max: 31}
+	months  = field{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}}
+	weekday = field{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}
+)
+
+// Schedule of a cron expression, "minute hour day-of-month month day-of-week"
+// or a descriptor like @daily or @every 30s.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// day of month and day of week restricted, a day matches one of them
+	domStar, dowStar bool
+
+	every time.Duration
+}
+
+// Parse reads a cron expression, fields accept *, values, ranges, lists and
+// steps (*/15, 1-5, mon-fri, 0,30).
+func Parse(expr string) (Schedule, error) {
+
+	var s Schedule
+
+	expr = strings.TrimSpace(expr)
+
+	if duration, found := strings.CutPrefix(expr, EVERY_PREFIX); found {
+
+		every, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			return s, errors.New("cron expression '" + expr + "': " + err.Error())
+		}
+		if every < time.Second {
+			return s, errors.New("cron expression '" + expr + "': the period must be 1s at least")
+		}
+
+		s.every = every
+		return s, nil
+	}
+
+	if descriptor, exists := descriptors[expr]; exists {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return s, errors.New("cron expression '" + expr + "' needs 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	var err error
+	parsed := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range []field{minutes, hours, days, months, weekday} {
+
+		*parsed[i], err = f.parse(strings.ToLower(fields[i]))
+		if err != nil {
+			return s, errors.New("cron expression '" + expr + "': " + err.Error())
+		}
+	}
+
+	// sunday is 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+func (f field) parse(expr string) (uint64, error) {
+
+	var bits uint64
+
+	for _, part := range strings.Split(expr, ",") {
+
+		rangeExpr, stepExpr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, errors.New("bad step '" + part + "'")
+			}
+		}
+
+		start, end := f.min, f.max
+		if rangeExpr != "*" {
+
+			startExpr, endExpr, isRange := strings.Cut(rangeExpr, "-")
+
+			var err error
+			start, err = f.value(startExpr)
+			if err != nil {
+				return 0, err
+			}
+
+			end = start
+			if isRange {
+				end, err = f.value(endExpr)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = f.max
+			}
+
+			if end < start {
+				return 0, errors.New("bad range '" + part + "'")
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+
+	return bits, nil
+}
+
+func (f field) value(expr string) (int, error) {
+
+	for i, name := range f.names {
+		if expr == name {
+			return i + f.min, nil
+		}
+	}
+
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.New("value '" + expr + "' out of " + strconv.Itoa(f.min) + "-" + strconv.Itoa(f.max))
+	}
+
+	return v, nil
+}
+
+// Next returns the first time of the schedule after t, zero when there is
+// none in the next 5 years (february 30th for instance).
+func (s Schedule) Next(t time.Time) time.Time {
+
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
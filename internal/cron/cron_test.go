@@ -0,0 +1,101 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cron
+
+import (
+	"alfred/internal/log"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+
+	from := time.Date(2024, time.January, 31, 10, 7, 30, 0, time.UTC)
+
+	tests := map[string]time.Time{
+		"*/15 * * * *":     time.Date(2024, time.January, 31, 10, 15, 0, 0, time.UTC),
+		"0 9-17 * * *":     time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC),
+		"30 8 * * mon-fri": time.Date(2024, time.February, 1, 8, 30, 0, 0, time.UTC),
+		"0 0 29 feb *":     time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * 7":        time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"@daily":           time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"@every 90s":       from.Add(90 * time.Second),
+	}
+
+	for expr, want := range tests {
+
+		s, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse %s failed with error %v", expr, err)
+		}
+
+		if next := s.Next(from); !next.Equal(want) {
+			t.Errorf("Next of %s is: %s, want: %s.", expr, next, want)
+		}
+	}
+
+	s, _ := Parse("0 0 30 2 *")
+	if next := s.Next(from); !next.IsZero() {
+		t.Errorf("Next of february 30th is: %s, want none.", next)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every 10ms", "@every soon"} {
+
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse %s should fail", expr)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+	defer Stop()
+
+	runs := 0
+	err := Start([]Job{{Name: "expire", Cron: "@hourly", FunctionFile: "orders.js", Run: func(ctx context.Context) error {
+		runs++
+		if runs > 1 {
+			return errors.New("no order")
+		}
+		return nil
+	}}})
+	if err != nil {
+		t.Fatalf("Start failed with error %v", err)
+	}
+
+	RunNow(context.Background(), "expire")
+	RunNow(context.Background(), "expire")
+
+	infos := GetJobs()
+	if len(infos) != 1 || len(infos[0].Runs) != 2 || infos[0].Runs[0].Error != "no order" || infos[0].Runs[1].Error != "" {
+		t.Errorf("Jobs are: %+v", infos)
+	}
+
+	if _, err = RunNow(context.Background(), "unknown"); err == nil {
+		t.Errorf("RunNow of an unknown job should fail")
+	}
+
+	err = Start([]Job{{Name: "bad", Cron: "every minute"}})
+	if err == nil {
+		t.Errorf("Start with a bad cron should fail")
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cron
+
+import (
+	"alfred/internal/log"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Runs kept in the history of each job
+const MAX_RUNS = 20
+
+// A scheduled job
+type Job struct {
+	Name         string
+	Cron         string
+	FunctionFile string
+	Run          func(ctx context.Context) error
+}
+
+// A job run of the history
+type Run struct {
+	Start    time.Time `json:"start"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// A scheduled job and its last runs, the most recent first
+type JobInfo struct {
+	Name         string    `json:"name"`
+	Cron         string    `json:"cron"`
+	FunctionFile string    `json:"functionFile"`
+	Next         time.Time `json:"next"`
+	Runs         []Run     `json:"runs"`
+}
+
+type scheduledJob struct {
+	job      Job
+	schedule Schedule
+
+	mutex sync.Mutex
+	next  time.Time
+
+	runMutex sync.Mutex
+}
+
+var (
+	mutex   sync.Mutex
+	jobs    = map[string]*scheduledJob{}
+	history = map[string][]Run{}
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+)
+
+// Start schedules the jobs, the previously scheduled jobs are stopped. The
+// history of the jobs still scheduled is kept.
+func Start(jobsToSchedule []Job) error {
+
+	scheduled := map[string]*scheduledJob{}
+
+	for _, job := range jobsToSchedule {
+
+		schedule, err := Parse(job.Cron)
+		if err != nil {
+			return errors.New(job.FunctionFile + " job " + job.Name + ": " + err.Error())
+		}
+
+		if _, exists := scheduled[job.Name]; exists {
+			return errors.New(job.FunctionFile + " job " + job.Name + " already exists")
+		}
+
+		scheduled[job.Name] = &scheduledJob{job: job, schedule: schedule}
+	}
+
+	Stop()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for name := range history {
+		if _, exists := scheduled[name]; !exists {
+			delete(history, name)
+		}
+	}
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	jobs = scheduled
+
+	for _, j := range scheduled {
+
+		running.Add(1)
+		go j.loop(ctx)
+	}
+
+	return nil
+}
+
+// Stop unschedules the jobs and waits for the runs in progress.
+func Stop() {
+
+	mutex.Lock()
+	if cancel != nil {
+		cancel()
+	}
+	jobs = map[string]*scheduledJob{}
+	mutex.Unlock()
+
+	running.Wait()
+}
+
+// GetJobs returns the scheduled jobs sorted by name.
+func GetJobs() []JobInfo {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	infos := []JobInfo{}
+
+	for name, j := range jobs {
+
+		j.mutex.Lock()
+		next := j.next
+		j.mutex.Unlock()
+
+		runs := append([]Run{}, history[name]...)
+
+		infos = append(infos, JobInfo{
+			Name:         name,
+			Cron:         j.job.Cron,
+			FunctionFile: j.job.FunctionFile,
+			Next:         next,
+			Runs:         runs,
+		})
+	}
+
+	sort.Slice(infos, func(i, k int) bool { return infos[i].Name < infos[k].Name })
+
+	return infos
+}
+
+// RunNow runs a job without waiting its schedule.
+func RunNow(ctx context.Context, name string) (Run, error) {
+
+	mutex.Lock()
+	j, exists := jobs[name]
+	mutex.Unlock()
+
+	if !exists {
+		return Run{}, errors.New("job " + name + " not found")
+	}
+
+	return j.run(ctx), nil
+}
+
+func (j *scheduledJob) loop(ctx context.Context) {
+
+	defer running.Done()
+
+	for {
+
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Warn(ctx, "job "+j.job.Name+" has no next run", nil, zap.String("cron", j.job.Cron))
+			return
+		}
+
+		j.mutex.Lock()
+		j.next = next
+		j.mutex.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			j.run(ctx)
+		}
+	}
+}
+
+// run calls the job, runs of the same job are serialized.
+func (j *scheduledJob) run(ctx context.Context) Run {
+
+	j.runMutex.Lock()
+	defer j.runMutex.Unlock()
+
+	start := time.Now()
+	err := j.job.Run(ctx)
+
+	run := Run{Start: start, Duration: time.Since(start).String()}
+
+	fields := []zap.Field{zap.String("job", j.job.Name), zap.String("function-file", j.job.FunctionFile), zap.String("duration", run.Duration)}
+	if err != nil {
+		run.Error = err.Error()
+		log.Warn(ctx, "job run failed", err, fields...)
+	} else {
+		log.Debug(ctx, "job run done", fields...)
+	}
+
+	mutex.Lock()
+	runs := append([]Run{run}, history[j.job.Name]...)
+	if len(runs) > MAX_RUNS {
+		runs = runs[:MAX_RUNS]
+	}
+	history[j.job.Name] = runs
+	mutex.Unlock()
+
+	return run
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DRIVER_POSTGRES = "postgres"
+	DRIVER_MYSQL    = "mysql"
+	DRIVER_SQLITE   = "sqlite"
+
+	DEFAULT_QUERY_TIMEOUT = 5 * time.Second
+)
+
+// Test database queried by the js functions, disabled without driver.
+type Config struct {
+	Driver       string `json:"driver"`
+	Dsn          string `json:"dsn"`
+	MaxOpenConns int    `json:"max-open-conns"`
+	QueryTimeout string `json:"query-timeout"`
+}
+
+// Result of an insert, update or delete statement
+type Result struct {
+	RowsAffected int64 `json:"rowsAffected"`
+	LastInsertId int64 `json:"lastInsertId"`
+}
+
+var (
+	db           *sql.DB
+	queryTimeout = DEFAULT_QUERY_TIMEOUT
+	dbMutex      sync.RWMutex
+)
+
+// Configure opens the database, the connection is checked with a ping.
+func Configure(c Config) error {
+
+	if c.Driver == "" {
+		return Close()
+	}
+
+	switch c.Driver {
+	case DRIVER_POSTGRES, DRIVER_MYSQL, DRIVER_SQLITE:
+	default:
+		return errors.New("database driver '" + c.Driver + "' not supported, use " + DRIVER_POSTGRES + ", " + DRIVER_MYSQL + " or " + DRIVER_SQLITE)
+	}
+
+	timeout := DEFAULT_QUERY_TIMEOUT
+	if c.QueryTimeout != "" {
+
+		var err error
+		timeout, err = time.ParseDuration(c.QueryTimeout)
+		if err != nil {
+			return errors.New("database query-timeout: " + err.Error())
+		}
+	}
+
+	conn, err := sql.Open(c.Driver, c.Dsn)
+	if err != nil {
+		return err
+	}
+
+	if c.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(c.MaxOpenConns)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = conn.PingContext(ctx)
+	if err != nil {
+		conn.Close()
+		return errors.New("database " + c.Driver + " connection failed: " + err.Error())
+	}
+
+	Close()
+
+	dbMutex.Lock()
+	db = conn
+	queryTimeout = timeout
+	dbMutex.Unlock()
+
+	return nil
+}
+
+func Close() error {
+
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
+	if db == nil {
+		return nil
+	}
+
+	err := db.Close()
+	db = nil
+
+	return err
+}
+
+func get() (*sql.DB, time.Duration, error) {
+
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+
+	if db == nil {
+		return nil, 0, errors.New("database not configured, set the database driver and dsn")
+	}
+
+	return db, queryTimeout, nil
+}
+
+// Rows of a select statement, values are in the columns order
+type Rows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+// Maps returns the rows values by column name
+func (r Rows) Maps() []map[string]interface{} {
+
+	maps := []map[string]interface{}{}
+
+	for _, values := range r.Values {
+
+		row := map[string]interface{}{}
+		for i, column := range r.Columns {
+			row[column] = values[i]
+		}
+
+		maps = append(maps, row)
+	}
+
+	return maps
+}
+
+// Query returns the rows of a select statement. Args placeholders are the
+// driver ones: $1 for postgres, ? for mysql and sqlite.
+func Query(query string, args ...interface{}) (Rows, error) {
+
+	var result Rows
+
+	conn, timeout, err := get()
+	if err != nil {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	result.Columns, err = rows.Columns()
+	if err != nil {
+		return result, err
+	}
+
+	for rows.Next() {
+
+		values := make([]interface{}, len(result.Columns))
+		pointers := make([]interface{}, len(result.Columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return result, err
+		}
+
+		// text columns of some drivers are scanned as bytes
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+
+		result.Values = append(result.Values, values)
+	}
+
+	return result, rows.Err()
+}
+
+// Exec runs an insert, update, delete or schema statement. The last insert id
+// is not supported by postgres, use a returning clause with Query.
+func Exec(query string, args ...interface{}) (Result, error) {
+
+	conn, timeout, err := get()
+	if err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res, err := conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	result.RowsAffected, _ = res.RowsAffected()
+	result.LastInsertId, _ = res.LastInsertId()
+
+	return result, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSqliteQueries(t *testing.T) {
+
+	_, err := Query("SELECT 1")
+	if err == nil {
+		t.Errorf("query without database should fail")
+	}
+
+	err = Configure(Config{Driver: DRIVER_SQLITE, Dsn: filepath.Join(t.TempDir(), "fixtures.db")})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+	defer Close()
+
+	_, err = Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)")
+	if err != nil {
+		t.Fatalf("exec failed with error: %v", err)
+	}
+
+	res, err := Exec("INSERT INTO users (name, email) VALUES (?, ?)", "bruce", "bruce@wayne-enterprises.com")
+	if err != nil {
+		t.Fatalf("exec failed with error: %v", err)
+	}
+
+	if res.RowsAffected != 1 || res.LastInsertId != 1 {
+		t.Errorf("insert result is: %+v", res)
+	}
+
+	rows, err := Query("SELECT id, name FROM users WHERE email = ?", "bruce@wayne-enterprises.com")
+	if err != nil {
+		t.Fatalf("query failed with error: %v", err)
+	}
+
+	users := rows.Maps()
+	if len(users) != 1 || users[0]["name"] != "bruce" || users[0]["id"] != int64(1) {
+		t.Errorf("rows are: %v", users)
+	}
+
+	err = Configure(Config{Driver: "oracle"})
+	if err == nil {
+		t.Errorf("unknown driver should fail")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Error of the references which can't be resolved
+var ErrUnresolved = errors.New("unresolved reference")
+
+// References of the json documents: ${NAME}, ${NAME:-default} and
+// ${file:path}, $${ is kept as a literal ${.
+var referenceRegex = regexp.MustCompile(`\$?\$\{(?:file:([^}]+)|([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?)\}`)
+
+// Interpolate resolves the environment variables and secret files references
+// of a json document. Resolved values are json escaped, so they can be used
+// in json strings, or as numbers and booleans outside of them. All the
+// references which can't be resolved are reported.
+func Interpolate(jsonData []byte) ([]byte, error) {
+
+	var unresolved []string
+
+	result := referenceRegex.ReplaceAllFunc(jsonData, func(reference []byte) []byte {
+
+		if strings.HasPrefix(string(reference), "$$") {
+			return reference[1:]
+		}
+
+		groups := referenceRegex.FindSubmatch(reference)
+		filePath, name, defaultValue := string(groups[1]), string(groups[2]), groups[3]
+
+		if filePath != "" {
+
+			content, err := os.ReadFile(strings.TrimSpace(filePath))
+			if err != nil {
+				unresolved = append(unresolved, "secret file "+err.Error())
+				return reference
+			}
+			return escape(strings.TrimRight(string(content), "\r\n"))
+		}
+
+		value, ok := os.LookupEnv(name)
+		if ok && value != "" {
+			return escape(value)
+		}
+
+		// the default value is written in the json document, it's kept as is
+		if defaultValue != nil {
+			return defaultValue
+		}
+
+		if !ok {
+			unresolved = append(unresolved, "environment variable "+name+" is not set")
+		}
+		return []byte{}
+	})
+
+	if len(unresolved) > 0 {
+		return jsonData, fmt.Errorf("%w: %s", ErrUnresolved, strings.Join(unresolved, ", "))
+	}
+
+	return result, nil
+}
+
+// escape a value like a json string content.
+func escape(value string) []byte {
+
+	escaped, _ := json.Marshal(value)
+
+	return escaped[1 : len(escaped)-1]
+}
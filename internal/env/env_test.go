@@ -0,0 +1,82 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+
+	t.Setenv("ALFRED_TEST_TOKEN", "abc\"123")
+	t.Setenv("ALFRED_TEST_PORT", "9100")
+	t.Setenv("ALFRED_TEST_EMPTY", "")
+
+	secretPath := filepath.Join(t.TempDir(), "password")
+	err := os.WriteFile(secretPath, []byte("s3cret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := `{"token":"Bearer ${ALFRED_TEST_TOKEN}","port":${ALFRED_TEST_PORT},"url":"${ALFRED_TEST_URL:-http://localhost}","empty":"${ALFRED_TEST_EMPTY}","password":"${file:` + secretPath + `}","literal":"$${ALFRED_TEST_TOKEN}","js":"${ not a reference }"}`
+
+	result, err := Interpolate([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values map[string]interface{}
+	err = json.Unmarshal(result, &values)
+	if err != nil {
+		t.Fatalf("Interpolate returns an invalid json document %s: %v", result, err)
+	}
+
+	expected := map[string]interface{}{
+		"token":    "Bearer abc\"123",
+		"port":     float64(9100),
+		"url":      "http://localhost",
+		"empty":    "",
+		"password": "s3cret",
+		"literal":  "${ALFRED_TEST_TOKEN}",
+		"js":       "${ not a reference }",
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("Interpolate %s returns %v, expected %v", key, values[key], value)
+		}
+	}
+}
+
+func TestInterpolateUnresolved(t *testing.T) {
+
+	document := `{"a":"${ALFRED_TEST_MISSING}","b":"${file:/not/existing/secret}"}`
+
+	result, err := Interpolate([]byte(document))
+	if !errors.Is(err, ErrUnresolved) {
+		t.Fatalf("Interpolate returns %v, expected an unresolved reference error", err)
+	}
+	if !strings.Contains(err.Error(), "environment variable ALFRED_TEST_MISSING is not set") || !strings.Contains(err.Error(), "/not/existing/secret") {
+		t.Errorf("Interpolate error doesn't list the references: %v", err)
+	}
+	if string(result) != document {
+		t.Errorf("Interpolate returns %s on error, expected the document", result)
+	}
+}
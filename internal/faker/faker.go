@@ -0,0 +1,440 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package faker
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	jfaker "github.com/jaswdr/faker"
+)
+
+// Date format of the dates params and values
+const DATE_FORMAT = time.RFC3339
+
+// Random data generator, values are reproducible when it's seeded. The js
+// runtime exposes its methods with a lower case first letter.
+type Faker struct {
+	mutex     sync.Mutex
+	generator *rand.Rand
+	f         jfaker.Faker
+}
+
+var (
+	global      = New(0)
+	globalMutex sync.RWMutex
+)
+
+// Methods not available as template helpers
+var notHelpers = map[string]bool{"Seed": true, "Pick": true}
+
+// New creates a generator, a zero seed is a random one.
+func New(seed int64) *Faker {
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	generator := rand.New(rand.NewSource(seed))
+
+	return &Faker{generator: generator, f: jfaker.Faker{Generator: generator}}
+}
+
+// SetSeed replaces the shared generator, a zero seed is a random one.
+func SetSeed(seed int64) {
+
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+	global = New(seed)
+}
+
+// Get returns the shared generator.
+func Get() *Faker {
+
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+
+	return global
+}
+
+// Seed returns a new generator with the given seed.
+func (f *Faker) Seed(seed int64) *Faker {
+
+	return New(seed)
+}
+
+func (f *Faker) Name() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Person().Name()
+}
+
+func (f *Faker) FirstName() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Person().FirstName()
+}
+
+func (f *Faker) LastName() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Person().LastName()
+}
+
+func (f *Faker) Email() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Internet().Email()
+}
+
+func (f *Faker) Username() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Internet().User()
+}
+
+func (f *Faker) Phone() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Phone().Number()
+}
+
+func (f *Faker) Company() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Company().Name()
+}
+
+func (f *Faker) JobTitle() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Company().JobTitle()
+}
+
+func (f *Faker) StreetAddress() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().StreetAddress()
+}
+
+func (f *Faker) City() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().City()
+}
+
+func (f *Faker) PostCode() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().PostCode()
+}
+
+func (f *Faker) Country() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().Country()
+}
+
+func (f *Faker) CountryCode() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().CountryCode()
+}
+
+func (f *Faker) Latitude() float64 {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().Latitude()
+}
+
+func (f *Faker) Longitude() float64 {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Address().Longitude()
+}
+
+func (f *Faker) Ipv4() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Internet().Ipv4()
+}
+
+func (f *Faker) Url() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Internet().URL()
+}
+
+// Uuid returns a version 4 UUID drawn from the generator, to be reproducible.
+func (f *Faker) Uuid() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var b [16]byte
+	f.generator.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return uuid.UUID(b).String()
+}
+
+// Int returns an integer between min and max, both included.
+func (f *Faker) Int(min int, max int) int {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.IntBetween(min, max)
+}
+
+// Float returns a number between min and max, rounded to decimals.
+func (f *Faker) Float(min float64, max float64, decimals int) float64 {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	value := min + f.generator.Float64()*(max-min)
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', decimals, 64), 64)
+
+	return rounded
+}
+
+func (f *Faker) Bool() bool {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Bool()
+}
+
+// Pick returns one of the values.
+func (f *Faker) Pick(values []interface{}) interface{} {
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return values[f.generator.Intn(len(values))]
+}
+
+// Date returns a date between from and to, RFC3339 or 2006-01-02 formatted.
+func (f *Faker) Date(from string, to string) (string, error) {
+
+	min, err := parseDate(from)
+	if err != nil {
+		return "", err
+	}
+
+	max, err := parseDate(to)
+	if err != nil {
+		return "", err
+	}
+
+	if max.Before(min) {
+		return "", errors.New("faker date " + to + " is before " + from)
+	}
+
+	return f.dateBetween(min, max), nil
+}
+
+// DatePast returns a date of the past year.
+func (f *Faker) DatePast() string {
+
+	now := time.Now()
+
+	return f.dateBetween(now.AddDate(-1, 0, 0), now)
+}
+
+// DateFuture returns a date of the next year.
+func (f *Faker) DateFuture() string {
+
+	now := time.Now()
+
+	return f.dateBetween(now, now.AddDate(1, 0, 0))
+}
+
+func (f *Faker) dateBetween(min time.Time, max time.Time) string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delta := max.Unix() - min.Unix()
+	if delta <= 0 {
+		return min.UTC().Format(DATE_FORMAT)
+	}
+
+	return time.Unix(min.Unix()+f.generator.Int63n(delta+1), 0).UTC().Format(DATE_FORMAT)
+}
+
+func (f *Faker) Word() string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Lorem().Word()
+}
+
+// Words returns count lorem words separated by spaces.
+func (f *Faker) Words(count int) string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return strings.Join(f.f.Lorem().Words(count), " ")
+}
+
+// Sentence returns a lorem sentence of count words.
+func (f *Faker) Sentence(count int) string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Lorem().Sentence(count)
+}
+
+// Paragraph returns a lorem paragraph of count sentences.
+func (f *Faker) Paragraph(count int) string {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.f.Lorem().Paragraph(count)
+}
+
+// CheckMethod checks a template helper method exists and gets its params count.
+func CheckMethod(name string, paramsCount int) error {
+
+	method, exists := reflect.TypeOf(&Faker{}).MethodByName(methodName(name))
+	if !exists || notHelpers[method.Name] {
+		return errors.New("faker method '" + name + "' not exists")
+	}
+
+	// the receiver is the first input
+	if method.Type.NumIn()-1 != paramsCount {
+		return errors.New("faker method '" + name + "' needs " + strconv.Itoa(method.Type.NumIn()-1) + " param(s)")
+	}
+
+	return nil
+}
+
+// Call runs a template helper method, params are converted to the method
+// params types.
+func (f *Faker) Call(name string, params []string) (string, error) {
+
+	err := CheckMethod(name, len(params))
+	if err != nil {
+		return "", err
+	}
+
+	method := reflect.ValueOf(f).MethodByName(methodName(name))
+
+	var in []reflect.Value
+	for i, param := range params {
+
+		param = strings.Trim(strings.TrimSpace(param), `'"`)
+
+		var value interface{}
+		switch method.Type().In(i).Kind() {
+		case reflect.Int:
+			value, err = strconv.Atoi(param)
+		case reflect.Float64:
+			value, err = strconv.ParseFloat(param, 64)
+		default:
+			value = param
+		}
+		if err != nil {
+			return "", errors.New("faker method '" + name + "' param '" + param + "': " + err.Error())
+		}
+
+		in = append(in, reflect.ValueOf(value))
+	}
+
+	out := method.Call(in)
+	if len(out) > 1 && !out[1].IsNil() {
+		return "", out[1].Interface().(error)
+	}
+
+	return fmt.Sprint(out[0].Interface()), nil
+}
+
+func methodName(name string) string {
+
+	if name == "" {
+		return name
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func parseDate(date string) (time.Time, error) {
+
+	if t, err := time.Parse(DATE_FORMAT, date); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return t, errors.New("faker date '" + date + "' is not RFC3339 or 2006-01-02 formatted")
+	}
+
+	return t, nil
+}
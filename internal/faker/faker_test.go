@@ -0,0 +1,74 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package faker
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSeed(t *testing.T) {
+
+	a := New(42)
+	b := New(42)
+
+	for i := 0; i < 3; i++ {
+
+		nameA, nameB := a.Name(), b.Name()
+		if nameA != nameB {
+			t.Errorf("seeded names are: %s and %s", nameA, nameB)
+		}
+
+		if uuidA, uuidB := a.Uuid(), b.Uuid(); uuidA != uuidB {
+			t.Errorf("seeded uuids are: %s and %s", uuidA, uuidB)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+
+	f := New(0)
+
+	value, err := f.Call("int", []string{"5", " 10"})
+	if err != nil {
+		t.Fatalf("call failed with error: %v", err)
+	}
+
+	if i, _ := strconv.Atoi(value); i < 5 || i > 10 {
+		t.Errorf("int between 5 and 10 is: %s", value)
+	}
+
+	value, err = f.Call("date", []string{"'2020-01-01'", "'2020-01-02'"})
+	if err != nil {
+		t.Fatalf("call failed with error: %v", err)
+	}
+
+	if value < "2020-01-01" || value > "2020-01-02T00:00:00Z" {
+		t.Errorf("date is: %s", value)
+	}
+
+	_, err = f.Call("date", []string{"'yesterday'", "'2020-01-02'"})
+	if err == nil {
+		t.Errorf("an invalid date should fail")
+	}
+
+	for name, count := range map[string]int{"int": 1, "seed": 1, "unknown": 0} {
+		if CheckMethod(name, count) == nil {
+			t.Errorf("method %s with %d param(s) should fail", name, count)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/database"
+	"alfred/internal/faker"
+	"alfred/internal/jwt"
+	"alfred/internal/messaging"
+	"alfred/internal/sandbox"
+	"context"
+
+	"github.com/dop251/goja"
+)
+
+// setAlfredApi adds the alfred.* helpers to the VM. Users declare an alfred
+// function, so helpers are set as properties of this function once the file
+// is loaded, or of a plain object if the file has none. The apis not allowed
+// by the sandbox of the file fail when they are used.
+func setAlfredApi(vm *goja.Runtime, s *sandbox.Sandbox) error {
+
+	var alfred *goja.Object
+
+	if v := vm.Get(FUNC_ALFRED); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		alfred = v.ToObject(vm)
+	} else {
+		alfred = vm.NewObject()
+		if err := vm.Set(FUNC_ALFRED, alfred); err != nil {
+			return err
+		}
+	}
+
+	apis := []struct {
+		name string
+		new  func() interface{}
+	}{
+		{sandbox.API_JWT, func() interface{} { return newJwtApi(vm) }},
+		{sandbox.API_CRYPTO, func() interface{} { return newCryptoApi(vm) }},
+		{sandbox.API_ENCODING, func() interface{} { return newEncodingApi(vm) }},
+		{sandbox.API_DB, func() interface{} { return newDbApi(vm) }},
+		{sandbox.API_MESSAGING, func() interface{} { return newMessagingApi(vm) }},
+		{sandbox.API_FAKER, func() interface{} { return faker.Get() }},
+	}
+
+	for _, api := range apis {
+
+		var err error
+		if s.Allows(api.name) {
+			err = alfred.Set(api.name, api.new())
+		} else {
+			err = disableApi(vm, alfred, api.name, api.name, s)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newJwtApi(vm *goja.Runtime) *goja.Object {
+
+	jwtApi := vm.NewObject()
+	jwtApi.Set("sign", jwt.Sign)
+	jwtApi.Set("verify", jwt.Verify)
+	jwtApi.Set("decode", jwt.Decode)
+
+	return jwtApi
+}
+
+func newDbApi(vm *goja.Runtime) *goja.Object {
+
+	dbApi := vm.NewObject()
+	dbApi.Set("query", func(query string, args ...interface{}) ([]*goja.Object, error) {
+
+		rows, err := database.Query(query, args...)
+
+		// js objects keep the columns order
+		objects := []*goja.Object{}
+		for _, values := range rows.Values {
+
+			o := vm.NewObject()
+			for i, column := range rows.Columns {
+				o.Set(column, values[i])
+			}
+
+			objects = append(objects, o)
+		}
+
+		return objects, err
+	})
+	dbApi.Set("exec", database.Exec)
+
+	return dbApi
+}
+
+func newMessagingApi(vm *goja.Runtime) *goja.Object {
+
+	messagingApi := vm.NewObject()
+	messagingApi.Set("publish", func(msg messaging.Message) error {
+		return messaging.Publish(context.Background(), msg)
+	})
+
+	return messagingApi
+}
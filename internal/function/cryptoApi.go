@@ -0,0 +1,143 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/url"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// Digests encodings, hex by default
+const (
+	ENCODING_HEX        = "hex"
+	ENCODING_BASE64     = "base64"
+	ENCODING_BASE64_URL = "base64url"
+)
+
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// newCryptoApi builds alfred.crypto: digests, hmac and random bytes.
+// alfred.crypto.hmac("sha256", secret, body, "base64")
+func newCryptoApi(vm *goja.Runtime) *goja.Object {
+
+	cryptoApi := vm.NewObject()
+
+	for name, newHash := range hashes {
+		newHash := newHash
+		cryptoApi.Set(name, func(data string, encoding string) (string, error) {
+			return digest(newHash(), data, encoding)
+		})
+	}
+
+	cryptoApi.Set("hmac", func(algorithm string, key string, data string, encoding string) (string, error) {
+
+		newHash, exists := hashes[strings.ToLower(algorithm)]
+		if !exists {
+			return "", errors.New("hmac algorithm " + algorithm + " not supported")
+		}
+
+		return digest(hmac.New(newHash, []byte(key)), data, encoding)
+	})
+
+	cryptoApi.Set("randomBytes", func(size int, encoding string) (string, error) {
+
+		if size <= 0 {
+			return "", errors.New("random bytes size must be positive")
+		}
+
+		b := make([]byte, size)
+		_, err := rand.Read(b)
+		if err != nil {
+			return "", err
+		}
+
+		return encode(b, encoding)
+	})
+
+	return cryptoApi
+}
+
+func digest(h hash.Hash, data string, encoding string) (string, error) {
+
+	h.Write([]byte(data))
+
+	return encode(h.Sum(nil), encoding)
+}
+
+func encode(b []byte, encoding string) (string, error) {
+
+	switch strings.ToLower(encoding) {
+	case "", ENCODING_HEX:
+		return hex.EncodeToString(b), nil
+	case ENCODING_BASE64:
+		return base64.StdEncoding.EncodeToString(b), nil
+	case ENCODING_BASE64_URL:
+		return base64.RawURLEncoding.EncodeToString(b), nil
+	}
+
+	return "", errors.New("encoding " + encoding + " not supported")
+}
+
+// newEncodingApi builds alfred.encoding: base64, hex and url encoders.
+// Decoders return strings, binary data is better kept hex or base64 encoded.
+func newEncodingApi(vm *goja.Runtime) *goja.Object {
+
+	encodingApi := vm.NewObject()
+
+	encodingApi.Set("base64Encode", func(data string) string {
+		return base64.StdEncoding.EncodeToString([]byte(data))
+	})
+	encodingApi.Set("base64Decode", func(data string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(data)
+		return string(b), err
+	})
+	encodingApi.Set("base64UrlEncode", func(data string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(data))
+	})
+	encodingApi.Set("base64UrlDecode", func(data string) (string, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data, "="))
+		return string(b), err
+	})
+	encodingApi.Set("hexEncode", func(data string) string {
+		return hex.EncodeToString([]byte(data))
+	})
+	encodingApi.Set("hexDecode", func(data string) (string, error) {
+		b, err := hex.DecodeString(data)
+		return string(b), err
+	})
+	encodingApi.Set("urlEncode", url.QueryEscape)
+	encodingApi.Set("urlDecode", url.QueryUnescape)
+
+	return encodingApi
+}
@@ -17,26 +17,36 @@
 package function
 
 import (
+	"alfred/internal/action"
 	"alfred/internal/helper"
+	"alfred/internal/log"
+	"alfred/internal/messaging"
 	"alfred/internal/mock"
+	"alfred/pkg/metrics"
 	"alfred/pkg/request"
+	"context"
 	"errors"
 	"sync"
 	"time"
 
 	"github.com/dop251/goja"
 	"github.com/dop251/goja_nodejs/console"
-	"github.com/dop251/goja_nodejs/require"
 )
 
 // VMPool manages a pool of Goja VMs
 type VMPool struct {
+	name     string
 	pool     chan *goja.Runtime
 	minSize  int
 	maxSize  int
 	mutex    sync.Mutex
 	current  int
+	inUse    int
+	timers   int           // VMs out of the pool running their timers
 	stopChan chan struct{} // Channel to stop cleanup goroutine
+
+	// 0 to wait for a VM without limit
+	acquireTimeout time.Duration
 }
 
 var (
@@ -46,22 +56,37 @@ var (
 
 const FUNC_UPDATE_HELPERS = "updateHelpers"
 const FUNC_ALFRED = "alfred"
+const FUNC_CALLBACK = "callback"
+const FUNC_ON_MESSAGE = "onMessage"
 
 type Function struct {
 	FileName             string
 	FileContent          string
 	HasFuncUpdateHelpers bool
 	HasFuncAlfred        bool
+	HasFuncCallback      bool
+	HasFuncOnMessage     bool
+	HasFuncOnData        bool
+	HasFuncHelpers       bool
+	HasFuncStream        bool
+
+	//exports.jobs of the file
+	jobs []Job
+
+	//compiled once, run in pool VMs
+	program *goja.Program
 }
 
 // initializePool creates a new VM pool with the specified size
-func initializePool(minSize, maxSize int) *VMPool {
+func initializePool(name string, minSize, maxSize int, acquireTimeout time.Duration) *VMPool {
 	pool := &VMPool{
-		pool:     make(chan *goja.Runtime, maxSize),
-		minSize:  minSize,
-		maxSize:  maxSize,
-		current:  minSize,
-		stopChan: make(chan struct{}),
+		name:           name,
+		pool:           make(chan *goja.Runtime, maxSize),
+		minSize:        minSize,
+		maxSize:        maxSize,
+		current:        minSize,
+		stopChan:       make(chan struct{}),
+		acquireTimeout: acquireTimeout,
 	}
 
 	// Initialize the pool with minimum number of VMs
@@ -70,6 +95,8 @@ func initializePool(minSize, maxSize int) *VMPool {
 		pool.pool <- vm
 	}
 
+	metrics.SetVmPoolSize(name, minSize)
+
 	// Start cleanup routine
 	go pool.cleanup()
 
@@ -79,43 +106,185 @@ func initializePool(minSize, maxSize int) *VMPool {
 // GetPool returns the global VM pool instance
 func GetPool() *VMPool {
 	once.Do(func() {
-		globalPool = initializePool(1, 1000) // Min 1, Max 1000 VMs
+		globalPool = initializePool(SHARED_POOL, 1, 1000, 0) // Min 1, Max 1000 VMs
 	})
 	return globalPool
 }
 
-// acquireVM gets a VM from the pool or creates a new one if needed
-func (p *VMPool) acquireVM() *goja.Runtime {
+// acquireVM gets a VM from the pool or creates a new one if needed, it fails
+// if none is available before the pool acquire timeout.
+func (p *VMPool) acquireVM() (*goja.Runtime, error) {
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveVmAcquireWait(p.name, time.Since(start))
+	}()
+
 	select {
 	case vm := <-p.pool:
-		return vm
+		p.addInUse(1)
+		return vm, nil
 	default:
 		// No VM available in pool, try to create new one
 		p.mutex.Lock()
 		if p.current < p.maxSize {
 			p.current++
+			metrics.SetVmPoolSize(p.name, p.current)
 			p.mutex.Unlock()
-			return createVM()
+			p.addInUse(1)
+			return createVM(), nil
 		}
 		p.mutex.Unlock()
-		// If we've reached maxSize, wait for an available VM
-		return <-p.pool
+	}
+
+	// If we've reached maxSize, wait for an available VM
+	if p.acquireTimeout == 0 {
+		vm := <-p.pool
+		p.addInUse(1)
+		return vm, nil
+	}
+
+	timer := time.NewTimer(p.acquireTimeout)
+	defer timer.Stop()
+
+	select {
+	case vm := <-p.pool:
+		p.addInUse(1)
+		return vm, nil
+	case <-timer.C:
+		metrics.IncVmAcquireTimeouts(p.name)
+		return nil, errors.New("no javascript VM available in pool '" + p.name + "' after " + p.acquireTimeout.String())
 	}
 }
 
-// releaseVM returns a VM to the pool or discards it if pool is full
+// releaseVM returns a VM to the pool, or discards it if pool is full. A VM
+// with pending timers frees its pool slot and comes back once they ran.
 func (p *VMPool) releaseVM(vm *goja.Runtime) {
+
+	stopSandbox(vm)
+
+	if t := getTimers(vm); t != nil && t.hasPending() {
+		if p.detachVM() {
+			go t.run(func() { p.attachVM(vm) })
+			return
+		}
+		log.Error(context.Background(), "js timers cleared", errors.New("too many VMs running timers in pool '"+p.name+"'"))
+		t.clearAll()
+	}
+
+	p.putVM(vm)
+}
+
+// detachVM frees the pool slot of a VM running its timers, up to the pool
+// max size of such VMs
+func (p *VMPool) detachVM() bool {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.timers >= p.maxSize {
+		return false
+	}
+
+	p.timers++
+	p.current--
+	p.inUse--
+	metrics.SetVmPoolSize(p.name, p.current)
+	metrics.SetVmPoolInUse(p.name, p.inUse)
+
+	return true
+}
+
+// attachVM returns a VM whose timers ran to the pool, if it has a free slot
+func (p *VMPool) attachVM(vm *goja.Runtime) {
+
+	p.mutex.Lock()
+	p.timers--
+	attached := p.current < p.maxSize && !hasStaleModules(vm)
+	if attached {
+		p.current++
+		metrics.SetVmPoolSize(p.name, p.current)
+	}
+	p.mutex.Unlock()
+
+	if !attached {
+		forgetVM(vm)
+		return
+	}
+
+	select {
+	case p.pool <- vm:
+	default:
+		p.discardVM(vm)
+	}
+}
+
+func (p *VMPool) putVM(vm *goja.Runtime) {
+
+	p.addInUse(-1)
+
+	// modules reloaded while the VM was in use
+	if hasStaleModules(vm) {
+		p.discardVM(vm)
+		return
+	}
+
 	select {
 	case p.pool <- vm:
 		// VM successfully returned to pool
 	default:
 		// Pool is full, discard the VM and decrease counter
-		p.mutex.Lock()
-		p.current--
-		p.mutex.Unlock()
+		p.discardVM(vm)
 	}
 }
 
+func (p *VMPool) discardVM(vm *goja.Runtime) {
+
+	forgetVM(vm)
+
+	p.mutex.Lock()
+	p.current--
+	metrics.SetVmPoolSize(p.name, p.current)
+	p.mutex.Unlock()
+}
+
+// renew replaces the idle VMs of the pool by new ones
+func (p *VMPool) renew() {
+
+	for {
+		select {
+		case vm := <-p.pool:
+			p.discardVM(vm)
+		default:
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+
+			for p.current < p.minSize {
+				p.pool <- createVM()
+				p.current++
+			}
+			metrics.SetVmPoolSize(p.name, p.current)
+			return
+		}
+	}
+}
+
+// forgetVM drops the state kept for a VM leaving its pool
+func forgetVM(vm *goja.Runtime) {
+
+	vmTimers.Delete(vm)
+	vmSandboxes.Delete(vm)
+	vmModules.Delete(vm)
+}
+
+func (p *VMPool) addInUse(delta int) {
+
+	p.mutex.Lock()
+	p.inUse += delta
+	metrics.SetVmPoolInUse(p.name, p.inUse)
+	p.mutex.Unlock()
+}
+
 // cleanup periodically removes excess VMs
 func (p *VMPool) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -126,19 +295,18 @@ func (p *VMPool) cleanup() {
 		case <-ticker.C:
 			p.mutex.Lock()
 			excess := p.current - p.minSize
-			if excess > 0 {
-				// Try to remove excess VMs
-				for i := 0; i < excess; i++ {
-					select {
-					case <-p.pool:
-						p.current--
-					default:
-						// No more VMs to remove
-						break
-					}
+			p.mutex.Unlock()
+
+			// Try to remove excess VMs
+			for i := 0; i < excess; i++ {
+				select {
+				case vm := <-p.pool:
+					p.discardVM(vm)
+				default:
+					// No more VMs to remove
+					i = excess
 				}
 			}
-			p.mutex.Unlock()
 		case <-p.stopChan:
 			return
 		}
@@ -148,8 +316,9 @@ func (p *VMPool) cleanup() {
 // createVM creates a new Goja VM instance
 func createVM() *goja.Runtime {
 	vm := goja.New()
-	new(require.Registry).Enable(vm)
+	enableModules(vm)
 	console.Enable(vm)
+	enableTimers(vm)
 	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
 
 	/*
@@ -163,14 +332,57 @@ func createVM() *goja.Runtime {
 func CreateFunction(fileName string, fileContent []byte) (Function, error) {
 
 	var err error
-	f := Function{fileName, string(fileContent), false, false}
 
-	f.HasFuncAlfred, err = f.CheckIfFuncExists(FUNC_ALFRED)
+	if isTypescript(fileName) {
+		fileContent, err = transpileTypescript(fileName, fileContent)
+		if err != nil {
+			return Function{FileName: fileName}, errors.New(fileName + ": " + err.Error())
+		}
+	}
+
+	f := Function{FileName: fileName, FileContent: string(fileContent)}
+
+	f.program, err = goja.Compile(fileName, f.FileContent, false)
 	if err != nil {
-		return f, err
+		return f, errors.New(fileName + ": " + err.Error())
+	}
+
+	// the file runs once, its top level code may have side effects
+	pool := GetPool()
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return f, errors.New(fileName + ": " + errorMessage(err))
 	}
+	defer pool.releaseVM(vm)
 
-	f.HasFuncUpdateHelpers, err = f.CheckIfFuncExists(FUNC_UPDATE_HELPERS)
+	err = f.load(vm)
+	if err != nil {
+		return f, errors.New(fileName + ": " + errorMessage(err))
+	}
+
+	for funcName, hasFunc := range map[string]*bool{
+		FUNC_ALFRED:         &f.HasFuncAlfred,
+		FUNC_UPDATE_HELPERS: &f.HasFuncUpdateHelpers,
+		FUNC_CALLBACK:       &f.HasFuncCallback,
+		FUNC_ON_MESSAGE:     &f.HasFuncOnMessage,
+		FUNC_ON_DATA:        &f.HasFuncOnData,
+		FUNC_HELPERS:        &f.HasFuncHelpers,
+		FUNC_STREAM:         &f.HasFuncStream,
+	} {
+		*hasFunc, err = f.funcExists(vm, funcName)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	if f.HasFuncHelpers {
+		_, err = f.helperNames(vm)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	f.jobs, err = f.readJobs(vm)
 	if err != nil {
 		return f, err
 	}
@@ -184,29 +396,37 @@ func (f *Function) UpdateHelpersListener(helpers []helper.Helper) ([]helper.Help
 		return helpers, errors.New("function file " + f.FileName + " not contains " + FUNC_UPDATE_HELPERS + " function")
 	}
 
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_UPDATE_HELPERS, time.Since(start))
+	}()
+
 	var updateHelpers func([]helper.Helper) ([]helper.Helper, error)
 
-	pool := GetPool()
-	vm := pool.acquireVM()
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return helpers, errors.New(f.FileName + ": " + errorMessage(err))
+	}
 	defer pool.releaseVM(vm)
 
 	//load js functions in vm
-	_, err := vm.RunString(f.FileContent)
+	err = f.load(vm)
 	if err != nil {
 
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return helpers, err
 	}
 
 	err = vm.ExportTo(vm.Get(FUNC_UPDATE_HELPERS), &updateHelpers)
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return helpers, err
 	}
 
 	updatedHelpers, err := updateHelpers(helpers)
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return helpers, err
 	}
 
@@ -219,55 +439,202 @@ func (f *Function) AlfredFunc(m mock.Mock, helpers []helper.Helper, req request.
 		return res, errors.New("function file " + f.FileName + " not contains " + FUNC_ALFRED + " function")
 	}
 
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_ALFRED, time.Since(start))
+	}()
+
 	var alfred func(mock.Mock, []helper.Helper, request.Req, request.Res) (request.Res, error)
-	pool := GetPool()
-	vm := pool.acquireVM()
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return res, errors.New(f.FileName + ": " + errorMessage(err))
+	}
 	defer pool.releaseVM(vm)
 
 	//load js functions in vm
-	_, err := vm.RunString(f.FileContent)
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, m.GetService())
+	}
 	if err != nil {
 
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return res, err
 	}
 
 	err = vm.ExportTo(vm.Get(FUNC_ALFRED), &alfred)
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return res, err
 	}
 
 	resUpdated, err := alfred(m, helpers, req, res)
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return res, err
 	}
 
 	return resUpdated, nil
 }
 
+// CallbackFunc lets the user js function update a callback (delay, url,
+// body...) before it's sent.
+func (f *Function) CallbackFunc(m mock.Mock, helpers []helper.Helper, req request.Req, res request.Res, cb action.Callback) (action.Callback, error) {
+
+	if !f.HasFuncCallback {
+		return cb, errors.New("function file " + f.FileName + " not contains " + FUNC_CALLBACK + " function")
+	}
+
+	if err := f.checkHttpApi(); err != nil {
+		return cb, err
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_CALLBACK, time.Since(start))
+	}()
+
+	var callback func(mock.Mock, []helper.Helper, request.Req, request.Res, action.Callback) (action.Callback, error)
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return cb, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, m.GetService())
+	}
+	if err != nil {
+
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return cb, err
+	}
+
+	err = vm.ExportTo(vm.Get(FUNC_CALLBACK), &callback)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return cb, err
+	}
+
+	cbUpdated, err := callback(m, helpers, req, res, cb)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return cb, err
+	}
+
+	return cbUpdated, nil
+}
+
+// OnMessageFunc handles a consumed message, the user js function can return
+// callbacks to send, webhooks of the event for instance.
+func (f *Function) OnMessageFunc(msg messaging.Message) ([]action.Callback, error) {
+
+	if !f.HasFuncOnMessage {
+		return nil, errors.New("function file " + f.FileName + " not contains " + FUNC_ON_MESSAGE + " function")
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_ON_MESSAGE, time.Since(start))
+	}()
+
+	var onMessage func(messaging.Message) ([]action.Callback, error)
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm, consumers use the core state
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, "")
+	}
+	if err != nil {
+
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	err = vm.ExportTo(vm.Get(FUNC_ON_MESSAGE), &onMessage)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	callbacks, err := onMessage(msg)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	if len(callbacks) > 0 {
+		if err = f.checkHttpApi(); err != nil {
+			return nil, err
+		}
+	}
+
+	return callbacks, nil
+}
+
+// load runs the file in the VM, then adds the alfred.* helpers. The
+// functions and the exports of the file run before in the VM are dropped.
+func (f *Function) load(vm *goja.Runtime) error {
+
+	f.applySandbox(vm)
+
+	for _, name := range []string{FUNC_ALFRED, FUNC_UPDATE_HELPERS, FUNC_CALLBACK, FUNC_ON_MESSAGE, FUNC_ON_DATA, FUNC_HELPERS, FUNC_STREAM} {
+		vm.Set(name, goja.Undefined())
+	}
+
+	exports := vm.NewObject()
+	module := vm.NewObject()
+	module.Set("exports", exports)
+	vm.Set("exports", exports)
+	vm.Set("module", module)
+
+	_, err := vm.RunProgram(f.program)
+	if err != nil {
+		return err
+	}
+
+	return setAlfredApi(vm, getVmSandbox(vm).sandbox)
+}
+
 func (f *Function) CheckIfFuncExists(funcName string) (bool, error) {
 
 	pool := GetPool()
-	vm := pool.acquireVM()
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return false, errors.New(f.FileName + ": " + errorMessage(err))
+	}
 	defer pool.releaseVM(vm)
 
 	//load js functions in vm
-	_, err := vm.RunString(f.FileContent)
+	err = f.load(vm)
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return false, err
 	}
 
+	return f.funcExists(vm, funcName)
+}
+
+// funcExists checks a function of the file loaded in the vm
+func (f *Function) funcExists(vm *goja.Runtime, funcName string) (bool, error) {
+
 	v, err := vm.RunString("typeof " + funcName + " === 'function'")
 	if err != nil {
-		err = errors.New(f.FileName + ": " + err.Error())
+		err = errors.New(f.FileName + ": " + errorMessage(err))
 		return false, err
 	}
 
 	return v.Export().(bool), nil
-
 }
 
 // Shutdown gracefully stops the pool and cleanup routine
@@ -18,17 +18,22 @@ package function
 
 import (
 	"alfred/pkg/files"
+	"errors"
+	"fmt"
 	"os"
 	"path"
 )
 
+// Error of the folders without function file
+var ErrNoFunctionFile = errors.New("no function file")
+
 func CreateFunctionCollectionFromFolder(path string) (FunctionCollection, error) {
 
 	functionCollection := FunctionCollection{}
 
-	matches, err := files.FindFiles(path, "*.js")
+	matches, err := files.FindFiles(path, "*.js", "*.ts")
 	if err != nil {
-		return functionCollection, err
+		return functionCollection, fmt.Errorf("%w: %s", ErrNoFunctionFile, err.Error())
 	}
 
 	for _, path := range matches {
@@ -44,6 +49,13 @@ func CreateFunctionCollectionFromFolder(path string) (FunctionCollection, error)
 			return functionCollection, err
 		}
 
+		if function.HasFuncHelpers {
+			_, err = function.RegisterHelpers()
+			if err != nil {
+				return functionCollection, err
+			}
+		}
+
 		functionCollection = append(functionCollection, function)
 	}
 
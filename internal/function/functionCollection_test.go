@@ -24,6 +24,10 @@ import (
 
 func TestCreateFunctionCollectionFromFolder(t *testing.T) {
 
+	// modules required by the example functions
+	SetModulesRoot("../../user-files/functions/")
+	defer SetModulesRoot("")
+
 	collection, err := CreateFunctionCollectionFromFolder("../../user-files/functions/")
 	if err != nil {
 
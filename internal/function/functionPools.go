@@ -0,0 +1,120 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool of the function files without their own pool
+const SHARED_POOL = "shared"
+
+// Pools of the function files isolated from the shared pool
+var (
+	functionPools      = map[string]*VMPool{}
+	functionPoolsMutex sync.RWMutex
+)
+
+// SetFunctionPool gives a function file its own pool of VMs, so a slow file
+// can't exhaust the shared pool. The existing pool is kept if its quotas did
+// not change.
+func SetFunctionPool(fileName string, minSize int, maxSize int, acquireTimeout time.Duration) {
+
+	functionPoolsMutex.Lock()
+	defer functionPoolsMutex.Unlock()
+
+	if pool, exists := functionPools[fileName]; exists {
+
+		if pool.minSize == minSize && pool.maxSize == maxSize && pool.acquireTimeout == acquireTimeout {
+			return
+		}
+
+		pool.Shutdown()
+	}
+
+	functionPools[fileName] = initializePool(fileName, minSize, maxSize, acquireTimeout)
+}
+
+func getFunctionPool(fileName string) *VMPool {
+
+	functionPoolsMutex.RLock()
+	defer functionPoolsMutex.RUnlock()
+
+	if pool, exists := functionPools[fileName]; exists {
+		return pool
+	}
+
+	return GetPool()
+}
+
+// ShutdownPools drains the shared pool and the function files pools, at
+// shutdown once the requests are served.
+func ShutdownPools() {
+
+	functionPoolsMutex.Lock()
+	defer functionPoolsMutex.Unlock()
+
+	for name, pool := range functionPools {
+		pool.Shutdown()
+		delete(functionPools, name)
+	}
+
+	if globalPool != nil {
+		globalPool.Shutdown()
+	}
+}
+
+// Pool usage of the admin ui
+type PoolStats struct {
+	Name    string `json:"name"`
+	Size    int    `json:"size"`
+	InUse   int    `json:"inUse"`
+	MinSize int    `json:"minSize"`
+	MaxSize int    `json:"maxSize"`
+}
+
+// GetPoolsStats returns the stats of the shared pool, then of the function
+// files pools sorted by name.
+func GetPoolsStats() []PoolStats {
+
+	stats := []PoolStats{GetPool().Stats()}
+
+	functionPoolsMutex.RLock()
+	defer functionPoolsMutex.RUnlock()
+
+	names := make([]string, 0, len(functionPools))
+	for name := range functionPools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats = append(stats, functionPools[name].Stats())
+	}
+
+	return stats
+}
+
+func (p *VMPool) Stats() PoolStats {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return PoolStats{Name: p.name, Size: p.current, InUse: p.inUse, MinSize: p.minSize, MaxSize: p.maxSize}
+}
@@ -0,0 +1,803 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/action"
+	"alfred/internal/database"
+	"alfred/internal/helper"
+	"alfred/internal/jwt"
+	"alfred/internal/log"
+	"alfred/internal/messaging"
+	"alfred/internal/mock"
+	"alfred/internal/sandbox"
+	"alfred/internal/store"
+	"alfred/pkg/request"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAlfredJwtApi(t *testing.T) {
+
+	err := jwt.Configure(jwt.Config{Secret: "alfred-secret"})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	f, err := CreateFunction("jwt.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			const token = alfred.jwt.sign({ sub: "bruce" });
+			res.body = alfred.jwt.verify(token).sub + " " + alfred.jwt.decode(token).header.alg;
+			try {
+				alfred.jwt.verify(token, { secret: "wrong" });
+			} catch (e) {
+				res.status = 401;
+			}
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{Status: 200})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != "bruce HS256" || res.Status != 401 {
+		t.Errorf("response is: %+v", res)
+	}
+}
+
+func TestAlfredFakerApi(t *testing.T) {
+
+	f, err := CreateFunction("faker.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			const seeded = alfred.faker.seed(42).name() === alfred.faker.seed(42).name();
+			const n = alfred.faker.int(1, 3);
+			res.body = seeded + " " + (n >= 1 && n <= 3) + " " + alfred.faker.pick(["a"]);
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != "true true a" {
+		t.Errorf("response body is: %s", res.Body)
+	}
+}
+
+func TestFunctionPool(t *testing.T) {
+
+	SetFunctionPool("slow.js", 0, 1, 10*time.Millisecond)
+
+	pool := getFunctionPool("slow.js")
+	if pool == GetPool() {
+		t.Fatalf("slow.js should have its own pool")
+	}
+
+	if getFunctionPool("other.js") != GetPool() {
+		t.Errorf("other.js should use the shared pool")
+	}
+
+	vm, err := pool.acquireVM()
+	if err != nil {
+		t.Fatalf("acquire failed with error: %v", err)
+	}
+
+	stats := pool.Stats()
+	if stats.Name != "slow.js" || stats.InUse != 1 || stats.MaxSize != 1 {
+		t.Errorf("unexpected pool stats %+v", stats)
+	}
+
+	_, err = pool.acquireVM()
+	if err == nil {
+		t.Errorf("acquire should fail when the pool quota is reached")
+	}
+
+	pool.releaseVM(vm)
+
+	vm, err = pool.acquireVM()
+	if err != nil {
+		t.Fatalf("acquire after release failed with error: %v", err)
+	}
+	pool.releaseVM(vm)
+
+	// same quotas, the pool is kept
+	SetFunctionPool("slow.js", 0, 1, 10*time.Millisecond)
+	if getFunctionPool("slow.js") != pool {
+		t.Errorf("slow.js pool should be kept")
+	}
+}
+
+func TestRequireModule(t *testing.T) {
+
+	dir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "lib", "double.js"), []byte("exports.double = function (n) { return n * 2; };"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetModulesRoot(dir)
+	defer SetModulesRoot("")
+
+	f, err := CreateFunction("require.js", []byte(`
+		var lib = require('./lib/double.js');
+		function alfred(mock, helpers, req, res) { res.body = String(lib.double(21)); return res; }
+	`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("function call failed with error: %v", err)
+	}
+
+	if res.Body != "42" {
+		t.Errorf("required module result is '%s', want '42'", res.Body)
+	}
+
+	// the changed module is required again once the modules are reloaded
+	err = os.WriteFile(filepath.Join(dir, "lib", "double.js"), []byte("exports.double = function (n) { return n * 3; };"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ReloadModules()
+
+	res, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("function call failed with error: %v", err)
+	}
+
+	if res.Body != "63" {
+		t.Errorf("reloaded module result is '%s', want '63'", res.Body)
+	}
+}
+
+func TestTypescriptFunction(t *testing.T) {
+
+	f, err := CreateFunction("typed.ts", []byte(`
+		interface Greeting { message: string; count?: number }
+		function alfred(mock: any, helpers: any[], req: any, res: any): any {
+			const greeting: Greeting = { message: "hello " + (req.query?.name ?? "bruce") };
+			res.body = JSON.stringify(greeting);
+			return res;
+		}
+	`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	if !f.HasFuncAlfred {
+		t.Fatalf("typescript function file should have an alfred function")
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{Query: map[string]string{"name": "alfred"}}, request.Res{})
+	if err != nil {
+		t.Fatalf("function call failed with error: %v", err)
+	}
+
+	if res.Body != `{"message":"hello alfred"}` {
+		t.Errorf("typescript function result is '%s'", res.Body)
+	}
+
+	_, err = CreateFunction("invalid.ts", []byte("function alfred(: any) {"))
+	if err == nil {
+		t.Errorf("invalid typescript should fail")
+	}
+}
+
+func TestTimers(t *testing.T) {
+
+	SetFunctionPool("timers.js", 0, 1, time.Second)
+
+	f, err := CreateFunction("timers.js", []byte(`
+		var fired = fired || [];
+		function alfred(mock, helpers, req, res) {
+			if (req.body === "read") {
+				res.body = fired.join(",");
+				fired = [];
+				return res;
+			}
+			setTimeout(function (name) { fired.push(name); }, 10, "timeout");
+			clearTimeout(setTimeout(function () { fired.push("cleared"); }, 10));
+			var ticks = 0;
+			var id = setInterval(function () {
+				if (++ticks === 3) {
+					fired.push("interval");
+					clearInterval(id);
+				}
+			}, 5);
+			res.body = "scheduled";
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != "scheduled" {
+		t.Errorf("response body is: %s", res.Body)
+	}
+
+	// the single VM of the pool comes back once its timers ran
+	time.Sleep(200 * time.Millisecond)
+	res, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "read"}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != "timeout,interval" {
+		t.Errorf("response body is: %s", res.Body)
+	}
+}
+
+func TestTimersDontPinVMs(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+	SetFunctionPool("pending-timers.js", 0, 1, 50*time.Millisecond)
+
+	f, err := CreateFunction("pending-timers.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			setTimeout(function () {}, 500);
+			try {
+				for (var i = 0; i < 1000; i++) {
+					setTimeout(function () {}, 500);
+				}
+			} catch (err) {
+				res.status = 400;
+			}
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	// the VM running its timers doesn't hold the single slot of the pool, the
+	// timers of the second call are cleared since a VM already runs timers
+	for i := 0; i < 2; i++ {
+		res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{Status: 200})
+		if err != nil {
+			t.Fatalf("alfred function failed with error: %v", err)
+		}
+
+		if res.Status != 400 {
+			t.Errorf("pending timers should be limited, status is: %d", res.Status)
+		}
+	}
+}
+
+func TestAlfredCryptoApi(t *testing.T) {
+
+	f, err := CreateFunction("crypto.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			var c = alfred.crypto, e = alfred.encoding;
+			res.body = [
+				c.md5("alfred"),
+				c.sha256("alfred", "base64"),
+				c.hmac("sha256", "secret", "alfred"),
+				c.randomBytes(16).length,
+				e.base64Decode(e.base64Encode("bruce wayne")),
+				e.hexEncode("ab"),
+				e.urlEncode("a b&c"),
+			].join(" ");
+			try {
+				c.hmac("md4", "secret", "alfred");
+			} catch (err) {
+				res.status = 400;
+			}
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{Status: 200})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	expected := "29cb2448018800ab65a9de297548b6e0 eytUerU3eQYwDGnNBlqkjswUPdzZUB7yt8ZAjMNZKsM= " +
+		"630db3baee546eb7c00ca0538a6193a6ac6ea1e96d5efe48530f07f64eb09bd7 32 bruce wayne 6162 a+b%26c"
+	if res.Body != expected || res.Status != 400 {
+		t.Errorf("response is: %+v", res)
+	}
+}
+
+func TestAlfredStateApi(t *testing.T) {
+
+	err := store.Configure(store.Config{Type: store.TYPE_MEMORY})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := CreateFunction("state.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			var cart = alfred.state.get("cart") || { items: [] };
+			cart.items.push(req.body);
+			alfred.state.set("cart", cart);
+			res.body = cart.items.join(",") + " " + alfred.state.keys().join(",");
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	core := mock.Mock{}
+	payments := mock.Mock{}
+	mock.MockCollection{Mocks: []*mock.Mock{&payments}}.SetService("payments")
+
+	f.AlfredFunc(core, nil, request.Req{Body: "a"}, request.Res{})
+	f.AlfredFunc(payments, nil, request.Req{Body: "c"}, request.Res{})
+
+	res, err := f.AlfredFunc(core, nil, request.Req{Body: "b"}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != "a,b cart" {
+		t.Errorf("core response body is: %s", res.Body)
+	}
+
+	value, _, _ := store.Get().Get("payments", "cart")
+	if value != `{"items":["c"]}` {
+		t.Errorf("payments cart is: %s", value)
+	}
+}
+
+func TestAlfredDbApi(t *testing.T) {
+
+	err := database.Configure(database.Config{Driver: database.DRIVER_SQLITE, Dsn: filepath.Join(t.TempDir(), "fixtures.db")})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+	defer database.Close()
+
+	_, err = database.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	if err != nil {
+		t.Fatalf("exec failed with error: %v", err)
+	}
+
+	f, err := CreateFunction("db.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			var inserted = alfred.db.exec("INSERT INTO users (name) VALUES (?)", req.body);
+			var users = alfred.db.query("SELECT id, name FROM users WHERE id = ?", inserted.lastInsertId);
+			res.body = JSON.stringify(users);
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "bruce"}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Body != `[{"id":1,"name":"bruce"}]` {
+		t.Errorf("response body is: %s", res.Body)
+	}
+}
+
+func TestOnMessage(t *testing.T) {
+
+	f, err := CreateFunction("consumer.js", []byte(`
+		function onMessage(message) {
+			var order = JSON.parse(message.body);
+			alfred.state.set("order-" + message.key, order.status);
+			return [{ url: "http://localhost:8080/webhooks/" + message.topic, body: message.body }];
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	if !f.HasFuncOnMessage {
+		t.Fatalf("function file should have an onMessage function")
+	}
+
+	callbacks, err := f.OnMessageFunc(messaging.Message{Topic: "orders", Key: "42", Body: `{"status":"shipped"}`})
+	if err != nil {
+		t.Fatalf("onMessage function failed with error: %v", err)
+	}
+
+	if len(callbacks) != 1 || callbacks[0].Url != "http://localhost:8080/webhooks/orders" || callbacks[0].Body != `{"status":"shipped"}` {
+		t.Errorf("callbacks are: %+v", callbacks)
+	}
+
+	value, _, _ := store.Get().Get(store.CORE_NAMESPACE, "order-42")
+	if value != `"shipped"` {
+		t.Errorf("order state is: %s", value)
+	}
+}
+
+func TestCustomHelpers(t *testing.T) {
+
+	f, err := CreateFunction("helpers.js", []byte(`
+		function helpers() {
+			return {
+				tenant: function(req) { return req.headers["X-Tenant"].toUpperCase(); },
+				visits: function(req) {
+					var visits = (alfred.state.get("visits") || 0) + 1;
+					alfred.state.set("visits", visits);
+					return visits;
+				}
+			};
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	names, err := f.RegisterHelpers()
+	if err != nil || len(names) != 2 {
+		t.Fatalf("custom helpers are: %v, error: %v", names, err)
+	}
+
+	h, err := helper.HelpersBuilder([]byte(`{"body":"{{ alfred.custom.tenant }} {{ alfred.custom.visits }}"}`))
+	if err != nil {
+		t.Fatalf("helpers creation failed with error: %v", err)
+	}
+
+	h, err = helper.GeneratorWatcher(request.Req{Headers: map[string]string{"X-Tenant": "acme"}}, h)
+	if err != nil {
+		t.Fatalf("generator watcher failed with error: %v", err)
+	}
+
+	if h[0].Value != "ACME" || h[1].Value != "1" {
+		t.Errorf("custom helpers values are: %s %s", h[0].Value, h[1].Value)
+	}
+
+	_, err = CreateFunction("bad-helpers.js", []byte(`function helpers() { return { tenant: "acme" }; }`))
+	if err == nil {
+		t.Errorf("function with a custom helper not being a function should fail")
+	}
+}
+
+func TestJobs(t *testing.T) {
+
+	f, err := CreateFunction("jobs.js", []byte(`
+		exports.jobs = [
+			{ cron: "@hourly", handler: function() { alfred.state.set("job-runs", (alfred.state.get("job-runs") || 0) + 1); } },
+			{ name: "webhook", cron: "*/5 * * * *", handler: function() { return [{ url: "http://localhost:8080/webhooks/tick" }]; } }
+		];`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	jobs := NewJobs(FunctionCollection{f})
+	if len(jobs) != 2 || jobs[0].Name != "jobs.js#0" || jobs[1].Name != "webhook" || jobs[1].Cron != "*/5 * * * *" {
+		t.Fatalf("jobs are: %+v", jobs)
+	}
+
+	_, err = f.JobFunc(0)
+	if err != nil {
+		t.Fatalf("job failed with error: %v", err)
+	}
+
+	value, _, _ := store.Get().Get(store.CORE_NAMESPACE, "job-runs")
+	if value != "1" {
+		t.Errorf("job runs state is: %s", value)
+	}
+
+	callbacks, err := f.JobFunc(1)
+	if err != nil || len(callbacks) != 1 || callbacks[0].Url != "http://localhost:8080/webhooks/tick" {
+		t.Errorf("job callbacks are: %+v, error: %v", callbacks, err)
+	}
+
+	_, err = CreateFunction("bad-jobs.js", []byte(`exports.jobs = [{ cron: "every minute", handler: function() {} }];`))
+	if err == nil {
+		t.Errorf("function with a bad job cron should fail")
+	}
+}
+
+func TestStreamFunc(t *testing.T) {
+
+	f, err := CreateFunction("stream.js", []byte(`
+		function* stream(mock, helpers, req, res) {
+			try {
+				yield "start";
+				for (let i = 1; i <= 3; i++) {
+					yield { body: { id: i, path: req.url }, delay: 10 * i };
+				}
+				yield { id: 4 };
+			} finally {
+				alfred.state.set("stream-closed", true);
+			}
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	if !f.HasFuncStream {
+		t.Fatalf("stream function not found")
+	}
+
+	var chunks []mock.StreamChunk
+	emit := func(chunk mock.StreamChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}
+
+	err = f.StreamFunc(mock.Mock{}, nil, request.Req{Url: "/events"}, request.Res{}, emit)
+	if err != nil {
+		t.Fatalf("stream function failed with error: %v", err)
+	}
+
+	if len(chunks) != 5 || chunks[0].Body != "start" || chunks[2].Body != `{"id":2,"path":"/events"}` || chunks[2].Delay != 20 || chunks[4].Body != `{"id":4}` {
+		t.Errorf("chunks are: %+v", chunks)
+	}
+
+	// the client is gone after the first chunk
+	chunks = nil
+	gone := func(chunk mock.StreamChunk) error {
+		chunks = append(chunks, chunk)
+		return context.Canceled
+	}
+
+	store.Get().Delete(store.CORE_NAMESPACE, "stream-closed")
+
+	err = f.StreamFunc(mock.Mock{}, nil, request.Req{}, request.Res{}, gone)
+	if err != context.Canceled || len(chunks) != 1 {
+		t.Errorf("stream should stop, got error: %v and chunks %+v", err, chunks)
+	}
+
+	closed, _, _ := store.Get().Get(store.CORE_NAMESPACE, "stream-closed")
+	if closed != "true" {
+		t.Errorf("generator should be closed")
+	}
+
+	f, err = CreateFunction("stream-array.js", []byte(`
+		function stream(mock, helpers, req, res) {
+			return ["a", { body: "b", event: "update" }];
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	chunks = nil
+	err = f.StreamFunc(mock.Mock{}, nil, request.Req{}, request.Res{}, emit)
+	if err != nil || len(chunks) != 2 || chunks[1].Body != "b" || chunks[1].Event != "update" {
+		t.Errorf("chunks are: %+v, error: %v", chunks, err)
+	}
+}
+
+func TestSandboxApis(t *testing.T) {
+
+	err := sandbox.Configure([]sandbox.Policy{
+		{Name: "team-a", Files: []string{"team-a-*.js"}, Allow: []string{sandbox.API_FAKER}},
+	}, "")
+	if err != nil {
+		t.Fatalf("sandbox configuration failed with error: %v", err)
+	}
+	defer sandbox.Configure(nil, "")
+
+	f, err := CreateFunction("team-a-apis.js", []byte(`
+		function alfred(mock, helpers, req, res) {
+			const used = [];
+			for (const api of ["faker", "db", "state", "require", "console", "setTimeout"]) {
+				try {
+					const v = api in alfred ? alfred[api] : globalThis[api];
+					if (v !== undefined) used.push(api);
+				} catch (e) {
+					if (api === "db") res.body = e.message;
+				}
+			}
+			res.headers = { used: used.join(",") };
+			return res;
+		}
+		function callback(mock, helpers, req, res, cb) {
+			return cb;
+		}
+		function onMessage(message) {
+			return message.body === "quiet" ? [] : [{ url: "http://localhost:8080/webhooks/" + message.topic }];
+		}
+		exports.jobs = [{ cron: "@hourly", handler: function() { return [{ url: "http://localhost:8080/webhooks/tick" }]; } }];`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Headers["used"] != "faker" || res.Body != "db api not allowed by sandbox policy team-a" {
+		t.Errorf("used apis are: %s, db error is: %s", res.Headers["used"], res.Body)
+	}
+
+	_, err = f.CallbackFunc(mock.Mock{}, nil, request.Req{}, request.Res{}, action.Callback{})
+	if err == nil {
+		t.Errorf("callback function should not be allowed to rewrite http callbacks")
+	}
+
+	_, err = f.OnMessageFunc(messaging.Message{Topic: "orders"})
+	if err == nil {
+		t.Errorf("onMessage function should not be allowed to return http callbacks")
+	}
+
+	callbacks, err := f.OnMessageFunc(messaging.Message{Topic: "orders", Body: "quiet"})
+	if err != nil || len(callbacks) != 0 {
+		t.Errorf("onMessage function without callbacks failed: %+v, %v", callbacks, err)
+	}
+
+	_, err = f.JobFunc(0)
+	if err == nil {
+		t.Errorf("job should not be allowed to return http callbacks")
+	}
+
+	// the pooled VMs get their apis back without policy
+	sandbox.Configure(nil, "")
+
+	res, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{}, request.Res{})
+	if err != nil {
+		t.Fatalf("alfred function failed with error: %v", err)
+	}
+
+	if res.Headers["used"] != "faker,db,state,require,console,setTimeout" {
+		t.Errorf("used apis are: %s", res.Headers["used"])
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+
+	err := sandbox.Configure([]sandbox.Policy{
+		{Name: "team-b", Files: []string{"team-b-*.js"}, MaxStackDepth: 50, Timeout: "1s"},
+	}, "")
+	if err != nil {
+		t.Fatalf("sandbox configuration failed with error: %v", err)
+	}
+	defer sandbox.Configure(nil, "")
+
+	f, err := CreateFunction("team-b-limits.js", []byte(`
+		function depth(n) { return n === 0 ? 0 : 1 + depth(n - 1); }
+		function alfred(mock, helpers, req, res) {
+			switch (req.body) {
+			case "shallow":
+				res.body = "" + depth(20);
+				break;
+			case "deep":
+				depth(1000);
+				break;
+			case "loop":
+				for (;;) {}
+			case "allocate":
+				let chunks = [];
+				for (let i = 0; i < 8; i++) {
+					chunks.push(new Array(100000).fill(i));
+					if (chunks.length > 10) chunks = [];
+				}
+				res.body = "allocated";
+				break;
+			}
+			return res;
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "shallow"}, request.Res{})
+	if err != nil || res.Body != "20" {
+		t.Errorf("shallow recursion is: %s, %v", res.Body, err)
+	}
+
+	tests := map[string]string{
+		"deep": "maximum call stack depth exceeded",
+		"loop": "stopped after the 1s timeout of sandbox policy team-b",
+	}
+
+	for body, want := range tests {
+
+		start := time.Now()
+		_, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: body}, request.Res{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s call error is: %v, want: %s", body, err, want)
+		}
+
+		if time.Since(start) > 3*time.Second {
+			t.Errorf("%s call stopped after %s", body, time.Since(start))
+		}
+	}
+
+	// the interrupted VMs are reusable
+	res, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "shallow"}, request.Res{})
+	if err != nil || res.Body != "20" {
+		t.Errorf("shallow recursion is: %s, %v", res.Body, err)
+	}
+
+	// the allocations of a concurrent call don't stop an other one
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		res, err := f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "allocate"}, request.Res{})
+		if err != nil || res.Body != "allocated" {
+			t.Errorf("allocating call is: %s, %v", res.Body, err)
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+
+		res, err = f.AlfredFunc(mock.Mock{}, nil, request.Req{Body: "shallow"}, request.Res{})
+		if err != nil || res.Body != "20" {
+			t.Errorf("allocation-free call is: %s, %v", res.Body, err)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestSandboxStream(t *testing.T) {
+
+	err := sandbox.Configure([]sandbox.Policy{
+		{Name: "team-c", Files: []string{"team-c-*.js"}, Allow: []string{sandbox.API_ALL}, Timeout: "100ms"},
+	}, "")
+	if err != nil {
+		t.Fatalf("sandbox configuration failed with error: %v", err)
+	}
+	defer sandbox.Configure(nil, "")
+
+	f, err := CreateFunction("team-c-stream.js", []byte(`
+		function* stream(mock, helpers, req, res) {
+			for (let i = 1; i <= 3; i++) {
+				yield { id: i };
+			}
+			if (req.body === "loop") for (;;) {}
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	// the chunks delays don't count in the timeout
+	chunks := 0
+	slow := func(chunk mock.StreamChunk) error {
+		chunks++
+		time.Sleep(60 * time.Millisecond)
+		return nil
+	}
+
+	err = f.StreamFunc(mock.Mock{}, nil, request.Req{}, request.Res{}, slow)
+	if err != nil || chunks != 3 {
+		t.Errorf("stream sent %d chunks, error: %v", chunks, err)
+	}
+
+	err = f.StreamFunc(mock.Mock{}, nil, request.Req{Body: "loop"}, request.Res{}, slow)
+	if err == nil || !strings.Contains(err.Error(), "stopped after the 100ms timeout of sandbox policy team-c") {
+		t.Errorf("stream error is: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package function
+
+import (
+	"alfred/internal/helper"
+	"alfred/pkg/metrics"
+	"alfred/pkg/request"
+	"errors"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const FUNC_HELPERS = "helpers"
+
+// HelperNames returns the custom helpers of the helpers() function, an object
+// of functions computing a value from the request.
+func (f *Function) HelperNames() ([]string, error) {
+
+	if !f.HasFuncHelpers {
+		return nil, errors.New("function file " + f.FileName + " not contains " + FUNC_HELPERS + " function")
+	}
+
+	pool := GetPool()
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm
+	err = f.load(vm)
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	return f.helperNames(vm)
+}
+
+// helperNames returns the custom helpers of the file loaded in the vm
+func (f *Function) helperNames(vm *goja.Runtime) ([]string, error) {
+
+	helpers, err := f.getHelpers(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range helpers.Keys() {
+
+		if _, isFunc := goja.AssertFunction(helpers.Get(name)); !isFunc {
+			return nil, errors.New(f.FileName + ": custom helper " + name + " is not a function")
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// RegisterHelpers adds the custom helpers of the file, used in mocks with
+// {{ alfred.custom.<name> }}.
+func (f Function) RegisterHelpers() ([]string, error) {
+
+	names, err := f.HelperNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+
+		name := name
+		helper.RegisterCustom(name, func(h helper.Helper, req request.Req) (string, error) {
+			return f.HelperFunc(name, req)
+		})
+	}
+
+	return names, nil
+}
+
+// HelperFunc computes the value of a custom helper, empty when the function
+// returns nothing.
+func (f *Function) HelperFunc(name string, req request.Req) (string, error) {
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_HELPERS, time.Since(start))
+	}()
+
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return "", errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm, custom helpers use the core state
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, "")
+	}
+	if err != nil {
+		return "", errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	helpers, err := f.getHelpers(vm)
+	if err != nil {
+		return "", err
+	}
+
+	helperFunc, isFunc := goja.AssertFunction(helpers.Get(name))
+	if !isFunc {
+		return "", errors.New(f.FileName + ": custom helper " + name + " is not a function")
+	}
+
+	value, err := helperFunc(goja.Undefined(), vm.ToValue(req))
+	if err != nil {
+		return "", errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		return "", nil
+	}
+
+	return value.String(), nil
+}
+
+// getHelpers returns the helpers() object of the file loaded in the vm.
+func (f *Function) getHelpers(vm *goja.Runtime) (*goja.Object, error) {
+
+	helpersFunc, _ := goja.AssertFunction(vm.Get(FUNC_HELPERS))
+	if helpersFunc == nil {
+		return nil, errors.New("function file " + f.FileName + " not contains " + FUNC_HELPERS + " function")
+	}
+
+	helpers, err := helpersFunc(goja.Undefined())
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	if goja.IsUndefined(helpers) || goja.IsNull(helpers) {
+		return nil, errors.New(f.FileName + ": " + FUNC_HELPERS + " function must return an object of functions")
+	}
+
+	return helpers.ToObject(vm), nil
+}
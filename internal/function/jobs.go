@@ -0,0 +1,202 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package function
+
+import (
+	"alfred/internal/action"
+	"alfred/internal/cron"
+	"alfred/pkg/metrics"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"go.uber.org/zap"
+)
+
+const FUNC_JOBS = "jobs"
+
+// A job of the exports.jobs list, its handler is called on the cron schedule
+type Job struct {
+	Name string
+	Cron string
+}
+
+// GetJobs returns the jobs exported by the file.
+func (f *Function) GetJobs() []Job {
+
+	return f.jobs
+}
+
+// readJobs reads exports.jobs of the file loaded in the vm, a list of
+// { name, cron, handler }. Jobs without name are named after the file and
+// their index.
+func (f *Function) readJobs(vm *goja.Runtime) ([]Job, error) {
+
+	jobsValues, err := getJobsValues(vm)
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	var jobs []Job
+	for i, value := range jobsValues {
+
+		job := value.ToObject(vm)
+
+		name := f.FileName + "#" + strconv.Itoa(i)
+		if n := job.Get("name"); n != nil && !goja.IsUndefined(n) {
+			name = n.String()
+		}
+
+		var cronExpr string
+		if c := job.Get("cron"); c != nil && !goja.IsUndefined(c) {
+			cronExpr = c.String()
+		}
+
+		_, err = cron.Parse(cronExpr)
+		if err != nil {
+			return nil, errors.New(f.FileName + " job " + name + ": " + err.Error())
+		}
+
+		if _, isFunc := goja.AssertFunction(job.Get("handler")); !isFunc {
+			return nil, errors.New(f.FileName + " job " + name + ": handler is not a function")
+		}
+
+		jobs = append(jobs, Job{Name: name, Cron: cronExpr})
+	}
+
+	return jobs, nil
+}
+
+// getJobsValues returns the exports.jobs items of the file loaded in the vm.
+func getJobsValues(vm *goja.Runtime) ([]goja.Value, error) {
+
+	exports := vm.Get("module").ToObject(vm).Get("exports")
+	if exports == nil || goja.IsUndefined(exports) || goja.IsNull(exports) {
+		return nil, nil
+	}
+
+	jobs := exports.ToObject(vm).Get(FUNC_JOBS)
+	if jobs == nil || goja.IsUndefined(jobs) || goja.IsNull(jobs) {
+		return nil, nil
+	}
+
+	var values []goja.Value
+	err := vm.ExportTo(jobs, &values)
+	if err != nil {
+		return nil, errors.New("exports." + FUNC_JOBS + " must be a list of { name, cron, handler }")
+	}
+
+	return values, nil
+}
+
+// JobFunc runs the handler of the job, it can return callbacks to send,
+// periodic webhooks for instance.
+func (f *Function) JobFunc(index int) ([]action.Callback, error) {
+
+	if index >= len(f.jobs) {
+		return nil, errors.New("function file " + f.FileName + " not contains job " + strconv.Itoa(index))
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_JOBS, time.Since(start))
+	}()
+
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm, jobs use the core state
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, "")
+	}
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	jobsValues, err := getJobsValues(vm)
+	if err != nil || index >= len(jobsValues) {
+		return nil, errors.New(f.FileName + ": job " + f.jobs[index].Name + " not found")
+	}
+
+	handler, _ := goja.AssertFunction(jobsValues[index].ToObject(vm).Get("handler"))
+
+	result, err := handler(goja.Undefined())
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	var callbacks []action.Callback
+	if !goja.IsUndefined(result) && !goja.IsNull(result) {
+
+		err = vm.ExportTo(result, &callbacks)
+		if err != nil {
+			return nil, errors.New(f.FileName + " job " + f.jobs[index].Name + " returned callbacks: " + err.Error())
+		}
+	}
+
+	if len(callbacks) > 0 {
+		if err = f.checkHttpApi(); err != nil {
+			return nil, err
+		}
+	}
+
+	return callbacks, nil
+}
+
+// NewJobs returns the jobs of the function files to schedule, their callbacks
+// are sent once the handler returned.
+func NewJobs(functions FunctionCollection) []cron.Job {
+
+	var jobs []cron.Job
+
+	for i := range functions {
+
+		f := functions[i]
+
+		for index, job := range f.GetJobs() {
+
+			index, name := index, job.Name
+
+			jobs = append(jobs, cron.Job{
+				Name:         name,
+				Cron:         job.Cron,
+				FunctionFile: f.FileName,
+				Run: func(ctx context.Context) error {
+
+					callbacks, err := f.JobFunc(index)
+					if err != nil {
+						return err
+					}
+
+					for _, callback := range callbacks {
+						go sendCallback(context.Background(), callback, zap.String("job", name))
+					}
+
+					return nil
+				},
+			})
+		}
+	}
+
+	return jobs
+}
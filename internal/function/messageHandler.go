@@ -0,0 +1,77 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/action"
+	"alfred/internal/log"
+	"alfred/internal/messaging"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sendCallback sends a callback returned by a message handler or a job.
+func sendCallback(ctx context.Context, callback action.Callback, fields ...zap.Field) {
+
+	fields = append(fields, zap.String("callback-url", callback.Url))
+
+	if callback.Method == "" {
+		callback.Method = action.CALLBACK_DEFAULT_METHOD
+	}
+
+	time.Sleep(callback.GetDelayDuration())
+
+	req, err := callback.CreateRequest()
+	if err != nil {
+		log.Error(ctx, "create callback request failed", err, fields...)
+		return
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		log.Error(ctx, "callback failed", err, fields...)
+		return
+	}
+
+	log.Debug(ctx, "callback sent", append(fields, zap.String("callback-responseStatus", resp.Status))...)
+}
+
+// NewMessageHandler runs the onMessage function of the file for each consumed
+// message, then sends the callbacks it returns.
+func NewMessageHandler(functions FunctionCollection, fileName string) (messaging.Handler, error) {
+
+	f, err := functions.GetFunction(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, msg messaging.Message) error {
+
+		callbacks, err := f.OnMessageFunc(msg)
+		if err != nil {
+			return err
+		}
+
+		for _, callback := range callbacks {
+			go sendCallback(ctx, callback, zap.String("topic", msg.Topic))
+		}
+
+		return nil
+	}, nil
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+)
+
+// Modules required by the functions files, relative paths and node_modules
+// packages are resolved from the modules root.
+var (
+	modulesRoot      string
+	modulesRootMutex sync.RWMutex
+
+	// compiled modules, replaced when the modules are reloaded
+	registry        = require.NewRegistry(require.WithLoader(loadModule))
+	registryVersion int
+	registryMutex   sync.RWMutex
+)
+
+// Registry version of the VMs, their modules cache is stale once the
+// modules are reloaded
+var vmModules sync.Map
+
+// SetModulesRoot sets the directory of the modules required by functions
+// files.
+func SetModulesRoot(root string) {
+
+	modulesRootMutex.Lock()
+	defer modulesRootMutex.Unlock()
+
+	modulesRoot = root
+}
+
+// ReloadModules drops the compiled modules, the modules files changed since
+// they were required are read again. The pooled VMs keep the modules they
+// required, they are replaced.
+func ReloadModules() {
+
+	registryMutex.Lock()
+	registry = require.NewRegistry(require.WithLoader(loadModule))
+	registryVersion++
+	registryMutex.Unlock()
+
+	GetPool().renew()
+
+	functionPoolsMutex.RLock()
+	defer functionPoolsMutex.RUnlock()
+
+	for _, pool := range functionPools {
+		pool.renew()
+	}
+}
+
+func enableModules(vm *goja.Runtime) {
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	registry.Enable(vm)
+	vmModules.Store(vm, registryVersion)
+}
+
+func hasStaleModules(vm *goja.Runtime) bool {
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	version, exists := vmModules.Load(vm)
+
+	return exists && version.(int) != registryVersion
+}
+
+// Function files are compiled with their file name, the modules paths are
+// then relative to the modules root. Typescript modules are transpiled.
+func loadModule(path string) ([]byte, error) {
+
+	modulesRootMutex.RLock()
+	root := modulesRoot
+	modulesRootMutex.RUnlock()
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, filepath.FromSlash(path))
+	}
+
+	content, err := require.DefaultSourceLoader(path)
+	if err != nil || !isTypescript(path) {
+		return content, err
+	}
+
+	return transpileTypescript(path, content)
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/sandbox"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Sandbox of the function file loaded in each pooled VM
+var vmSandboxes sync.Map
+
+// Globals of the VMs removed by the sandbox policies, by api
+var sandboxedGlobals = map[string][]string{
+	sandbox.API_REQUIRE: {"require"},
+	sandbox.API_CONSOLE: {"console"},
+	sandbox.API_TIMERS:  {"setTimeout", "setInterval", "clearTimeout", "clearInterval"},
+}
+
+type vmSandbox struct {
+	// globals of the VM when it was created, restored for the files allowed
+	// to use them
+	globals map[string]goja.Value
+
+	sandbox *sandbox.Sandbox
+	stop    func()
+
+	// time of the call while the guard was running
+	elapsed time.Duration
+}
+
+func getVmSandbox(vm *goja.Runtime) *vmSandbox {
+
+	if vs, exists := vmSandboxes.Load(vm); exists {
+		return vs.(*vmSandbox)
+	}
+
+	vs := &vmSandbox{globals: map[string]goja.Value{}}
+	for _, names := range sandboxedGlobals {
+		for _, name := range names {
+			vs.globals[name] = vm.Get(name)
+		}
+	}
+	vmSandboxes.Store(vm, vs)
+
+	return vs
+}
+
+// applySandbox restricts the VM to the sandbox of the function file before
+// the file is loaded. The sandbox guard runs until the VM is released.
+func (f *Function) applySandbox(vm *goja.Runtime) {
+
+	s := sandbox.Get(f.FileName)
+
+	vs := getVmSandbox(vm)
+	vs.stopGuard()
+	vs.sandbox = s
+
+	stackDepth := math.MaxInt32
+	if s != nil && s.MaxStackDepth > 0 {
+		stackDepth = s.MaxStackDepth
+	}
+	vm.SetMaxCallStackSize(stackDepth)
+
+	global := vm.GlobalObject()
+	for api, names := range sandboxedGlobals {
+		for _, name := range names {
+
+			if s.Allows(api) {
+				global.DefineDataProperty(name, vs.globals[name], goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_TRUE)
+			} else {
+				disableApi(vm, global, name, api, s)
+			}
+		}
+	}
+
+	vs.startGuard(vm)
+}
+
+// stopSandbox stops the guard of the call, before the VM is released
+func stopSandbox(vm *goja.Runtime) {
+
+	if vs, exists := vmSandboxes.Load(vm); exists {
+		vs.(*vmSandbox).stopGuard()
+	}
+}
+
+// checkHttpApi fails if the sandbox of the function file doesn't allow the
+// http callbacks, rewritten by the callback function or returned by the
+// onMessage function and the jobs.
+func (f *Function) checkHttpApi() error {
+
+	if s := sandbox.Get(f.FileName); !s.Allows(sandbox.API_HTTP) {
+		return errors.New(f.FileName + ": " + sandbox.API_HTTP + " api not allowed by sandbox policy " + s.Policy)
+	}
+
+	return nil
+}
+
+// allows tells if the function file loaded in the VM can use the api
+func allows(vm *goja.Runtime, api string) bool {
+
+	return getVmSandbox(vm).sandbox.Allows(api)
+}
+
+// disableApi replaces a property by a getter failing with the sandbox
+// policy, so a function file using a disabled api gets a clear error.
+func disableApi(vm *goja.Runtime, o *goja.Object, name string, api string, s *sandbox.Sandbox) error {
+
+	err := errors.New(api + " api not allowed by sandbox policy " + s.Policy)
+
+	getter := vm.ToValue(func() (goja.Value, error) {
+		return nil, err
+	})
+
+	return o.DefineAccessorProperty(name, getter, nil, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// startGuard interrupts the VM once the call runs longer than the sandbox
+// timeout.
+func (vs *vmSandbox) startGuard(vm *goja.Runtime) {
+
+	vs.elapsed = 0
+	vs.resumeGuard(vm)
+}
+
+// pauseGuard stops counting the time of the call while go code runs, the
+// chunk delays of a stream for instance.
+func (vs *vmSandbox) pauseGuard() {
+
+	vs.stopGuard()
+}
+
+// resumeGuard counts again the time of the call, from where the guard was
+// paused.
+func (vs *vmSandbox) resumeGuard(vm *goja.Runtime) {
+
+	s := vs.sandbox
+	if s == nil || s.Timeout == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	start := time.Now()
+
+	go func() {
+
+		defer close(stopped)
+
+		timer := time.NewTimer(s.Timeout - vs.elapsed)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			vm.Interrupt(errors.New("stopped after the " + s.Timeout.String() + " timeout of sandbox policy " + s.Policy))
+		}
+	}()
+
+	vs.stop = func() {
+		close(done)
+		<-stopped
+		vm.ClearInterrupt()
+
+		vs.elapsed += time.Since(start)
+	}
+}
+
+func (vs *vmSandbox) stopGuard() {
+
+	if vs.stop != nil {
+		vs.stop()
+		vs.stop = nil
+	}
+}
+
+// errorMessage describes the errors of the calls, goja stack overflows have
+// no message.
+func errorMessage(err error) string {
+
+	var overflow *goja.StackOverflowError
+	if errors.As(err, &overflow) {
+		return "maximum call stack depth exceeded"
+	}
+
+	return err.Error()
+}
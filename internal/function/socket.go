@@ -0,0 +1,118 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/mock"
+	"alfred/pkg/metrics"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const FUNC_ON_DATA = "onData"
+
+// Data received by a socket mock
+type SocketData struct {
+	Protocol   string `json:"protocol"`
+	RemoteAddr string `json:"remoteAddr"`
+	Text       string `json:"text"`
+	Hex        string `json:"hex"`
+	Base64     string `json:"base64"`
+}
+
+// Reply of the onData function, text, hex or base64 encoded. Close ends the
+// tcp connection once the reply is sent.
+type SocketReply struct {
+	Text   string `json:"text"`
+	Hex    string `json:"hex"`
+	Base64 string `json:"base64"`
+	Delay  int    `json:"delay"`
+	Close  bool   `json:"close"`
+}
+
+func NewSocketData(protocol string, remoteAddr string, data []byte) SocketData {
+
+	return SocketData{
+		Protocol:   protocol,
+		RemoteAddr: remoteAddr,
+		Text:       string(data),
+		Hex:        hex.EncodeToString(data),
+		Base64:     base64.StdEncoding.EncodeToString(data),
+	}
+}
+
+func (r SocketReply) Bytes() ([]byte, error) {
+
+	if r.Hex != "" {
+		return hex.DecodeString(r.Hex)
+	}
+
+	if r.Base64 != "" {
+		return base64.StdEncoding.DecodeString(r.Base64)
+	}
+
+	return []byte(r.Text), nil
+}
+
+// OnDataFunc lets the user js function answer the data received by a socket
+// mock, nil when the function returns nothing.
+func (f *Function) OnDataFunc(m mock.Mock, data SocketData) (*SocketReply, error) {
+
+	if !f.HasFuncOnData {
+		return nil, errors.New("function file " + f.FileName + " not contains " + FUNC_ON_DATA + " function")
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_ON_DATA, time.Since(start))
+	}()
+
+	var onData func(mock.Mock, SocketData) (*SocketReply, error)
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return nil, errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, m.GetService())
+	}
+	if err != nil {
+
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	err = vm.ExportTo(vm.Get(FUNC_ON_DATA), &onData)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	reply, err := onData(m, data)
+	if err != nil {
+		err = errors.New(f.FileName + ": " + errorMessage(err))
+		return nil, err
+	}
+
+	return reply, nil
+}
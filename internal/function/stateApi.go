@@ -0,0 +1,92 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/sandbox"
+	"alfred/internal/store"
+	"sort"
+
+	"github.com/dop251/goja"
+)
+
+// setStateApi adds alfred.state to the VM, values are kept as json documents
+// in the store namespace of the mock service.
+func setStateApi(vm *goja.Runtime, service string) error {
+
+	alfred := vm.Get(FUNC_ALFRED).ToObject(vm)
+	if !allows(vm, sandbox.API_STATE) {
+		return disableApi(vm, alfred, "state", sandbox.API_STATE, getVmSandbox(vm).sandbox)
+	}
+
+	s := store.Get()
+	namespace := store.Namespace(service)
+
+	// native js objects, not wrapped go maps
+	parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+
+	state := vm.NewObject()
+
+	state.Set("get", func(key string) (goja.Value, error) {
+
+		value, exists, err := s.Get(namespace, key)
+		if err != nil || !exists {
+			return goja.Undefined(), err
+		}
+
+		return parse(goja.Undefined(), vm.ToValue(value))
+	})
+
+	state.Set("set", func(key string, value goja.Value) error {
+
+		data, err := stringify(goja.Undefined(), value)
+		if err != nil {
+			return err
+		}
+
+		// undefined is not serializable
+		if goja.IsUndefined(data) {
+			data = vm.ToValue("null")
+		}
+
+		return s.Set(namespace, key, data.String())
+	})
+
+	state.Set("delete", func(key string) error {
+		return s.Delete(namespace, key)
+	})
+
+	state.Set("keys", func() ([]string, error) {
+
+		entries, err := s.List(namespace)
+
+		keys := []string{}
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		return keys, err
+	})
+
+	state.Set("clear", func() error {
+		return s.Wipe(namespace)
+	})
+
+	return alfred.Set("state", state)
+}
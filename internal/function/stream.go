@@ -0,0 +1,168 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package function
+
+import (
+	"alfred/internal/helper"
+	"alfred/internal/mock"
+	"alfred/pkg/metrics"
+	"alfred/pkg/request"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const FUNC_STREAM = "stream"
+
+// StreamFunc emits the chunks produced by the stream function of the file: a
+// generator yielding them, or a function returning an array. A chunk is a
+// string, or an object with its body, delay and event; other objects are sent
+// as json. The VM is kept until the stream ends or the emit fails.
+func (f *Function) StreamFunc(m mock.Mock, helpers []helper.Helper, req request.Req, res request.Res, emit func(mock.StreamChunk) error) error {
+
+	if !f.HasFuncStream {
+		return errors.New("function file " + f.FileName + " not contains " + FUNC_STREAM + " function")
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveFunction(f.FileName, FUNC_STREAM, time.Since(start))
+	}()
+
+	var stream func(mock.Mock, []helper.Helper, request.Req, request.Res) (goja.Value, error)
+	pool := getFunctionPool(f.FileName)
+	vm, err := pool.acquireVM()
+	if err != nil {
+		return errors.New(f.FileName + ": " + errorMessage(err))
+	}
+	defer pool.releaseVM(vm)
+
+	//load js functions in vm
+	err = f.load(vm)
+	if err == nil {
+		err = setStateApi(vm, m.GetService())
+	}
+	if err == nil {
+		err = vm.ExportTo(vm.Get(FUNC_STREAM), &stream)
+	}
+	if err != nil {
+		return errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	chunks, err := stream(m, helpers, req, res)
+	if err != nil {
+		return errors.New(f.FileName + ": " + errorMessage(err))
+	}
+
+	if goja.IsUndefined(chunks) || goja.IsNull(chunks) {
+		return nil
+	}
+
+	// the time spent sending the chunks, and waiting their delays, doesn't
+	// count in the sandbox limits
+	vs := getVmSandbox(vm)
+	send := func(chunk mock.StreamChunk) error {
+		vs.pauseGuard()
+		defer vs.resumeGuard(vm)
+		return emit(chunk)
+	}
+
+	iterator := chunks.ToObject(vm)
+
+	// array of chunks
+	next, isGenerator := goja.AssertFunction(iterator.Get("next"))
+	if !isGenerator {
+
+		length := int(iterator.Get("length").ToInteger())
+		for i := 0; i < length; i++ {
+
+			err = send(toStreamChunk(iterator.Get(strconv.Itoa(i))))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for {
+		result, err := next(iterator)
+		if err != nil {
+			return errors.New(f.FileName + ": " + errorMessage(err))
+		}
+
+		step := result.ToObject(vm)
+		if step.Get("done").ToBoolean() {
+			return nil
+		}
+
+		err = send(toStreamChunk(step.Get("value")))
+		if err != nil {
+
+			// the finally blocks of the generator run
+			if end, ok := goja.AssertFunction(iterator.Get("return")); ok {
+				_, _ = end(iterator)
+			}
+
+			return err
+		}
+	}
+}
+
+func toStreamChunk(value goja.Value) mock.StreamChunk {
+
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return mock.StreamChunk{}
+	}
+
+	if body, ok := value.Export().(string); ok {
+		return mock.StreamChunk{Body: body}
+	}
+
+	object, ok := value.Export().(map[string]interface{})
+	if !ok {
+		data, _ := json.Marshal(value.Export())
+		return mock.StreamChunk{Body: string(data)}
+	}
+
+	body, exists := object["body"]
+	if !exists {
+		data, _ := json.Marshal(object)
+		return mock.StreamChunk{Body: string(data)}
+	}
+
+	chunk := mock.StreamChunk{}
+
+	if str, ok := body.(string); ok {
+		chunk.Body = str
+	} else {
+		data, _ := json.Marshal(body)
+		chunk.Body = string(data)
+	}
+
+	if delay, ok := object["delay"].(int64); ok {
+		chunk.Delay = int(delay)
+	} else if delay, ok := object["delay"].(float64); ok {
+		chunk.Delay = int(delay)
+	}
+
+	chunk.Event, _ = object["event"].(string)
+
+	return chunk
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"alfred/internal/log"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Pending timers are cleared after this time, and their VM released
+const TIMERS_TIMEOUT = 5 * time.Minute
+
+// setTimeout and setInterval throw past this number of pending timers of a VM
+const MAX_PENDING_TIMERS = 100
+
+// Timers of the VMs, a VM with pending timers leaves its pool until they all
+// ran.
+var vmTimers sync.Map
+
+// setTimeout and setInterval of a VM. Callbacks run after the function call,
+// in an event loop owning the VM, so a pooled VM is never used concurrently.
+type timers struct {
+	vm      *goja.Runtime
+	mutex   sync.Mutex
+	nextId  int64
+	pending map[int64]*timer
+	ready   chan *timer
+}
+
+type timer struct {
+	id       int64
+	callback goja.Callable
+	args     []goja.Value
+	delay    time.Duration
+	interval bool
+	goTimer  *time.Timer
+}
+
+// enableTimers adds the timers functions to the VM
+func enableTimers(vm *goja.Runtime) {
+
+	// room for every pending timer, and the ones cleared after they fired
+	t := &timers{vm: vm, pending: map[int64]*timer{}, ready: make(chan *timer, 2*MAX_PENDING_TIMERS)}
+	vmTimers.Store(vm, t)
+
+	vm.Set("setTimeout", func(call goja.FunctionCall) goja.Value {
+		return t.schedule(call, false)
+	})
+	vm.Set("setInterval", func(call goja.FunctionCall) goja.Value {
+		return t.schedule(call, true)
+	})
+	vm.Set("clearTimeout", t.clear)
+	vm.Set("clearInterval", t.clear)
+}
+
+func getTimers(vm *goja.Runtime) *timers {
+
+	t, exists := vmTimers.Load(vm)
+	if !exists {
+		return nil
+	}
+
+	return t.(*timers)
+}
+
+func (t *timers) schedule(call goja.FunctionCall, interval bool) goja.Value {
+
+	callback, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(t.vm.NewTypeError("timer callback is not a function"))
+	}
+
+	delay := time.Duration(call.Argument(1).ToInteger()) * time.Millisecond
+	if delay < 0 {
+		delay = 0
+	}
+
+	// intervals of 0ms would never let the VM go
+	if interval && delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+
+	var args []goja.Value
+	if len(call.Arguments) > 2 {
+		// goja reuses the arguments slice
+		args = append(args, call.Arguments[2:]...)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.pending) >= MAX_PENDING_TIMERS {
+		panic(t.vm.NewGoError(errors.New("too many pending timers, " + strconv.Itoa(MAX_PENDING_TIMERS) + " max")))
+	}
+
+	t.nextId++
+	tm := &timer{id: t.nextId, callback: callback, args: args, delay: delay, interval: interval}
+	t.pending[tm.id] = tm
+	t.arm(tm)
+
+	return t.vm.ToValue(tm.id)
+}
+
+func (t *timers) arm(tm *timer) {
+
+	tm.goTimer = time.AfterFunc(tm.delay, func() {
+		// the channel has room for every pending timer, the send never
+		// blocks the goroutine of a timer cleared after it fired
+		select {
+		case t.ready <- tm:
+		default:
+		}
+	})
+}
+
+func (t *timers) clear(id int64) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if tm, exists := t.pending[id]; exists {
+		tm.goTimer.Stop()
+		delete(t.pending, id)
+	}
+}
+
+func (t *timers) hasPending() bool {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.pending) > 0
+}
+
+// run executes the timers callbacks until none is pending, then calls done.
+func (t *timers) run(done func()) {
+
+	defer done()
+	defer t.drain()
+
+	timeout := time.NewTimer(TIMERS_TIMEOUT)
+	defer timeout.Stop()
+
+	for t.hasPending() {
+
+		select {
+		case tm := <-t.ready:
+
+			t.mutex.Lock()
+			_, exists := t.pending[tm.id]
+			t.mutex.Unlock()
+
+			// cleared after it fired
+			if !exists {
+				continue
+			}
+
+			// each callback runs with the sandbox limits of a call
+			vs := getVmSandbox(t.vm)
+			vs.startGuard(t.vm)
+			_, err := tm.callback(goja.Undefined(), tm.args...)
+			vs.stopGuard()
+			if err != nil {
+				log.Error(context.Background(), "js timer callback failed", err)
+			}
+
+			t.mutex.Lock()
+			if _, exists = t.pending[tm.id]; exists {
+				if tm.interval {
+					t.arm(tm)
+				} else {
+					delete(t.pending, tm.id)
+				}
+			}
+			t.mutex.Unlock()
+
+		case <-timeout.C:
+
+			log.Error(context.Background(), "js timers cleared", errors.New("timers still pending after "+TIMERS_TIMEOUT.String()))
+			t.clearAll()
+		}
+	}
+}
+
+func (t *timers) clearAll() {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for id, tm := range t.pending {
+		tm.goTimer.Stop()
+		delete(t.pending, id)
+	}
+}
+
+// drain drops the cleared timers which fired before the event loop stopped
+func (t *timers) drain() {
+
+	for {
+		select {
+		case <-t.ready:
+		default:
+			return
+		}
+	}
+}
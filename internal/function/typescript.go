@@ -0,0 +1,59 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package function
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+func isTypescript(fileName string) bool {
+
+	return strings.EqualFold(filepath.Ext(fileName), ".ts")
+}
+
+// transpileTypescript strips the types and lowers the syntax the VMs do not
+// support, top level functions are kept global.
+func transpileTypescript(fileName string, content []byte) ([]byte, error) {
+
+	result := api.Transform(string(content), api.TransformOptions{
+		Loader:     api.LoaderTS,
+		Sourcefile: fileName,
+		Target:     api.ES2015,
+	})
+
+	if len(result.Errors) > 0 {
+
+		var messages []string
+		for _, e := range result.Errors {
+
+			message := e.Text
+			if e.Location != nil {
+				message = fmt.Sprintf("%d:%d %s", e.Location.Line, e.Location.Column, e.Text)
+			}
+			messages = append(messages, message)
+		}
+
+		return nil, errors.New("typescript transpilation failed: " + strings.Join(messages, ", "))
+	}
+
+	return result.Code, nil
+}
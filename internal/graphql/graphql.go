@@ -0,0 +1,123 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+import (
+	"alfred/pkg/request"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// LoadSchema parses a schema definition (SDL) used to validate incoming operations.
+func LoadSchema(name string, sdl []byte) (*ast.Schema, error) {
+
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: name, Input: string(sdl)})
+	if err != nil {
+		return nil, errors.New("graphql schema " + name + ": " + err.Error())
+	}
+
+	return schema, nil
+}
+
+// ParseOperation reads a GraphQL over HTTP body. When the operation name is
+// not provided, it is taken from the query document.
+func ParseOperation(body []byte) (request.GraphqlOperation, error) {
+
+	var op request.GraphqlOperation
+
+	err := json.Unmarshal(body, &op)
+	if err != nil {
+		return op, errors.New("graphql body is not a valid json: " + err.Error())
+	}
+
+	if op.Query == "" {
+		return op, errors.New("graphql body has no query")
+	}
+
+	if op.OperationName == "" {
+
+		doc, err := parser.ParseQuery(&ast.Source{Input: op.Query})
+		if err != nil {
+			return op, errors.New("graphql query parsing failed: " + err.Error())
+		}
+
+		// only an anonymous document with a single operation can be resolved
+		operation := doc.Operations.ForName("")
+		if operation != nil {
+			op.OperationName = operation.Name
+		}
+	}
+
+	return op, nil
+}
+
+// Validate checks the operation document and its variables against the schema.
+func Validate(schema *ast.Schema, op request.GraphqlOperation) gqlerror.List {
+
+	doc, errs := gqlparser.LoadQuery(schema, op.Query)
+	if errs != nil {
+		return errs
+	}
+
+	operation := doc.Operations.ForName(op.OperationName)
+	if operation == nil {
+		return gqlerror.List{gqlerror.Errorf("operation '%s' not found in the query document", op.OperationName)}
+	}
+
+	_, err := validator.VariableValues(schema, operation, op.Variables)
+	if err != nil {
+
+		var gqlErr *gqlerror.Error
+		if errors.As(err, &gqlErr) {
+			return gqlerror.List{gqlErr}
+		}
+
+		return gqlerror.List{gqlerror.Wrap(err)}
+	}
+
+	return nil
+}
+
+// MatchVariables returns true if all expected variables are present with the
+// same value in the operation variables.
+func MatchVariables(expected map[string]interface{}, variables map[string]interface{}) bool {
+
+	for k, v := range expected {
+
+		value, exists := variables[k]
+		if !exists || !reflect.DeepEqual(v, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ErrorsBody builds a GraphQL compliant errors response body.
+func ErrorsBody(errs gqlerror.List) []byte {
+
+	body, _ := json.Marshal(map[string]interface{}{"errors": errs})
+
+	return body
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphql
+
+import (
+	"testing"
+)
+
+const testSchema = `
+type Query {
+  user(id: ID!): User
+}
+
+type User {
+  id: ID!
+  name: String!
+}`
+
+func TestParseOperation(t *testing.T) {
+
+	op, err := ParseOperation([]byte(`{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`))
+	if err != nil {
+		t.Errorf("parse operation failed with error: %v", err)
+	}
+
+	if op.OperationName != "GetUser" {
+		t.Errorf("operation name is: '%s', want: 'GetUser'", op.OperationName)
+	}
+
+	if op.Variables["id"] != "1" {
+		t.Errorf("operation variable id is: '%v', want: '1'", op.Variables["id"])
+	}
+
+	_, err = ParseOperation([]byte(`{"variables":{"id":"1"}}`))
+	if err == nil {
+		t.Errorf("parse operation without query should fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	schema, err := LoadSchema("test.graphql", []byte(testSchema))
+	if err != nil {
+		t.Fatalf("load schema failed with error: %v", err)
+	}
+
+	op, _ := ParseOperation([]byte(`{"query":"query GetUser($id: ID!) { user(id: $id) { name } }","variables":{"id":"1"}}`))
+	if errs := Validate(schema, op); errs != nil {
+		t.Errorf("valid operation rejected: %v", errs)
+	}
+
+	op, _ = ParseOperation([]byte(`{"query":"query GetUser($id: ID!) { user(id: $id) { age } }","variables":{"id":"1"}}`))
+	if errs := Validate(schema, op); errs == nil {
+		t.Errorf("operation with unknown field should be rejected")
+	}
+
+	op, _ = ParseOperation([]byte(`{"query":"query GetUser($id: ID!) { user(id: $id) { name } }"}`))
+	if errs := Validate(schema, op); errs == nil {
+		t.Errorf("operation without required variable should be rejected")
+	}
+}
+
+func TestMatchVariables(t *testing.T) {
+
+	variables := map[string]interface{}{"id": "1", "limit": float64(10)}
+
+	if !MatchVariables(map[string]interface{}{"id": "1"}, variables) {
+		t.Errorf("variables subset should match")
+	}
+
+	if MatchVariables(map[string]interface{}{"id": "2"}, variables) {
+		t.Errorf("variables with a different value should not match")
+	}
+
+	if MatchVariables(map[string]interface{}{"other": "1"}, variables) {
+		t.Errorf("missing variable should not match")
+	}
+}
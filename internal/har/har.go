@@ -0,0 +1,163 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package har
+
+import (
+	"alfred/internal/mock"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters replaced in the mock names
+var mockNameRegexp = regexp.MustCompile(`[^\w]+`)
+
+// Har is an HTTP Archive, as exported by browsers or proxies.
+type Har struct {
+	Log struct {
+		Entries []Entry `json:"entries"`
+	} `json:"log"`
+}
+
+type Entry struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Timings  struct {
+		Wait float64 `json:"wait"`
+	} `json:"timings"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	Url    string `json:"url"`
+}
+
+type Response struct {
+	Status  int         `json:"status"`
+	Headers []NameValue `json:"headers"`
+	Content struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func LoadHar(filePath string) (Har, error) {
+
+	var har Har
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return har, errors.New("har file " + filePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &har)
+	if err != nil {
+		return har, errors.New("har file " + filePath + " is not a valid json: " + err.Error())
+	}
+
+	return har, nil
+}
+
+// GenerateMocks creates a mock for each method and path captured, the first
+// entry is kept when a request has been captured several times. The server
+// wait time is replayed as response time.
+func GenerateMocks(har Har) ([]mock.Mock, error) {
+
+	var mocks []mock.Mock
+
+	captured := map[string]bool{}
+
+	for _, entry := range har.Log.Entries {
+
+		u, err := url.Parse(entry.Request.Url)
+		if err != nil {
+			return mocks, errors.New("har entry url " + entry.Request.Url + ": " + err.Error())
+		}
+
+		method := strings.ToUpper(entry.Request.Method)
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+
+		if captured[method+path] || entry.Response.Status <= 0 {
+			continue
+		}
+		captured[method+path] = true
+
+		var m mock.Mock
+
+		m.Name = getMockName(method, path)
+		m.Request.Method = method
+		m.Request.Url = path
+
+		headers := http.Header{}
+		for _, h := range entry.Response.Headers {
+			headers.Add(h.Name, h.Value)
+		}
+		if headers.Get("Content-Type") == "" && entry.Response.Content.MimeType != "" {
+			headers.Set("Content-Type", entry.Response.Content.MimeType)
+		}
+
+		m.Response = mock.BuildRecordedResponse(entry.Response.Status, headers, getBody(entry.Response))
+		m.Response.MinResponseTime = int(entry.Timings.Wait)
+
+		mocks = append(mocks, m)
+	}
+
+	return mocks, nil
+}
+
+// Binary contents are not kept
+func getBody(response Response) string {
+
+	body := response.Content.Text
+
+	if response.Content.Encoding == "base64" {
+
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return ""
+		}
+		body = string(decoded)
+	}
+
+	if !utf8.ValidString(body) {
+		return ""
+	}
+
+	return body
+}
+
+func getMockName(method string, path string) string {
+
+	name := strings.ToLower(method) + "-" + path
+
+	return strings.Trim(mockNameRegexp.ReplaceAllString(name, "-"), "-")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package har
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testHar = `{
+  "log": {
+    "entries": [
+      {
+        "request": {"method": "GET", "url": "https://example.com/api/users?page=1"},
+        "response": {
+          "status": 200,
+          "headers": [{"name": "content-type", "value": "application/json"}, {"name": "content-encoding", "value": "gzip"}],
+          "content": {"mimeType": "application/json", "text": "[{\"id\":1}]"}
+        },
+        "timings": {"wait": 42.7}
+      },
+      {
+        "request": {"method": "GET", "url": "https://example.com/api/users?page=2"},
+        "response": {"status": 200, "content": {"text": "[]"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://example.com/hello"},
+        "response": {"status": 200, "content": {"mimeType": "text/plain", "text": "aGVsbG8=", "encoding": "base64"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://example.com/logo.png"},
+        "response": {"status": 200, "content": {"mimeType": "image/png", "text": "/w==", "encoding": "base64"}}
+      }
+    ]
+  }
+}`
+
+func TestGenerateMocks(t *testing.T) {
+
+	var capture Har
+	err := json.Unmarshal([]byte(testHar), &capture)
+	if err != nil {
+		t.Fatalf("har unmarshal failed with error: %v", err)
+	}
+
+	mocks, err := GenerateMocks(capture)
+	if err != nil {
+		t.Fatalf("generate mocks failed with error: %v", err)
+	}
+
+	if len(mocks) != 3 {
+		t.Fatalf("generated mocks count is: %d, want: 3", len(mocks))
+	}
+
+	users := mocks[0]
+	if users.Name != "get-api-users" || users.Request.Url != "/api/users" {
+		t.Errorf("users mock is: '%s' %s, want: 'get-api-users' /api/users", users.Name, users.Request.Url)
+	}
+
+	if string(users.Response.Body) != `[{"id":1}]` || users.Response.MinResponseTime != 42 {
+		t.Errorf("users mock response is: %s %dms, want: [{\"id\":1}] 42ms", string(users.Response.Body), users.Response.MinResponseTime)
+	}
+
+	if users.Response.Headers["Content-Type"] != "application/json" || users.Response.Headers["Content-Encoding"] != "" {
+		t.Errorf("users mock headers are: %v", users.Response.Headers)
+	}
+
+	if string(mocks[1].Response.Body) != `"hello"` {
+		t.Errorf("hello mock body is: %s, want: \"hello\"", string(mocks[1].Response.Body))
+	}
+
+	if len(mocks[2].Response.Body) != 0 {
+		t.Errorf("binary body should not be kept, got: %s", string(mocks[2].Response.Body))
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package health
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	STATUS_UP   = "UP"
+	STATUS_DOWN = "DOWN"
+)
+
+// Checks of the readiness
+const (
+	CHECK_MOCKS     = "mocks"
+	CHECK_FUNCTIONS = "functions"
+	CHECK_LISTENERS = "listeners"
+	CHECK_SOCKETS   = "sockets"
+	CHECK_SMTP      = "smtp"
+	CHECK_UNMATCHED = "unmatched"
+)
+
+// Error of the checks not done yet
+var ErrStarting = errors.New("starting")
+
+type Check struct {
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Details string    `json:"details,omitempty"`
+	Since   time.Time `json:"since"`
+}
+
+var (
+	checks      = map[string]Check{}
+	checksMutex sync.RWMutex
+	startTime   = time.Now()
+)
+
+// Set records the check result, down with an error. The since date is kept
+// while the status does not change.
+func Set(name string, err error, details string) {
+
+	check := Check{Name: name, Status: STATUS_UP, Details: details, Since: time.Now()}
+	if err != nil {
+		check.Status = STATUS_DOWN
+		check.Error = err.Error()
+	}
+
+	checksMutex.Lock()
+	defer checksMutex.Unlock()
+
+	if previous, exists := checks[name]; exists && previous.Status == check.Status {
+		check.Since = previous.Since
+	}
+
+	checks[name] = check
+}
+
+// Starting sets the checks down until their first result
+func Starting(names ...string) {
+
+	for _, name := range names {
+		Set(name, ErrStarting, "")
+	}
+}
+
+// IsReady returns true if every check is up, and the checks sorted by name
+func IsReady() (bool, []Check) {
+
+	checksMutex.RLock()
+	defer checksMutex.RUnlock()
+
+	ready := true
+	list := make([]Check, 0, len(checks))
+
+	for _, check := range checks {
+		ready = ready && check.Status == STATUS_UP
+		list = append(list, check)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return ready, list
+}
+
+// Checks failing the liveness, the strict mode unmatched requests
+var liveness = []string{CHECK_UNMATCHED}
+
+// IsAlive returns false if a liveness check is down, and the liveness checks
+// with the given ones sorted by name
+func IsAlive(names ...string) (bool, []Check) {
+
+	list := Get(append(names, liveness...)...)
+
+	alive := true
+	for _, check := range list {
+		for _, name := range liveness {
+			alive = alive && (check.Name != name || check.Status == STATUS_UP)
+		}
+	}
+
+	return alive, list
+}
+
+// Get returns the given checks done, sorted by name
+func Get(names ...string) []Check {
+
+	checksMutex.RLock()
+	defer checksMutex.RUnlock()
+
+	list := make([]Check, 0, len(names))
+
+	for _, name := range names {
+		if check, exists := checks[name]; exists {
+			list = append(list, check)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// Uptime of the process
+func Uptime() time.Duration {
+
+	return time.Since(startTime)
+}
+
+// Reset forgets the checks
+func Reset() {
+
+	checksMutex.Lock()
+	defer checksMutex.Unlock()
+
+	checks = map[string]Check{}
+}
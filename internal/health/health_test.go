@@ -0,0 +1,85 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package health
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+
+	Reset()
+	defer Reset()
+
+	if ready, checks := IsReady(); !ready || len(checks) != 0 {
+		t.Errorf("ready expected without check")
+	}
+
+	Starting(CHECK_MOCKS, CHECK_LISTENERS)
+
+	if ready, checks := IsReady(); ready || len(checks) != 2 || checks[0].Name != CHECK_LISTENERS || checks[0].Error != ErrStarting.Error() {
+		t.Errorf("not ready expected while starting, got %v", checks)
+	}
+
+	Set(CHECK_MOCKS, nil, "2 mock(s)")
+	Set(CHECK_LISTENERS, nil, "")
+
+	ready, checks := IsReady()
+	if !ready {
+		t.Errorf("ready expected once started, got %v", checks)
+	}
+
+	since := checks[1].Since
+
+	Set(CHECK_MOCKS, nil, "3 mock(s)")
+	if _, checks = IsReady(); checks[1].Since != since || checks[1].Details != "3 mock(s)" {
+		t.Errorf("since date should be kept, got %v", checks[1])
+	}
+
+	Set(CHECK_FUNCTIONS, errors.New("syntax error"), "")
+	if ready, checks = IsReady(); ready || checks[0].Status != STATUS_DOWN || checks[0].Error != "syntax error" {
+		t.Errorf("not ready expected with a check down, got %v", checks)
+	}
+
+	if checks = Get(CHECK_MOCKS, CHECK_FUNCTIONS, CHECK_SMTP); len(checks) != 2 || checks[0].Name != CHECK_FUNCTIONS || checks[1].Details != "3 mock(s)" {
+		t.Errorf("mocks and functions checks expected, got %v", checks)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+
+	Reset()
+	defer Reset()
+
+	Set(CHECK_MOCKS, errors.New("hot reload failed"), "")
+
+	if alive, checks := IsAlive(CHECK_MOCKS); !alive || len(checks) != 1 {
+		t.Errorf("alive expected with the mocks down, got %v", checks)
+	}
+
+	Set(CHECK_UNMATCHED, errors.New("unmatched request GET /users"), "1 unmatched request(s)")
+
+	if alive, checks := IsAlive(CHECK_MOCKS); alive || len(checks) != 2 || checks[1].Name != CHECK_UNMATCHED {
+		t.Errorf("not alive expected with an unmatched request, got %v", checks)
+	}
+
+	Set(CHECK_UNMATCHED, nil, "")
+
+	if alive, _ := IsAlive(); !alive {
+		t.Errorf("alive expected once the unmatched requests cleared")
+	}
+}
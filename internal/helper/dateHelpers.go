@@ -32,11 +32,25 @@ const DATE_PRIVATE_PARAMS_REF_NAME = "dateRef"
 const DATE_PRIVATE_PARAMS_ISUTC_NAME = "isUTC"
 const DATE_PRIVATE_PARAMS_FORMAT_NAME = "format"
 const DATE_PRIVATE_PARAMS_ADD_NAME = "add"
+const DATE_PRIVATE_PARAMS_TZ_NAME = "tz"
 
 const DATE_PRIVATE_PARAMS_ADD_VALUE_NAME = "addValue"
 
 var DATE_REFS = [4]string{DATE_REF_DATE, DATE_REF_NOW}
 
+// named formats usable with .format('...')
+const DATE_FORMAT_UNIX = "unix"
+const DATE_FORMAT_UNIX_MILLI = "unixMilli"
+
+var DATE_FORMATS = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339Nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"iso8601":     "2006-01-02T15:04:05.000Z07:00",
+	"date":        time.DateOnly,
+	"time":        time.TimeOnly,
+}
+
 func sanitizeDateHelper(h Helper) (Helper, error) {
 
 	ref, err := checkDateHelperRef(h)
@@ -62,6 +76,12 @@ func sanitizeDateHelper(h Helper) (Helper, error) {
 	}
 	h.AddPrivateParam(DATE_PRIVATE_PARAMS_ADD_VALUE_NAME, addValue)
 
+	tz, tzError := getTimeZoneFromStr(h.Target)
+	if tzError != nil {
+		return h, tzError
+	}
+	h.AddPrivateParam(DATE_PRIVATE_PARAMS_TZ_NAME, tz)
+
 	if ref == DATE_REF_DATE {
 
 		h.Value, err = GetTargetDateStringValue(h)
@@ -112,6 +132,10 @@ func getTimeFormatFromStr(dateStr string) (string, error) {
 		return "", errors.New("bad format '" + dateStr + "' need somthing like .format('unix') or .format('2006-01-02T15:04:05.000Z')")
 	}
 
+	if namedFormat, exists := DATE_FORMATS[formatStr[1]]; exists {
+		return namedFormat, nil
+	}
+
 	return formatStr[1], nil
 }
 
@@ -123,12 +147,12 @@ func getTimeAddFromStr(dateStr string) (string, error) {
 
 	addStr := regexp.MustCompile(".*add[(]'([^)]*)'[)].*").FindStringSubmatch(dateStr)
 	if len(addStr) <= 1 {
-		return "", errors.New("bad add '" + dateStr + "' need somthing like .add(10ms) or .add(-1h)")
+		return "", errors.New("bad add '" + dateStr + "' need somthing like .add(10ms), .add(-1h) or .add(2d)")
 	}
 
 	durationStr := strings.TrimSpace(addStr[1])
 
-	_, err := time.ParseDuration(durationStr)
+	_, err := parseDateDuration(durationStr)
 	if err != nil {
 		return "", err
 	}
@@ -136,6 +160,56 @@ func getTimeAddFromStr(dateStr string) (string, error) {
 	return durationStr, nil
 }
 
+// parseDateDuration is time.ParseDuration with days, like 2d or -1d12h.
+func parseDateDuration(durationStr string) (time.Duration, error) {
+
+	sign, unsigned := "", durationStr
+	if strings.HasPrefix(unsigned, "-") || strings.HasPrefix(unsigned, "+") {
+		sign, unsigned = unsigned[:1], unsigned[1:]
+	}
+
+	daysStr, remainder, hasDays := strings.Cut(unsigned, "d")
+	days, err := strconv.Atoi(daysStr)
+	if !hasDays || err != nil {
+		return time.ParseDuration(durationStr)
+	}
+
+	duration := time.Duration(days) * 24 * time.Hour
+
+	if remainder != "" {
+		remainderDuration, err := time.ParseDuration(remainder)
+		if err != nil {
+			return 0, errors.New("bad duration '" + durationStr + "'")
+		}
+		duration += remainderDuration
+	}
+
+	if sign == "-" {
+		duration = -duration
+	}
+
+	return duration, nil
+}
+
+func getTimeZoneFromStr(dateStr string) (string, error) {
+
+	if !strings.Contains(dateStr, "tz(") {
+		return "", nil
+	}
+
+	tzStr := regexp.MustCompile(".*tz[(]'([^)]*)'[)].*").FindStringSubmatch(dateStr)
+	if len(tzStr) <= 1 {
+		return "", errors.New("bad tz '" + dateStr + "' need somthing like .tz('Europe/Paris')")
+	}
+
+	_, err := time.LoadLocation(tzStr[1])
+	if err != nil {
+		return "", err
+	}
+
+	return tzStr[1], nil
+}
+
 func checkDateHelperRef(h Helper) (string, error) {
 
 	s := regexp.MustCompile("([^ .(]*)[ .(]?.*").FindAllStringSubmatch(h.Target, -1)[0][1]
@@ -163,6 +237,7 @@ func GetTargetDateStringValue(h Helper) (string, error) {
 	isUtc := h.GetPrivateParam(DATE_PRIVATE_PARAMS_ISUTC_NAME)
 	format := h.GetPrivateParam(DATE_PRIVATE_PARAMS_FORMAT_NAME)
 	addValue := h.GetPrivateParam(DATE_PRIVATE_PARAMS_ADD_VALUE_NAME)
+	tz := h.GetPrivateParam(DATE_PRIVATE_PARAMS_TZ_NAME)
 
 	var theDate time.Time
 
@@ -180,17 +255,26 @@ func GetTargetDateStringValue(h Helper) (string, error) {
 		theDate = theDate.UTC()
 	}
 
+	if tz != "" {
+		location, _ := time.LoadLocation(tz)
+		theDate = theDate.In(location)
+	}
+
 	if addValue != "" {
-		duration, _ := time.ParseDuration(addValue)
+		duration, _ := parseDateDuration(addValue)
 		theDate = theDate.Add(duration)
 	}
 
 	if format != "" {
 
-		if format == "unix" {
+		if format == DATE_FORMAT_UNIX {
 			unixDateStr := strconv.Itoa(int(theDate.Unix()))
 			return unixDateStr, nil
 		}
+
+		if format == DATE_FORMAT_UNIX_MILLI {
+			return strconv.FormatInt(theDate.UnixMilli(), 10), nil
+		}
 		return theDate.Format(format), nil
 	}
 
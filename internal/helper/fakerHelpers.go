@@ -0,0 +1,82 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package helper
+
+import (
+	"alfred/internal/faker"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Faker helpers are populated with the random helpers, seeded ones give the
+// same value to each request.
+func sanitizeFakerHelper(h Helper) (Helper, error) {
+
+	method := regexp.MustCompile(`^(\w+)\s*(?:[(](.*)[)])?$`).FindStringSubmatch(h.Target)
+	if method == nil {
+		return h, errors.New("bad faker format '" + h.Target + "' need something like int(1,100)")
+	}
+
+	var params []string
+	if strings.TrimSpace(method[2]) != "" {
+		params = strings.Split(method[2], ",")
+	}
+
+	err := faker.CheckMethod(method[1], len(params))
+	if err != nil {
+		return h, err
+	}
+
+	for i, v := range params {
+		h.AddPrivateParam("param-"+strconv.Itoa(i), v)
+	}
+
+	if seed := h.GetPrivateParam(PARAM_SEED); seed != "" {
+		if _, err := strconv.ParseInt(seed, 10, 64); err != nil {
+			return h, errors.New("faker seed '" + seed + "' is not an integer")
+		}
+	}
+
+	// Target is now method name only
+	h.Target = method[1]
+
+	return h, nil
+}
+
+func GetFakerHelperValue(h Helper) (string, error) {
+
+	var params []string
+	for i := 0; ; i++ {
+
+		param, exists := h.GetPrivateParams()["param-"+strconv.Itoa(i)]
+		if !exists {
+			break
+		}
+
+		params = append(params, param)
+	}
+
+	f := faker.Get()
+	if seed := h.GetPrivateParam(PARAM_SEED); seed != "" {
+		seedValue, _ := strconv.ParseInt(seed, 10, 64)
+		f = faker.New(seedValue)
+	}
+
+	return f.Call(h.Target, params)
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package helper
+
+import (
+	"alfred/pkg/request"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// generated helper types
+const UUID = "uuid"
+const COUNTER = "counter"
+const REGEX = "regex"
+const CUSTOM = "custom"
+
+// uuid helpers targets
+const UUID_V4 = "v4"
+const UUID_V7 = "v7"
+
+// Generator computes the value of a helper for a request, helper params are
+// in its private params.
+type Generator func(h Helper, req request.Req) (string, error)
+
+var (
+	generators      = map[string]Generator{}
+	generatorsMutex sync.RWMutex
+
+	customHelpers      = map[string]Generator{}
+	customHelpersMutex sync.RWMutex
+
+	counters      = map[string]*atomic.Int64{}
+	countersMutex sync.Mutex
+)
+
+func init() {
+
+	Register(UUID, uuidGenerator)
+	Register(COUNTER, counterGenerator)
+	Register(REGEX, regexGenerator)
+	Register(CUSTOM, customGenerator)
+}
+
+// Register adds a helper type, its {{ alfred.<type>.<target> }} helpers are
+// computed by the generator for each request.
+func Register(helperType string, generator Generator) {
+
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+
+	generators[helperType] = generator
+}
+
+// IsGenerated returns true if the helper type is computed by a registered
+// generator.
+func IsGenerated(helperType string) bool {
+
+	generatorsMutex.RLock()
+	defer generatorsMutex.RUnlock()
+
+	_, exists := generators[helperType]
+
+	return exists
+}
+
+// RegisterCustom adds a {{ alfred.custom.<name> }} helper, as the helpers of
+// the js function files.
+func RegisterCustom(name string, generator Generator) {
+
+	customHelpersMutex.Lock()
+	defer customHelpersMutex.Unlock()
+
+	customHelpers[name] = generator
+}
+
+// GeneratorWatcher computes the values of the generated helpers, helpers in
+// error keep an empty value.
+func GeneratorWatcher(req request.Req, h []Helper) ([]Helper, error) {
+
+	var errs []error
+
+	for i, helper := range h {
+
+		if helper.HasValue() {
+			continue
+		}
+
+		generatorsMutex.RLock()
+		generator, exists := generators[helper.Type]
+		generatorsMutex.RUnlock()
+
+		if !exists {
+			continue
+		}
+
+		value, err := generator(helper, req)
+		if err != nil {
+			errs = append(errs, errors.New(helper.String+": "+err.Error()))
+			continue
+		}
+
+		h[i].Value = value
+	}
+
+	return h, errors.Join(errs...)
+}
+
+func sanitizeGeneratedHelper(h Helper) (Helper, error) {
+
+	switch h.Type {
+	case UUID:
+
+		if h.Target != UUID_V4 && h.Target != UUID_V7 {
+			return h, errors.New("alfred " + UUID + " helper version " + h.Target + " unknown, use " + UUID_V4 + " or " + UUID_V7)
+		}
+
+	case COUNTER:
+
+		if h.Target == "" {
+			return h, errors.New("alfred " + COUNTER + " helper needs a name, like {{ alfred.counter.orders }}")
+		}
+
+		if start := h.GetPrivateParam(PARAM_START); start != "" {
+			if _, err := strconv.ParseInt(start, 10, 64); err != nil {
+				return h, errors.New("alfred " + COUNTER + " helper start " + start + " is not an integer")
+			}
+		}
+
+	case REGEX:
+
+		if h.Regex == nil {
+			return h, errors.New("alfred " + REGEX + " helper needs a valid @regex:'...' param with a capture group")
+		}
+
+		source := strings.SplitN(h.Target, ".", 2)[0]
+		if source != "path" && source != "url" && source != "body" && source != "headers" && source != "query" {
+			return h, errors.New("alfred " + REGEX + " helper source " + h.Target + " unknown, use path, url, body, headers.<name> or query.<name>")
+		}
+	}
+
+	return h, nil
+}
+
+func uuidGenerator(h Helper, req request.Req) (string, error) {
+
+	if h.Target == UUID_V7 {
+		return newUuidV7()
+	}
+
+	return uuid.NewString(), nil
+}
+
+// newUuidV7 returns a time ordered uuid: unix milliseconds, then random bits.
+func newUuidV7() (string, error) {
+
+	var u uuid.UUID
+
+	_, err := rand.Read(u[:])
+	if err != nil {
+		return "", err
+	}
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixMilli()))
+	copy(u[0:6], ms[2:8])
+
+	u[6] = (u[6] & 0x0f) | 0x70 // version 7
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return u.String(), nil
+}
+
+// counterGenerator returns the next value of the named counter, shared by all
+// the mocks, from the @start param or 1.
+func counterGenerator(h Helper, req request.Req) (string, error) {
+
+	countersMutex.Lock()
+	counter, exists := counters[h.Target]
+	if !exists {
+
+		counter = &atomic.Int64{}
+
+		start, _ := strconv.ParseInt(h.GetPrivateParam(PARAM_START), 10, 64)
+		if h.GetPrivateParam(PARAM_START) == "" {
+			start = 1
+		}
+		counter.Store(start - 1)
+
+		counters[h.Target] = counter
+	}
+	countersMutex.Unlock()
+
+	return strconv.FormatInt(counter.Add(1), 10), nil
+}
+
+// ResetCounters restarts all the counters.
+func ResetCounters() {
+
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+
+	counters = map[string]*atomic.Int64{}
+}
+
+// regexGenerator returns the first capture group of the regex in the request
+// path, url, body, header or query parameter.
+func regexGenerator(h Helper, req request.Req) (string, error) {
+
+	source, name, _ := strings.Cut(h.Target, ".")
+
+	var value string
+
+	switch source {
+	case "path":
+		value = req.Url
+		if u, err := url.ParseRequestURI(req.Url); err == nil {
+			value = u.Path
+		}
+	case "url":
+		value = req.Url
+	case "body":
+		value = req.Body
+	case "headers":
+		for header, headerValue := range req.Headers {
+			if strings.EqualFold(header, name) {
+				value = headerValue
+				break
+			}
+		}
+	case "query":
+		value = req.Query[name]
+	}
+
+	match := h.Regex.FindStringSubmatch(value)
+	if len(match) < 2 {
+		return "", fmt.Errorf("regex %s doesn't match the request %s", h.Regex.String(), h.Target)
+	}
+
+	return match[1], nil
+}
+
+func customGenerator(h Helper, req request.Req) (string, error) {
+
+	customHelpersMutex.RLock()
+	generator, exists := customHelpers[h.Target]
+	customHelpersMutex.RUnlock()
+
+	if !exists {
+		return "", errors.New("custom helper " + h.Target + " not registered by a function file")
+	}
+
+	return generator(h, req)
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package helper
+
+import (
+	"alfred/pkg/request"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUuidV7Helper(t *testing.T) {
+
+	h, err := createHelper("{{ alfred.uuid.v7 }}", "alfred.uuid.v7")
+	if err != nil {
+		t.Fatalf("Create helper fail with error %v", err)
+	}
+
+	helpers, err := GeneratorWatcher(request.Req{}, []Helper{h})
+	if err != nil {
+		t.Fatalf("Generator watcher fail with error %v", err)
+	}
+
+	u, err := uuid.Parse(helpers[0].Value)
+	if err != nil || u.Version() != 7 {
+		t.Errorf("Helper value is: %s, want a uuid v7.", helpers[0].Value)
+	}
+
+	_, err = createHelper("{{ alfred.uuid.v9 }}", "alfred.uuid.v9")
+	if err == nil {
+		t.Errorf("Create helper with an unknown uuid version should fail")
+	}
+}
+
+func TestCounterHelper(t *testing.T) {
+
+	ResetCounters()
+
+	h, err := createHelper("{{ alfred.counter.orders @start:'100' }}", "alfred.counter.orders @start:'100'")
+	if err != nil {
+		t.Fatalf("Create helper fail with error %v", err)
+	}
+
+	for _, want := range []string{"100", "101", "102"} {
+
+		helpers, _ := GeneratorWatcher(request.Req{}, []Helper{h.Clone()})
+		if helpers[0].Value != want {
+			t.Errorf("Counter value is: %s, want: %s.", helpers[0].Value, want)
+		}
+	}
+
+	_, err = createHelper("{{ alfred.counter.orders @start:'one' }}", "alfred.counter.orders @start:'one'")
+	if err == nil {
+		t.Errorf("Create helper with a bad start should fail")
+	}
+}
+
+func TestRegexHelper(t *testing.T) {
+
+	req := request.Req{
+		Url:     "/orders/42?expand=true",
+		Body:    `{"customer":"C-7"}`,
+		Headers: map[string]string{"X-Tenant": "acme-eu"},
+		Query:   map[string]string{"expand": "true"},
+	}
+
+	tests := map[string]string{
+		"alfred.regex.path @regex:'/orders/(\\d+)'":          "42",
+		"alfred.regex.body @regex:'\"customer\":\"([^\"]*)'": "C-7",
+		"alfred.regex.headers.x-tenant @regex:'-(\\w+)$'":    "eu",
+		"alfred.regex.query.expand @regex:'(.*)'":            "true",
+	}
+
+	for target, want := range tests {
+
+		h, err := createHelper("{{ "+target+" }}", target)
+		if err != nil {
+			t.Fatalf("Create helper %s fail with error %v", target, err)
+		}
+
+		helpers, err := GeneratorWatcher(req, []Helper{h})
+		if err != nil || helpers[0].Value != want {
+			t.Errorf("Helper %s value is: %s (%v), want: %s.", target, helpers[0].Value, err, want)
+		}
+	}
+
+	_, err := createHelper("{{ alfred.regex.path }}", "alfred.regex.path")
+	if err == nil {
+		t.Errorf("Create regex helper without regex should fail")
+	}
+}
+
+func TestCustomHelper(t *testing.T) {
+
+	RegisterCustom("tenant", func(h Helper, req request.Req) (string, error) {
+		return strings.ToUpper(req.Headers["X-Tenant"]), nil
+	})
+	RegisterCustom("broken", func(h Helper, req request.Req) (string, error) {
+		return "", errors.New("broken")
+	})
+
+	tenant, _ := createHelper("{{ alfred.custom.tenant }}", "alfred.custom.tenant")
+	broken, _ := createHelper("{{ alfred.custom.broken }}", "alfred.custom.broken")
+
+	helpers, err := GeneratorWatcher(request.Req{Headers: map[string]string{"X-Tenant": "acme"}}, []Helper{tenant, broken})
+	if err == nil {
+		t.Errorf("Generator watcher should return the broken helper error")
+	}
+
+	if helpers[0].Value != "ACME" || helpers[1].HasValue() {
+		t.Errorf("Custom helpers values are: '%s' '%s', want: 'ACME' ''.", helpers[0].Value, helpers[1].Value)
+	}
+}
+
+func TestDateHelperTzAndDays(t *testing.T) {
+
+	h, err := createHelper("{{ alfred.time.date(2024,01,31,23,0,0,0).utc.tz('Asia/Tokyo').add('-1d').format('rfc3339') }}", "alfred.time.date(2024,01,31,23,0,0,0).utc.tz('Asia/Tokyo').add('-1d').format('rfc3339')")
+	if err != nil {
+		t.Fatalf("Create helper fail with error %v", err)
+	}
+
+	date, _ := time.ParseInLocation("2006-01-02 15", "2024-01-31 23", time.Local)
+	want := date.UTC().In(time.FixedZone("JST", 9*3600)).Add(-24 * time.Hour).Format(time.RFC3339)
+	if h.Value != want {
+		t.Errorf("Date helper value is: %s, want: %s.", h.Value, want)
+	}
+
+	_, err = createHelper("{{ alfred.time.now.tz('Mars/Olympus') }}", "alfred.time.now.tz('Mars/Olympus')")
+	if err == nil {
+		t.Errorf("Create date helper with an unknown time zone should fail")
+	}
+}
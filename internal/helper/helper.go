@@ -27,11 +27,31 @@ type PathHelperKey string
 const REQUEST = "req"
 const DATE = "time"
 const RANDOM = "random"
+const FAKER = "faker"
 const PATH_REGEX = "pathRegex"
+const RATE_LIMIT = "rateLimit"
+
+// rate limit helpers targets
+const RATE_LIMIT_RETRY_AFTER = "retryAfter"
+const RATE_LIMIT_LIMIT = "limit"
+
+// request helpers target of the TLS client certificate fields
+const CLIENT_CERT_TARGET = "clientCert"
+
+// request helpers target of the connection details
+const CONNECTION_TARGET = "connection"
+
+// request helpers target of the Authorization credentials
+const AUTH_TARGET = "auth"
+
+// request helpers target of the multipart/form-data fields and files
+const MULTIPART_TARGET = "multipart"
 
 // helper params
 const PARAM_NAME = "name"
 const PARAM_REGEX = "regex"
+const PARAM_SEED = "seed"
+const PARAM_START = "start"
 
 // json tag used with users js functions
 type Helper struct {
@@ -17,6 +17,8 @@
 package helper
 
 import (
+	"bytes"
+	"encoding/xml"
 	"errors"
 	"strings"
 )
@@ -41,3 +43,25 @@ func HelperReplacement(data string, helpers []Helper) (string, error) {
 
 	return data, errors.New("helper hasn't capture values for these helpers string: " + failedHelpers)
 }
+
+// Same as HelperReplacement, but helpers values are escaped to keep xml
+// documents valid.
+func XmlHelperReplacement(data string, helpers []Helper) (string, error) {
+
+	escapedHelpers := []Helper{}
+
+	for _, helper := range helpers {
+
+		var buffer bytes.Buffer
+		err := xml.EscapeText(&buffer, []byte(helper.Value))
+		if err != nil {
+			return data, err
+		}
+
+		escapedHelper := helper.Clone()
+		escapedHelper.Value = buffer.String()
+		escapedHelpers = append(escapedHelpers, escapedHelper)
+	}
+
+	return HelperReplacement(data, escapedHelpers)
+}
@@ -18,6 +18,8 @@ package helper
 
 import (
 	"alfred/internal/log"
+	"alfred/pkg/request"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"strings"
@@ -41,6 +43,18 @@ func RequestHelperWatcher(data []byte, r *http.Request, h []Helper) ([]Helper, e
 	//Watch HTTP query and params
 	h = paramWatcher(r, h)
 
+	//Watch TLS client certificate
+	h = clientCertWatcher(r, h)
+
+	//Watch client ip, sni and protocols
+	h = connectionWatcher(r, h)
+
+	//Watch Authorization credentials
+	h = authWatcher(r, h)
+
+	//Watch multipart/form-data fields and files
+	h = multipartWatcher(data, r, h)
+
 	//Watch HTTP body
 	h, err = bodyWatcher(data, r, h)
 	if err != nil {
@@ -53,6 +67,19 @@ func RequestHelperWatcher(data []byte, r *http.Request, h []Helper) ([]Helper, e
 	return h, err
 }
 
+// RequestHeadersWatcher watches the request values available once its body
+// is consumed: query, client certificate, connection, credentials and
+// headers.
+func RequestHeadersWatcher(r *http.Request, h []Helper) []Helper {
+
+	h = paramWatcher(r, h)
+	h = clientCertWatcher(r, h)
+	h = connectionWatcher(r, h)
+	h = authWatcher(r, h)
+
+	return headersWatcher(r.Header, h)
+}
+
 func jsonWatcher(d []byte, h []Helper) ([]Helper, error) {
 
 	for i, helper := range h {
@@ -85,6 +112,64 @@ func textWatcher(d []byte, h []Helper) ([]Helper, error) {
 	return h, nil
 }
 
+func clientCertWatcher(r *http.Request, h []Helper) []Helper {
+
+	clientCert := request.GetClientCert(r.TLS)
+	if clientCert == nil {
+		return h
+	}
+
+	return objectWatcher(CLIENT_CERT_TARGET, clientCert, h)
+}
+
+func connectionWatcher(r *http.Request, h []Helper) []Helper {
+
+	return objectWatcher(CONNECTION_TARGET, request.GetConnection(r), h)
+}
+
+func authWatcher(r *http.Request, h []Helper) []Helper {
+
+	auth := request.GetAuth(r)
+	if auth == nil {
+		return h
+	}
+
+	return objectWatcher(AUTH_TARGET, auth, h)
+}
+
+func multipartWatcher(data []byte, r *http.Request, h []Helper) []Helper {
+
+	if !request.IsMultipart(r.Header.Get("Content-Type")) {
+		return h
+	}
+
+	parts, err := request.ParseMultipart(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		log.Error(r.Context(), "multipart body parsing failed on multipartWatcher", err)
+		return h
+	}
+
+	return objectWatcher(MULTIPART_TARGET, parts, h)
+}
+
+// objectWatcher sets the helpers targeting the object fields, the target is
+// the object name followed by the json path of the field.
+func objectWatcher(name string, object interface{}, h []Helper) []Helper {
+
+	objectJson, _ := json.Marshal(object)
+
+	for i, helper := range h {
+
+		if helper.Value != "" || !strings.HasPrefix(helper.Target, name+".") {
+			continue
+		}
+
+		h[i].Value = gjson.GetBytes(objectJson, strings.TrimPrefix(helper.Target, name+".")).String()
+	}
+
+	return h
+}
+
 func paramWatcher(r *http.Request, h []Helper) []Helper {
 
 	err := r.ParseForm()
@@ -153,6 +238,11 @@ func bodyWatcher(data []byte, r *http.Request, h []Helper) ([]Helper, error) {
 		}
 
 		return helpers, nil
+	} else if request.IsMultipart(r.Header.Get("Content-type")) {
+
+		// parts are watched by multipartWatcher
+		return h, nil
+
 	} else if strings.Contains(r.Header.Get("Content-type"), "text") {
 
 		helpers, err := textWatcher(data, h)
@@ -197,6 +287,22 @@ func headersWatcher(headers http.Header, h []Helper) []Helper {
 	return h
 }
 
+// RateLimitWatcher sets the rate limit helpers of a request over the limit.
+func RateLimitWatcher(h []Helper, retryAfter string, limit string) []Helper {
+
+	for i, helper := range h {
+
+		switch helper.Target {
+		case RATE_LIMIT_RETRY_AFTER:
+			h[i].Value = retryAfter
+		case RATE_LIMIT_LIMIT:
+			h[i].Value = limit
+		}
+	}
+
+	return h
+}
+
 func DateWatcher(h []Helper) ([]Helper, error) {
 
 	for i, helper := range h {
@@ -221,6 +327,17 @@ func RandomWatcher(h []Helper) ([]Helper, error) {
 			continue
 		}
 
+		if helper.Type == FAKER {
+
+			value, err := GetFakerHelperValue(helper)
+			if err != nil {
+				return h, err
+			}
+
+			h[i].Value = value
+			continue
+		}
+
 		methodParams := []string{}
 		privateParams := helper.GetPrivateParams()
 
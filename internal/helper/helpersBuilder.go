@@ -23,7 +23,7 @@ import (
 )
 
 var (
-	TYPES = [...]string{REQUEST, DATE, RANDOM, PATH_REGEX}
+	TYPES = [...]string{REQUEST, DATE, RANDOM, FAKER, PATH_REGEX, RATE_LIMIT}
 )
 
 func createHelper(helperString string, helperTarget string) (Helper, error) {
@@ -53,6 +53,16 @@ func createHelper(helperString string, helperTarget string) (Helper, error) {
 
 	}
 
+	helperStart, helperStartExists := params[PARAM_START]
+	if helperStartExists && h.Type == COUNTER {
+		h.AddPrivateParam(PARAM_START, helperStart)
+	}
+
+	helperSeed, helperSeedExists := params[PARAM_SEED]
+	if helperSeedExists && h.Type == FAKER {
+		h.AddPrivateParam(PARAM_SEED, helperSeed)
+	}
+
 	h, error := sanitizeHelper(h)
 	if error != nil {
 		return h, error
@@ -71,6 +81,10 @@ func detectHelperType(helperTarget string) (string, error) {
 		}
 	}
 
+	if IsGenerated(s[1]) {
+		return s[1], nil
+	}
+
 	return "", errors.New("helper type '" + s[1] + "' is not handled by Alfred")
 }
 
@@ -104,7 +118,7 @@ func findHelpersStrings(jsonData []byte) [][]string {
 func isKnownParam(param string) bool {
 
 	//knownParams := [...]string{PARAM_NAME, PARAM_TYPE, PARAM_DESC}
-	knownParams := [...]string{PARAM_NAME, PARAM_REGEX}
+	knownParams := [...]string{PARAM_NAME, PARAM_REGEX, PARAM_SEED, PARAM_START}
 
 	for _, knownParam := range knownParams {
 
@@ -162,6 +176,12 @@ func sanitizeHelper(h Helper) (Helper, error) {
 	} else if h.Type == RANDOM {
 
 		return sanitizeRandomHelper(h)
+	} else if h.Type == FAKER {
+
+		return sanitizeFakerHelper(h)
+	} else if IsGenerated(h.Type) {
+
+		return sanitizeGeneratedHelper(h)
 	}
 
 	return h, nil
@@ -74,3 +74,29 @@ func TestFindHelpersStrings(t *testing.T) {
 
 	}
 }
+
+func TestCreateFakerHelper(t *testing.T) {
+
+	h, err := createHelper("{{ alfred.faker.int(1,100) @seed:'42' }}", "alfred.faker.int(1,100) @seed:'42'")
+	if err != nil {
+		t.Fatalf("Create helper fail with error %v", err)
+	}
+
+	if h.Type != FAKER || h.Target != "int" {
+		t.Errorf("Helper type and target are: %s %s, want: %s int.", h.Type, h.Target, FAKER)
+	}
+
+	first, err := GetFakerHelperValue(h)
+	if err != nil {
+		t.Fatalf("Faker helper value fail with error %v", err)
+	}
+
+	if second, _ := GetFakerHelperValue(h); first != second {
+		t.Errorf("Seeded helper values are: %s and %s.", first, second)
+	}
+
+	_, err = createHelper("{{ alfred.faker.int(1) }}", "alfred.faker.int(1)")
+	if err == nil {
+		t.Errorf("Create helper with a missing param should fail")
+	}
+}
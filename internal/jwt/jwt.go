@@ -0,0 +1,437 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_ALGORITHM = "HS256"
+
+// Keys and default claims used to sign and verify tokens, options given to
+// Sign and Verify override them.
+type Config struct {
+	Algorithm      string `json:"algorithm"`
+	Secret         string `json:"secret"`
+	PrivateKeyPath string `json:"private-key-path"`
+	PublicKeyPath  string `json:"public-key-path"`
+	Issuer         string `json:"issuer"`
+	ExpiresIn      string `json:"expires-in"`
+}
+
+type Options struct {
+	Algorithm string `json:"algorithm"`
+	Secret    string `json:"secret"`
+	// PEM encoded RSA keys
+	PrivateKey string `json:"privateKey"`
+	PublicKey  string `json:"publicKey"`
+	Issuer     string `json:"issuer"`
+	ExpiresIn  string `json:"expiresIn"`
+	Kid        string `json:"kid"`
+}
+
+// Decoded token, without signature verification.
+type Token struct {
+	Header    map[string]interface{} `json:"header"`
+	Payload   map[string]interface{} `json:"payload"`
+	Signature string                 `json:"signature"`
+}
+
+type keys struct {
+	conf       Config
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+var (
+	defaults      keys
+	defaultsMutex sync.RWMutex
+)
+
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Configure loads the default keys, an RSA public key is derived from the
+// private key when not given.
+func Configure(c Config) error {
+
+	k := keys{conf: c}
+
+	if k.conf.Algorithm == "" {
+		k.conf.Algorithm = DEFAULT_ALGORITHM
+	}
+
+	if _, err := getHash(k.conf.Algorithm); err != nil {
+		return err
+	}
+
+	if k.conf.ExpiresIn != "" {
+		if _, err := time.ParseDuration(k.conf.ExpiresIn); err != nil {
+			return errors.New("jwt expires-in: " + err.Error())
+		}
+	}
+
+	if c.PrivateKeyPath != "" {
+
+		content, err := os.ReadFile(c.PrivateKeyPath)
+		if err != nil {
+			return errors.New("jwt private key: " + err.Error())
+		}
+
+		k.privateKey, err = parsePrivateKey(string(content))
+		if err != nil {
+			return errors.New("jwt private key " + c.PrivateKeyPath + ": " + err.Error())
+		}
+
+		k.publicKey = &k.privateKey.PublicKey
+	}
+
+	if c.PublicKeyPath != "" {
+
+		content, err := os.ReadFile(c.PublicKeyPath)
+		if err != nil {
+			return errors.New("jwt public key: " + err.Error())
+		}
+
+		k.publicKey, err = parsePublicKey(string(content))
+		if err != nil {
+			return errors.New("jwt public key " + c.PublicKeyPath + ": " + err.Error())
+		}
+	}
+
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaults = k
+
+	return nil
+}
+
+func getDefaults() keys {
+
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+
+	return defaults
+}
+
+// Sign mints a token from the claims, "iat", "exp" and "iss" claims are
+// added when missing and an issuer or an expiration is configured.
+func Sign(claims map[string]interface{}, o Options) (string, error) {
+
+	k := getDefaults()
+
+	algorithm := firstNotEmpty(o.Algorithm, k.conf.Algorithm, DEFAULT_ALGORITHM)
+	hash, err := getHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	payload := map[string]interface{}{}
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	now := time.Now()
+
+	if _, exists := payload["iat"]; !exists {
+		payload["iat"] = now.Unix()
+	}
+
+	if issuer := firstNotEmpty(o.Issuer, k.conf.Issuer); issuer != "" {
+		if _, exists := payload["iss"]; !exists {
+			payload["iss"] = issuer
+		}
+	}
+
+	if expiresIn := firstNotEmpty(o.ExpiresIn, k.conf.ExpiresIn); expiresIn != "" {
+		if _, exists := payload["exp"]; !exists {
+
+			duration, err := time.ParseDuration(expiresIn)
+			if err != nil {
+				return "", errors.New("jwt expiresIn: " + err.Error())
+			}
+
+			payload["exp"] = now.Add(duration).Unix()
+		}
+	}
+
+	header := map[string]interface{}{"alg": algorithm, "typ": "JWT"}
+	if o.Kid != "" {
+		header["kid"] = o.Kid
+	}
+
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	payloadJson, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerJson) + "." + encode(payloadJson)
+
+	var signature []byte
+
+	switch algorithm[:2] {
+	case "HS":
+
+		secret := firstNotEmpty(o.Secret, k.conf.Secret)
+		if secret == "" {
+			return "", errors.New("jwt " + algorithm + " needs a secret")
+		}
+
+		signature = hmacSum(hash, secret, signingInput)
+
+	case "RS":
+
+		privateKey := k.privateKey
+		if o.PrivateKey != "" {
+			privateKey, err = parsePrivateKey(o.PrivateKey)
+			if err != nil {
+				return "", errors.New("jwt private key: " + err.Error())
+			}
+		}
+
+		if privateKey == nil {
+			return "", errors.New("jwt " + algorithm + " needs a private key")
+		}
+
+		h := hash.New()
+		h.Write([]byte(signingInput))
+
+		signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, hash, h.Sum(nil))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + encode(signature), nil
+}
+
+// Verify checks the token signature and its "exp" and "nbf" claims, then
+// returns its claims. The token algorithm must match the expected one.
+func Verify(token string, o Options) (map[string]interface{}, error) {
+
+	k := getDefaults()
+
+	decoded, err := Decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := firstNotEmpty(o.Algorithm, k.conf.Algorithm, DEFAULT_ALGORITHM)
+	if decoded.Header["alg"] != algorithm {
+		return nil, errors.New("jwt algorithm is not " + algorithm)
+	}
+
+	hash, err := getHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signingInput := token[:strings.LastIndex(token, ".")]
+
+	signature, err := base64.RawURLEncoding.DecodeString(decoded.Signature)
+	if err != nil {
+		return nil, errors.New("jwt signature is not base64url encoded")
+	}
+
+	switch algorithm[:2] {
+	case "HS":
+
+		secret := firstNotEmpty(o.Secret, k.conf.Secret)
+		if secret == "" {
+			return nil, errors.New("jwt " + algorithm + " needs a secret")
+		}
+
+		if !hmac.Equal(signature, hmacSum(hash, secret, signingInput)) {
+			return nil, errors.New("jwt signature is invalid")
+		}
+
+	case "RS":
+
+		publicKey := k.publicKey
+		if o.PublicKey != "" {
+			publicKey, err = parsePublicKey(o.PublicKey)
+			if err != nil {
+				return nil, errors.New("jwt public key: " + err.Error())
+			}
+		}
+
+		if publicKey == nil {
+			return nil, errors.New("jwt " + algorithm + " needs a public key")
+		}
+
+		h := hash.New()
+		h.Write([]byte(signingInput))
+
+		if rsa.VerifyPKCS1v15(publicKey, hash, h.Sum(nil), signature) != nil {
+			return nil, errors.New("jwt signature is invalid")
+		}
+	}
+
+	now := time.Now().Unix()
+
+	if exp, ok := decoded.Payload["exp"].(float64); ok && now >= int64(exp) {
+		return nil, errors.New("jwt is expired")
+	}
+
+	if nbf, ok := decoded.Payload["nbf"].(float64); ok && now < int64(nbf) {
+		return nil, errors.New("jwt is not valid yet")
+	}
+
+	if issuer := firstNotEmpty(o.Issuer, k.conf.Issuer); issuer != "" && decoded.Payload["iss"] != issuer {
+		return nil, errors.New("jwt issuer is not " + issuer)
+	}
+
+	return decoded.Payload, nil
+}
+
+// Decode reads the token header and payload, without signature verification.
+func Decode(token string) (Token, error) {
+
+	var decoded Token
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return decoded, errors.New("jwt must have 3 parts")
+	}
+
+	for i, target := range []*map[string]interface{}{&decoded.Header, &decoded.Payload} {
+
+		content, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[i], "="))
+		if err != nil {
+			return decoded, errors.New("jwt part is not base64url encoded")
+		}
+
+		err = json.Unmarshal(content, target)
+		if err != nil {
+			return decoded, errors.New("jwt part is not a json object")
+		}
+	}
+
+	decoded.Signature = parts[2]
+
+	return decoded, nil
+}
+
+func getHash(algorithm string) (crypto.Hash, error) {
+
+	if len(algorithm) == 5 && (algorithm[:2] == "HS" || algorithm[:2] == "RS") {
+		if hash, exists := hashes[algorithm[2:]]; exists {
+			return hash, nil
+		}
+	}
+
+	return 0, errors.New("jwt algorithm " + algorithm + " is not supported, use HS256, HS384, HS512, RS256, RS384 or RS512")
+}
+
+func hmacSum(hash crypto.Hash, secret string, signingInput string) []byte {
+
+	mac := hmac.New(hash.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+
+	return mac.Sum(nil)
+}
+
+func parsePrivateKey(pemContent string) (*rsa.PrivateKey, error) {
+
+	block, _ := pem.Decode([]byte(pemContent))
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+
+	return rsaKey, nil
+}
+
+func parsePublicKey(pemContent string) (*rsa.PublicKey, error) {
+
+	block, _ := pem.Decode([]byte(pemContent))
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("not an RSA certificate")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+
+	return rsaKey, nil
+}
+
+func encode(content []byte) string {
+
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func firstNotEmpty(values ...string) string {
+
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestSignAndVerifyHmac(t *testing.T) {
+
+	err := Configure(Config{Secret: "alfred-secret", Issuer: "alfred", ExpiresIn: "1h"})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	token, err := Sign(map[string]interface{}{"sub": "bruce"}, Options{})
+	if err != nil {
+		t.Fatalf("sign failed with error: %v", err)
+	}
+
+	claims, err := Verify(token, Options{})
+	if err != nil {
+		t.Fatalf("verify failed with error: %v", err)
+	}
+
+	if claims["sub"] != "bruce" || claims["iss"] != "alfred" || claims["exp"] == nil {
+		t.Errorf("claims are: %v", claims)
+	}
+
+	_, err = Verify(token, Options{Secret: "wrong"})
+	if err == nil {
+		t.Errorf("a token signed with another secret should fail")
+	}
+
+	expired, _ := Sign(map[string]interface{}{"sub": "bruce", "exp": 1}, Options{})
+	_, err = Verify(expired, Options{})
+	if err == nil {
+		t.Errorf("an expired token should fail")
+	}
+
+	_, err = Verify(token, Options{Algorithm: "HS512"})
+	if err == nil {
+		t.Errorf("a token of another algorithm should fail")
+	}
+}
+
+func TestSignAndVerifyRsa(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("key generation failed with error: %v", err)
+	}
+
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	publicKeyDer, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer}))
+
+	err = Configure(Config{})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	token, err := Sign(map[string]interface{}{"sub": "bruce"}, Options{Algorithm: "RS256", PrivateKey: privateKey, Kid: "key-1"})
+	if err != nil {
+		t.Fatalf("sign failed with error: %v", err)
+	}
+
+	decoded, err := Decode(token)
+	if err != nil {
+		t.Fatalf("decode failed with error: %v", err)
+	}
+
+	if decoded.Header["kid"] != "key-1" || decoded.Header["alg"] != "RS256" {
+		t.Errorf("header is: %v", decoded.Header)
+	}
+
+	_, err = Verify(token, Options{Algorithm: "RS256", PublicKey: publicKey})
+	if err != nil {
+		t.Errorf("verify failed with error: %v", err)
+	}
+
+	_, err = Verify(token, Options{Algorithm: "RS256"})
+	if err == nil {
+		t.Errorf("verify without public key should fail")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+
+	err := Configure(Config{Algorithm: "none"})
+	if err == nil {
+		t.Errorf("the none algorithm should fail")
+	}
+
+	err = Configure(Config{ExpiresIn: "tomorrow"})
+	if err == nil {
+		t.Errorf("an invalid expires-in should fail")
+	}
+}
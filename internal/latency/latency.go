@@ -0,0 +1,322 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package latency
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Distributions of the latencies between the profile percentiles
+const (
+	DISTRIBUTION_PIECEWISE = "piecewise"
+	DISTRIBUTION_LOGNORMAL = "lognormal"
+	DISTRIBUTION_UNIFORM   = "uniform"
+)
+
+// Latency profile, a named response time curve used by mocks. Durations are
+// strings like 20ms or 1.5s.
+type Profile struct {
+	Name         string `json:"name"`
+	Distribution string `json:"distribution,omitempty"`
+	Min          string `json:"min,omitempty"`
+	P50          string `json:"p50,omitempty"`
+	P90          string `json:"p90,omitempty"`
+	P95          string `json:"p95,omitempty"`
+	P99          string `json:"p99,omitempty"`
+	Max          string `json:"max,omitempty"`
+}
+
+// A percentile of the curve
+type point struct {
+	rank     float64
+	duration time.Duration
+}
+
+type curve struct {
+	profile Profile
+
+	distribution string
+	points       []point
+	min, max     time.Duration
+
+	// lognormal parameters
+	mu, sigma float64
+}
+
+// normal distribution quantiles of the percentiles
+var zScores = map[float64]float64{0.9: 1.2816, 0.95: 1.6449, 0.99: 2.3263}
+
+var (
+	mutex      sync.RWMutex
+	configured = map[string]Profile{}
+	curves     = map[string]curve{}
+)
+
+// Configure replaces the profiles, the changes made at runtime are dropped.
+func Configure(profiles []Profile) error {
+
+	newCurves := map[string]curve{}
+	newConfigured := map[string]Profile{}
+
+	for _, p := range profiles {
+
+		c, err := compile(p)
+		if err != nil {
+			return err
+		}
+
+		if _, exists := newCurves[p.Name]; exists {
+			return errors.New("latency profile " + p.Name + " is defined twice")
+		}
+
+		newCurves[p.Name] = c
+		newConfigured[p.Name] = p
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	curves = newCurves
+	configured = newConfigured
+
+	return nil
+}
+
+// Set adds or replaces a profile at runtime, the mocks using it get its new
+// curve from the next request.
+func Set(p Profile) error {
+
+	c, err := compile(p)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	curves[p.Name] = c
+
+	return nil
+}
+
+// Reset restores the configured profile, a profile added at runtime is
+// removed.
+func Reset(name string) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, exists := curves[name]; !exists {
+		return errors.New("latency profile " + name + " not found")
+	}
+
+	p, isConfigured := configured[name]
+	if !isConfigured {
+		delete(curves, name)
+		return nil
+	}
+
+	curves[name], _ = compile(p)
+
+	return nil
+}
+
+func Exists(name string) bool {
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	_, exists := curves[name]
+
+	return exists
+}
+
+// List returns the profiles sorted by name.
+func List() []Profile {
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	profiles := []Profile{}
+	for _, c := range curves {
+		profiles = append(profiles, c.profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+
+	return profiles
+}
+
+// Draw returns a latency of the profile curve, 0 when the profile doesn't
+// exist.
+func Draw(name string) time.Duration {
+
+	mutex.RLock()
+	c, exists := curves[name]
+	mutex.RUnlock()
+
+	if !exists {
+		return 0
+	}
+
+	return c.draw(rand.Float64(), rand.NormFloat64())
+}
+
+func (c curve) draw(u float64, n float64) time.Duration {
+
+	var d time.Duration
+
+	switch c.distribution {
+	case DISTRIBUTION_UNIFORM:
+
+		d = c.min + time.Duration(u*float64(c.max-c.min))
+
+	case DISTRIBUTION_LOGNORMAL:
+
+		d = time.Duration(math.Exp(c.mu + c.sigma*n))
+
+	default:
+
+		// inverse of the piecewise linear cumulative distribution
+		for i := 1; i < len(c.points); i++ {
+
+			previous, current := c.points[i-1], c.points[i]
+			if u <= current.rank {
+				share := (u - previous.rank) / (current.rank - previous.rank)
+				d = previous.duration + time.Duration(share*float64(current.duration-previous.duration))
+				break
+			}
+		}
+	}
+
+	if d < c.min {
+		d = c.min
+	}
+	if c.max > 0 && d > c.max {
+		d = c.max
+	}
+
+	return d
+}
+
+func compile(p Profile) (curve, error) {
+
+	c := curve{profile: p, distribution: p.Distribution}
+
+	if p.Name == "" {
+		return c, errors.New("latency profile needs a name")
+	}
+
+	if c.distribution == "" {
+		c.distribution = DISTRIBUTION_PIECEWISE
+	}
+
+	var err error
+	c.min, err = parseDuration(p.Name, "min", p.Min)
+	if err != nil {
+		return c, err
+	}
+
+	c.max, err = parseDuration(p.Name, "max", p.Max)
+	if err != nil {
+		return c, err
+	}
+
+	c.points = []point{{0, c.min}}
+	for _, percentile := range []struct {
+		rank  float64
+		name  string
+		value string
+	}{{0.5, "p50", p.P50}, {0.9, "p90", p.P90}, {0.95, "p95", p.P95}, {0.99, "p99", p.P99}} {
+
+		if percentile.value == "" {
+			continue
+		}
+
+		d, err := parseDuration(p.Name, percentile.name, percentile.value)
+		if err != nil {
+			return c, err
+		}
+
+		if d < c.points[len(c.points)-1].duration {
+			return c, errors.New("latency profile " + p.Name + " " + percentile.name + " is lower than the previous percentile")
+		}
+
+		c.points = append(c.points, point{percentile.rank, d})
+	}
+
+	last := c.points[len(c.points)-1].duration
+	if c.max > 0 && c.max < last {
+		return c, errors.New("latency profile " + p.Name + " max is lower than its percentiles")
+	}
+
+	switch c.distribution {
+	case DISTRIBUTION_PIECEWISE:
+
+		if len(c.points) == 1 && c.max == 0 {
+			return c, errors.New("latency profile " + p.Name + " needs percentiles or a max")
+		}
+
+		// the slowest requests are up to the max, or the last percentile
+		end := c.max
+		if end == 0 {
+			end = last
+		}
+		c.points = append(c.points, point{1, end})
+
+	case DISTRIBUTION_LOGNORMAL:
+
+		if len(c.points) < 3 || c.points[1].rank != 0.5 {
+			return c, errors.New("latency profile " + p.Name + " lognormal distribution needs the p50 and a higher percentile")
+		}
+
+		// median and highest percentile of the curve
+		high := c.points[len(c.points)-1]
+		c.mu = math.Log(float64(c.points[1].duration))
+		c.sigma = (math.Log(float64(high.duration)) - c.mu) / zScores[high.rank]
+
+	case DISTRIBUTION_UNIFORM:
+
+		if c.max <= c.min {
+			return c, errors.New("latency profile " + p.Name + " uniform distribution needs a max greater than its min")
+		}
+
+	default:
+
+		return c, errors.New("latency profile " + p.Name + " distribution " + p.Distribution + " unknown, use " + DISTRIBUTION_PIECEWISE + ", " + DISTRIBUTION_LOGNORMAL + " or " + DISTRIBUTION_UNIFORM)
+	}
+
+	return c, nil
+}
+
+func parseDuration(name string, field string, value string) (time.Duration, error) {
+
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, errors.New("latency profile " + name + " " + field + " '" + value + "' is not a valid duration")
+	}
+
+	return d, nil
+}
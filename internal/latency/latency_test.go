@@ -0,0 +1,114 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package latency
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPiecewiseDraw(t *testing.T) {
+
+	c, err := compile(Profile{Name: "payments", P50: "20ms", P95: "200ms", P99: "1s", Max: "2s"})
+	if err != nil {
+		t.Fatalf("compile failed with error %v", err)
+	}
+
+	tests := map[float64]time.Duration{
+		0:     0,
+		0.25:  10 * time.Millisecond,
+		0.5:   20 * time.Millisecond,
+		0.95:  200 * time.Millisecond,
+		0.97:  600 * time.Millisecond,
+		0.995: 1500 * time.Millisecond,
+	}
+
+	for u, want := range tests {
+
+		d := c.draw(u, 0)
+		if d.Round(time.Millisecond) != want {
+			t.Errorf("latency at %v is: %s, want: %s.", u, d, want)
+		}
+	}
+}
+
+func TestLognormalPercentiles(t *testing.T) {
+
+	err := Configure([]Profile{{Name: "search", Distribution: DISTRIBUTION_LOGNORMAL, P50: "50ms", P99: "500ms"}})
+	if err != nil {
+		t.Fatalf("Configure failed with error %v", err)
+	}
+
+	draws := make([]time.Duration, 20000)
+	for i := range draws {
+		draws[i] = Draw("search")
+	}
+	sort.Slice(draws, func(i, j int) bool { return draws[i] < draws[j] })
+
+	p50, p99 := draws[len(draws)/2], draws[len(draws)*99/100]
+	if p50 < 45*time.Millisecond || p50 > 55*time.Millisecond || p99 < 400*time.Millisecond || p99 > 600*time.Millisecond {
+		t.Errorf("lognormal p50 and p99 are: %s %s, want about 50ms 500ms.", p50, p99)
+	}
+}
+
+func TestRuntimeProfiles(t *testing.T) {
+
+	err := Configure([]Profile{{Name: "fixed", Min: "10ms", Max: "10ms", P50: "10ms"}})
+	if err != nil {
+		t.Fatalf("Configure failed with error %v", err)
+	}
+
+	err = Set(Profile{Name: "fixed", Distribution: DISTRIBUTION_UNIFORM, Min: "1s", Max: "2s"})
+	if err != nil {
+		t.Fatalf("Set failed with error %v", err)
+	}
+
+	if d := Draw("fixed"); d < time.Second || d > 2*time.Second {
+		t.Errorf("runtime profile latency is: %s", d)
+	}
+
+	_ = Set(Profile{Name: "added", Max: "5ms"})
+	_ = Reset("added")
+	_ = Reset("fixed")
+
+	if d := Draw("fixed"); d != 10*time.Millisecond || Exists("added") || len(List()) != 1 {
+		t.Errorf("reset profile latency is: %s, profiles are: %+v", d, List())
+	}
+
+	if Reset("unknown") == nil || Draw("unknown") != 0 {
+		t.Errorf("unknown profile should fail to reset and have no latency")
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+
+	for _, p := range []Profile{
+		{P50: "20ms"},
+		{Name: "empty"},
+		{Name: "bad", P50: "fast"},
+		{Name: "decreasing", P50: "200ms", P95: "20ms"},
+		{Name: "max", P99: "1s", Max: "100ms"},
+		{Name: "lognormal", Distribution: DISTRIBUTION_LOGNORMAL, P99: "1s"},
+		{Name: "uniform", Distribution: DISTRIBUTION_UNIFORM, Min: "1s"},
+		{Name: "shape", Distribution: "gamma", P50: "20ms"},
+	} {
+
+		if _, err := compile(p); err == nil {
+			t.Errorf("profile %+v should fail", p)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"alfred/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	ACCESS_LOG_FORMAT_JSON   = "json"
+	ACCESS_LOG_FORMAT_LOGFMT = "logfmt"
+
+	ACCESS_LOG_OUTPUT_STDOUT = "stdout"
+	ACCESS_LOG_OUTPUT_FILE   = "file"
+	ACCESS_LOG_OUTPUT_SYSLOG = "syslog"
+)
+
+type AccessLogConfig struct {
+	Format        string
+	Output        string
+	FilePath      string
+	MaxSizeMb     int
+	MaxBackups    int
+	MaxAgeDays    int
+	SyslogAddress string
+	SyslogTag     string
+}
+
+// AccessEntry is an access log line, the mock used and its log level are
+// set while the request is served.
+type AccessEntry struct {
+	Time       string  `json:"dateTime"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Mock       string  `json:"mock,omitempty"`
+	Status     int     `json:"status"`
+	LatencyMs  float64 `json:"latency-ms"`
+	BodySize   int     `json:"body-size"`
+	RemoteAddr string  `json:"remote-addr"`
+	TraceId    string  `json:"trace-id,omitempty"`
+
+	// mock log level, 'error' mutes the entry
+	LogLevel string `json:"-"`
+}
+
+type accessLogger struct {
+	format string
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+type accessEntryKey struct{}
+
+var (
+	// nil while the access log is disabled
+	access      *accessLogger
+	accessMutex sync.RWMutex
+)
+
+// InitAccessLogger enables the access log with the given format and output.
+func InitAccessLogger(config AccessLogConfig) error {
+
+	if config.Format != ACCESS_LOG_FORMAT_JSON && config.Format != ACCESS_LOG_FORMAT_LOGFMT {
+		return errors.New("access log format '" + config.Format + "' unknown, it should be " + ACCESS_LOG_FORMAT_JSON + " or " + ACCESS_LOG_FORMAT_LOGFMT)
+	}
+
+	var writer io.Writer
+
+	switch config.Output {
+	case ACCESS_LOG_OUTPUT_STDOUT:
+		writer = os.Stdout
+	case ACCESS_LOG_OUTPUT_FILE:
+		writer = &lumberjack.Logger{
+			Filename:   config.FilePath,
+			MaxSize:    config.MaxSizeMb,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAgeDays,
+		}
+	case ACCESS_LOG_OUTPUT_SYSLOG:
+		syslogWriter, err := newSyslogWriter(config.SyslogAddress, config.SyslogTag)
+		if err != nil {
+			return errors.New("access log syslog connection failed: " + err.Error())
+		}
+		writer = syslogWriter
+	default:
+		return errors.New("access log output '" + config.Output + "' unknown, it should be " + ACCESS_LOG_OUTPUT_STDOUT + ", " + ACCESS_LOG_OUTPUT_FILE + " or " + ACCESS_LOG_OUTPUT_SYSLOG)
+	}
+
+	accessMutex.Lock()
+	access = &accessLogger{format: config.Format, writer: writer}
+	accessMutex.Unlock()
+
+	return nil
+}
+
+func AccessLogEnabled() bool {
+
+	accessMutex.RLock()
+	defer accessMutex.RUnlock()
+
+	return access != nil
+}
+
+// WithAccessEntry adds the access entry to the request context, to be
+// completed by handlers.
+func WithAccessEntry(ctx context.Context, entry *AccessEntry) context.Context {
+
+	return context.WithValue(ctx, accessEntryKey{}, entry)
+}
+
+func GetAccessEntry(ctx context.Context) *AccessEntry {
+
+	entry, _ := ctx.Value(accessEntryKey{}).(*AccessEntry)
+
+	return entry
+}
+
+// WriteAccessEntry writes the entry, unless its mock log level is 'error'.
+// Its time and latency are set by the caller.
+func WriteAccessEntry(ctx context.Context, entry *AccessEntry) {
+
+	accessMutex.RLock()
+	logger := access
+	accessMutex.RUnlock()
+
+	if logger == nil || strings.EqualFold(entry.LogLevel, LOG_LEVEL_ERROR) {
+		return
+	}
+
+	traceId, _, _ := tracing.GetSpanContext(tracing.GetSpanFromContext(ctx))
+	if traceId != "00000000000000000000000000000000" {
+		entry.TraceId = traceId
+	}
+
+	var line []byte
+	if logger.format == ACCESS_LOG_FORMAT_LOGFMT {
+		line = entry.logfmt()
+	} else {
+		line, _ = json.Marshal(entry)
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	_, err := logger.writer.Write(append(line, '\n'))
+	if err != nil {
+		Error(ctx, "failed to write access log", err)
+	}
+}
+
+func (entry *AccessEntry) logfmt() []byte {
+
+	var b strings.Builder
+
+	b.WriteString("dateTime=" + entry.Time)
+	b.WriteString(" method=" + entry.Method)
+	b.WriteString(" path=" + logfmtValue(entry.Path))
+	if entry.Mock != "" {
+		b.WriteString(" mock=" + logfmtValue(entry.Mock))
+	}
+	b.WriteString(" status=" + strconv.Itoa(entry.Status))
+	b.WriteString(" latency-ms=" + strconv.FormatFloat(entry.LatencyMs, 'f', -1, 64))
+	b.WriteString(" body-size=" + strconv.Itoa(entry.BodySize))
+	b.WriteString(" remote-addr=" + logfmtValue(entry.RemoteAddr))
+	if entry.TraceId != "" {
+		b.WriteString(" trace-id=" + entry.TraceId)
+	}
+
+	return []byte(b.String())
+}
+
+// Quote values with spaces, quotes or equal signs
+func logfmtValue(value string) string {
+
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
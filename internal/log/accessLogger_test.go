@@ -0,0 +1,101 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAccessEntry(t *testing.T) {
+
+	buffer := new(bytes.Buffer)
+	access = &accessLogger{format: ACCESS_LOG_FORMAT_LOGFMT, writer: buffer}
+	defer func() { access = nil }()
+
+	entry := &AccessEntry{Method: "GET", Path: "/some path", Mock: "my-mock", Status: 200, LatencyMs: 1.5, BodySize: 12, RemoteAddr: "127.0.0.1:1234", Time: time.Now().UTC().Format(time.RFC3339Nano)}
+	WriteAccessEntry(context.Background(), entry)
+
+	line := buffer.String()
+	for _, expected := range []string{"method=GET", `path="/some path"`, "mock=my-mock", "status=200", "latency-ms=1.5", "body-size=12"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("logfmt access entry '%s' does not contain '%s'", line, expected)
+		}
+	}
+
+	buffer.Reset()
+	access.format = ACCESS_LOG_FORMAT_JSON
+	WriteAccessEntry(context.Background(), entry)
+
+	var decoded AccessEntry
+	err := json.Unmarshal(buffer.Bytes(), &decoded)
+	if err != nil || decoded.Mock != "my-mock" || decoded.Status != 200 {
+		t.Errorf("json access entry is: '%s', error: %v", buffer.String(), err)
+	}
+
+	// muted mock
+	buffer.Reset()
+	entry.LogLevel = LOG_LEVEL_ERROR
+	WriteAccessEntry(context.Background(), entry)
+	if buffer.Len() != 0 {
+		t.Errorf("access entry of a mock with error log level should be muted, got: '%s'", buffer.String())
+	}
+}
+
+func TestInitAccessLoggerErrors(t *testing.T) {
+
+	err := InitAccessLogger(AccessLogConfig{Format: "xml", Output: ACCESS_LOG_OUTPUT_STDOUT})
+	if err == nil {
+		t.Errorf("unknown access log format should fail")
+	}
+
+	err = InitAccessLogger(AccessLogConfig{Format: ACCESS_LOG_FORMAT_JSON, Output: "kafka"})
+	if err == nil {
+		t.Errorf("unknown access log output should fail")
+	}
+
+	if AccessLogEnabled() {
+		t.Errorf("access log should not be enabled after errors")
+	}
+}
+
+func TestWithLevel(t *testing.T) {
+
+	InitLogger("test", false, "1.0")
+
+	ctx, err := WithLevel(context.Background(), "DEBUG")
+	if err != nil {
+		t.Fatalf("with level failed with error: %v", err)
+	}
+
+	if !enabled(ctx, ZAP_LEVEL_MAP[LOG_LEVEL_DEBUG]) {
+		t.Errorf("debug should be enabled with a debug context level")
+	}
+
+	if enabled(context.Background(), ZAP_LEVEL_MAP[LOG_LEVEL_DEBUG]) {
+		t.Errorf("debug should not be enabled with the info logger level")
+	}
+
+	_, err = WithLevel(context.Background(), "verbose")
+	if err == nil {
+		t.Errorf("unknown level should fail")
+	}
+}
@@ -76,8 +76,9 @@ func InitLogger(component string, debug bool, version string) RootLogger {
 		logger.dyn.SetLevel(zap.DebugLevel)
 	}
 
+	// levels are checked before logging, to allow overrides by context
 	logger.lowLogger, _ = zap.Config{
-		Level:             logger.dyn,
+		Level:             zap.NewAtomicLevelAt(zap.DebugLevel),
 		Development:       false,
 		Encoding:          "json",
 		DisableCaller:     true,
@@ -141,6 +142,9 @@ func addTracingContextFields(ctx context.Context, fields ...zapcore.Field) []zap
 Debug is for all logs that are for developers.
 */
 func Debug(ctx context.Context, msg string, fields ...zapcore.Field) {
+	if !enabled(ctx, zap.DebugLevel) {
+		return
+	}
 	fields = addContextInfo(ctx, fields...)
 	fields = appendLogFields(ctx, fields...)
 	GetLogger().lowLogger.Debug(msg, fields...)
@@ -151,6 +155,9 @@ Info is for all logs that are for informative purpose.
 Incoming requests for example, or outgoing results.
 */
 func Info(ctx context.Context, msg string, fields ...zapcore.Field) {
+	if !enabled(ctx, zap.InfoLevel) {
+		return
+	}
 	allFields := appendLogFields(ctx, fields...)
 
 	GetLogger().lowLogger.Info(msg, allFields...)
@@ -181,11 +188,44 @@ func GetLevel() string {
 	return GetLogger().dyn.Level().String()
 }
 
+type levelKey struct{}
+
+/*
+WithLevel overrides the logger level for logs using the returned context,
+a mock can be debugged without the noise of other ones for example.
+*/
+func WithLevel(ctx context.Context, levelStr string) (context.Context, error) {
+
+	for _, level := range LOG_LEVEL_LIST {
+		if strings.EqualFold(levelStr, level) {
+
+			return context.WithValue(ctx, levelKey{}, ZAP_LEVEL_MAP[level]), nil
+		}
+	}
+
+	return ctx, errors.New("Logger level " + levelStr + " does not exist. It should be one of " + fmt.Sprint(LOG_LEVEL_LIST))
+}
+
+// Context level first, then the logger one
+func enabled(ctx context.Context, level zapcore.Level) bool {
+
+	if ctx != nil {
+		if ctxLevel, ok := ctx.Value(levelKey{}).(zapcore.Level); ok {
+			return ctxLevel.Enabled(level)
+		}
+	}
+
+	return GetLogger().dyn.Enabled(level)
+}
+
 /*
 Info is for all logs that can help to investigate a specific behavior but doesn't indicate an issue that needs to be investigated
 Incoming requests for example, or outgoing results.
 */
 func Warn(ctx context.Context, msg string, err error, fields ...zapcore.Field) {
+	if !enabled(ctx, zap.WarnLevel) {
+		return
+	}
 	allFields := appendLogFields(ctx, fields...)
 
 	errMsg := "nil"
@@ -214,6 +254,9 @@ func Error(ctx context.Context, msg string, err error, fields ...zapcore.Field)
 	span := tracing.GetSpanFromContext(ctx)
 	tracing.SetSpanStatusError(&span, err)
 
+	if !enabled(ctx, zap.ErrorLevel) {
+		return
+	}
 	GetLogger().lowLogger.Error(msg, allFields...)
 }
 
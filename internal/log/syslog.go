@@ -0,0 +1,37 @@
+//go:build !windows && !plan9
+
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"io"
+	"log/syslog"
+	"strings"
+)
+
+// Connect to the local syslog daemon, or to a remote one with a
+// 'network://host:port' address (udp://localhost:514 for example).
+func newSyslogWriter(address string, tag string) (io.Writer, error) {
+
+	network := ""
+	if parts := strings.SplitN(address, "://", 2); len(parts) == 2 {
+		network, address = parts[0], parts[1]
+	}
+
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const KAFKA_DEFAULT_GROUP = "alfred"
+
+type KafkaConfig struct {
+	Brokers  []string
+	ClientId string
+}
+
+type kafkaBroker struct {
+	conf   KafkaConfig
+	writer *kafka.Writer
+}
+
+// NewKafkaBroker creates the broker, connections are opened by the first
+// message published or consumed.
+func NewKafkaBroker(c KafkaConfig) (Broker, error) {
+
+	if len(c.Brokers) == 0 {
+		return nil, errors.New("kafka brokers list is empty")
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(c.Brokers...),
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           10 * time.Millisecond,
+		Transport:              &kafka.Transport{ClientID: c.ClientId},
+	}
+
+	return &kafkaBroker{conf: c, writer: writer}, nil
+}
+
+func (b *kafkaBroker) Publish(ctx context.Context, msg Message) error {
+
+	m := kafka.Message{Topic: msg.Topic, Value: []byte(msg.Body)}
+
+	if msg.Key != "" {
+		m.Key = []byte(msg.Key)
+	}
+
+	for k, v := range msg.Headers {
+		m.Headers = append(m.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	return b.writer.WriteMessages(ctx, m)
+}
+
+// Consume commits the messages once handled.
+func (b *kafkaBroker) Consume(ctx context.Context, topic string, group string, handler Handler) error {
+
+	if group == "" {
+		group = KAFKA_DEFAULT_GROUP
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: b.conf.Brokers,
+		GroupID: group,
+		Topic:   topic,
+		Dialer:  &kafka.Dialer{ClientID: b.conf.ClientId, Timeout: 10 * time.Second},
+	})
+	defer reader.Close()
+
+	for {
+
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		msg := Message{Broker: KAFKA, Topic: m.Topic, Key: string(m.Key), Body: string(m.Value), Headers: map[string]string{}}
+		for _, h := range m.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+
+		err = handler(ctx, msg)
+		if err != nil {
+			return err
+		}
+
+		err = reader.CommitMessages(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *kafkaBroker) Close() error {
+
+	return b.writer.Close()
+}
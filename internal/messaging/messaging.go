@@ -0,0 +1,190 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package messaging
+
+import (
+	"alfred/internal/log"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const KAFKA = "kafka"
+
+// Consumers are restarted after this delay when their connection fails
+const CONSUME_RETRY_DELAY = 5 * time.Second
+
+// Message published by mocks and js functions, or consumed from a topic
+type Message struct {
+	Broker  string            `json:"broker,omitempty"`
+	Topic   string            `json:"topic"`
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Handler of the consumed messages
+type Handler func(ctx context.Context, msg Message) error
+
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	// Consume runs the handler for each message of the topic until the
+	// context is done.
+	Consume(ctx context.Context, topic string, group string, handler Handler) error
+	Close() error
+}
+
+var (
+	brokers      = map[string]Broker{}
+	brokersMutex sync.RWMutex
+)
+
+// Register makes the broker available to the mocks, a previous broker with
+// the same name is closed.
+func Register(name string, b Broker) error {
+
+	brokersMutex.Lock()
+	previous := brokers[name]
+	brokers[name] = b
+	brokersMutex.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+
+	return nil
+}
+
+func Get(name string) (Broker, error) {
+
+	brokersMutex.RLock()
+	defer brokersMutex.RUnlock()
+
+	if name == "" {
+		name = KAFKA
+	}
+
+	b, exists := brokers[name]
+	if !exists {
+		return nil, errors.New("broker '" + name + "' not configured")
+	}
+
+	return b, nil
+}
+
+// Publish sends the message with its broker, kafka by default
+func Publish(ctx context.Context, msg Message) error {
+
+	b, err := Get(msg.Broker)
+	if err != nil {
+		return err
+	}
+
+	if msg.Topic == "" {
+		return errors.New("message topic is empty")
+	}
+
+	return b.Publish(ctx, msg)
+}
+
+// Consumers running in background
+var consumers sync.WaitGroup
+
+// Subscribe consumes the topic in background until the context is done,
+// handler errors are logged and the message skipped.
+func Subscribe(ctx context.Context, broker string, topic string, group string, handler Handler) error {
+
+	b, err := Get(broker)
+	if err != nil {
+		return err
+	}
+
+	fields := []zap.Field{zap.String("broker", broker), zap.String("topic", topic)}
+
+	consumers.Add(1)
+	go func() {
+
+		defer consumers.Done()
+
+		for ctx.Err() == nil {
+
+			err := b.Consume(ctx, topic, group, func(ctx context.Context, msg Message) error {
+
+				err := handler(ctx, msg)
+				if err != nil {
+					log.Error(ctx, "message handler failed", err, fields...)
+				}
+
+				return nil
+			})
+			if err == nil {
+				continue
+			}
+
+			log.Error(ctx, "consumer failed, restarted in "+CONSUME_RETRY_DELAY.String(), err, fields...)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(CONSUME_RETRY_DELAY):
+			}
+		}
+	}()
+
+	log.Info(ctx, "consuming "+topic+" messages", fields...)
+
+	return nil
+}
+
+// WaitConsumers returns once the consumers stopped, after their context is
+// done.
+func WaitConsumers() {
+
+	consumers.Wait()
+}
+
+// Names of the configured brokers
+func Names() []string {
+
+	brokersMutex.RLock()
+	defer brokersMutex.RUnlock()
+
+	var names []string
+	for name := range brokers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func Close() error {
+
+	brokersMutex.Lock()
+	defer brokersMutex.Unlock()
+
+	var errs []error
+	for name, b := range brokers {
+		errs = append(errs, b.Close())
+		delete(brokers, name)
+	}
+
+	return errors.Join(errs...)
+}
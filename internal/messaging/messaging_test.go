@@ -0,0 +1,129 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package messaging
+
+import (
+	"alfred/internal/log"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// In memory broker, consumers get the messages published on their topic
+type fakeBroker struct {
+	messages chan Message
+	closed   bool
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, msg Message) error {
+
+	b.messages <- msg
+	return nil
+}
+
+func (b *fakeBroker) Consume(ctx context.Context, topic string, group string, handler Handler) error {
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-b.messages:
+			if msg.Topic == topic {
+				handler(ctx, msg)
+			}
+		}
+	}
+}
+
+func (b *fakeBroker) Close() error {
+
+	b.closed = true
+	return nil
+}
+
+func TestPublishAndSubscribe(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+
+	err := Publish(context.Background(), Message{Topic: "orders"})
+	if err == nil {
+		t.Errorf("publish without broker should fail")
+	}
+
+	broker := &fakeBroker{messages: make(chan Message, 10)}
+	err = Register(KAFKA, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan Message, 10)
+	err = Subscribe(ctx, KAFKA, "orders", "", func(ctx context.Context, msg Message) error {
+		received <- msg
+		return errors.New("handler errors are logged")
+	})
+	if err != nil {
+		t.Fatalf("subscribe failed with error: %v", err)
+	}
+
+	err = Publish(context.Background(), Message{Topic: "orders", Key: "42", Body: "created"})
+	if err == nil {
+		err = Publish(context.Background(), Message{Topic: "orders", Key: "43", Body: "created"})
+	}
+	if err != nil {
+		t.Fatalf("publish failed with error: %v", err)
+	}
+
+	for _, key := range []string{"42", "43"} {
+		select {
+		case msg := <-received:
+			if msg.Key != key || msg.Body != "created" {
+				t.Errorf("message is: %+v", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %s not consumed", key)
+		}
+	}
+
+	// the consumers stop with their context
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		WaitConsumers()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("consumers not stopped")
+	}
+
+	err = Publish(context.Background(), Message{Broker: "mqtt", Topic: "orders"})
+	if err == nil {
+		t.Errorf("publish with an unknown broker should fail")
+	}
+
+	Close()
+	if !broker.closed || len(Names()) != 0 {
+		t.Errorf("brokers should be closed")
+	}
+}
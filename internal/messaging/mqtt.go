@@ -0,0 +1,115 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const MQTT = "mqtt"
+
+const MQTT_TIMEOUT = 10 * time.Second
+
+type MqttConfig struct {
+	// tcp://host:1883, ssl://host:8883 or ws://host:80
+	Url      string
+	ClientId string
+	Username string
+	Password string
+	Qos      byte
+}
+
+type mqttBroker struct {
+	conf   MqttConfig
+	client mqtt.Client
+}
+
+// NewMqttBroker connects to the broker, the client reconnects automatically.
+func NewMqttBroker(c MqttConfig) (Broker, error) {
+
+	if c.Url == "" {
+		return nil, errors.New("mqtt broker url is empty")
+	}
+
+	if c.Qos > 2 {
+		return nil, errors.New("mqtt qos must be 0, 1 or 2")
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(c.Url).
+		SetClientID(c.ClientId).
+		SetUsername(c.Username).
+		SetPassword(c.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(MQTT_TIMEOUT)
+
+	client := mqtt.NewClient(opts)
+
+	err := waitToken(client.Connect())
+	if err != nil {
+		return nil, errors.New("mqtt broker " + c.Url + " connection failed: " + err.Error())
+	}
+
+	return &mqttBroker{conf: c, client: client}, nil
+}
+
+// MQTT messages have no key nor headers, they are ignored
+func (b *mqttBroker) Publish(ctx context.Context, msg Message) error {
+
+	return waitToken(b.client.Publish(msg.Topic, b.conf.Qos, false, msg.Body))
+}
+
+// Consume subscribes to the topic, a shared subscription when a group is
+// set: the messages are then dispatched to a single member of the group.
+func (b *mqttBroker) Consume(ctx context.Context, topic string, group string, handler Handler) error {
+
+	filter := topic
+	if group != "" {
+		filter = "$share/" + group + "/" + topic
+	}
+
+	err := waitToken(b.client.Subscribe(filter, b.conf.Qos, func(_ mqtt.Client, m mqtt.Message) {
+		handler(ctx, Message{Broker: MQTT, Topic: m.Topic(), Body: string(m.Payload())})
+	}))
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+
+	return waitToken(b.client.Unsubscribe(filter))
+}
+
+func (b *mqttBroker) Close() error {
+
+	b.client.Disconnect(250)
+
+	return nil
+}
+
+func waitToken(token mqtt.Token) error {
+
+	if !token.WaitTimeout(MQTT_TIMEOUT) {
+		return errors.New("mqtt operation timed out")
+	}
+
+	return token.Error()
+}
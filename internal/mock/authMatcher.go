@@ -0,0 +1,143 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"alfred/pkg/request"
+	"net/http"
+	"reflect"
+)
+
+const DEFAULT_API_KEY_HEADER = "X-API-Key"
+
+// Authentication mismatch, with the status answered when the mock rejects
+// failing requests.
+type AuthError struct {
+	Status    int
+	Challenge string
+	Reject    bool
+	message   string
+}
+
+func (e *AuthError) Error() string {
+
+	return e.message
+}
+
+func matchAuth(a MockAuth, r *http.Request) error {
+
+	auth := request.GetAuth(r)
+
+	missing := func(challenge string, message string) error {
+		return &AuthError{Status: http.StatusUnauthorized, Challenge: challenge, Reject: a.Reject, message: message}
+	}
+
+	invalid := func(message string) error {
+		return &AuthError{Status: http.StatusForbidden, Reject: a.Reject, message: message}
+	}
+
+	if a.Basic != nil {
+
+		if auth == nil || auth.Scheme != request.AUTH_SCHEME_BASIC {
+			return missing(`Basic realm="alfred"`, "no basic auth credentials")
+		}
+
+		if a.Basic.Username != "" && a.Basic.Username != auth.Username {
+			return invalid("basic auth username '" + auth.Username + "' is not '" + a.Basic.Username + "'")
+		}
+
+		if a.Basic.Password != "" && a.Basic.Password != auth.Password {
+			return invalid("basic auth password of '" + auth.Username + "' is wrong")
+		}
+	}
+
+	if a.Bearer != nil {
+
+		if auth == nil || auth.Scheme != request.AUTH_SCHEME_BEARER {
+			return missing("Bearer", "no bearer token")
+		}
+
+		if a.Bearer.Token != "" && a.Bearer.Token != auth.Token {
+			return invalid("bearer token is wrong")
+		}
+
+		for k, v := range a.Bearer.Claims {
+
+			claim, exists := auth.Claims[k]
+			if !exists || !reflect.DeepEqual(v, claim) {
+				return invalid("bearer token claim '" + k + "' not matching")
+			}
+		}
+	}
+
+	if a.ApiKey != nil {
+
+		var apiKey string
+		if a.ApiKey.Query != "" {
+			apiKey = r.URL.Query().Get(a.ApiKey.Query)
+		} else if a.ApiKey.Header != "" {
+			apiKey = r.Header.Get(a.ApiKey.Header)
+		} else {
+			apiKey = r.Header.Get(DEFAULT_API_KEY_HEADER)
+		}
+
+		if apiKey == "" {
+			return missing("", "no api key")
+		}
+
+		if a.ApiKey.Value != "" && a.ApiKey.Value != apiKey {
+			return invalid("api key is wrong")
+		}
+	}
+
+	return nil
+}
+
+func (a MockAuth) getMatchersCount() int {
+
+	count := 0
+
+	if a.Basic != nil {
+		count++
+		count += countNotEmpty(a.Basic.Username, a.Basic.Password)
+	}
+
+	if a.Bearer != nil {
+		count++
+		count += countNotEmpty(a.Bearer.Token) + len(a.Bearer.Claims)
+	}
+
+	if a.ApiKey != nil {
+		count++
+		count += countNotEmpty(a.ApiKey.Value)
+	}
+
+	return count
+}
+
+func countNotEmpty(values ...string) int {
+
+	count := 0
+
+	for _, v := range values {
+		if v != "" {
+			count++
+		}
+	}
+
+	return count
+}
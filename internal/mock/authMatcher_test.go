@@ -0,0 +1,70 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchAuth(t *testing.T) {
+
+	// JWT with claims {"sub": "bruce", "scope": "profile"}
+	token := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJicnVjZSIsInNjb3BlIjoicHJvZmlsZSJ9."
+
+	bearer := MockAuth{Bearer: &MockBearerAuth{Claims: map[string]interface{}{"scope": "profile"}}, Reject: true}
+	basic := MockAuth{Basic: &MockBasicAuth{Username: "admin", Password: "secret"}}
+	apiKey := MockAuth{ApiKey: &MockApiKeyAuth{Query: "key", Value: "my-key"}}
+
+	tests := []struct {
+		name   string
+		auth   MockAuth
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"bearer", bearer, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }, 0},
+		{"bearer missing", bearer, func(r *http.Request) {}, http.StatusUnauthorized},
+		{"bearer wrong claims", bearer, func(r *http.Request) { r.Header.Set("Authorization", "Bearer abc") }, http.StatusForbidden},
+		{"basic", basic, func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, 0},
+		{"basic wrong password", basic, func(r *http.Request) { r.SetBasicAuth("admin", "nope") }, http.StatusForbidden},
+		{"basic with bearer", basic, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }, http.StatusUnauthorized},
+		{"api key", apiKey, func(r *http.Request) { r.URL.RawQuery = "key=my-key" }, 0},
+		{"api key wrong", apiKey, func(r *http.Request) { r.URL.RawQuery = "key=other" }, http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		test.setup(r)
+
+		err := matchAuth(test.auth, r)
+
+		if test.status == 0 {
+			if err != nil {
+				t.Errorf("%s: should match, got: %v", test.name, err)
+			}
+			continue
+		}
+
+		var authErr *AuthError
+		if !errors.As(err, &authErr) || authErr.Status != test.status || authErr.Reject != test.auth.Reject {
+			t.Errorf("%s: error is: %v, want status: %d", test.name, err, test.status)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"alfred/internal/conf"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bigger body files are streamed, not loaded in memory
+const BODY_FILE_INLINE_MAX_SIZE = 1 << 20
+
+// loadBodyFile inlines small text body files so helpers can be used in them,
+// binary or large ones are streamed at each request. A Content-Type header is
+// added if the mock has none.
+func (r *MockResponse) loadBodyFile(mockDir string) error {
+
+	filePath := getBodyFilePath(r.BodyFile, mockDir)
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return errors.New("body file " + filePath + " is a directory")
+	}
+
+	if !r.hasHeader("Content-Type") {
+
+		sniff := make([]byte, 512)
+		n, _ := io.ReadFull(file, sniff)
+
+		r.SetHeader("Content-Type", getContentType(filePath, sniff[:n]))
+
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Body = nil
+	r.bodyFilePath = ""
+
+	if info.Size() <= BODY_FILE_INLINE_MAX_SIZE {
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+
+		if utf8.Valid(content) {
+
+			// not json bodies are json strings
+			if !json.Valid(content) {
+				content, _ = json.Marshal(string(content))
+			}
+
+			r.Body = content
+			return nil
+		}
+	}
+
+	r.bodyFilePath = filePath
+
+	return nil
+}
+
+// Body files are relative to the mock file directory, or to the body files
+// directory.
+func getBodyFilePath(bodyFile string, mockDir string) string {
+
+	if filepath.IsAbs(bodyFile) {
+		return bodyFile
+	}
+
+	if mockDir != "" {
+		if _, err := os.Stat(filepath.Join(mockDir, bodyFile)); err == nil {
+			return filepath.Join(mockDir, bodyFile)
+		}
+	}
+
+	config, _ := conf.GetConfiguration()
+
+	return config.Alfred.Core.BodiesDir + bodyFile
+}
+
+func getContentType(filePath string, content []byte) string {
+
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(content)
+}
+
+func (r MockResponse) hasHeader(name string) bool {
+
+	for k := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *MockResponse) SetHeader(name string, value string) {
+
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
+
+	r.Headers[name] = value
+}
+
+// GetBodyFilePath returns the body file streamed at each request, empty if
+// the body is inlined.
+func (m Mock) GetBodyFilePath() string {
+
+	return m.Response.bodyFilePath
+}
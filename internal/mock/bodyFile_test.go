@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBodyFile(t *testing.T) {
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello world"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "image.png"), []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff, 0xfe}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := BuildMockFromJsonFile([]byte(`{"name":"text","request":{"url":"/text"},"response":{"body-file":"hello.txt"}}`), filepath.Join(dir, "text.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.GetResponseBody() != "hello world" || m.GetBodyFilePath() != "" {
+		t.Errorf("text body file should be inlined, got '%s'", m.GetResponseBody())
+	}
+	if m.GetResponseHeader("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("text body file content type should be detected, got '%s'", m.GetResponseHeader("Content-Type"))
+	}
+
+	m, err = BuildMockFromJsonFile([]byte(`{"name":"binary","request":{"url":"/binary"},"response":{"body-file":"image.png","headers":{"content-type":"image/x-test"}}}`), filepath.Join(dir, "binary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.GetResponseBody() != "" || m.GetBodyFilePath() != filepath.Join(dir, "image.png") {
+		t.Errorf("binary body file should be streamed, got path '%s'", m.GetBodyFilePath())
+	}
+	if m.GetResponseHeader("Content-Type") != "image/x-test" {
+		t.Errorf("binary body file content type should not be overridden, got '%s'", m.GetResponseHeader("Content-Type"))
+	}
+
+	_, err = BuildMockFromJsonFile([]byte(`{"name":"missing","request":{"url":"/missing"},"response":{"body-file":"missing.bin"}}`), filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("missing body file should fail")
+	}
+}
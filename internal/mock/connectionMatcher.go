@@ -0,0 +1,99 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"alfred/pkg/request"
+	"errors"
+	"net"
+	"strings"
+)
+
+func matchConnection(expected MockRequestConnection, connection *request.Connection) error {
+
+	if expected.Tls && !connection.Tls {
+		return errors.New("connection is not tls")
+	}
+
+	if len(expected.Ip) > 0 {
+
+		networks := expected.networks
+		if networks == nil {
+
+			var err error
+			networks, err = request.ParseIpNetworks(expected.Ip)
+			if err != nil {
+				return errors.New("connection ip " + err.Error())
+			}
+		}
+
+		if !request.ContainsIp(networks, net.ParseIP(connection.ClientIp)) {
+			return errors.New("client ip '" + connection.ClientIp + "' is not in '" + strings.Join(expected.Ip, ", ") + "'")
+		}
+	}
+
+	if expected.Sni != "" && !matchSni(expected.Sni, connection.Sni) {
+		return errors.New("sni '" + connection.Sni + "' is not '" + expected.Sni + "'")
+	}
+
+	if expected.Alpn != "" && expected.Alpn != connection.Alpn {
+		return errors.New("alpn protocol '" + connection.Alpn + "' is not '" + expected.Alpn + "'")
+	}
+
+	if expected.Protocol != "" && !strings.EqualFold(expected.Protocol, connection.Protocol) {
+		return errors.New("protocol '" + connection.Protocol + "' is not '" + expected.Protocol + "'")
+	}
+
+	return nil
+}
+
+// matchSni matches a host name, *.example.com matches the sub domains of
+// example.com
+func matchSni(expected string, sni string) bool {
+
+	if domain, wildcard := strings.CutPrefix(expected, "*."); wildcard {
+		return strings.HasSuffix(strings.ToLower(sni), "."+strings.ToLower(domain))
+	}
+
+	return strings.EqualFold(expected, sni)
+}
+
+// validate parses the ip networks once for the matcher
+func (c *MockRequestConnection) validate() error {
+
+	networks, err := request.ParseIpNetworks(c.Ip)
+	if err != nil {
+		return errors.New("connection ip " + err.Error())
+	}
+
+	c.networks = networks
+
+	return nil
+}
+
+func (c MockRequestConnection) getMatchersCount() int {
+
+	count := countNotEmpty(c.Sni, c.Alpn, c.Protocol)
+
+	if len(c.Ip) > 0 {
+		count++
+	}
+	if c.Tls {
+		count++
+	}
+
+	return count
+}
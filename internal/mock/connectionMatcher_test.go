@@ -0,0 +1,120 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"alfred/pkg/request"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchConnection(t *testing.T) {
+
+	connection := &request.Connection{
+		RemoteIp: "10.1.2.3",
+		ClientIp: "10.1.2.3",
+		Protocol: "HTTP/2.0",
+		Tls:      true,
+		Sni:      "tenant-a.gateway.local",
+		Alpn:     "h2",
+	}
+
+	tests := []struct {
+		name     string
+		expected MockRequestConnection
+		match    bool
+	}{
+		{"empty", MockRequestConnection{}, true},
+		{"ip", MockRequestConnection{Ip: []string{"10.1.2.3"}}, true},
+		{"cidr", MockRequestConnection{Ip: []string{"192.168.0.0/16", "10.0.0.0/8"}}, true},
+		{"other cidr", MockRequestConnection{Ip: []string{"192.168.0.0/16"}}, false},
+		{"sni", MockRequestConnection{Sni: "Tenant-A.gateway.local"}, true},
+		{"sni wildcard", MockRequestConnection{Sni: "*.gateway.local"}, true},
+		{"other sni", MockRequestConnection{Sni: "*.tenant-b.local"}, false},
+		{"alpn", MockRequestConnection{Alpn: "h2", Protocol: "http/2.0", Tls: true}, true},
+		{"other alpn", MockRequestConnection{Alpn: "http/1.1"}, false},
+	}
+
+	for _, test := range tests {
+
+		err := matchConnection(test.expected, connection)
+		if test.match && err != nil {
+			t.Errorf("%s: connection should match, got error '%s'", test.name, err.Error())
+		}
+		if !test.match && err == nil {
+			t.Errorf("%s: connection should not match", test.name)
+		}
+	}
+
+	// plain http request forwarded by a load balancer
+	r := httptest.NewRequest("GET", "/tenants", nil)
+	r.RemoteAddr = "172.16.0.1:43512"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 172.16.0.1")
+
+	// the forwarded addresses are only read from a trusted proxy
+	untrusted := request.GetConnection(r)
+	if untrusted.ClientIp != "172.16.0.1" {
+		t.Errorf("client ip should be the remote ip, connection is: %+v", untrusted)
+	}
+
+	err := request.SetTrustedProxies([]string{"172.16.0.0/12"})
+	if err != nil {
+		t.Fatalf("trusted proxies failed with error: %v", err)
+	}
+	defer request.SetTrustedProxies(nil)
+
+	forwarded := request.GetConnection(r)
+	if forwarded.ClientIp != "203.0.113.7" || forwarded.RemoteIp != "172.16.0.1" || forwarded.RemotePort != "43512" {
+		t.Errorf("forwarded connection is: %+v", forwarded)
+	}
+
+	// the addresses before the last untrusted one are set by the client
+	r.Header.Set("X-Forwarded-For", "10.0.0.1, 203.0.113.7, 172.16.0.2")
+	if spoofed := request.GetConnection(r); spoofed.ClientIp != "203.0.113.7" {
+		t.Errorf("spoofed client ip should be ignored, connection is: %+v", spoofed)
+	}
+
+	err = matchConnection(MockRequestConnection{Ip: []string{"203.0.113.0/24"}}, forwarded)
+	if err != nil {
+		t.Errorf("forwarded client ip should match, got error '%s'", err.Error())
+	}
+
+	err = matchConnection(MockRequestConnection{Tls: true}, forwarded)
+	if err == nil {
+		t.Errorf("plain http connection should not match a tls matcher")
+	}
+}
+
+func TestConnectionMatcherValidation(t *testing.T) {
+
+	_, err := BuildMockFromJson([]byte(`{"name":"tenant","request":{"method":"GET","url":"/tenants","connection":{"ip":["10.0.0.0/33"]}},"response":{"status":200}}`))
+	if err == nil {
+		t.Errorf("invalid CIDR range should fail")
+	}
+
+	m, err := BuildMockFromJson([]byte(`{"name":"tenant","request":{"method":"GET","url":"/tenants","connection":{"ip":["::1","10.0.0.0/8"],"sni":"*.gateway.local"}},"response":{"status":200}}`))
+	if err != nil {
+		t.Fatalf("mock build failed with error: %v", err)
+	}
+
+	if m.GetMatchersCount() != 2 {
+		t.Errorf("matchers count is: %d", m.GetMatchersCount())
+	}
+
+	if len(m.Request.Connection.networks) != 2 {
+		t.Errorf("ip networks should be parsed at the mock build, got %v", m.Request.Connection.networks)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"errors"
+	"time"
+)
+
+func (p *MockFunctionPool) validate(functionFile string) error {
+
+	if functionFile == "" {
+		return errors.New("function pool without function file")
+	}
+
+	if p.MaxSize <= 0 || p.MinSize < 0 || p.MinSize > p.MaxSize {
+		return errors.New("function pool sizes must be 0 <= min-size <= max-size and max-size > 0")
+	}
+
+	if p.AcquireTimeout != "" {
+
+		timeout, err := time.ParseDuration(p.AcquireTimeout)
+		if err != nil {
+			return err
+		}
+
+		p.acquireTimeout = timeout
+	}
+
+	return nil
+}
@@ -18,11 +18,18 @@ package mock
 
 import (
 	"alfred/internal/helper"
+	"alfred/internal/latency"
+	"alfred/internal/ratelimit"
 	"encoding/json"
 	"math/rand"
+	"net"
+	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 type MockRequest struct {
@@ -30,11 +37,114 @@ type MockRequest struct {
 	Url            string `json:"url"`
 	UrlRegexStr    string `json:"urlRegex"`
 	UrlTransformed string
+	Graphql        *MockGraphql           `json:"graphql,omitempty"`
+	Soap           *MockSoap              `json:"soap,omitempty"`
+	ClientCert     *MockClientCert        `json:"clientCert,omitempty"`
+	Auth           *MockAuth              `json:"auth,omitempty"`
+	Multipart      *MockMultipart         `json:"multipart,omitempty"`
+	Connection     *MockRequestConnection `json:"connection,omitempty"`
+
+	Headers map[string]MockValueMatcher `json:"headers,omitempty"`
+	Query   map[string]MockValueMatcher `json:"query,omitempty"`
+	Body    []MockValueMatcher          `json:"body,omitempty"`
 
 	//use to manage url helpers
 	RegexUrl *regexp.Regexp
 }
 
+type MockGraphql struct {
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	SchemaFile    string                 `json:"schema-file"`
+
+	//use to validate incoming operations
+	Schema *ast.Schema `json:"-"`
+}
+
+type MockSoap struct {
+	Action    string `json:"action"`
+	Operation string `json:"operation"`
+}
+
+// Client certificate (mTLS) matchers, a field left empty matches any value
+type MockClientCert struct {
+	CommonName       string `json:"commonName,omitempty"`
+	Organization     string `json:"organization,omitempty"`
+	IssuerCommonName string `json:"issuerCommonName,omitempty"`
+	DnsName          string `json:"dnsName,omitempty"`
+	Fingerprint      string `json:"fingerprint,omitempty"`
+	Verified         bool   `json:"verified,omitempty"`
+}
+
+// Connection matchers, a field left empty matches any value. The client ip is
+// matched against addresses or CIDR ranges, the sni against a host name or a
+// *.domain wildcard.
+type MockRequestConnection struct {
+	Ip       []string `json:"ip,omitempty"`
+	Sni      string   `json:"sni,omitempty"`
+	Alpn     string   `json:"alpn,omitempty"`
+	Protocol string   `json:"protocol,omitempty"`
+	Tls      bool     `json:"tls,omitempty"`
+
+	// ip networks parsed at the mock build
+	networks []*net.IPNet
+}
+
+// Header, query parameter or body value matchers, every field set must match.
+// Matches is a regular expression matching the whole value.
+type MockValueMatcher struct {
+	EqualTo  *string `json:"equalTo,omitempty"`
+	Contains string  `json:"contains,omitempty"`
+	Matches  string  `json:"matches,omitempty"`
+
+	// pattern compiled at the mock build
+	regex *regexp.Regexp
+}
+
+// Authentication matchers, a field left empty matches any value. With reject,
+// failing requests are answered with a 401 (no credentials) or a 403 (wrong
+// credentials) instead of not matching the mock.
+type MockAuth struct {
+	Basic  *MockBasicAuth  `json:"basic,omitempty"`
+	Bearer *MockBearerAuth `json:"bearer,omitempty"`
+	ApiKey *MockApiKeyAuth `json:"api-key,omitempty"`
+	Reject bool            `json:"reject,omitempty"`
+}
+
+type MockBasicAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type MockBearerAuth struct {
+	Token  string                 `json:"token,omitempty"`
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// The api key is read from the header (X-API-Key by default) or from the
+// query parameter if set.
+type MockApiKeyAuth struct {
+	Header string `json:"header,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+// Multipart/form-data matchers, fields values and files are matched by field
+// name, one of the files of a field must match, a file field left empty
+// matches any value.
+type MockMultipart struct {
+	Fields map[string]string            `json:"fields,omitempty"`
+	Files  map[string]MockMultipartFile `json:"files,omitempty"`
+}
+
+type MockMultipartFile struct {
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	MinSize     int    `json:"minSize,omitempty"`
+	MaxSize     int    `json:"maxSize,omitempty"`
+	Contains    string `json:"contains,omitempty"`
+}
+
 type MockResponse struct {
 	Status          int               `json:"status"`
 	Body            json.RawMessage   `json:"body"`
@@ -42,6 +152,83 @@ type MockResponse struct {
 	Headers         map[string]string `json:"headers"`
 	MinResponseTime int               `json:"minResponseTime"`
 	MaxResponseTime int               `json:"maxResponseTime"`
+	LatencyProfile  string            `json:"latency-profile,omitempty"`
+	Stream          *MockStream       `json:"stream,omitempty"`
+
+	//binary or large body file, streamed at each request
+	bodyFilePath string
+}
+
+// Directory served as static assets under the mock url. With fallback, the
+// paths not found are answered with the index file (single page apps).
+type MockStatic struct {
+	Dir      string `json:"dir"`
+	Index    string `json:"index,omitempty"`
+	Fallback bool   `json:"fallback,omitempty"`
+
+	//directory resolved at mock build
+	root string
+}
+
+// TCP or UDP listener answering the received data with the first reply
+// matching it, or with the onData function of the mock function file.
+type MockSocket struct {
+	Protocol        string            `json:"protocol"`
+	Ip              string            `json:"ip,omitempty"`
+	Port            string            `json:"port"`
+	Replies         []MockSocketReply `json:"replies,omitempty"`
+	CloseAfterReply bool              `json:"close-after-reply,omitempty"`
+}
+
+// Data containing match, or match-hex bytes, is answered with reply, or
+// reply-hex bytes. Empty matchers match any data.
+type MockSocketReply struct {
+	Match    string `json:"match,omitempty"`
+	MatchHex string `json:"match-hex,omitempty"`
+	Reply    string `json:"reply,omitempty"`
+	ReplyHex string `json:"reply-hex,omitempty"`
+	Delay    int    `json:"delay,omitempty"`
+
+	//bytes decoded at mock build
+	match []byte
+	reply []byte
+}
+
+// Connection behavior of the mock response: keep-alive (true by default),
+// chunked transfer encoding and body throttled to bytes per second.
+type MockConnection struct {
+	KeepAlive      *bool `json:"keep-alive,omitempty"`
+	Chunked        bool  `json:"chunked,omitempty"`
+	BytesPerSecond int   `json:"bytes-per-second,omitempty"`
+}
+
+// CORS of the mock, overriding the global one. Empty allowed methods and
+// headers allow the requested ones.
+type MockCors struct {
+	AllowedOrigins   []string `json:"allowed-origins,omitempty"`
+	AllowedMethods   []string `json:"allowed-methods,omitempty"`
+	AllowedHeaders   []string `json:"allowed-headers,omitempty"`
+	ExposedHeaders   []string `json:"exposed-headers,omitempty"`
+	AllowCredentials bool     `json:"allow-credentials,omitempty"`
+	MaxAge           int      `json:"max-age,omitempty"`
+}
+
+// Response headers rules of the mock: skipped headers are not changed by the
+// global rules, removed headers are deleted from the response.
+type MockResponseHeaders struct {
+	Skip   []string `json:"skip,omitempty"`
+	Remove []string `json:"remove,omitempty"`
+}
+
+// Own pool of javascript VMs of the mock function file, mocks sharing the
+// function file share the pool. The acquire timeout fails the function call
+// when all the VMs are busy.
+type MockFunctionPool struct {
+	MinSize        int    `json:"min-size,omitempty"`
+	MaxSize        int    `json:"max-size"`
+	AcquireTimeout string `json:"acquire-timeout,omitempty"`
+
+	acquireTimeout time.Duration
 }
 
 type MockAction struct {
@@ -55,8 +242,41 @@ type MockAction struct {
 	Headers          map[string]string `json:"headers"`
 }
 
+// Request sent after the mock response, body, url and headers can use helpers
+type MockCallback struct {
+	Method  string            `json:"method,omitempty"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Delay   int               `json:"delay,omitempty"`
+	Timeout string            `json:"timeout,omitempty"`
+}
+
+// Message published after the mock response, key, headers and body can use
+// helpers. The broker is kafka by default.
+type MockMessage struct {
+	Broker  string            `json:"broker,omitempty"`
+	Topic   string            `json:"topic"`
+	Key     string            `json:"key,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Delay   int               `json:"delay,omitempty"`
+}
+
+// Requests allowed per period, the response (429 by default) answers the
+// requests over the limit.
+type MockRateLimit struct {
+	Requests int           `json:"requests"`
+	Period   string        `json:"period,omitempty"`
+	Burst    int           `json:"burst,omitempty"`
+	Response *MockResponse `json:"response,omitempty"`
+
+	limiter *ratelimit.Limiter
+}
+
 type Mock struct {
 	Name             string       `json:"name"`
+	Tags             []string     `json:"tags,omitempty"`
 	Request          MockRequest  `json:"request"`
 	Response         MockResponse `json:"response"`
 	jsonBytes        []byte
@@ -64,8 +284,36 @@ type Mock struct {
 	dateHelpers      []helper.Helper
 	randomHelpers    []helper.Helper
 	pathRegexHelpers []helper.Helper
-	FunctionFile     string       `json:"function-file"`
-	Actions          []MockAction `json:"actions"`
+	FunctionFile     string            `json:"function-file"`
+	FunctionPool     *MockFunctionPool `json:"function-pool,omitempty"`
+	Actions          []MockAction      `json:"actions"`
+	Callbacks        []MockCallback    `json:"callbacks,omitempty"`
+	Messages         []MockMessage     `json:"messages,omitempty"`
+	RateLimit        *MockRateLimit    `json:"rate-limit,omitempty"`
+	rateLimitHelpers []helper.Helper
+	generatorHelpers []helper.Helper
+	LogLevel         string          `json:"log-level,omitempty"`
+	Static           *MockStatic     `json:"static,omitempty"`
+	Socket           *MockSocket     `json:"socket,omitempty"`
+	Compression      *bool           `json:"compression,omitempty"`
+	Connection       *MockConnection `json:"connection,omitempty"`
+	Cors             *MockCors       `json:"cors,omitempty"`
+
+	ResponseHeaders *MockResponseHeaders `json:"response-headers,omitempty"`
+
+	//directory of the mock file, body files can be relative to it
+	dir string
+
+	//service serving the mock, empty for the core mocks
+	service string
+
+	//changed at runtime with the patch api
+	patched bool
+}
+
+func (m Mock) GetService() string {
+
+	return m.service
 }
 
 func (m *Mock) AddRequestHelper(h helper.Helper) {
@@ -114,6 +362,69 @@ func (m Mock) HasRandomHelper() bool {
 	return len(m.randomHelpers) > 0
 }
 
+func (m *Mock) AddGeneratorHelper(h helper.Helper) {
+
+	m.generatorHelpers = append(m.generatorHelpers, h)
+}
+
+func (m Mock) HasGeneratorHelper() bool {
+
+	return len(m.generatorHelpers) > 0
+}
+
+func (m Mock) IsGraphql() bool {
+
+	return m.Request.Graphql != nil
+}
+
+func (m Mock) IsSoap() bool {
+
+	return m.Request.Soap != nil
+}
+
+func (m Mock) HasClientCertMatcher() bool {
+
+	return m.Request.ClientCert != nil
+}
+
+func (m Mock) HasAuthMatcher() bool {
+
+	return m.Request.Auth != nil
+}
+
+func (m Mock) HasValueMatchers() bool {
+
+	return m.Request.getValueMatchersCount() > 0
+}
+
+func (m Mock) HasConnectionMatcher() bool {
+
+	return m.Request.Connection != nil
+}
+
+func (m Mock) HasMultipartMatcher() bool {
+	return m.Request.Multipart != nil
+}
+
+func (m Mock) HasGraphqlSchema() bool {
+
+	return m.IsGraphql() && m.Request.Graphql.Schema != nil
+}
+
+// Response compression of the mock, the global one if not set.
+func (m Mock) IsCompressed(global bool) bool {
+
+	if m.Compression != nil {
+		return *m.Compression
+	}
+
+	return global
+}
+
+func (m Mock) IsStatic() bool {
+	return m.Static != nil
+}
+
 func (m Mock) HasRegexUrl() bool {
 
 	return len(m.Request.UrlRegexStr) > 0
@@ -124,14 +435,51 @@ func (m *Mock) HasFunctionFile() bool {
 	return m.FunctionFile != ""
 }
 
+func (m Mock) HasFunctionPool() bool {
+	return m.FunctionPool != nil
+}
+
+func (p MockFunctionPool) GetAcquireTimeout() time.Duration {
+	return p.acquireTimeout
+}
+
 func (m *Mock) GetFunctionFile() string {
 
 	return m.FunctionFile
 }
 
+func (m *Mock) AddRateLimitHelper(h helper.Helper) {
+
+	m.rateLimitHelpers = append(m.rateLimitHelpers, h)
+}
+
+func (m Mock) HasRateLimit() bool {
+
+	return m.RateLimit != nil && m.RateLimit.limiter != nil
+}
+
+// TakeRateLimitToken returns false and the wait before the next allowed
+// request if the mock rate limit is exceeded.
+func (m Mock) TakeRateLimitToken() (bool, time.Duration) {
+
+	if !m.HasRateLimit() {
+		return true, 0
+	}
+
+	return m.RateLimit.limiter.Allow()
+}
+
+func (m Mock) GetRateLimitHelpers() []helper.Helper {
+
+	helpers := make([]helper.Helper, len(m.rateLimitHelpers))
+	copy(helpers, m.rateLimitHelpers)
+
+	return helpers
+}
+
 func (m Mock) HasHelper() bool {
 
-	return m.HasDatetHelper() || m.HasRequestHelper() || m.HasRandomHelper() || m.HasPathRegexHelper()
+	return m.HasDatetHelper() || m.HasRequestHelper() || m.HasRandomHelper() || m.HasPathRegexHelper() || m.HasGeneratorHelper()
 }
 
 func (m Mock) UpdateRequestHelpers(h []helper.Helper) Mock {
@@ -208,14 +556,29 @@ func (m Mock) GetRandomHelpers() []helper.Helper {
 	return clones
 }
 
+// An array of truct keep references to struct, so we have to clone
+// the array before returning it.
+func (m Mock) GetGeneratorHelpers() []helper.Helper {
+
+	var clones []helper.Helper
+
+	for _, h := range m.generatorHelpers {
+		clones = append(clones, h.Clone())
+	}
+
+	return clones
+}
+
 func (m Mock) GetJsonHelpers() string {
 
 	var jsonHelpers string
 	helpers := append(
 		append(
-			append([]helper.Helper{}, m.GetRequestHelpers()...),
-			m.GetDateHelpers()...,
-		), m.GetRandomHelpers()...)
+			append(
+				append([]helper.Helper{}, m.GetRequestHelpers()...),
+				m.GetDateHelpers()...,
+			), m.GetRandomHelpers()...,
+		), m.GetGeneratorHelpers()...)
 
 	for i := range helpers {
 
@@ -230,6 +593,10 @@ func (m Mock) GetRequestMethod() string {
 		return m.Request.Method
 	}
 
+	if m.IsGraphql() || m.IsSoap() {
+		return http.MethodPost
+	}
+
 	return "GET"
 }
 
@@ -249,10 +616,25 @@ func (m Mock) GetRequestUrl() string {
 		return m.Request.UrlTransformed
 	}
 
+	// static mocks serve the whole url subtree
+	if m.IsStatic() && !strings.HasSuffix(m.Request.Url, "/") {
+		return m.Request.Url + "/"
+	}
+
 	return m.Request.Url
 
 }
 
+// Url or url regex as written in the mock, used as route name.
+func (m Mock) GetRequestRoute() string {
+
+	if m.Request.UrlRegexStr != "" {
+		return m.Request.UrlRegexStr
+	}
+
+	return m.Request.Url
+}
+
 func (m Mock) GetName() string {
 
 	if m.Name != "" {
@@ -268,14 +650,20 @@ func (m Mock) GetResponseStatus() int {
 
 func (m Mock) GetResponseBody() string {
 
+	return m.Response.GetBody()
+}
+
+// A json string body is returned unquoted.
+func (r MockResponse) GetBody() string {
+
 	// Declare a variable to hold the unmarshaled text
 	var body string
 
 	// Unmarshal the raw message into the string variable
-	err := json.Unmarshal(m.Response.Body, &body)
+	err := json.Unmarshal(r.Body, &body)
 	if err != nil {
 		// Handle error
-		return string(m.Response.Body)
+		return string(r.Body)
 	}
 	return body
 }
@@ -284,8 +672,26 @@ func (m Mock) GetResponseHeaders() map[string]string {
 	return m.Response.Headers
 }
 
+// Get a response header value, header name is case insensitive.
+func (m Mock) GetResponseHeader(name string) string {
+
+	for k, v := range m.Response.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// GetDelay draws the response time of the mock latency profile, or between
+// its min and max response times.
 func (m *Mock) GetDelay() time.Duration {
 
+	if m.Response.LatencyProfile != "" {
+		return latency.Draw(m.Response.LatencyProfile)
+	}
+
 	if m.Response.MaxResponseTime <= m.Response.MinResponseTime {
 		return time.Duration(m.Response.MinResponseTime) * time.Millisecond
 	}
@@ -297,3 +703,13 @@ func (m Mock) GetActions() []MockAction {
 
 	return m.Actions
 }
+
+func (m Mock) GetCallbacks() []MockCallback {
+
+	return m.Callbacks
+}
+
+func (m Mock) GetMessages() []MockMessage {
+
+	return m.Messages
+}
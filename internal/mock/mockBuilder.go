@@ -18,28 +18,57 @@ package mock
 
 import (
 	"alfred/internal/conf"
+	"alfred/internal/env"
+	"alfred/internal/graphql"
 	"alfred/internal/helper"
+	"alfred/internal/latency"
 	"alfred/internal/log"
+	"alfred/internal/ratelimit"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/vektah/gqlparser/v2/ast"
 	"go.uber.org/zap"
 )
 
 func BuildMockFromJson(jsonData []byte) (Mock, error) {
 
+	return buildMockFromJson(jsonData, "")
+}
+
+// BuildMockFromJsonFile builds the mock of a mock file, its body file can be
+// relative to the mock file directory.
+func BuildMockFromJsonFile(jsonData []byte, filePath string) (Mock, error) {
+
+	return buildMockFromJson(jsonData, filepath.Dir(filePath))
+}
+
+func buildMockFromJson(jsonData []byte, dir string) (Mock, error) {
+
 	var mock Mock
-	err := json.Unmarshal(jsonData, &mock)
+
+	//environment variables and secrets are resolved at load time
+	jsonData, err := env.Interpolate(jsonData)
+	if err != nil {
+		return mock, err
+	}
+
+	err = json.Unmarshal(jsonData, &mock)
 	if err != nil {
 		return mock, err
 	}
 
+	mock.dir = dir
+
 	if mock.Response.BodyFile != "" {
 
-		mock.Response.Body, err = getBodyFileContent(mock.Response.BodyFile)
+		err = mock.Response.loadBodyFile(dir)
 		if err != nil {
 			return mock, err
 		}
@@ -55,6 +84,30 @@ func BuildMockFromJson(jsonData []byte) (Mock, error) {
 		}
 	}
 
+	if mock.IsStatic() {
+
+		err = mock.Static.resolve(dir)
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.IsSocket() {
+
+		err = mock.Socket.validate()
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.IsGraphql() && mock.Request.Graphql.SchemaFile != "" {
+
+		mock.Request.Graphql.Schema, err = getGraphqlSchema(mock.Request.Graphql.SchemaFile)
+		if err != nil {
+			return mock, err
+		}
+	}
+
 	//clean json before save
 	buffer := new(bytes.Buffer)
 	err = json.Compact(buffer, jsonData)
@@ -77,10 +130,14 @@ func BuildMockFromJson(jsonData []byte) (Mock, error) {
 
 		} else if h.Type == helper.DATE {
 			mock.AddDatetHelper(h)
-		} else if h.Type == helper.RANDOM {
+		} else if h.Type == helper.RANDOM || h.Type == helper.FAKER {
 			mock.AddRandomHelper(h)
 		} else if h.Type == helper.PATH_REGEX {
 			mock.AddPathRegexHelper(h)
+		} else if h.Type == helper.RATE_LIMIT {
+			mock.AddRateLimitHelper(h)
+		} else if helper.IsGenerated(h.Type) {
+			mock.AddGeneratorHelper(h)
 		}
 
 		log.Debug(context.Background(), "helper "+h.Name+" found :'"+h.Target+"'"+" of type : '"+h.Type+"'", zap.String("mock-name", mock.GetName()))
@@ -98,20 +155,119 @@ func BuildMockFromJson(jsonData []byte) (Mock, error) {
 		mock.SetRegexUrl()
 	}
 
+	if mock.RateLimit != nil {
+		mock.RateLimit.limiter, err = ratelimit.NewLimiter(mock.RateLimit.Requests, mock.RateLimit.Period, mock.RateLimit.Burst)
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.IsStream() {
+
+		err = mock.Response.Stream.validate()
+		if err != nil {
+			return mock, err
+		}
+
+		if len(mock.Response.Body) > 0 || mock.Response.BodyFile != "" {
+			return mock, errors.New("stream mock " + mock.GetName() + " has a body, its chunks are sent instead")
+		}
+	}
+
+	if mock.HasValueMatchers() {
+
+		err = mock.Request.validateValues()
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.HasConnectionMatcher() {
+
+		err = mock.Request.Connection.validate()
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.HasFunctionPool() {
+
+		err = mock.FunctionPool.validate(mock.FunctionFile)
+		if err != nil {
+			return mock, err
+		}
+	}
+
+	if mock.Response.LatencyProfile != "" && !latency.Exists(mock.Response.LatencyProfile) {
+		return mock, errors.New("latency profile " + mock.Response.LatencyProfile + " not configured")
+	}
+
+	if mock.LogLevel != "" {
+		_, err = log.WithLevel(context.Background(), mock.LogLevel)
+		if err != nil {
+			return mock, err
+		}
+	}
+
 	return mock, nil
 }
 
-func getBodyFileContent(bodyFileName string) ([]byte, error) {
+func getGraphqlSchema(schemaFileName string) (*ast.Schema, error) {
 
 	config, _ := conf.GetConfiguration()
 
-	filePath := config.Alfred.Core.BodiesDir + bodyFileName
+	filePath := config.Alfred.Core.SchemasDir + schemaFileName
 
 	fileBytes, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	return fileBytes, nil
+	return graphql.LoadSchema(schemaFileName, fileBytes)
+}
+
+// Headers of recorded responses not replayed by mocks, they are computed when
+// the mock response is written.
+var recordedHeadersSkipped = []string{"Content-Length", "Content-Encoding", "Transfer-Encoding", "Connection", "Keep-Alive", "Date"}
+
+// BuildRecordedResponse creates a mock response from a recorded one (HAR
+// capture, Postman example, ...). Json bodies are kept as json documents.
+func BuildRecordedResponse(status int, headers http.Header, body string) MockResponse {
+
+	response := MockResponse{Status: status}
+
+	for name := range headers {
+
+		if isRecordedHeaderSkipped(name) {
+			continue
+		}
+
+		if response.Headers == nil {
+			response.Headers = map[string]string{}
+		}
+		response.Headers[http.CanonicalHeaderKey(name)] = headers.Get(name)
+	}
+
+	if body == "" {
+		return response
+	}
+
+	if strings.Contains(headers.Get("Content-Type"), "json") && json.Valid([]byte(body)) {
+		response.Body = json.RawMessage(body)
+	} else {
+		response.Body, _ = json.Marshal(body)
+	}
+
+	return response
+}
+
+func isRecordedHeaderSkipped(name string) bool {
+
+	for _, skipped := range recordedHeadersSkipped {
+		if strings.EqualFold(name, skipped) {
+			return true
+		}
+	}
 
+	return false
 }
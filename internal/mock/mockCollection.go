@@ -18,16 +18,30 @@ package mock
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 )
 
 type MockCollection struct {
 	Mocks []*Mock
 }
 
+// Mocks sharing the same method and url are served by the same route, the
+// request is then handled by the first mock matching it.
+type MockRoute struct {
+	Pattern string
+	Mocks   []*Mock
+}
+
 type MockInfo struct {
 	Name   string
 	Method string
 	Url    string
+	Tags   []string `json:",omitempty"`
 }
 
 func (c *MockCollection) HasRegexUrlMock() bool {
@@ -48,7 +62,7 @@ func (c MockCollection) GetMockInfoList() []MockInfo {
 
 	for _, m := range c.Mocks {
 
-		mockInfo := MockInfo{m.GetName(), m.GetRequestMethod(), m.GetRequestUrl()}
+		mockInfo := MockInfo{m.GetName(), m.GetRequestMethod(), m.GetRequestUrl(), m.Tags}
 		mockInfoList = append(mockInfoList, mockInfo)
 
 	}
@@ -56,6 +70,80 @@ func (c MockCollection) GetMockInfoList() []MockInfo {
 	return mockInfoList
 }
 
+// GetSocketMocks returns the mocks served by TCP or UDP listeners
+func (c MockCollection) GetSocketMocks() []*Mock {
+
+	var mocks []*Mock
+
+	for _, m := range c.Mocks {
+		if m.IsSocket() {
+			mocks = append(mocks, m)
+		}
+	}
+
+	return mocks
+}
+
+// SetService names the service of the collection mocks, their functions state
+// is stored in the service namespace.
+func (c MockCollection) SetService(name string) {
+
+	for _, m := range c.Mocks {
+		m.service = name
+	}
+}
+
+func (c MockCollection) HasCorsMock() bool {
+
+	for _, m := range c.Mocks {
+		if m.Cors != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c MockCollection) HasResponseHeadersMock() bool {
+
+	for _, m := range c.Mocks {
+		if m.ResponseHeaders != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindByRoute returns the first mock of the method serving the path, without
+// matching the request details, nil if none.
+func (c MockCollection) FindByRoute(method string, path string) *Mock {
+
+	for _, m := range c.Mocks {
+
+		if m.GetRequestMethod() == method && m.IsActive() && m.servesPath(path) {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// servesPath checks the mock url, whatever the request method
+func (m Mock) servesPath(path string) bool {
+
+	// regex urls may have been replaced by the mock transformed url
+	if m.GetRequestUrl() == path {
+		return true
+	}
+
+	if m.HasRegexUrl() && m.Request.RegexUrl.MatchString(path) {
+		return true
+	}
+
+	return m.IsStatic() && strings.HasPrefix(path+"/", m.GetRequestUrl())
+}
+
 func (c MockCollection) GetJsonStrMockList() string {
 
 	mockInfoList := c.GetMockInfoList()
@@ -65,3 +153,148 @@ func (c MockCollection) GetJsonStrMockList() string {
 	return string(jsonStr)
 
 }
+
+// Group mocks by route, inside a route most specific mocks come first.
+func (c MockCollection) GetRoutes() []MockRoute {
+
+	var routes []MockRoute
+	routesIndex := map[string]int{}
+
+	for _, m := range c.Mocks {
+
+		// served by their own listener
+		if m.IsSocket() {
+			continue
+		}
+
+		pattern := "/" + m.GetRequestMethod() + m.GetRequestUrl()
+
+		i, exists := routesIndex[pattern]
+		if !exists {
+			i = len(routes)
+			routesIndex[pattern] = i
+			routes = append(routes, MockRoute{Pattern: pattern})
+		}
+
+		routes[i].Mocks = append(routes[i].Mocks, m)
+	}
+
+	for _, route := range routes {
+
+		mocks := route.Mocks
+		sort.SliceStable(mocks, func(i, j int) bool {
+			return mocks[i].GetMatchersCount() > mocks[j].GetMatchersCount()
+		})
+	}
+
+	return routes
+}
+
+func (route MockRoute) HasStaticMock() bool {
+
+	for _, m := range route.Mocks {
+		if m.IsStatic() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Find the first active route mock matching the request, if none, the
+// returned error explains why each mock has been rejected.
+func (route MockRoute) FindMock(r *http.Request, body []byte) (*Mock, error) {
+
+	var mismatches []error
+
+	for _, m := range route.ActiveMocks() {
+
+		err := m.Match(r, body)
+		if err == nil {
+			return m, nil
+		}
+
+		mismatches = append(mismatches, fmt.Errorf("%s: %w", m.GetName(), err))
+	}
+
+	if len(mismatches) == 0 {
+		return nil, errors.New("no mock of the active profiles")
+	}
+
+	return nil, errors.Join(mismatches...)
+}
+
+// FindRoute returns the route serving the request path, as the server mux
+// does once regex urls are replaced by their transformed url.
+func (c MockCollection) FindRoute(method string, path string) (MockRoute, bool) {
+
+	for _, m := range c.Mocks {
+		if m.HasRegexUrl() && m.Request.RegexUrl.MatchString(path) {
+			path = m.Request.UrlTransformed
+			break
+		}
+	}
+
+	routes := c.GetRoutes()
+	mux := http.NewServeMux()
+	patterns := map[string]bool{}
+
+	for i, route := range routes {
+		mux.Handle(route.Pattern, routeIndex(i))
+		patterns[route.Pattern] = true
+	}
+
+	for i, route := range routes {
+		if pattern := strings.TrimSuffix(route.Pattern, "/"); route.HasStaticMock() && !patterns[pattern] {
+			mux.Handle(pattern, routeIndex(i))
+		}
+	}
+
+	handler, _ := mux.Handler(&http.Request{Method: method, URL: &url.URL{Path: "/" + method + path}})
+	if i, ok := handler.(routeIndex); ok {
+		return routes[i], true
+	}
+
+	return MockRoute{}, false
+}
+
+// Index of the route served by the mux of FindRoute
+type routeIndex int
+
+func (i routeIndex) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+// Mock never used, as an other mock of its route has the same matchers
+type DuplicateMock struct {
+	Mock     *Mock
+	Shadowed *Mock
+}
+
+// GetDuplicateMocks returns the mocks shadowed by the first mock of their
+// route having the same request matchers.
+func (c MockCollection) GetDuplicateMocks() []DuplicateMock {
+
+	var duplicates []DuplicateMock
+
+	for _, route := range c.GetRoutes() {
+
+		first := map[string]*Mock{}
+
+		for _, m := range route.Mocks {
+
+			// mocks of different profiles are not served together
+			matchers, _ := json.Marshal(struct {
+				Request MockRequest
+				Tags    []string
+			}{m.Request, m.Tags})
+
+			if mock, exists := first[string(matchers)]; exists {
+				duplicates = append(duplicates, DuplicateMock{Mock: mock, Shadowed: m})
+				continue
+			}
+
+			first[string(matchers)] = m
+		}
+	}
+
+	return duplicates
+}
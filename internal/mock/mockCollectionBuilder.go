@@ -47,31 +47,42 @@ func getMocksFilesContent(filePaths []string) ([][]byte, error) {
 
 func CreateMockCollectionFromFolder(path string) MockCollection {
 
+	mockCollection, err := LoadMockCollectionFromFolder(path)
+	if err != nil {
+		log.Error(context.Background(), "Application Panic", err)
+		panic(fmt.Errorf("fatal error, mocks load: %w", err))
+	}
+
+	return mockCollection
+}
+
+// LoadMockCollectionFromFolder builds the folder mocks, a mock in error
+// stops the load. Used at startup and on reloads.
+func LoadMockCollectionFromFolder(path string) (MockCollection, error) {
+
+	var mockCollection MockCollection
+
 	//matches, err := files.FindFiles(path, "*.json")
 	matches, err := files.FindAllFiles(path, "*.json")
 	if err != nil {
 		log.Error(context.Background(), "error during mocks load...", err)
-		//panic("error during mocks load......" + err.Error())
 	}
 
 	filesContent, err := getMocksFilesContent(matches)
 	if err != nil {
-		log.Error(context.Background(), "Application Panic", errors.New("error during mocks content load..."+err.Error()))
-		panic("error during mocks content load......" + err.Error())
+		return mockCollection, errors.New("error during mocks content load..." + err.Error())
 	}
 
-	var mockCollection MockCollection
-
-	for _, fileContent := range filesContent {
+	for i, fileContent := range filesContent {
 
-		currentMock, err := BuildMockFromJson(fileContent)
+		currentMock, err := BuildMockFromJsonFile(fileContent, matches[i])
 		if err != nil {
 			log.Error(context.Background(), "Error during mock build from json", err, zap.String("text-provided", string(fileContent)))
-			panic(fmt.Errorf("fatal error, mock build from json: %w", err))
+			return mockCollection, fmt.Errorf("mock build from json %s: %w", matches[i], err)
 		}
 
 		mockCollection.Mocks = append(mockCollection.Mocks, &currentMock)
 	}
 
-	return mockCollection
+	return mockCollection, nil
 }
@@ -0,0 +1,205 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"alfred/internal/graphql"
+	"alfred/internal/soap"
+	"alfred/pkg/request"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Match checks the request against the mock matchers, method and url are
+// already handled by the router. The returned error explains the mismatch.
+func (m *Mock) Match(r *http.Request, body []byte) error {
+
+	if m.IsGraphql() {
+
+		op, err := graphql.ParseOperation(body)
+		if err != nil {
+			return err
+		}
+
+		if m.Request.Graphql.OperationName != "" && m.Request.Graphql.OperationName != op.OperationName {
+			return errors.New("graphql operation name '" + op.OperationName + "' is not '" + m.Request.Graphql.OperationName + "'")
+		}
+
+		if !graphql.MatchVariables(m.Request.Graphql.Variables, op.Variables) {
+			return errors.New("graphql variables not matching")
+		}
+	}
+
+	if m.IsSoap() {
+
+		if m.Request.Soap.Action != "" && m.Request.Soap.Action != soap.GetAction(r) {
+			return errors.New("soap action '" + soap.GetAction(r) + "' is not '" + m.Request.Soap.Action + "'")
+		}
+
+		if m.Request.Soap.Operation != "" {
+
+			operation, err := soap.GetOperation(body)
+			if err != nil {
+				return err
+			}
+
+			if operation != m.Request.Soap.Operation {
+				return errors.New("soap operation '" + operation + "' is not '" + m.Request.Soap.Operation + "'")
+			}
+		}
+	}
+
+	if m.HasClientCertMatcher() {
+
+		err := matchClientCert(*m.Request.ClientCert, request.GetClientCert(r.TLS))
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.HasValueMatchers() {
+
+		err := m.Request.matchValues(r, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.HasConnectionMatcher() {
+
+		err := matchConnection(*m.Request.Connection, request.GetConnection(r))
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.HasAuthMatcher() {
+
+		err := matchAuth(*m.Request.Auth, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.HasMultipartMatcher() {
+
+		err := matchMultipart(*m.Request.Multipart, r.Header.Get("Content-Type"), body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matchClientCert(expected MockClientCert, cert *request.ClientCert) error {
+
+	if cert == nil {
+		return errors.New("no client certificate")
+	}
+
+	if expected.Verified && !cert.Verified {
+		return errors.New("client certificate '" + cert.Subject + "' is not verified")
+	}
+
+	if expected.CommonName != "" && expected.CommonName != cert.CommonName {
+		return errors.New("client certificate common name '" + cert.CommonName + "' is not '" + expected.CommonName + "'")
+	}
+
+	if expected.IssuerCommonName != "" && expected.IssuerCommonName != cert.IssuerCommonName {
+		return errors.New("client certificate issuer common name '" + cert.IssuerCommonName + "' is not '" + expected.IssuerCommonName + "'")
+	}
+
+	if expected.Organization != "" && !contains(cert.Organization, expected.Organization) {
+		return errors.New("client certificate organization is not '" + expected.Organization + "'")
+	}
+
+	if expected.DnsName != "" && !contains(cert.DnsNames, expected.DnsName) {
+		return errors.New("client certificate dns names do not contain '" + expected.DnsName + "'")
+	}
+
+	if expected.Fingerprint != "" && !strings.EqualFold(expected.Fingerprint, cert.Fingerprint) {
+		return errors.New("client certificate fingerprint '" + cert.Fingerprint + "' is not '" + expected.Fingerprint + "'")
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count the matchers used by the mock, the more a mock has matchers the more
+// it is specific.
+func (m Mock) GetMatchersCount() int {
+
+	count := 0
+
+	if m.IsGraphql() {
+
+		if m.Request.Graphql.OperationName != "" {
+			count++
+		}
+
+		count += len(m.Request.Graphql.Variables)
+	}
+
+	if m.IsSoap() {
+
+		if m.Request.Soap.Action != "" {
+			count++
+		}
+
+		if m.Request.Soap.Operation != "" {
+			count++
+		}
+	}
+
+	if m.HasClientCertMatcher() {
+
+		count++
+		count += countNotEmpty(m.Request.ClientCert.CommonName, m.Request.ClientCert.Organization, m.Request.ClientCert.IssuerCommonName, m.Request.ClientCert.DnsName, m.Request.ClientCert.Fingerprint)
+
+		if m.Request.ClientCert.Verified {
+			count++
+		}
+	}
+
+	count += m.Request.getValueMatchersCount()
+
+	if m.HasConnectionMatcher() {
+		count += m.Request.Connection.getMatchersCount()
+	}
+
+	if m.HasAuthMatcher() {
+		count += m.Request.Auth.getMatchersCount()
+	}
+
+	if m.HasMultipartMatcher() {
+		count += m.Request.Multipart.getMatchersCount()
+	}
+
+	return count
+}
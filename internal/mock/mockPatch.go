@@ -32,17 +32,24 @@ func MockPatch(m *Mock, jsonStr []byte) error {
 		return err
 	}
 
-	patchedMock, err := BuildMockFromJson(jsonData)
+	patchedMock, err := buildMockFromJson(jsonData, m.dir)
 	if err != nil {
 		return err
 	}
 
 	MergeMock(m, patchedMock)
+	m.patched = true
 
 	return nil
 
 }
 
+// IsPatched returns true if the mock was changed at runtime.
+func (m Mock) IsPatched() bool {
+
+	return m.patched
+}
+
 func MergeMock(dest *Mock, src Mock) {
 
 	dest.Request = src.Request
@@ -51,7 +58,11 @@ func MergeMock(dest *Mock, src Mock) {
 	dest.requestHelpers = src.requestHelpers
 	dest.dateHelpers = src.dateHelpers
 	dest.randomHelpers = src.randomHelpers
+	dest.generatorHelpers = src.generatorHelpers
 	dest.FunctionFile = src.FunctionFile
 	dest.Actions = src.Actions
+	dest.Callbacks = src.Callbacks
+	dest.RateLimit = src.RateLimit
+	dest.rateLimitHelpers = src.rateLimitHelpers
 
 }
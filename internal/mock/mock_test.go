@@ -17,6 +17,8 @@
 package mock
 
 import (
+	"alfred/internal/latency"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -44,3 +46,149 @@ func TestGetDelay(t *testing.T) {
 	}
 
 }
+
+func buildCollection(t *testing.T, jsonMocks ...string) MockCollection {
+
+	var c MockCollection
+
+	for _, jsonMock := range jsonMocks {
+
+		m, err := BuildMockFromJson([]byte(jsonMock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Mocks = append(c.Mocks, &m)
+	}
+
+	return c
+}
+
+func TestFindRoute(t *testing.T) {
+
+	c := buildCollection(t,
+		`{"name": "get-user", "request": {"method": "GET", "url": "/users/1"}}`,
+		`{"name": "get-user-regex", "request": {"method": "GET", "urlRegex": "/users/[0-9]+/orders"}}`,
+		`{"name": "post-user", "request": {"method": "POST", "url": "/users/1"}}`,
+	)
+
+	route, ok := c.FindRoute("GET", "/users/1")
+	if !ok || route.Mocks[0].GetName() != "get-user" {
+		t.Errorf("GET /users/1 should be served by get-user")
+	}
+
+	route, ok = c.FindRoute("GET", "/users/42/orders")
+	if !ok || route.Mocks[0].GetName() != "get-user-regex" {
+		t.Errorf("GET /users/42/orders should be served by get-user-regex")
+	}
+
+	if _, ok = c.FindRoute("DELETE", "/users/1"); ok {
+		t.Errorf("DELETE /users/1 should not have route")
+	}
+}
+
+func TestGetDuplicateMocks(t *testing.T) {
+
+	c := buildCollection(t,
+		`{"name": "first", "request": {"method": "GET", "url": "/users"}}`,
+		`{"name": "second", "request": {"method": "GET", "url": "/users"}}`,
+		`{"name": "soap", "request": {"method": "GET", "url": "/users", "soap": {"action": "list"}}}`,
+	)
+
+	duplicates := c.GetDuplicateMocks()
+	if len(duplicates) != 1 || duplicates[0].Mock.GetName() != "first" || duplicates[0].Shadowed.GetName() != "second" {
+		t.Errorf("unexpected duplicates %v", duplicates)
+	}
+}
+
+func TestBuildMockFromJsonEnv(t *testing.T) {
+
+	t.Setenv("ALFRED_TEST_API_KEY", "my-key")
+
+	m, err := BuildMockFromJson([]byte(`{"name": "env", "request": {"method": "GET", "url": "${ALFRED_TEST_URL:-/env}", "auth": {"api-key": {"header": "X-API-Key", "value": "${ALFRED_TEST_API_KEY}"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Request.Url != "/env" || m.Request.Auth.ApiKey.Value != "my-key" {
+		t.Errorf("mock references not resolved: %s %s", m.Request.Url, m.Request.Auth.ApiKey.Value)
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"name": "env", "request": {"method": "GET", "url": "/${ALFRED_TEST_MISSING}"}}`))
+	if err == nil {
+		t.Errorf("a missing environment variable should fail")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+
+	defer SetProfiles(nil)
+
+	c := buildCollection(t,
+		`{"name": "pay", "request": {"method": "POST", "url": "/pay"}}`,
+		`{"name": "pay-degraded", "tags": ["payments-degraded"], "request": {"method": "POST", "url": "/pay"}}`,
+		`{"name": "pay-down", "tags": ["payments-down"], "request": {"method": "POST", "url": "/pay"}}`,
+	)
+
+	if len(c.GetDuplicateMocks()) != 0 {
+		t.Errorf("mocks of different profiles should not be duplicates")
+	}
+
+	tags := c.GetTags()
+	if len(tags) != 2 || tags[0] != "payments-degraded" || tags[1] != "payments-down" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	route, _ := c.FindRoute("POST", "/pay")
+	r := httptest.NewRequest("POST", "/pay", nil)
+
+	m, err := route.FindMock(r, nil)
+	if err != nil || m.GetName() != "pay" {
+		t.Errorf("without profile, the untagged mock should be served")
+	}
+
+	SetProfiles([]string{"payments-degraded"})
+
+	m, err = route.FindMock(r, nil)
+	if err != nil || m.GetName() != "pay-degraded" {
+		t.Errorf("the mock of the active profile should be served first")
+	}
+
+	inactive := route.InactiveMocks()
+	if len(inactive) != 1 || inactive[0].GetName() != "pay-down" {
+		t.Errorf("unexpected inactive mocks %v", inactive)
+	}
+}
+
+func TestProfilesInactiveRoute(t *testing.T) {
+
+	c := buildCollection(t, `{"name": "pay-down", "tags": ["payments-down"], "request": {"method": "POST", "url": "/pay"}}`)
+
+	route, _ := c.FindRoute("POST", "/pay")
+
+	m, err := route.FindMock(httptest.NewRequest("POST", "/pay", nil), nil)
+	if m != nil || err == nil {
+		t.Errorf("a route without active mock should not serve the request")
+	}
+}
+
+func TestLatencyProfile(t *testing.T) {
+
+	err := latency.Configure([]latency.Profile{{Name: "slow-api", Min: "30ms", Max: "30ms", P50: "30ms"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer latency.Configure(nil)
+
+	m, err := BuildMockFromJson([]byte(`{"name": "slow", "request": {"method": "GET", "url": "/slow"}, "response": {"latency-profile": "slow-api", "minResponseTime": 5000}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delay := m.GetDelay(); delay != 30*time.Millisecond {
+		t.Errorf("mock delay is: %s, want: 30ms", delay)
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"name": "typo", "request": {"method": "GET", "url": "/typo"}, "response": {"latency-profile": "slow-apy"}}`))
+	if err == nil {
+		t.Errorf("a mock with an unknown latency profile should fail")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"alfred/pkg/request"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func matchMultipart(expected MockMultipart, contentType string, body []byte) error {
+
+	if !request.IsMultipart(contentType) {
+		return errors.New("content type '" + contentType + "' is not multipart/form-data")
+	}
+
+	parts, err := request.ParseMultipart(contentType, body)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range expected.Fields {
+
+		field, exists := parts.Fields[name]
+		if !exists {
+			return errors.New("no multipart field '" + name + "'")
+		}
+
+		if field != value {
+			return errors.New("multipart field '" + name + "' value '" + field + "' is not '" + value + "'")
+		}
+	}
+
+	for name, expectedFile := range expected.Files {
+
+		files := parts.Files[name]
+		if len(files) == 0 {
+			return errors.New("no multipart file '" + name + "'")
+		}
+
+		// one of the files uploaded with the field matches
+		for _, file := range files {
+
+			err = matchMultipartFile(name, expectedFile, file)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matchMultipartFile(name string, expected MockMultipartFile, file request.MultipartFile) error {
+
+	if expected.Filename != "" && expected.Filename != file.Filename {
+		return errors.New("multipart file '" + name + "' filename '" + file.Filename + "' is not '" + expected.Filename + "'")
+	}
+
+	if expected.ContentType != "" && !strings.EqualFold(expected.ContentType, file.ContentType) {
+		return errors.New("multipart file '" + name + "' content type '" + file.ContentType + "' is not '" + expected.ContentType + "'")
+	}
+
+	if expected.MinSize != 0 && file.Size < expected.MinSize {
+		return fmt.Errorf("multipart file '%s' size %d is lower than %d", name, file.Size, expected.MinSize)
+	}
+
+	if expected.MaxSize != 0 && file.Size > expected.MaxSize {
+		return fmt.Errorf("multipart file '%s' size %d is greater than %d", name, file.Size, expected.MaxSize)
+	}
+
+	if expected.Contains != "" && !strings.Contains(file.Content, expected.Contains) {
+		return errors.New("multipart file '" + name + "' does not contain '" + expected.Contains + "'")
+	}
+
+	return nil
+}
+
+func (m MockMultipart) getMatchersCount() int {
+
+	count := len(m.Fields)
+
+	for _, f := range m.Files {
+		count++
+		count += countNotEmpty(f.Filename, f.ContentType, f.Contains)
+
+		if f.MinSize != 0 {
+			count++
+		}
+		if f.MaxSize != 0 {
+			count++
+		}
+	}
+
+	return count
+}
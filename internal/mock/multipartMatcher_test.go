@@ -0,0 +1,121 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"alfred/pkg/request"
+	"bytes"
+	"mime/multipart"
+	"testing"
+)
+
+func TestMatchMultipart(t *testing.T) {
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	err := writer.WriteField("title", "holidays")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := writer.CreateFormFile("avatar", "bruce.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.Write([]byte("fake png content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = writer.CreateFormFile("avatar", "robin.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.Write([]byte("fake jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	tests := []struct {
+		name     string
+		expected MockMultipart
+		match    bool
+	}{
+		{"field", MockMultipart{Fields: map[string]string{"title": "holidays"}}, true},
+		{"wrong field", MockMultipart{Fields: map[string]string{"title": "work"}}, false},
+		{"missing field", MockMultipart{Fields: map[string]string{"author": "bruce"}}, false},
+		{"file", MockMultipart{Files: map[string]MockMultipartFile{"avatar": {Filename: "bruce.png", MinSize: 1, MaxSize: 100, Contains: "png"}}}, true},
+		{"second file", MockMultipart{Files: map[string]MockMultipartFile{"avatar": {Filename: "robin.jpg", MaxSize: 10}}}, true},
+		{"file too big", MockMultipart{Files: map[string]MockMultipartFile{"avatar": {MaxSize: 5}}}, false},
+		{"missing file", MockMultipart{Files: map[string]MockMultipartFile{"document": {}}}, false},
+	}
+
+	for _, test := range tests {
+
+		err := matchMultipart(test.expected, writer.FormDataContentType(), body.Bytes())
+		if test.match && err != nil {
+			t.Errorf("%s: multipart should match, got error '%s'", test.name, err.Error())
+		}
+		if !test.match && err == nil {
+			t.Errorf("%s: multipart should not match", test.name)
+		}
+	}
+
+	err = matchMultipart(MockMultipart{}, "application/json", []byte("{}"))
+	if err == nil {
+		t.Errorf("json body should not match a multipart matcher")
+	}
+}
+
+func TestParseMultipartFiles(t *testing.T) {
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	for _, name := range []string{"first.txt", "second.txt"} {
+
+		file, err := writer.CreateFormFile("attachments", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = file.Write([]byte(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Close()
+
+	parts, err := request.ParseMultipart(writer.FormDataContentType(), body.Bytes())
+	if err != nil {
+		t.Fatalf("multipart parsing failed with error: %v", err)
+	}
+
+	files := parts.Files["attachments"]
+	if len(files) != 2 || files[0].Filename != "first.txt" || files[1].Content != "second.txt" {
+		t.Errorf("multipart files are: %+v", files)
+	}
+
+	if first, found := parts.First("attachments"); !found || first.Filename != "first.txt" {
+		t.Errorf("first file is: %+v", first)
+	}
+
+	if _, found := parts.First("documents"); found {
+		t.Errorf("no file expected for an unknown field")
+	}
+}
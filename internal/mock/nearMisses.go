@@ -0,0 +1,129 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Number of near misses reported for an unmatched request
+const MAX_NEAR_MISSES = 5
+
+// Mock close to a request no mock matched, the reason explains why it was not
+// used.
+type NearMiss struct {
+	Mock   string `json:"mock"`
+	Method string `json:"method"`
+	Url    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// NearMisses returns the mocks closest to a request no mock matched: the mocks
+// of its route with their mismatch, then the mocks of its url with an other
+// method, then the mocks of its method sharing the longest url prefix.
+func (c MockCollection) NearMisses(r *http.Request, method string, path string, body []byte) []NearMiss {
+
+	var misses []NearMiss
+	seen := map[*Mock]bool{}
+
+	if route, found := c.FindRoute(method, path); found {
+
+		for _, m := range route.Mocks {
+
+			seen[m] = true
+
+			reason := "not in the active profiles"
+			if m.IsActive() {
+
+				err := m.Match(r, body)
+				if err == nil {
+					continue
+				}
+				reason = err.Error()
+			}
+
+			misses = append(misses, newNearMiss(m, reason))
+		}
+	}
+
+	type candidate struct {
+		mock   *Mock
+		reason string
+		score  int
+	}
+
+	var candidates []candidate
+
+	for _, m := range c.Mocks {
+
+		if seen[m] || m.IsSocket() {
+			continue
+		}
+
+		if m.servesPath(path) {
+			candidates = append(candidates, candidate{m, "method is " + m.GetRequestMethod() + ", not " + method, len(path) + 1})
+			continue
+		}
+
+		if m.GetRequestMethod() != method {
+			continue
+		}
+
+		if score := commonPrefixLength(m.GetRequestUrl(), path); score > 0 {
+			candidates = append(candidates, candidate{m, "url is " + m.GetRequestUrl() + ", not " + path, score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	for _, c := range candidates {
+		misses = append(misses, newNearMiss(c.mock, c.reason))
+	}
+
+	if len(misses) > MAX_NEAR_MISSES {
+		misses = misses[:MAX_NEAR_MISSES]
+	}
+
+	return misses
+}
+
+func newNearMiss(m *Mock, reason string) NearMiss {
+
+	return NearMiss{Mock: m.GetName(), Method: m.GetRequestMethod(), Url: m.GetRequestUrl(), Reason: reason}
+}
+
+// commonPrefixLength returns the length of the url path segments shared by
+// both urls, the root is not counted
+func commonPrefixLength(url string, path string) int {
+
+	urlSegments := strings.Split(strings.Trim(url, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	length := 0
+	for i := 0; i < len(urlSegments) && i < len(pathSegments); i++ {
+
+		if urlSegments[i] == "" || urlSegments[i] != pathSegments[i] {
+			break
+		}
+		length += len(urlSegments[i]) + 1
+	}
+
+	return length
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNearMisses(t *testing.T) {
+
+	c := buildCollection(t,
+		`{"name": "get-order", "request": {"method": "GET", "url": "/orders/1", "auth": {"bearer": {"token": "abc"}}}}`,
+		`{"name": "delete-order", "request": {"method": "DELETE", "url": "/orders/1"}}`,
+		`{"name": "get-orders", "request": {"method": "GET", "url": "/orders"}}`,
+		`{"name": "get-users", "request": {"method": "GET", "url": "/users"}}`,
+	)
+
+	r := httptest.NewRequest("GET", "/orders/1", nil)
+
+	misses := c.NearMisses(r, "GET", "/orders/1", nil)
+	if len(misses) != 3 {
+		t.Fatalf("near misses are: %v", misses)
+	}
+
+	if misses[0].Mock != "get-order" || misses[1].Mock != "delete-order" || misses[2].Mock != "get-orders" {
+		t.Errorf("near misses are: %v", misses)
+	}
+
+	if misses[1].Reason != "method is DELETE, not GET" || misses[2].Reason != "url is /orders, not /orders/1" {
+		t.Errorf("near misses reasons are: %v", misses)
+	}
+
+	// no route for the request
+	misses = c.NearMisses(r, "POST", "/orders/2", nil)
+	if len(misses) != 0 {
+		t.Errorf("near misses are: %v", misses)
+	}
+
+	misses = c.NearMisses(r, "GET", "/orders/2", nil)
+	if len(misses) != 2 || misses[0].Mock != "get-order" || misses[1].Mock != "get-orders" {
+		t.Errorf("near misses are: %v", misses)
+	}
+}
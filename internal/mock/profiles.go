@@ -0,0 +1,136 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"sort"
+	"sync"
+)
+
+// Active profiles, mocks tagged with one of them are served before the
+// untagged mocks, the other tagged mocks are not served.
+var (
+	profiles      = map[string]bool{}
+	profilesMutex sync.RWMutex
+)
+
+// SetProfiles replaces the active profiles, they are kept on mocks reload.
+func SetProfiles(names []string) {
+
+	active := map[string]bool{}
+	for _, name := range names {
+		if name != "" {
+			active[name] = true
+		}
+	}
+
+	profilesMutex.Lock()
+	defer profilesMutex.Unlock()
+
+	profiles = active
+}
+
+// GetProfiles returns the sorted active profiles.
+func GetProfiles() []string {
+
+	profilesMutex.RLock()
+	defer profilesMutex.RUnlock()
+
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (m Mock) HasTags() bool {
+
+	return len(m.Tags) > 0
+}
+
+// IsActive returns true when the mock has no tag or one of its tags is an
+// active profile.
+func (m Mock) IsActive() bool {
+
+	if !m.HasTags() {
+		return true
+	}
+
+	profilesMutex.RLock()
+	defer profilesMutex.RUnlock()
+
+	for _, tag := range m.Tags {
+		if profiles[tag] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetTags returns the sorted tags of the collection mocks.
+func (c MockCollection) GetTags() []string {
+
+	found := map[string]bool{}
+	tags := []string{}
+
+	for _, m := range c.Mocks {
+		for _, tag := range m.Tags {
+			if !found[tag] {
+				found[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// ActiveMocks returns the route mocks in the order they are tried: the mocks
+// of the active profiles, then the untagged mocks.
+func (route MockRoute) ActiveMocks() []*Mock {
+
+	var tagged, untagged []*Mock
+
+	for _, m := range route.Mocks {
+
+		if !m.HasTags() {
+			untagged = append(untagged, m)
+		} else if m.IsActive() {
+			tagged = append(tagged, m)
+		}
+	}
+
+	return append(tagged, untagged...)
+}
+
+// InactiveMocks returns the route mocks not served, none of their tags is an
+// active profile.
+func (route MockRoute) InactiveMocks() []*Mock {
+
+	var inactive []*Mock
+
+	for _, m := range route.Mocks {
+		if !m.IsActive() {
+			inactive = append(inactive, m)
+		}
+	}
+
+	return inactive
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	SOCKET_TCP = "tcp"
+	SOCKET_UDP = "udp"
+)
+
+func (m Mock) IsSocket() bool {
+	return m.Socket != nil
+}
+
+// validate checks the listener and decodes the hex payloads
+func (s *MockSocket) validate() error {
+
+	s.Protocol = strings.ToLower(s.Protocol)
+	if s.Protocol != SOCKET_TCP && s.Protocol != SOCKET_UDP {
+		return errors.New("socket protocol '" + s.Protocol + "' not supported, use " + SOCKET_TCP + " or " + SOCKET_UDP)
+	}
+
+	if s.Port == "" {
+		return errors.New("socket mock needs a port")
+	}
+
+	for i := range s.Replies {
+
+		r := &s.Replies[i]
+
+		var err error
+
+		r.match = []byte(r.Match)
+		if r.MatchHex != "" {
+			r.match, err = hex.DecodeString(strings.ReplaceAll(r.MatchHex, " ", ""))
+			if err != nil {
+				return errors.New("socket reply match-hex: " + err.Error())
+			}
+		}
+
+		r.reply = []byte(r.Reply)
+		if r.ReplyHex != "" {
+			r.reply, err = hex.DecodeString(strings.ReplaceAll(r.ReplyHex, " ", ""))
+			if err != nil {
+				return errors.New("socket reply reply-hex: " + err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s MockSocket) GetAddress() string {
+
+	return s.Ip + ":" + s.Port
+}
+
+// FindReply returns the first reply matching the data
+func (s MockSocket) FindReply(data []byte) (MockSocketReply, bool) {
+
+	for _, r := range s.Replies {
+		if bytes.Contains(data, r.match) {
+			return r, true
+		}
+	}
+
+	return MockSocketReply{}, false
+}
+
+func (r MockSocketReply) GetBytes() []byte {
+
+	return r.reply
+}
+
+func (r MockSocketReply) GetDelay() time.Duration {
+
+	return time.Duration(r.Delay) * time.Millisecond
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const DEFAULT_STATIC_INDEX = "index.html"
+
+// The static directory is relative to the mock file directory, or to the
+// working directory.
+func (s *MockStatic) resolve(mockDir string) error {
+
+	if s.Dir == "" {
+		return errors.New("static mock needs a dir")
+	}
+
+	if s.Index == "" {
+		s.Index = DEFAULT_STATIC_INDEX
+	}
+
+	root := s.Dir
+	if !filepath.IsAbs(root) && mockDir != "" {
+		if info, err := os.Stat(filepath.Join(mockDir, root)); err == nil && info.IsDir() {
+			root = filepath.Join(mockDir, root)
+		}
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return errors.New("static dir " + root + " is not a directory")
+	}
+
+	s.root = root
+
+	return nil
+}
+
+func (m Mock) GetStaticRoot() string {
+
+	if !m.IsStatic() {
+		return ""
+	}
+
+	return m.Static.root
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticMock(t *testing.T) {
+
+	dir := t.TempDir()
+
+	err := os.Mkdir(filepath.Join(dir, "site"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := BuildMockFromJsonFile([]byte(`{"name":"static","request":{"url":"/site"},"static":{"dir":"site"}}`), filepath.Join(dir, "static.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.GetRequestUrl() != "/site/" {
+		t.Errorf("static mock should serve the url subtree, got '%s'", m.GetRequestUrl())
+	}
+	if m.GetStaticRoot() != filepath.Join(dir, "site") {
+		t.Errorf("static dir should be relative to the mock file, got '%s'", m.GetStaticRoot())
+	}
+	if m.Static.Index != DEFAULT_STATIC_INDEX {
+		t.Errorf("static index should default to %s, got '%s'", DEFAULT_STATIC_INDEX, m.Static.Index)
+	}
+
+	_, err = BuildMockFromJsonFile([]byte(`{"name":"missing","request":{"url":"/missing"},"static":{"dir":"missing"}}`), filepath.Join(dir, "missing.json"))
+	if err == nil {
+		t.Errorf("missing static dir should fail")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Formats of the streamed chunks
+const (
+	STREAM_FORMAT_RAW    = "raw"
+	STREAM_FORMAT_NDJSON = "ndjson"
+	STREAM_FORMAT_SSE    = "sse"
+)
+
+// Response body streamed by chunks. A chunk is sent after its delay in
+// milliseconds, the interval between chunks by default. The ndjson format ends
+// each chunk with a new line, the sse format sends them as server-sent events.
+type MockStream struct {
+	Chunks   []MockStreamChunk `json:"chunks,omitempty"`
+	Interval int               `json:"interval,omitempty"`
+	Format   string            `json:"format,omitempty"`
+}
+
+// A json string body is sent as is, other json values are sent compacted
+type MockStreamChunk struct {
+	Body  json.RawMessage `json:"body,omitempty"`
+	Delay int             `json:"delay,omitempty"`
+	Event string          `json:"event,omitempty"`
+}
+
+// Chunk ready to be sent
+type StreamChunk struct {
+	Body  string
+	Delay int
+	Event string
+}
+
+func (m Mock) IsStream() bool {
+
+	return m.Response.Stream != nil
+}
+
+func (s MockStream) validate() error {
+
+	switch s.Format {
+	case "", STREAM_FORMAT_RAW, STREAM_FORMAT_NDJSON, STREAM_FORMAT_SSE:
+	default:
+		return errors.New("stream format '" + s.Format + "' not supported, use " + STREAM_FORMAT_RAW + ", " + STREAM_FORMAT_NDJSON + " or " + STREAM_FORMAT_SSE)
+	}
+
+	if s.Interval < 0 {
+		return errors.New("stream interval is negative")
+	}
+
+	for _, chunk := range s.Chunks {
+		if chunk.Delay < 0 {
+			return errors.New("stream chunk delay is negative")
+		}
+	}
+
+	return nil
+}
+
+// GetChunks returns the chunks of the mock file with their json body decoded
+func (s MockStream) GetChunks() []StreamChunk {
+
+	chunks := make([]StreamChunk, 0, len(s.Chunks))
+
+	for _, chunk := range s.Chunks {
+
+		var body string
+		if json.Unmarshal(chunk.Body, &body) != nil {
+
+			compacted := new(bytes.Buffer)
+			if json.Compact(compacted, chunk.Body) == nil {
+				body = compacted.String()
+			}
+		}
+
+		chunks = append(chunks, StreamChunk{Body: body, Delay: chunk.Delay, Event: chunk.Event})
+	}
+
+	return chunks
+}
+
+// GetDelay returns the delay before the chunk of the index, the first chunk
+// is sent without interval
+func (s MockStream) GetDelay(chunk StreamChunk, index int) int {
+
+	if chunk.Delay != 0 || index == 0 {
+		return chunk.Delay
+	}
+
+	return s.Interval
+}
+
+// Encode formats the chunk sent
+func (s MockStream) Encode(chunk StreamChunk) []byte {
+
+	switch s.Format {
+	case STREAM_FORMAT_NDJSON:
+		return []byte(strings.TrimSuffix(chunk.Body, "\n") + "\n")
+	case STREAM_FORMAT_SSE:
+
+		var event strings.Builder
+		if chunk.Event != "" {
+			event.WriteString("event: " + chunk.Event + "\n")
+		}
+		for _, line := range strings.Split(chunk.Body, "\n") {
+			event.WriteString("data: " + line + "\n")
+		}
+		event.WriteString("\n")
+
+		return []byte(event.String())
+	}
+
+	return []byte(chunk.Body)
+}
+
+// GetContentType returns the content type of the stream format, empty for raw
+// streams
+func (s MockStream) GetContentType() string {
+
+	switch s.Format {
+	case STREAM_FORMAT_NDJSON:
+		return "application/x-ndjson"
+	case STREAM_FORMAT_SSE:
+		return "text/event-stream"
+	}
+
+	return ""
+}
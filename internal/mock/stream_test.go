@@ -0,0 +1,68 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import "testing"
+
+func TestStream(t *testing.T) {
+
+	m, err := BuildMockFromJson([]byte(`{"name": "events", "request": {"url": "/events"}, "response": {"stream": {
+		"format": "sse",
+		"interval": 100,
+		"chunks": [
+			{"body": "connected"},
+			{"body": {"id": 1, "status": "pending"}, "event": "order"},
+			{"body": "line 1\nline 2", "delay": 500}
+		]}}}`))
+	if err != nil {
+		t.Fatalf("mock build failed with error: %v", err)
+	}
+
+	if !m.IsStream() {
+		t.Fatalf("mock should stream")
+	}
+
+	stream := *m.Response.Stream
+	chunks := stream.GetChunks()
+	if len(chunks) != 3 || chunks[1].Body != `{"id":1,"status":"pending"}` {
+		t.Fatalf("chunks are: %+v", chunks)
+	}
+
+	delays := []int{stream.GetDelay(chunks[0], 0), stream.GetDelay(chunks[1], 1), stream.GetDelay(chunks[2], 2)}
+	if delays[0] != 0 || delays[1] != 100 || delays[2] != 500 {
+		t.Errorf("delays are: %v", delays)
+	}
+
+	encoded := string(stream.Encode(chunks[1])) + string(stream.Encode(chunks[2]))
+	if encoded != "event: order\ndata: {\"id\":1,\"status\":\"pending\"}\n\ndata: line 1\ndata: line 2\n\n" {
+		t.Errorf("sse events are: %q", encoded)
+	}
+
+	stream.Format = STREAM_FORMAT_NDJSON
+	if string(stream.Encode(chunks[1])) != "{\"id\":1,\"status\":\"pending\"}\n" || stream.GetContentType() != "application/x-ndjson" {
+		t.Errorf("ndjson chunk is: %q", stream.Encode(chunks[1]))
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"request": {"url": "/events"}, "response": {"stream": {"format": "xml"}}}`))
+	if err == nil {
+		t.Errorf("unknown stream format should fail")
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"request": {"url": "/events"}, "response": {"body": "done", "stream": {"chunks": [{"body": "a"}]}}}`))
+	if err == nil {
+		t.Errorf("stream mock with a body should fail")
+	}
+}
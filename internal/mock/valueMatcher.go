@@ -0,0 +1,161 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// matchValues matches the headers, query parameters and body of the request
+func (r MockRequest) matchValues(req *http.Request, body []byte) error {
+
+	for name, expected := range r.Headers {
+
+		err := expected.matchAny("header '"+name+"'", req.Header.Values(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	query := req.URL.Query()
+	for name, expected := range r.Query {
+
+		err := expected.matchAny("query parameter '"+name+"'", query[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, expected := range r.Body {
+
+		err := expected.match("body", string(body))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchAny matches one of the values, a missing value does not match
+func (v MockValueMatcher) matchAny(target string, values []string) error {
+
+	if len(values) == 0 {
+		return errors.New("no " + target)
+	}
+
+	var err error
+	for _, value := range values {
+
+		err = v.match(target, value)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (v MockValueMatcher) match(target string, value string) error {
+
+	if v.EqualTo != nil && value != *v.EqualTo {
+		return errors.New(target + " '" + value + "' is not '" + *v.EqualTo + "'")
+	}
+
+	if v.Contains != "" && !strings.Contains(value, v.Contains) {
+		return errors.New(target + " '" + value + "' does not contain '" + v.Contains + "'")
+	}
+
+	if v.Matches != "" {
+
+		regex := v.regex
+		if regex == nil {
+
+			var err error
+			regex, err = compileWholeValueRegex(v.Matches)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !regex.MatchString(value) {
+			return errors.New(target + " '" + value + "' does not match '" + v.Matches + "'")
+		}
+	}
+
+	return nil
+}
+
+// compileWholeValueRegex compiles a pattern matching the whole value
+func compileWholeValueRegex(pattern string) (*regexp.Regexp, error) {
+
+	regex, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, errors.New("value pattern '" + pattern + "' is not valid: " + err.Error())
+	}
+
+	return regex, nil
+}
+
+// validateValues compiles the value patterns once for the matchers
+func (r *MockRequest) validateValues() error {
+
+	for _, matchers := range []map[string]MockValueMatcher{r.Headers, r.Query} {
+
+		for name, matcher := range matchers {
+
+			err := matcher.validate()
+			if err != nil {
+				return err
+			}
+			matchers[name] = matcher
+		}
+	}
+
+	for i := range r.Body {
+
+		err := r.Body[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *MockValueMatcher) validate() error {
+
+	if v.EqualTo == nil && v.Contains == "" && v.Matches == "" {
+		return errors.New("value matcher without equalTo, contains or matches")
+	}
+
+	if v.Matches == "" {
+		return nil
+	}
+
+	var err error
+	v.regex, err = compileWholeValueRegex(v.Matches)
+
+	return err
+}
+
+func (r MockRequest) getValueMatchersCount() int {
+
+	return len(r.Headers) + len(r.Query) + len(r.Body)
+}
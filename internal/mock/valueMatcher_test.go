@@ -0,0 +1,75 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package mock
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValueMatchers(t *testing.T) {
+
+	m, err := BuildMockFromJson([]byte(`{"name":"orders","request":{"method":"POST","url":"/orders",
+		"headers":{"X-Tenant":{"equalTo":"acme"}},
+		"query":{"status":{"matches":"open|closed"}},
+		"body":[{"contains":"\"item\""}]},"response":{"status":201}}`))
+	if err != nil {
+		t.Fatalf("mock build failed with error: %v", err)
+	}
+
+	if m.GetMatchersCount() != 3 || m.Request.Query["status"].regex == nil {
+		t.Errorf("matchers count is: %d, the patterns should be compiled at the mock build", m.GetMatchersCount())
+	}
+
+	tests := []struct {
+		name   string
+		tenant string
+		query  string
+		body   string
+		match  bool
+	}{
+		{"all", "acme", "status=open", `{"item": 1}`, true},
+		{"other header", "globex", "status=open", `{"item": 1}`, false},
+		{"no query", "acme", "", `{"item": 1}`, false},
+		{"partial pattern", "acme", "status=opened", `{"item": 1}`, false},
+		{"other body", "acme", "status=closed", `{"items": 1}`, false},
+	}
+
+	for _, test := range tests {
+
+		r := httptest.NewRequest("POST", "/orders?"+test.query, strings.NewReader(test.body))
+		r.Header.Set("X-Tenant", test.tenant)
+
+		err := m.Match(r, []byte(test.body))
+		if test.match && err != nil {
+			t.Errorf("%s: request should match, got error '%s'", test.name, err.Error())
+		}
+		if !test.match && err == nil {
+			t.Errorf("%s: request should not match", test.name)
+		}
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"name":"orders","request":{"method":"GET","url":"/orders","query":{"status":{"matches":"("}}},"response":{"status":200}}`))
+	if err == nil {
+		t.Errorf("invalid pattern should fail")
+	}
+
+	_, err = BuildMockFromJson([]byte(`{"name":"orders","request":{"method":"GET","url":"/orders","headers":{"X-Tenant":{}}},"response":{"status":200}}`))
+	if err == nil {
+		t.Errorf("empty value matcher should fail")
+	}
+}
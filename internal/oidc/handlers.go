@@ -0,0 +1,397 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth2 error answered by the token and authorize endpoints
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Discovery serves the OpenID provider metadata.
+func Discovery(w http.ResponseWriter, r *http.Request) {
+
+	p := getProvider()
+	issuer := p.getIssuer(r)
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + AUTHORIZE_PATH,
+		"token_endpoint":                        issuer + TOKEN_PATH,
+		"jwks_uri":                              issuer + JWKS_PATH,
+		"userinfo_endpoint":                     issuer + USERINFO_PATH,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "password", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "offline_access"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"plain", "S256"},
+	})
+}
+
+// Jwks serves the public key tokens are signed with.
+func Jwks(w http.ResponseWriter, r *http.Request) {
+
+	writeJson(w, http.StatusOK, map[string]interface{}{"keys": []interface{}{getProvider().jwk()}})
+}
+
+// Authorize logs in the user without login page: the login_hint user, or the
+// first configured user, then redirects to the client with a code.
+func Authorize(w http.ResponseWriter, r *http.Request) {
+
+	p := getProvider()
+	params := r.URL.Query()
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err == nil {
+			params = r.Form
+		}
+	}
+
+	client, exists := p.getClient(params.Get("client_id"))
+	if !exists {
+		writeJson(w, http.StatusBadRequest, oauthError{"invalid_client", "unknown client_id '" + params.Get("client_id") + "'"})
+		return
+	}
+
+	redirectUri := params.Get("redirect_uri")
+	if redirectUri == "" && len(client.RedirectUris) > 0 {
+		redirectUri = client.RedirectUris[0]
+	}
+
+	redirect, err := url.Parse(redirectUri)
+	if err != nil || redirectUri == "" || !client.allowsRedirectUri(redirectUri) {
+		writeJson(w, http.StatusBadRequest, oauthError{"invalid_request", "redirect_uri '" + redirectUri + "' is not allowed"})
+		return
+	}
+
+	// from now on errors are sent to the client
+	query := redirect.Query()
+	if state := params.Get("state"); state != "" {
+		query.Set("state", state)
+	}
+
+	redirectWith := func() {
+		redirect.RawQuery = query.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	}
+
+	if params.Get("response_type") != "code" {
+		query.Set("error", "unsupported_response_type")
+		redirectWith()
+		return
+	}
+
+	var user User
+	var found bool
+	if loginHint := params.Get("login_hint"); loginHint != "" {
+		user, found = p.getUser(loginHint)
+	} else if len(p.conf.Users) > 0 {
+		user, found = p.conf.Users[0], true
+	}
+
+	if !found {
+		query.Set("error", "access_denied")
+		query.Set("error_description", "no user to log in")
+		redirectWith()
+		return
+	}
+
+	code, err := p.addCode(authorizationCode{
+		clientId:            client.ClientId,
+		redirectUri:         params.Get("redirect_uri"),
+		username:            user.Username,
+		scope:               params.Get("scope"),
+		nonce:               params.Get("nonce"),
+		codeChallenge:       params.Get("code_challenge"),
+		codeChallengeMethod: params.Get("code_challenge_method"),
+	})
+	if err != nil {
+		query.Set("error", "server_error")
+		redirectWith()
+		return
+	}
+
+	query.Set("code", code)
+	redirectWith()
+}
+
+// Token serves the authorization_code, client_credentials, password and
+// refresh_token grants.
+func Token(w http.ResponseWriter, r *http.Request) {
+
+	p := getProvider()
+
+	err := r.ParseForm()
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, oauthError{"invalid_request", err.Error()})
+		return
+	}
+
+	client, ok := p.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="alfred"`)
+		writeJson(w, http.StatusUnauthorized, oauthError{"invalid_client", "client authentication failed"})
+		return
+	}
+
+	issuer := p.getIssuer(r)
+
+	var user *User
+	var scope, nonce string
+
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+
+		code, valid := p.useCode(r.Form.Get("code"))
+		if !valid || code.clientId != client.ClientId || code.redirectUri != r.Form.Get("redirect_uri") {
+			writeJson(w, http.StatusBadRequest, oauthError{"invalid_grant", "code is invalid, expired or issued to another client"})
+			return
+		}
+
+		if !verifyCodeChallenge(code, r.Form.Get("code_verifier")) {
+			writeJson(w, http.StatusBadRequest, oauthError{"invalid_grant", "code_verifier is wrong"})
+			return
+		}
+
+		u, _ := p.getUser(code.username)
+		user, scope, nonce = &u, code.scope, code.nonce
+
+	case "password":
+
+		u, exists := p.getUser(r.Form.Get("username"))
+		if !exists || subtle.ConstantTimeCompare([]byte(u.Password), []byte(r.Form.Get("password"))) != 1 {
+			writeJson(w, http.StatusBadRequest, oauthError{"invalid_grant", "wrong username or password"})
+			return
+		}
+
+		user, scope = &u, r.Form.Get("scope")
+
+	case "client_credentials":
+
+		scope = r.Form.Get("scope")
+
+	case "refresh_token":
+
+		claims, err := p.verify(issuer, r.Form.Get("refresh_token"), TOKEN_USE_REFRESH)
+		if err != nil || claims["client_id"] != client.ClientId {
+			writeJson(w, http.StatusBadRequest, oauthError{"invalid_grant", "refresh_token is invalid"})
+			return
+		}
+
+		scope, _ = claims["scope"].(string)
+		if username, ok := claims["sub"].(string); ok && username != client.ClientId {
+			if u, exists := p.getUser(username); exists {
+				user = &u
+			}
+		}
+
+	default:
+		writeJson(w, http.StatusBadRequest, oauthError{"unsupported_grant_type", "grant_type '" + r.Form.Get("grant_type") + "' is not supported"})
+		return
+	}
+
+	response, err := p.issueTokens(issuer, client, user, scope, nonce)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, oauthError{"server_error", err.Error()})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJson(w, http.StatusOK, response)
+}
+
+// Userinfo serves the claims of the access token user.
+func Userinfo(w http.ResponseWriter, r *http.Request) {
+
+	p := getProvider()
+
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="alfred"`)
+		writeJson(w, http.StatusUnauthorized, oauthError{"invalid_request", "no bearer token"})
+		return
+	}
+
+	claims, err := p.verify(p.getIssuer(r), strings.TrimSpace(token), TOKEN_USE_ACCESS)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJson(w, http.StatusUnauthorized, oauthError{"invalid_token", err.Error()})
+		return
+	}
+
+	sub, _ := claims["sub"].(string)
+	userinfo := map[string]interface{}{}
+
+	if user, exists := p.getUser(sub); exists {
+		for k, v := range user.Claims {
+			userinfo[k] = v
+		}
+	}
+	userinfo["sub"] = sub
+
+	writeJson(w, http.StatusOK, userinfo)
+}
+
+func (p *provider) issueTokens(issuer string, client Client, user *User, scope string, nonce string) (map[string]interface{}, error) {
+
+	sub := client.ClientId
+	if user != nil {
+		sub = user.Username
+	}
+
+	claims := map[string]interface{}{
+		"sub":       sub,
+		"aud":       client.ClientId,
+		"client_id": client.ClientId,
+		"token_use": TOKEN_USE_ACCESS,
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	accessToken, err := p.sign(issuer, claims, p.conf.AccessTokenLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	claims["token_use"] = TOKEN_USE_REFRESH
+	refreshToken, err := p.sign(issuer, claims, p.conf.RefreshTokenLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    lifetimeSeconds(p.conf.AccessTokenLifetime),
+		"refresh_token": refreshToken,
+	}
+	if scope != "" {
+		response["scope"] = scope
+	}
+
+	// id token of the users who logged in with the openid scope
+	if user != nil && hasScope(scope, "openid") {
+
+		idClaims := map[string]interface{}{}
+		for k, v := range user.Claims {
+			idClaims[k] = v
+		}
+		idClaims["sub"] = user.Username
+		idClaims["aud"] = client.ClientId
+		if nonce != "" {
+			idClaims["nonce"] = nonce
+		}
+
+		response["id_token"], err = p.sign(issuer, idClaims, p.conf.IdTokenLifetime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// Clients authenticate with basic credentials or form parameters, public
+// clients only give their id.
+func (p *provider) authenticateClient(r *http.Request) (Client, bool) {
+
+	clientId, clientSecret, basic := r.BasicAuth()
+	if !basic {
+		clientId, clientSecret = r.Form.Get("client_id"), r.Form.Get("client_secret")
+	}
+
+	client, exists := p.getClient(clientId)
+	if !exists {
+		return client, false
+	}
+
+	if client.ClientSecret == "" {
+		return client, true
+	}
+
+	return client, subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) == 1
+}
+
+// getIssuer returns the configured issuer, or the url the provider is reached with.
+func (p *provider) getIssuer(r *http.Request) string {
+
+	if p.conf.Issuer != "" {
+		return p.conf.Issuer
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + p.conf.Path
+}
+
+func verifyCodeChallenge(code authorizationCode, verifier string) bool {
+
+	switch code.codeChallengeMethod {
+	case "":
+		return code.codeChallenge == "" || code.codeChallenge == verifier
+	case "plain":
+		return code.codeChallenge == verifier
+	case "S256":
+		sum := sha256.Sum256([]byte(verifier))
+		return code.codeChallenge == base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	return false
+}
+
+func hasScope(scope string, expected string) bool {
+
+	for _, s := range strings.Fields(scope) {
+		if s == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lifetimeSeconds(lifetime string) int {
+
+	duration, _ := time.ParseDuration(lifetime)
+
+	return int(duration.Seconds())
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+
+	body, _ := json.Marshal(v)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,356 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"alfred/internal/jwt"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_PATH = "/oidc"
+const DEFAULT_TOKEN_LIFETIME = "1h"
+const DEFAULT_REFRESH_TOKEN_LIFETIME = "24h"
+
+// Authorization codes are exchanged right after the redirection
+const CODE_LIFETIME = 5 * time.Minute
+
+const (
+	DISCOVERY_PATH = "/.well-known/openid-configuration"
+	AUTHORIZE_PATH = "/authorize"
+	TOKEN_PATH     = "/token"
+	JWKS_PATH      = "/jwks"
+	USERINFO_PATH  = "/userinfo"
+)
+
+const (
+	TOKEN_USE_ACCESS  = "access"
+	TOKEN_USE_REFRESH = "refresh"
+)
+
+// Identity provider configuration, the issuer defaults to the url the
+// provider is reached with and an RSA key is generated when none is given.
+type Config struct {
+	Path                 string
+	Issuer               string
+	PrivateKeyPath       string
+	AccessTokenLifetime  string
+	IdTokenLifetime      string
+	RefreshTokenLifetime string
+	Clients              []Client
+	Users                []User
+}
+
+// A client without secret is a public client.
+type Client struct {
+	ClientId     string
+	ClientSecret string
+	RedirectUris []string
+}
+
+type User struct {
+	Username string
+	Password string
+	Claims   map[string]interface{}
+}
+
+type authorizationCode struct {
+	clientId            string
+	redirectUri         string
+	username            string
+	scope               string
+	nonce               string
+	codeChallenge       string
+	codeChallengeMethod string
+	expiration          time.Time
+}
+
+type provider struct {
+	conf          Config
+	privateKeyPem string
+	publicKeyPem  string
+	publicKey     *rsa.PublicKey
+	kid           string
+
+	codes      map[string]authorizationCode
+	codesMutex sync.Mutex
+}
+
+var (
+	current      *provider
+	currentMutex sync.RWMutex
+)
+
+// Configure creates the provider, it's kept on mocks reload so issued codes
+// and tokens stay valid.
+func Configure(c Config) error {
+
+	if c.Path == "" {
+		c.Path = DEFAULT_PATH
+	}
+	c.Path = "/" + strings.Trim(c.Path, "/")
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+
+	if c.AccessTokenLifetime == "" {
+		c.AccessTokenLifetime = DEFAULT_TOKEN_LIFETIME
+	}
+
+	if c.IdTokenLifetime == "" {
+		c.IdTokenLifetime = DEFAULT_TOKEN_LIFETIME
+	}
+
+	if c.RefreshTokenLifetime == "" {
+		c.RefreshTokenLifetime = DEFAULT_REFRESH_TOKEN_LIFETIME
+	}
+
+	for _, lifetime := range []string{c.AccessTokenLifetime, c.IdTokenLifetime, c.RefreshTokenLifetime} {
+		if _, err := time.ParseDuration(lifetime); err != nil {
+			return errors.New("oidc token lifetime: " + err.Error())
+		}
+	}
+
+	clientIds := map[string]bool{}
+	for _, client := range c.Clients {
+
+		if client.ClientId == "" || clientIds[client.ClientId] {
+			return errors.New("oidc client-id '" + client.ClientId + "' is empty or not unique")
+		}
+		clientIds[client.ClientId] = true
+	}
+
+	p := &provider{conf: c, codes: map[string]authorizationCode{}}
+
+	var privateKey *rsa.PrivateKey
+	var err error
+
+	if c.PrivateKeyPath != "" {
+
+		content, err := os.ReadFile(c.PrivateKeyPath)
+		if err != nil {
+			return errors.New("oidc private key: " + err.Error())
+		}
+
+		privateKey, err = parsePrivateKey(content)
+		if err != nil {
+			return errors.New("oidc private key " + c.PrivateKeyPath + ": " + err.Error())
+		}
+
+	} else {
+
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return err
+		}
+	}
+
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	p.privateKeyPem = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}))
+	p.publicKeyPem = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer}))
+	p.publicKey = &privateKey.PublicKey
+
+	sum := sha256.Sum256(publicKeyDer)
+	p.kid = hex.EncodeToString(sum[:8])
+
+	currentMutex.Lock()
+	defer currentMutex.Unlock()
+	current = p
+
+	return nil
+}
+
+func getProvider() *provider {
+
+	currentMutex.RLock()
+	defer currentMutex.RUnlock()
+
+	return current
+}
+
+// GetPath returns the path the provider endpoints are served under.
+func GetPath() string {
+
+	if p := getProvider(); p != nil {
+		return p.conf.Path
+	}
+
+	return DEFAULT_PATH
+}
+
+func (p *provider) getClient(clientId string) (Client, bool) {
+
+	for _, client := range p.conf.Clients {
+		if client.ClientId == clientId {
+			return client, true
+		}
+	}
+
+	return Client{}, false
+}
+
+func (p *provider) getUser(username string) (User, bool) {
+
+	for _, user := range p.conf.Users {
+		if user.Username == username {
+			return user, true
+		}
+	}
+
+	return User{}, false
+}
+
+// Redirect uris are checked only if the client declares some.
+func (c Client) allowsRedirectUri(redirectUri string) bool {
+
+	if len(c.RedirectUris) == 0 {
+		return true
+	}
+
+	for _, uri := range c.RedirectUris {
+		if uri == redirectUri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *provider) addCode(code authorizationCode) (string, error) {
+
+	value, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	code.expiration = time.Now().Add(CODE_LIFETIME)
+
+	p.codesMutex.Lock()
+	defer p.codesMutex.Unlock()
+
+	// forget expired codes
+	for k, c := range p.codes {
+		if time.Now().After(c.expiration) {
+			delete(p.codes, k)
+		}
+	}
+
+	p.codes[value] = code
+
+	return value, nil
+}
+
+// Codes can be used once.
+func (p *provider) useCode(value string) (authorizationCode, bool) {
+
+	p.codesMutex.Lock()
+	defer p.codesMutex.Unlock()
+
+	code, exists := p.codes[value]
+	delete(p.codes, value)
+
+	if !exists || time.Now().After(code.expiration) {
+		return code, false
+	}
+
+	return code, true
+}
+
+func (p *provider) sign(issuer string, claims map[string]interface{}, lifetime string) (string, error) {
+
+	return jwt.Sign(claims, jwt.Options{
+		Algorithm:  "RS256",
+		PrivateKey: p.privateKeyPem,
+		Issuer:     issuer,
+		ExpiresIn:  lifetime,
+		Kid:        p.kid,
+	})
+}
+
+func (p *provider) verify(issuer string, token string, tokenUse string) (map[string]interface{}, error) {
+
+	claims, err := jwt.Verify(token, jwt.Options{Algorithm: "RS256", PublicKey: p.publicKeyPem, Issuer: issuer})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["token_use"] != tokenUse {
+		return nil, errors.New("token is not an " + tokenUse + " token")
+	}
+
+	return claims, nil
+}
+
+// Jwk of the signing key
+func (p *provider) jwk() map[string]interface{} {
+
+	return map[string]interface{}{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": p.kid,
+		"n":   base64.RawURLEncoding.EncodeToString(p.publicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.publicKey.E)).Bytes()),
+	}
+}
+
+func parsePrivateKey(content []byte) (*rsa.PrivateKey, error) {
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+
+	return rsaKey, nil
+}
+
+func randomString() (string, error) {
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func configureTestProvider(t *testing.T) {
+
+	err := Configure(Config{
+		Clients: []Client{
+			{ClientId: "my-app", ClientSecret: "my-app-secret", RedirectUris: []string{"http://localhost:3000/callback"}},
+			{ClientId: "my-spa"},
+		},
+		Users: []User{{Username: "bruce", Password: "wayne", Claims: map[string]interface{}{"email": "bruce@wayne-enterprises.com"}}},
+	})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+}
+
+func postToken(form url.Values, clientId string, clientSecret string) (int, map[string]interface{}) {
+
+	r := httptest.NewRequest(http.MethodPost, "http://alfred"+DEFAULT_PATH+TOKEN_PATH, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientId != "" {
+		r.SetBasicAuth(clientId, clientSecret)
+	}
+
+	w := httptest.NewRecorder()
+	Token(w, r)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	return w.Code, body
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+
+	configureTestProvider(t)
+
+	verifier := "a-very-long-code-verifier-of-the-test-client"
+	sum := sha256.Sum256([]byte(verifier))
+
+	r := httptest.NewRequest(http.MethodGet, "http://alfred"+DEFAULT_PATH+AUTHORIZE_PATH+"?"+url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"my-spa"},
+		"redirect_uri":          {"http://localhost:4200/"},
+		"scope":                 {"openid email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode(), nil)
+	w := httptest.NewRecorder()
+	Authorize(w, r)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if w.Code != http.StatusFound || err != nil {
+		t.Fatalf("authorize answered %d, location: %s", w.Code, w.Header().Get("Location"))
+	}
+
+	code := location.Query().Get("code")
+	if code == "" || location.Query().Get("state") != "xyz" {
+		t.Fatalf("redirect location is: %s", location)
+	}
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"http://localhost:4200/"}, "client_id": {"my-spa"}, "code_verifier": {"wrong"}}
+	status, _ := postToken(form, "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("wrong code verifier answered %d", status)
+	}
+
+	// the code is burnt by the failed attempt
+	form.Set("code_verifier", verifier)
+	status, _ = postToken(form, "", "")
+	if status != http.StatusBadRequest {
+		t.Errorf("used code answered %d", status)
+	}
+}
+
+func TestTokenAndUserinfo(t *testing.T) {
+
+	configureTestProvider(t)
+
+	status, _ := postToken(url.Values{"grant_type": {"client_credentials"}}, "my-app", "wrong")
+	if status != http.StatusUnauthorized {
+		t.Errorf("wrong client secret answered %d", status)
+	}
+
+	status, body := postToken(url.Values{"grant_type": {"password"}, "username": {"bruce"}, "password": {"wayne"}, "scope": {"openid"}}, "my-app", "my-app-secret")
+	if status != http.StatusOK || body["id_token"] == nil || body["refresh_token"] == nil {
+		t.Fatalf("password grant answered %d: %v", status, body)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://alfred"+DEFAULT_PATH+USERINFO_PATH, nil)
+	r.Header.Set("Authorization", "Bearer "+body["access_token"].(string))
+	w := httptest.NewRecorder()
+	Userinfo(w, r)
+
+	var userinfo map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &userinfo)
+	if w.Code != http.StatusOK || userinfo["sub"] != "bruce" || userinfo["email"] != "bruce@wayne-enterprises.com" {
+		t.Errorf("userinfo answered %d: %v", w.Code, userinfo)
+	}
+
+	// refresh tokens are not access tokens
+	r.Header.Set("Authorization", "Bearer "+body["refresh_token"].(string))
+	w = httptest.NewRecorder()
+	Userinfo(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("userinfo with a refresh token answered %d", w.Code)
+	}
+
+	status, refreshed := postToken(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {body["refresh_token"].(string)}}, "my-app", "my-app-secret")
+	if status != http.StatusOK || refreshed["access_token"] == nil {
+		t.Errorf("refresh grant answered %d: %v", status, refreshed)
+	}
+}
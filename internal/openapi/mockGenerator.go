@@ -0,0 +1,486 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+import (
+	"alfred/internal/mock"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const DEFAULT_RESPONSE_STATUS = http.StatusOK
+
+// Max nested objects generated from a schema (avoid infinite recursion)
+const MAX_FAKE_DEPTH = 5
+
+// Max items generated for an array
+const MAX_FAKE_ITEMS = 3
+
+var pathParamRegexp = regexp.MustCompile(`{([^}/]+)}`)
+var helperNameRegexp = regexp.MustCompile(`[^\w.-]`)
+
+var methods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// GenerateMocks creates a mock for each operation of the specification. The
+// response body comes from the spec examples, or is built with random
+// helpers from the response schema.
+func GenerateMocks(doc *openapi3.T) ([]mock.Mock, error) {
+
+	var mocks []mock.Mock
+
+	if doc.Paths == nil {
+		return mocks, nil
+	}
+
+	basePath := getBasePath(doc)
+	paths := doc.Paths.Map()
+
+	for _, path := range getSortedKeys(paths) {
+
+		operations := paths[path].Operations()
+
+		for _, method := range methods {
+
+			op, exists := operations[method]
+			if !exists {
+				continue
+			}
+
+			m, err := generateMock(basePath+path, method, op)
+			if err != nil {
+				return mocks, err
+			}
+
+			mocks = append(mocks, m)
+		}
+	}
+
+	return mocks, nil
+}
+
+func generateMock(path string, method string, op *openapi3.Operation) (mock.Mock, error) {
+
+	var m mock.Mock
+
+	m.Name = getMockName(path, method, op)
+	m.Request.Method = method
+
+	params := getPathParams(path)
+	if len(params) > 0 {
+		m.Request.UrlRegexStr = buildUrlRegex(path)
+	} else {
+		m.Request.Url = path
+	}
+
+	status, response := selectResponse(op.Responses)
+	m.Response.Status = status
+
+	if response == nil || len(response.Content) == 0 {
+		return m, nil
+	}
+
+	contentType, media := selectContent(response.Content)
+	m.Response.Headers = map[string]string{"Content-Type": contentType}
+
+	body, err := buildBody(contentType, media, params)
+	if err != nil {
+		return m, err
+	}
+	m.Response.Body = body
+
+	return m, nil
+}
+
+// Servers url path is used as mocks url prefix
+func getBasePath(doc *openapi3.T) string {
+
+	if len(doc.Servers) == 0 || strings.Contains(doc.Servers[0].URL, "{") {
+		return ""
+	}
+
+	u, err := url.Parse(doc.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+func getMockName(path string, method string, op *openapi3.Operation) string {
+
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+
+	name := strings.ToLower(method) + "-" + pathParamRegexp.ReplaceAllString(path, "$1")
+
+	return strings.Trim(regexp.MustCompile(`[^\w]+`).ReplaceAllString(name, "-"), "-")
+}
+
+// Path params name with their regex group index
+func getPathParams(path string) map[string]int {
+
+	params := map[string]int{}
+
+	for i, match := range pathParamRegexp.FindAllStringSubmatch(path, -1) {
+		params[match[1]] = i + 1
+	}
+
+	return params
+}
+
+func buildUrlRegex(path string) string {
+
+	var urlRegex string
+
+	literals := pathParamRegexp.Split(path, -1)
+	for i, literal := range literals {
+
+		urlRegex += regexp.QuoteMeta(literal)
+		if i < len(literals)-1 {
+			urlRegex += "([^/]+)"
+		}
+	}
+
+	return urlRegex + "$"
+}
+
+// The first success response is preferred, then the default one.
+func selectResponse(responses *openapi3.Responses) (int, *openapi3.Response) {
+
+	if responses == nil || responses.Len() == 0 {
+		return DEFAULT_RESPONSE_STATUS, nil
+	}
+
+	responsesMap := responses.Map()
+	codes := getSortedKeys(responsesMap)
+
+	selected := codes[0]
+	for _, code := range codes {
+
+		if strings.HasPrefix(code, "2") {
+			selected = code
+			break
+		}
+
+		if code == "default" {
+			selected = code
+		}
+	}
+
+	status, err := strconv.Atoi(selected)
+	if err != nil {
+		status = DEFAULT_RESPONSE_STATUS
+	}
+
+	return status, responsesMap[selected].Value
+}
+
+// Json content is preferred.
+func selectContent(content openapi3.Content) (string, *openapi3.MediaType) {
+
+	contentTypes := getSortedKeys(content)
+
+	for _, contentType := range contentTypes {
+		if strings.Contains(contentType, "json") {
+			return contentType, content[contentType]
+		}
+	}
+
+	return contentTypes[0], content[contentTypes[0]]
+}
+
+func buildBody(contentType string, media *openapi3.MediaType, params map[string]int) (json.RawMessage, error) {
+
+	isJson := strings.Contains(contentType, "json")
+
+	example := getMediaTypeExample(media)
+	if example != nil {
+
+		if exampleStr, isString := example.(string); isString && !isJson {
+			return json.Marshal(exampleStr)
+		}
+
+		return json.Marshal(example)
+	}
+
+	if media.Schema == nil || media.Schema.Value == nil {
+		return nil, nil
+	}
+
+	// helpers can be used as json numbers, so the body is saved as string
+	if isJson {
+		return json.Marshal(fakeJson(media.Schema.Value, "", "body", params, 0))
+	}
+
+	return json.Marshal(fakeString(media.Schema.Value, "", "body", params))
+}
+
+func getMediaTypeExample(media *openapi3.MediaType) interface{} {
+
+	if media.Example != nil {
+		return media.Example
+	}
+
+	for _, name := range getSortedKeys(media.Examples) {
+
+		example := media.Examples[name]
+		if example.Value != nil && example.Value.Value != nil {
+			return example.Value.Value
+		}
+	}
+
+	if media.Schema != nil && media.Schema.Value != nil && media.Schema.Value.Example != nil {
+		return media.Schema.Value.Example
+	}
+
+	return nil
+}
+
+// Build a json document from a schema, using alfred helpers for values.
+func fakeJson(schema *openapi3.Schema, name string, path string, params map[string]int, depth int) string {
+
+	if depth > MAX_FAKE_DEPTH {
+		return "null"
+	}
+
+	if schema.Example != nil {
+		example, _ := json.Marshal(schema.Example)
+		return string(example)
+	}
+
+	if len(schema.Enum) > 0 {
+		enum, _ := json.Marshal(schema.Enum[0])
+		return string(enum)
+	}
+
+	if len(schema.AllOf) > 0 {
+		return fakeJson(mergeAllOf(schema), name, path, params, depth+1)
+	}
+
+	if len(schema.OneOf) > 0 && schema.OneOf[0].Value != nil {
+		return fakeJson(schema.OneOf[0].Value, name, path, params, depth+1)
+	}
+
+	if len(schema.AnyOf) > 0 && schema.AnyOf[0].Value != nil {
+		return fakeJson(schema.AnyOf[0].Value, name, path, params, depth+1)
+	}
+
+	switch schema.Type {
+
+	case openapi3.TypeArray:
+
+		if schema.Items == nil || schema.Items.Value == nil {
+			return "[]"
+		}
+
+		itemsNb := int(schema.MinItems)
+		if itemsNb < 1 {
+			itemsNb = 1
+		}
+		if itemsNb > MAX_FAKE_ITEMS {
+			itemsNb = MAX_FAKE_ITEMS
+		}
+
+		var items []string
+		for i := 0; i < itemsNb; i++ {
+			items = append(items, fakeJson(schema.Items.Value, name, path+"."+strconv.Itoa(i), params, depth+1))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+
+		if index, isParam := params[name]; isParam {
+			return "{{ alfred.pathRegex." + strconv.Itoa(index) + " }}"
+		}
+
+		min, max := 0, 1000
+		if schema.Min != nil {
+			min = int(*schema.Min)
+		}
+		if schema.Max != nil {
+			max = int(*schema.Max)
+		}
+
+		return "{{ alfred.random.RandomIntBetween(" + strconv.Itoa(min) + "," + strconv.Itoa(max) + ") @name:'" + getHelperName(path) + "' }}"
+
+	case openapi3.TypeBoolean:
+
+		return randomHelper("RandomBoolean", path)
+
+	case openapi3.TypeString:
+
+		return "\"" + fakeString(schema, name, path, params) + "\""
+	}
+
+	if len(schema.Properties) > 0 || schema.Type == openapi3.TypeObject {
+
+		var properties []string
+		for _, propertyName := range getSortedKeys(schema.Properties) {
+
+			property := schema.Properties[propertyName]
+			if property.Value == nil {
+				continue
+			}
+
+			key, _ := json.Marshal(propertyName)
+			properties = append(properties, string(key)+": "+fakeJson(property.Value, propertyName, path+"."+propertyName, params, depth+1))
+		}
+
+		return "{" + strings.Join(properties, ", ") + "}"
+	}
+
+	return "null"
+}
+
+// Build a string value from a schema, using formats and property names to
+// find an adequate alfred helper.
+func fakeString(schema *openapi3.Schema, name string, path string, params map[string]int) string {
+
+	if index, isParam := params[name]; isParam {
+		return "{{ alfred.pathRegex." + strconv.Itoa(index) + " }}"
+	}
+
+	switch schema.Format {
+	case "uuid":
+		return randomHelper("RandomUUID", path)
+	case "email":
+		return randomHelper("RandomEmail", path)
+	case "date-time":
+		return "{{ alfred.time.now.utc.format('2006-01-02T15:04:05Z') }}"
+	case "date":
+		return "{{ alfred.time.now.format('2006-01-02') }}"
+	case "uri", "url":
+		return randomHelper("RandomUrl", path)
+	case "ipv4":
+		return randomHelper("RandomIP", path)
+	case "ipv6":
+		return randomHelper("RandomIpv6", path)
+	case "hostname":
+		return randomHelper("RandomDomainName", path)
+	case "password":
+		return randomHelper("RandomPassword", path)
+	}
+
+	return randomHelper(getFakerMethodFromName(name), path)
+}
+
+func getFakerMethodFromName(name string) string {
+
+	name = strings.ToLower(regexp.MustCompile(`[-_ ]`).ReplaceAllString(name, ""))
+
+	namesFakerMethods := []struct {
+		keyword string
+		method  string
+	}{
+		{"email", "RandomEmail"},
+		{"firstname", "RandomPersonFirstName"},
+		{"lastname", "RandomPersonLastName"},
+		{"username", "RandomUsername"},
+		{"company", "RandomCompanyName"},
+		{"city", "RandomAddressCity"},
+		{"country", "RandomAddressCountry"},
+		{"street", "RandomAddressStreetAddress"},
+		{"address", "RandomAddressStreetAddress"},
+		{"phone", "RandomPhoneNumberExt"},
+		{"url", "RandomUrl"},
+		{"currency", "RandomCurrencyCode"},
+		{"description", "RandomLoremSentence"},
+		{"title", "RandomLoremWords"},
+		{"name", "RandomPersonFullName"},
+	}
+
+	for _, nameFakerMethod := range namesFakerMethods {
+		if strings.Contains(name, nameFakerMethod.keyword) {
+			return nameFakerMethod.method
+		}
+	}
+
+	if strings.HasSuffix(name, "id") {
+		return "RandomUUID"
+	}
+
+	return "RandomLoremWord"
+}
+
+// Each value has its own helper name, if not, helpers with the same faker
+// method would share the same value.
+func randomHelper(fakerMethod string, path string) string {
+
+	return "{{ alfred.random." + fakerMethod + " @name:'" + getHelperName(path) + "' }}"
+}
+
+func getHelperName(path string) string {
+
+	return helperNameRegexp.ReplaceAllString(path, "-")
+}
+
+func mergeAllOf(schema *openapi3.Schema) *openapi3.Schema {
+
+	merged := openapi3.NewObjectSchema()
+	merged.Properties = openapi3.Schemas{}
+
+	for k, v := range schema.Properties {
+		merged.Properties[k] = v
+	}
+
+	for _, s := range schema.AllOf {
+
+		if s.Value == nil {
+			continue
+		}
+
+		for k, v := range s.Value.Properties {
+			merged.Properties[k] = v
+		}
+	}
+
+	// not an object composition
+	if len(merged.Properties) == 0 && schema.AllOf[0].Value != nil {
+		return schema.AllOf[0].Value
+	}
+
+	return merged
+}
+
+func getSortedKeys[V any](m map[string]V) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+import (
+	"alfred/internal/mock"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+servers:
+  - url: http://localhost:8080/api
+paths:
+  /users/{userId}:
+    get:
+      operationId: getUser
+      parameters:
+        - name: userId
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        '404':
+          description: not found
+        '200':
+          description: a user
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  userId:
+                    type: string
+                  email:
+                    type: string
+                    format: email
+                  age:
+                    type: integer
+                    minimum: 18
+                    maximum: 99
+  /users:
+    post:
+      responses:
+        '201':
+          description: created
+          content:
+            application/json:
+              example:
+                id: 1
+`
+
+func TestGenerateMocks(t *testing.T) {
+
+	doc, err := LoadSpecFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec failed with error: %v", err)
+	}
+
+	mocks, err := GenerateMocks(doc)
+	if err != nil {
+		t.Fatalf("generate mocks failed with error: %v", err)
+	}
+
+	if len(mocks) != 2 {
+		t.Fatalf("generated mocks count is: %d, want: 2", len(mocks))
+	}
+
+	create := mocks[0]
+	if create.Name != "post-api-users" || create.Request.Method != "POST" || create.Request.Url != "/api/users" {
+		t.Errorf("create mock is: '%s' %s %s, want: 'post-api-users' POST /api/users", create.Name, create.Request.Method, create.Request.Url)
+	}
+
+	if create.Response.Status != 201 || strings.ReplaceAll(string(create.Response.Body), " ", "") != `{"id":1}` {
+		t.Errorf("create mock response is: %d %s, want: 201 {\"id\":1}", create.Response.Status, string(create.Response.Body))
+	}
+
+	get := mocks[1]
+	if get.Request.UrlRegexStr != "/api/users/([^/]+)$" {
+		t.Errorf("get mock url regex is: '%s', want: '/api/users/([^/]+)$'", get.Request.UrlRegexStr)
+	}
+
+	if get.Response.Status != 200 {
+		t.Errorf("get mock status is: %d, want: 200", get.Response.Status)
+	}
+
+	var body string
+	err = json.Unmarshal(get.Response.Body, &body)
+	if err != nil {
+		t.Fatalf("get mock body is not a json string: %v", err)
+	}
+
+	for _, expected := range []string{"{{ alfred.pathRegex.1 }}", "RandomIntBetween(18,99)", "RandomEmail"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("get mock body '%s' does not contain '%s'", body, expected)
+		}
+	}
+
+	// generated mocks must be loadable
+	data, _ := json.Marshal(get)
+	var loaded mock.Mock
+	err = json.Unmarshal(data, &loaded)
+	if err != nil || loaded.Request.UrlRegexStr != get.Request.UrlRegexStr {
+		t.Errorf("generated mock reload failed: %v", err)
+	}
+}
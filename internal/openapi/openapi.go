@@ -0,0 +1,61 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// LoadSpec loads and validates an OpenAPI 3 specification file (json or yaml).
+func LoadSpec(filePath string) (*openapi3.T, error) {
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+
+	doc, err := loader.LoadFromFile(filePath)
+	if err != nil {
+		return nil, errors.New("openapi spec " + filePath + ": " + err.Error())
+	}
+
+	err = doc.Validate(context.Background())
+	if err != nil {
+		return nil, errors.New("openapi spec " + filePath + " not valid: " + err.Error())
+	}
+
+	return doc, nil
+}
+
+// LoadSpecFromData loads and validates an OpenAPI 3 specification content.
+func LoadSpecFromData(data []byte) (*openapi3.T, error) {
+
+	loader := openapi3.NewLoader()
+
+	doc, err := loader.LoadFromData(data)
+	if err != nil {
+		return nil, errors.New("openapi spec: " + err.Error())
+	}
+
+	err = doc.Validate(context.Background())
+	if err != nil {
+		return nil, errors.New("openapi spec not valid: " + err.Error())
+	}
+
+	return doc, nil
+}
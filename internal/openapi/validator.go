@@ -0,0 +1,118 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+const (
+	// Violations are logged
+	VALIDATION_MODE_LOG = "log"
+	// Violations are logged and answered with a 400 (request) or 500 (response) status
+	VALIDATION_MODE_STRICT = "strict"
+)
+
+// Validator checks requests and mocks responses against an OpenAPI
+// specification. Like generated mocks, routes are matched on the servers
+// base path, whatever the host is.
+type Validator struct {
+	router   routers.Router
+	basePath string
+	Strict   bool
+}
+
+func NewValidator(doc *openapi3.T, mode string) (*Validator, error) {
+
+	if mode != VALIDATION_MODE_LOG && mode != VALIDATION_MODE_STRICT {
+		return nil, errors.New("unknown openapi validation mode '" + mode + "'")
+	}
+
+	// keep violations messages short in logs
+	openapi3.SchemaErrorDetailsDisabled = true
+
+	basePath := getBasePath(doc)
+	doc.Servers = nil
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, errors.New("openapi router creation failed: " + err.Error())
+	}
+
+	return &Validator{router: router, basePath: basePath, Strict: mode == VALIDATION_MODE_STRICT}, nil
+}
+
+// FindRoute returns the validation input of the spec operation matching the
+// request, or nil if the request is not described by the spec.
+func (v *Validator) FindRoute(r *http.Request) *openapi3filter.RequestValidationInput {
+
+	if !strings.HasPrefix(r.URL.Path, v.basePath) {
+		return nil
+	}
+
+	req := r.Clone(r.Context())
+	req.URL.Path = strings.TrimPrefix(r.URL.Path, v.basePath)
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return nil
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:            true,
+			IncludeResponseStatus: true,
+			AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		},
+	}
+}
+
+func (v *Validator) ValidateRequest(ctx context.Context, input *openapi3filter.RequestValidationInput, body []byte) error {
+
+	input.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return openapi3filter.ValidateRequest(ctx, input)
+}
+
+func (v *Validator) ValidateResponse(ctx context.Context, input *openapi3filter.RequestValidationInput, status int, header http.Header, body []byte) error {
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 header,
+		Options:                input.Options,
+	}
+	responseInput.SetBodyBytes(body)
+
+	return openapi3filter.ValidateResponse(ctx, responseInput)
+}
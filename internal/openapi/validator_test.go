@@ -0,0 +1,83 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+
+	doc, err := LoadSpecFromData([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("load spec failed with error: %v", err)
+	}
+
+	validator, err := NewValidator(doc, VALIDATION_MODE_STRICT)
+	if err != nil {
+		t.Fatalf("validator creation failed with error: %v", err)
+	}
+
+	if !validator.Strict {
+		t.Errorf("validator should be strict")
+	}
+
+	if validator.FindRoute(httptest.NewRequest(http.MethodGet, "/other/users/1", nil)) != nil {
+		t.Errorf("a route outside the spec base path should not be found")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "http://any-host/api/users/1", nil)
+	input := validator.FindRoute(r)
+	if input == nil {
+		t.Fatalf("route not found for %s", r.URL.Path)
+	}
+
+	if input.PathParams["userId"] != "1" {
+		t.Errorf("path param userId is: '%s', want: '1'", input.PathParams["userId"])
+	}
+
+	err = validator.ValidateRequest(context.Background(), input, nil)
+	if err != nil {
+		t.Errorf("request validation failed with error: %v", err)
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+
+	err = validator.ValidateResponse(context.Background(), input, 200, header, []byte(`{"userId":"1","age":20}`))
+	if err != nil {
+		t.Errorf("response validation failed with error: %v", err)
+	}
+
+	err = validator.ValidateResponse(context.Background(), input, 200, header, []byte(`{"userId":"1","age":"old"}`))
+	if err == nil || !strings.Contains(err.Error(), "age") {
+		t.Errorf("response validation should fail on age, got: %v", err)
+	}
+
+	err = validator.ValidateResponse(context.Background(), input, 500, header, []byte(`{}`))
+	if err == nil {
+		t.Errorf("response validation should fail on a status not in the spec")
+	}
+
+	_, err = NewValidator(doc, "unknown")
+	if err == nil {
+		t.Errorf("validator creation should fail with an unknown mode")
+	}
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postman
+
+import (
+	"alfred/internal/mock"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Postman variables, like {{baseUrl}}
+var variableRegexp = regexp.MustCompile(`{{[^}]*}}`)
+
+// Collection is a Postman collection (v2.0 and v2.1 formats).
+type Collection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item []Item `json:"item"`
+}
+
+// Item is a request, or a folder when it contains items.
+type Item struct {
+	Name     string     `json:"name"`
+	Item     []Item     `json:"item"`
+	Request  *Request   `json:"request"`
+	Response []Response `json:"response"`
+}
+
+type Request struct {
+	Method string     `json:"method"`
+	Url    Url        `json:"url"`
+	Header []KeyValue `json:"header"`
+}
+
+type Url struct {
+	Raw  string   `json:"raw"`
+	Path []string `json:"path"`
+}
+
+// Saved example response
+type Response struct {
+	Name   string     `json:"name"`
+	Code   int        `json:"code"`
+	Header []KeyValue `json:"header"`
+	Body   string     `json:"body"`
+}
+
+type KeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Url can be a simple string in collections
+func (u *Url) UnmarshalJSON(data []byte) error {
+
+	var raw string
+	if json.Unmarshal(data, &raw) == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	type urlObject Url
+	return json.Unmarshal(data, (*urlObject)(u))
+}
+
+func LoadCollection(filePath string) (Collection, error) {
+
+	var collection Collection
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return collection, errors.New("postman collection " + filePath + ": " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &collection)
+	if err != nil {
+		return collection, errors.New("postman collection " + filePath + " is not a valid json: " + err.Error())
+	}
+
+	return collection, nil
+}
+
+// GenerateMocks creates a mock for each request of the collection, folders
+// included. The first saved example is used as response, path variables
+// (:id or {{id}}) are matched with a url regex.
+func GenerateMocks(collection Collection) ([]mock.Mock, error) {
+
+	return generateMocks(collection.Item, "")
+}
+
+func generateMocks(items []Item, folder string) ([]mock.Mock, error) {
+
+	var mocks []mock.Mock
+
+	for _, item := range items {
+
+		name := item.Name
+		if folder != "" {
+			name = folder + "/" + item.Name
+		}
+
+		if item.Request == nil {
+
+			folderMocks, err := generateMocks(item.Item, name)
+			if err != nil {
+				return mocks, err
+			}
+			mocks = append(mocks, folderMocks...)
+
+			continue
+		}
+
+		m, err := generateMock(name, item)
+		if err != nil {
+			return mocks, err
+		}
+		mocks = append(mocks, m)
+	}
+
+	return mocks, nil
+}
+
+func generateMock(name string, item Item) (mock.Mock, error) {
+
+	var m mock.Mock
+
+	m.Name = name
+
+	m.Request.Method = strings.ToUpper(item.Request.Method)
+	if m.Request.Method == "" {
+		m.Request.Method = http.MethodGet
+	}
+
+	path, err := getPath(item.Request.Url)
+	if err != nil {
+		return m, errors.New("postman request '" + name + "' url: " + err.Error())
+	}
+
+	segments := strings.Split(path, "/")
+	hasVariable := false
+	for i, segment := range segments {
+
+		if strings.HasPrefix(segment, ":") || variableRegexp.MatchString(segment) {
+			segments[i] = "([^/]+)"
+			hasVariable = true
+		} else {
+			segments[i] = regexp.QuoteMeta(segment)
+		}
+	}
+
+	if hasVariable {
+		m.Request.UrlRegexStr = strings.Join(segments, "/") + "$"
+	} else {
+		m.Request.Url = path
+	}
+
+	if len(item.Response) == 0 {
+		return m, nil
+	}
+
+	example := item.Response[0]
+
+	headers := http.Header{}
+	for _, h := range example.Header {
+		if !h.Disabled {
+			headers.Add(h.Key, h.Value)
+		}
+	}
+
+	status := example.Code
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	m.Response = mock.BuildRecordedResponse(status, headers, example.Body)
+
+	return m, nil
+}
+
+// Url path without the host, which is often a variable like {{baseUrl}}
+func getPath(u Url) (string, error) {
+
+	if len(u.Path) > 0 {
+		return "/" + strings.Join(u.Path, "/"), nil
+	}
+
+	raw := u.Raw
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+
+	if loc := variableRegexp.FindStringIndex(raw); loc != nil && loc[0] == 0 {
+		raw = raw[loc[1]:]
+	} else if strings.Contains(raw, "://") {
+
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return "", err
+		}
+		raw = parsed.Path
+	} else if i := strings.Index(raw, "/"); i > 0 {
+		//host without scheme
+		raw = raw[i:]
+	}
+
+	if !strings.HasPrefix(raw, "/") {
+		raw = "/" + raw
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postman
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testCollection = `{
+  "info": {"name": "test"},
+  "item": [
+    {
+      "name": "users",
+      "item": [
+        {
+          "name": "get user",
+          "request": {
+            "method": "GET",
+            "url": {"raw": "{{baseUrl}}/users/:id?full=true", "path": ["users", ":id"]}
+          },
+          "response": [
+            {
+              "name": "ok",
+              "code": 200,
+              "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "Content-Length", "value": "12"}],
+              "body": "{\"id\": \"1\"}"
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "health",
+      "request": {"method": "get", "url": "https://api.example.com/health?verbose"},
+      "response": [{"code": 204}]
+    },
+    {
+      "name": "hello",
+      "request": {"method": "POST", "url": "{{baseUrl}}/hello"},
+      "response": [{"code": 200, "header": [{"key": "Content-Type", "value": "text/plain"}], "body": "hello"}]
+    }
+  ]
+}`
+
+func TestGenerateMocks(t *testing.T) {
+
+	var collection Collection
+	err := json.Unmarshal([]byte(testCollection), &collection)
+	if err != nil {
+		t.Fatalf("collection unmarshal failed with error: %v", err)
+	}
+
+	mocks, err := GenerateMocks(collection)
+	if err != nil {
+		t.Fatalf("generate mocks failed with error: %v", err)
+	}
+
+	if len(mocks) != 3 {
+		t.Fatalf("generated mocks count is: %d, want: 3", len(mocks))
+	}
+
+	user := mocks[0]
+	if user.Name != "users/get user" || user.Request.UrlRegexStr != "/users/([^/]+)$" {
+		t.Errorf("user mock is: '%s' %s, want: 'users/get user' /users/([^/]+)$", user.Name, user.Request.UrlRegexStr)
+	}
+
+	if string(user.Response.Body) != `{"id": "1"}` || user.Response.Headers["Content-Length"] != "" {
+		t.Errorf("user mock response is: %s %v", string(user.Response.Body), user.Response.Headers)
+	}
+
+	health := mocks[1]
+	if health.Request.Method != "GET" || health.Request.Url != "/health" || health.Response.Status != 204 {
+		t.Errorf("health mock is: %s %s %d, want: GET /health 204", health.Request.Method, health.Request.Url, health.Response.Status)
+	}
+
+	hello := mocks[2]
+	if hello.Request.Url != "/hello" || string(hello.Response.Body) != `"hello"` {
+		t.Errorf("hello mock is: %s %s, want: /hello \"hello\"", hello.Request.Url, string(hello.Response.Body))
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DEFAULT_PERIOD = time.Second
+
+// Token bucket limiter: the bucket holds up to burst tokens, refilled with
+// requests tokens per period.
+type Limiter struct {
+	requests int
+	period   time.Duration
+	burst    float64
+	perToken time.Duration
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a full limiter, an empty period means a second and a
+// zero burst means the requests number.
+func NewLimiter(requests int, period string, burst int) (*Limiter, error) {
+
+	if requests <= 0 {
+		return nil, errors.New("rate limit requests must be positive, got " + strconv.Itoa(requests))
+	}
+
+	periodDuration := DEFAULT_PERIOD
+	if period != "" {
+
+		var err error
+		periodDuration, err = time.ParseDuration(period)
+		if err != nil || periodDuration <= 0 {
+			return nil, errors.New("rate limit period '" + period + "' is not a valid duration")
+		}
+	}
+
+	if burst <= 0 {
+		burst = requests
+	}
+
+	return &Limiter{
+		requests: requests,
+		period:   periodDuration,
+		burst:    float64(burst),
+		perToken: periodDuration / time.Duration(requests),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}, nil
+}
+
+// Allow takes a token, if none is left it returns false and the wait before
+// the next token.
+func (l *Limiter) Allow() (bool, time.Duration) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.perToken)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - l.tokens) * float64(l.perToken))
+}
+
+// Requests allowed by period, as a string to be used in headers.
+func (l *Limiter) String() string {
+
+	return strconv.Itoa(l.requests) + " per " + l.period.String()
+}
+
+func (l *Limiter) GetRequests() int {
+
+	return l.requests
+}
+
+// RetryAfterSeconds rounds up a wait, as the Retry-After header value.
+func RetryAfterSeconds(wait time.Duration) string {
+
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.Itoa(seconds)
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+
+	limiter, err := NewLimiter(10, "100ms", 2)
+	if err != nil {
+		t.Fatalf("limiter creation failed with error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow(); !allowed {
+			t.Errorf("request %d in burst should be allowed", i)
+		}
+	}
+
+	allowed, wait := limiter.Allow()
+	if allowed || wait <= 0 || wait > 10*time.Millisecond {
+		t.Errorf("request over burst is: %v, %v", allowed, wait)
+	}
+
+	time.Sleep(wait + time.Millisecond)
+	if allowed, _ := limiter.Allow(); !allowed {
+		t.Errorf("request after the wait should be allowed")
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+
+	limiter, err := NewLimiter(60, "1m", 0)
+	if err != nil || limiter.burst != 60 || limiter.perToken != time.Second {
+		t.Errorf("limiter is: %+v, %v", limiter, err)
+	}
+
+	if _, err := NewLimiter(0, "", 0); err == nil {
+		t.Errorf("zero requests should fail")
+	}
+
+	if _, err := NewLimiter(1, "minute", 0); err == nil {
+		t.Errorf("invalid period should fail")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+
+	tests := map[time.Duration]string{
+		0:                       "1",
+		300 * time.Millisecond:  "1",
+		time.Second:             "1",
+		1500 * time.Millisecond: "2",
+	}
+
+	for wait, want := range tests {
+		if got := RetryAfterSeconds(wait); got != want {
+			t.Errorf("retry after of %v is: %s, want: %s", wait, got, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Apis of the function files, a policy allows a list of them
+const (
+	API_JWT       = "jwt"
+	API_CRYPTO    = "crypto"
+	API_ENCODING  = "encoding"
+	API_FAKER     = "faker"
+	API_DB        = "db"
+	API_MESSAGING = "messaging"
+	API_STATE     = "state"
+	API_TIMERS    = "timers"
+	API_REQUIRE   = "require"
+	API_CONSOLE   = "console"
+
+	// http callbacks sent by alfred, rewritten by the callback function or
+	// returned by the onMessage function and the jobs
+	API_HTTP = "http"
+
+	// all the apis
+	API_ALL = "*"
+)
+
+var apis = []string{API_JWT, API_CRYPTO, API_ENCODING, API_FAKER, API_DB, API_MESSAGING, API_STATE, API_TIMERS, API_REQUIRE, API_CONSOLE, API_HTTP}
+
+// Sandbox policy of the function files matching one of its file name
+// patterns. Only the apis of the allowlist are available to the files,
+// durations are strings like 500ms.
+type Policy struct {
+	Name          string   `json:"name"`
+	Files         []string `json:"files,omitempty"`
+	Allow         []string `json:"allow,omitempty"`
+	MaxStackDepth int      `json:"max-stack-depth,omitempty"`
+	Timeout       string   `json:"timeout,omitempty"`
+}
+
+// Sandbox is a compiled policy. A nil sandbox runs the function files
+// without restriction.
+type Sandbox struct {
+	Policy string
+
+	// 0 when not limited
+	MaxStackDepth int
+	Timeout       time.Duration
+
+	files   []string
+	allowed map[string]bool
+}
+
+var (
+	mutex          sync.RWMutex
+	sandboxes      []*Sandbox
+	defaultSandbox *Sandbox
+)
+
+// Configure replaces the policies. The function files matching none of them
+// get the default policy, or run without restriction if it's empty.
+func Configure(policies []Policy, defaultPolicy string) error {
+
+	newSandboxes := []*Sandbox{}
+	var newDefault *Sandbox
+
+	for _, p := range policies {
+
+		s, err := compile(p)
+		if err != nil {
+			return err
+		}
+
+		for _, other := range newSandboxes {
+			if other.Policy == p.Name {
+				return errors.New("sandbox policy " + p.Name + " is defined twice")
+			}
+		}
+
+		if p.Name == defaultPolicy {
+			newDefault = s
+		}
+
+		newSandboxes = append(newSandboxes, s)
+	}
+
+	if defaultPolicy != "" && newDefault == nil {
+		return errors.New("default sandbox policy " + defaultPolicy + " not configured")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	sandboxes = newSandboxes
+	defaultSandbox = newDefault
+
+	return nil
+}
+
+// Get returns the sandbox of a function file, the first policy matching its
+// name wins.
+func Get(fileName string) *Sandbox {
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for _, s := range sandboxes {
+		for _, pattern := range s.files {
+			if matched, _ := path.Match(pattern, fileName); matched {
+				return s
+			}
+		}
+	}
+
+	return defaultSandbox
+}
+
+// Allows tells if the api is in the allowlist of the sandbox
+func (s *Sandbox) Allows(api string) bool {
+
+	if s == nil {
+		return true
+	}
+
+	return s.allowed[API_ALL] || s.allowed[api]
+}
+
+func compile(p Policy) (*Sandbox, error) {
+
+	if p.Name == "" {
+		return nil, errors.New("sandbox policy without name")
+	}
+
+	s := &Sandbox{Policy: p.Name, MaxStackDepth: p.MaxStackDepth, files: p.Files, allowed: map[string]bool{}}
+
+	if p.MaxStackDepth < 0 {
+		return nil, errors.New("sandbox policy " + p.Name + ": max-stack-depth must be positive")
+	}
+
+	for _, pattern := range p.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("sandbox policy " + p.Name + ": file pattern " + pattern + " is not valid")
+		}
+	}
+
+	for _, api := range p.Allow {
+
+		if api != API_ALL && !isApi(api) {
+			return nil, errors.New("sandbox policy " + p.Name + ": unknown api " + api + ", apis are " + strings.Join(apis, ", "))
+		}
+
+		s.allowed[api] = true
+	}
+
+	var err error
+
+	if p.Timeout != "" {
+		s.Timeout, err = time.ParseDuration(p.Timeout)
+		if err != nil || s.Timeout <= 0 {
+			return nil, errors.New("sandbox policy " + p.Name + ": timeout " + p.Timeout + " is not a positive duration")
+		}
+	}
+
+	return s, nil
+}
+
+func isApi(name string) bool {
+
+	for _, api := range apis {
+		if api == name {
+			return true
+		}
+	}
+
+	return false
+}
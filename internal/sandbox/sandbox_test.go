@@ -0,0 +1,108 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sandbox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+
+	err := Configure([]Policy{
+		{Name: "team-a", Files: []string{"team-a-*.js", "cart.ts"}, Allow: []string{API_STATE, API_FAKER}, MaxStackDepth: 200, Timeout: "500ms"},
+		{Name: "trusted", Files: []string{"*.js"}, Allow: []string{API_ALL}},
+		{Name: "locked"},
+	}, "locked")
+	if err != nil {
+		t.Fatalf("Configure failed with error %v", err)
+	}
+	defer Configure(nil, "")
+
+	tests := map[string]string{
+		"team-a-orders.js": "team-a",
+		"cart.ts":          "team-a",
+		"payments.js":      "trusted",
+		"payments.ts":      "locked",
+	}
+
+	for fileName, want := range tests {
+
+		s := Get(fileName)
+		if s == nil || s.Policy != want {
+			t.Errorf("policy of %s is: %v, want: %s.", fileName, s, want)
+		}
+	}
+
+	s := Get("team-a-orders.js")
+	if s.MaxStackDepth != 200 || s.Timeout != 500*time.Millisecond {
+		t.Errorf("team-a limits are: %d %s, want: 200 500ms.", s.MaxStackDepth, s.Timeout)
+	}
+
+	if !s.Allows(API_STATE) || s.Allows(API_DB) || s.Allows(API_HTTP) {
+		t.Errorf("team-a allowlist is: %v, want: state and faker.", s.allowed)
+	}
+
+	if !Get("payments.js").Allows(API_DB) {
+		t.Error("trusted policy should allow all the apis.")
+	}
+
+	if Get("payments.ts").Allows(API_CONSOLE) {
+		t.Error("locked policy should allow no api.")
+	}
+}
+
+func TestGetWithoutDefault(t *testing.T) {
+
+	err := Configure([]Policy{{Name: "team-a", Files: []string{"team-a-*.js"}}}, "")
+	if err != nil {
+		t.Fatalf("Configure failed with error %v", err)
+	}
+	defer Configure(nil, "")
+
+	s := Get("payments.js")
+	if s != nil {
+		t.Fatalf("policy of payments.js is: %s, want: none.", s.Policy)
+	}
+
+	if !s.Allows(API_DB) {
+		t.Error("function files without policy should use all the apis.")
+	}
+}
+
+func TestConfigureErrors(t *testing.T) {
+
+	tests := map[string][]Policy{
+		"unknown api":    {{Name: "a", Allow: []string{"fs"}}},
+		"negative stack": {{Name: "a", MaxStackDepth: -1}},
+		"bad timeout":    {{Name: "a", Timeout: "soon"}},
+		"bad pattern":    {{Name: "a", Files: []string{"[a-"}}},
+		"defined twice":  {{Name: "a"}, {Name: "a"}},
+		"no name":        {{Files: []string{"*.js"}}},
+	}
+
+	for name, policies := range tests {
+
+		if err := Configure(policies, ""); err == nil {
+			t.Errorf("%s: Configure should fail.", name)
+		}
+	}
+
+	if err := Configure([]Policy{{Name: "a"}}, "b"); err == nil {
+		t.Error("Configure should fail with an unknown default policy.")
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/function"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	ADMIN_UI_PATH    = ADMIN_PATH + "/ui"
+	ADMIN_MOCKS_PATH = ADMIN_PATH + "/mocks"
+	ADMIN_POOLS_PATH = ADMIN_PATH + "/pools"
+)
+
+// Single page of the admin ui, it polls the admin endpoints
+//
+//go:embed ui/index.html
+var adminUiPage []byte
+
+func AdminUi(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := w.Write(adminUiPage)
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
+
+// Mocks admin endpoint: GET returns the mocks served
+func MocksAdmin(w http.ResponseWriter, r *http.Request, mockCollection mock.MockCollection) {
+
+	writeAdminJson(w, r, mockCollection.GetMockInfoList())
+}
+
+// Pools admin endpoint: GET returns the usage of the javascript VM pools
+func PoolsAdmin(w http.ResponseWriter, r *http.Request) {
+
+	writeAdminJson(w, r, function.GetPoolsStats())
+}
+
+func writeAdminJson(w http.ResponseWriter, r *http.Request, data interface{}) {
+
+	body, _ := json.Marshal(data)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(body)
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
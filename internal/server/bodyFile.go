@@ -0,0 +1,52 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// serveBodyFile streams a mock body file. Without a specific status, range and
+// conditional requests are handled.
+func serveBodyFile(w http.ResponseWriter, r *http.Request, filePath string, status int) error {
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if status == 0 || status == http.StatusOK {
+		http.ServeContent(w, r, "", info.ModTime(), file)
+		return nil
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(status)
+
+	_, err = io.Copy(w, file)
+
+	return err
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/chaos"
+	"alfred/internal/log"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_PATH = "/__admin"
+const ADMIN_CHAOS_PATH = ADMIN_PATH + "/chaos"
+
+// Chaos admin endpoint: GET returns the profile, PUT replaces it and DELETE
+// disables chaos.
+func ChaosAdmin(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodPut:
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Error(r.Context(), "failed to read request body", err)
+			http.Error(w, "chaos profile error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		profile := chaos.GetProfile()
+		err = json.Unmarshal(data, &profile)
+		if err == nil {
+			err = chaos.SetProfile(profile)
+		}
+		if err != nil {
+			http.Error(w, "chaos profile error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Info(r.Context(), "chaos profile set", zap.String("chaos-profile", string(data)))
+
+	case http.MethodDelete:
+
+		chaos.Enable(false)
+		log.Info(r.Context(), "chaos disabled")
+	}
+
+	body, _ := json.Marshal(chaos.GetProfile())
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(body)
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	ENCODING_BROTLI  = "br"
+	ENCODING_GZIP    = "gzip"
+	ENCODING_DEFLATE = "deflate"
+)
+
+// Max size of the decoded request bodies when the max body size is not set,
+// compressed bodies would otherwise inflate without limit
+const DEFAULT_MAX_DECODED_BODY_SIZE = 10 << 20
+
+// Response encodings by order of preference
+var compressionEncodings = []string{ENCODING_BROTLI, ENCODING_GZIP, ENCODING_DEFLATE}
+
+// decompressRequestMiddleware decodes the compressed request bodies, mocks
+// matchers and js functions get the plain body. Decoded bodies over the max
+// body size, or the default max decoded body size if not set, are answered
+// with a 413.
+func decompressRequestMiddleware(maxBodySize int64) func(http.Handler) http.Handler {
+
+	if maxBodySize <= 0 {
+		maxBodySize = DEFAULT_MAX_DECODED_BODY_SIZE
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := decompressBody(encoding, r.Body, maxBodySize)
+			if errors.Is(err, errBodyTooLarge) {
+				serveBodyTooLarge(w, r, maxBodySize)
+				return
+			}
+			if err != nil {
+				http.Error(w, "request body decoding failed: "+err.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Del("Content-Encoding")
+			r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func decompressBody(encoding string, body io.Reader, maxSize int64) ([]byte, error) {
+
+	switch encoding {
+	case ENCODING_GZIP, "x-gzip":
+
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		return readLimited(reader, maxSize)
+
+	case ENCODING_DEFLATE:
+
+		// deflate is zlib wrapped, but some clients send raw deflate
+		buffered := bufio.NewReader(body)
+		if !hasZlibHeader(buffered) {
+			return readLimited(flate.NewReader(buffered), maxSize)
+		}
+
+		reader, err := zlib.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		return readLimited(reader, maxSize)
+
+	case ENCODING_BROTLI:
+
+		return readLimited(brotli.NewReader(body), maxSize)
+	}
+
+	return nil, errors.New("content encoding '" + encoding + "' not supported")
+}
+
+// hasZlibHeader checks the 2 bytes zlib header of a body: the deflate method
+// and a checksum multiple of 31.
+func hasZlibHeader(reader *bufio.Reader) bool {
+
+	header, err := reader.Peek(2)
+	if err != nil {
+		return false
+	}
+
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// negotiateEncoding returns the preferred response encoding accepted by the
+// client, empty if none.
+func negotiateEncoding(acceptEncoding string) string {
+
+	accepted := map[string]bool{}
+
+	for _, value := range strings.Split(acceptEncoding, ",") {
+
+		name, params, _ := strings.Cut(value, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		// q=0 means not acceptable
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+
+		accepted[name] = true
+	}
+
+	for _, encoding := range compressionEncodings {
+		if accepted[encoding] || accepted["*"] {
+			return encoding
+		}
+	}
+
+	return ""
+}
+
+// Response writer compressing the body once it reaches the minimum size,
+// smaller bodies are sent as is when the writer is closed.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buffer  []byte
+	encoder io.WriteCloser
+	skip    bool
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string, minSize int) *compressWriter {
+	return &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+
+	// bodies already encoded, partial or empty ones are not compressed
+	if cw.Header().Get("Content-Encoding") != "" || status == http.StatusPartialContent || status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		cw.skip = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.skip {
+		return cw.ResponseWriter.Write(data)
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(data)
+	}
+
+	cw.buffer = append(cw.buffer, data...)
+	if len(cw.buffer) >= cw.minSize {
+
+		err := cw.startCompression()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+func (cw *compressWriter) startCompression() error {
+
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	switch cw.encoding {
+	case ENCODING_BROTLI:
+		cw.encoder = brotli.NewWriter(cw.ResponseWriter)
+	case ENCODING_GZIP:
+		cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+	default:
+		cw.encoder, _ = zlib.NewWriterLevel(cw.ResponseWriter, zlib.DefaultCompression)
+	}
+
+	_, err := cw.encoder.Write(cw.buffer)
+	cw.buffer = nil
+
+	return err
+}
+
+// Flush compresses what has been written so far, streamed responses are not
+// held until the minimum size is reached.
+func (cw *compressWriter) Flush() {
+
+	if !cw.skip && cw.encoder == nil && cw.status != 0 {
+		if cw.startCompression() != nil {
+			return
+		}
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close ends the compressed body, or sends the small body uncompressed.
+func (cw *compressWriter) Close() error {
+
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+
+	if cw.skip || cw.status == 0 {
+		return nil
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	_, err := cw.ResponseWriter.Write(cw.buffer)
+	cw.buffer = nil
+
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/mock"
+	"context"
+	"net/http"
+	"time"
+)
+
+// Throttled bodies are sent by slices, every throttleInterval
+const throttleInterval = 100 * time.Millisecond
+
+// Response writer applying the mock connection behavior: connection closed
+// after the response, chunked transfer encoding forced, body throttled.
+type connectionWriter struct {
+	http.ResponseWriter
+	ctx            context.Context
+	chunked        bool
+	bytesPerSecond int
+	wroteHeader    bool
+}
+
+func newConnectionWriter(w http.ResponseWriter, r *http.Request, connection mock.MockConnection) *connectionWriter {
+
+	// HTTP/2 has no connection header, streams are multiplexed
+	if connection.KeepAlive != nil && !*connection.KeepAlive && r.ProtoMajor == 1 {
+		w.Header().Set("Connection", "close")
+	}
+
+	return &connectionWriter{
+		ResponseWriter: w,
+		ctx:            r.Context(),
+		chunked:        connection.Chunked,
+		bytesPerSecond: connection.BytesPerSecond,
+	}
+}
+
+func (cw *connectionWriter) WriteHeader(status int) {
+
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	// without content length, a flushed HTTP/1.1 response is chunked
+	if cw.chunked {
+		cw.Header().Del("Content-Length")
+	}
+
+	cw.ResponseWriter.WriteHeader(status)
+
+	if cw.chunked {
+		cw.Flush()
+	}
+}
+
+func (cw *connectionWriter) Write(data []byte) (int, error) {
+
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.bytesPerSecond <= 0 {
+		return cw.ResponseWriter.Write(data)
+	}
+
+	sliceSize := int(int64(cw.bytesPerSecond) * int64(throttleInterval) / int64(time.Second))
+	if sliceSize < 1 {
+		sliceSize = 1
+	}
+
+	written := 0
+	for written < len(data) {
+
+		end := written + sliceSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		n, err := cw.ResponseWriter.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		cw.Flush()
+
+		// the client is gone
+		select {
+		case <-cw.ctx.Done():
+			return written, cw.ctx.Err()
+		case <-time.After(time.Duration(n) * time.Second / time.Duration(cw.bytesPerSecond)):
+		}
+	}
+
+	return written, nil
+}
+
+func (cw *connectionWriter) Flush() {
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
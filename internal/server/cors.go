@@ -0,0 +1,163 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// corsMiddleware answers the preflight requests, with the CORS of the mock
+// serving the requested method and path or the global one, and adds the
+// global CORS headers to the other requests. Preflights of paths having an
+// OPTIONS mock are left to it.
+func corsMiddleware(global *mock.MockCors, mockCollection mock.MockCollection) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestedMethod := r.Header.Get("Access-Control-Request-Method")
+
+			if r.Method == http.MethodOptions && requestedMethod != "" && mockCollection.FindByRoute(http.MethodOptions, r.URL.Path) == nil {
+
+				cors := global
+				if m := mockCollection.FindByRoute(requestedMethod, r.URL.Path); m != nil && m.Cors != nil {
+					cors = m.Cors
+				}
+
+				if cors != nil {
+
+					log.Debug(r.Context(), "cors preflight answered", zap.String("request-path", r.RequestURI), zap.String("origin", origin))
+
+					setPreflightHeaders(w.Header(), *cors, origin, requestedMethod, r.Header.Get("Access-Control-Request-Headers"))
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+
+			if global != nil {
+				setCorsHeaders(w.Header(), *global, origin)
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func buildGlobalCors(config conf.CorsConfig) *mock.MockCors {
+
+	if !config.Enable {
+		return nil
+	}
+
+	return &mock.MockCors{
+		AllowedOrigins:   config.AllowedOrigins,
+		AllowedMethods:   config.AllowedMethods,
+		AllowedHeaders:   config.AllowedHeaders,
+		ExposedHeaders:   config.ExposedHeaders,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           config.MaxAge,
+	}
+}
+
+// setCorsHeaders replaces the CORS headers of a response, none if the origin
+// is not allowed.
+func setCorsHeaders(header http.Header, cors mock.MockCors, origin string) bool {
+
+	for name := range header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			header.Del(name)
+		}
+	}
+
+	allowedOrigin := getAllowedOrigin(cors, origin)
+	if allowedOrigin == "" {
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowedOrigin)
+	if allowedOrigin != "*" && !strings.Contains(strings.Join(header.Values("Vary"), ","), "Origin") {
+		header.Add("Vary", "Origin")
+	}
+
+	if cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if len(cors.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
+	}
+
+	return true
+}
+
+func setPreflightHeaders(header http.Header, cors mock.MockCors, origin string, requestedMethod string, requestedHeaders string) {
+
+	if !setCorsHeaders(header, cors, origin) {
+		return
+	}
+
+	header.Del("Access-Control-Expose-Headers")
+
+	if len(cors.AllowedMethods) > 0 {
+		header.Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+	} else {
+		header.Set("Access-Control-Allow-Methods", requestedMethod)
+	}
+
+	if len(cors.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+	} else if requestedHeaders != "" {
+		header.Set("Access-Control-Allow-Headers", requestedHeaders)
+	}
+
+	if cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	}
+}
+
+// The wildcard is not allowed with credentials, the origin is sent back.
+func getAllowedOrigin(cors mock.MockCors, origin string) string {
+
+	for _, allowed := range cors.AllowedOrigins {
+
+		if allowed == "*" {
+			if cors.AllowCredentials {
+				return origin
+			}
+			return "*"
+		}
+
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+
+	return ""
+}
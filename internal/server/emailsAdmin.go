@@ -0,0 +1,72 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/smtp"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const ADMIN_EMAILS_PATH = ADMIN_PATH + "/emails"
+
+// Emails admin endpoint: GET returns the mails captured by the smtp server,
+// filtered by the to, from and subject query args, DELETE clears them.
+// /__admin/emails/{id} returns a single mail.
+func EmailsAdmin(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method == http.MethodDelete {
+
+		smtp.Clear()
+
+		log.Info(r.Context(), "captured emails cleared")
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var data interface{}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/"+r.Method+ADMIN_EMAILS_PATH), "/")
+	if id != "" {
+
+		email, ok := smtp.Get(id)
+		if !ok {
+			http.Error(w, "email "+id+" not found", http.StatusNotFound)
+			return
+		}
+		data = email
+
+	} else {
+
+		query := r.URL.Query()
+		data = smtp.List(smtp.Filter{
+			To:      query.Get("to"),
+			From:    query.Get("from"),
+			Subject: query.Get("subject"),
+		})
+	}
+
+	body, _ := json.Marshal(data)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(body)
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/health"
+	"net/http"
+)
+
+const (
+	HEALTH_PATH = "/__health"
+	READY_PATH  = "/__ready"
+)
+
+type healthResponse struct {
+	Status string         `json:"status"`
+	Uptime string         `json:"uptime"`
+	Checks []health.Check `json:"checks,omitempty"`
+}
+
+// Liveness probe: Alfred answers, 503 once a request is unmatched in strict
+// mode. The mocks load and the function files compilation are reported, a
+// failed hot reload keeps the previous mocks served.
+func Health(w http.ResponseWriter, r *http.Request) {
+
+	alive, checks := health.IsAlive(health.CHECK_MOCKS, health.CHECK_FUNCTIONS)
+
+	response := healthResponse{Status: health.STATUS_UP, Uptime: health.Uptime().String(), Checks: checks}
+
+	if !alive {
+		response.Status = health.STATUS_DOWN
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	writeAdminJson(w, r, response)
+}
+
+// Readiness probe: 503 until the mocks are loaded, the function files
+// compiled and the listeners started, or while one of them is failing.
+func Ready(w http.ResponseWriter, r *http.Request) {
+
+	ready, checks := health.IsReady()
+
+	response := healthResponse{Status: health.STATUS_UP, Uptime: health.Uptime().String(), Checks: checks}
+
+	if !ready {
+		response.Status = health.STATUS_DOWN
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	writeAdminJson(w, r, response)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/cron"
+	"alfred/internal/log"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_JOBS_PATH = ADMIN_PATH + "/jobs"
+
+// Jobs admin endpoint: GET returns the scheduled jobs, their next run and
+// their last runs. POST /__admin/jobs/{name} runs a job now and returns the
+// run.
+func JobsAdmin(w http.ResponseWriter, r *http.Request) {
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/"+r.Method+ADMIN_JOBS_PATH), "/")
+
+	if r.Method == http.MethodPost {
+
+		run, err := cron.RunNow(r.Context(), name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		log.Info(r.Context(), "job run from the admin api", zap.String("job", name))
+
+		writeAdminJson(w, r, run)
+		return
+	}
+
+	jobs := cron.GetJobs()
+
+	if name != "" {
+
+		for _, job := range jobs {
+			if job.Name == name {
+				writeAdminJson(w, r, job)
+				return
+			}
+		}
+
+		http.Error(w, "job "+name+" not found", http.StatusNotFound)
+		return
+	}
+
+	writeAdminJson(w, r, jobs)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/latency"
+	"alfred/internal/log"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_LATENCY_PATH = ADMIN_PATH + "/latency-profiles"
+
+// Latency profiles admin endpoint: GET returns the profiles, PUT
+// /__admin/latency-profiles/{name} adds or replaces a profile and DELETE
+// restores its configured curve.
+func LatencyAdmin(w http.ResponseWriter, r *http.Request) {
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/"+r.Method+ADMIN_LATENCY_PATH), "/")
+
+	switch r.Method {
+	case http.MethodPut:
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Error(r.Context(), "failed to read request body", err)
+		}
+
+		var profile latency.Profile
+		err = json.Unmarshal(data, &profile)
+		if err == nil {
+			profile.Name = name
+			err = latency.Set(profile)
+		}
+		if err != nil {
+			http.Error(w, "latency profile error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Info(r.Context(), "latency profile set", zap.String("latency-profile", name), zap.String("curve", string(data)))
+
+	case http.MethodDelete:
+
+		err := latency.Reset(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		log.Info(r.Context(), "latency profile reset", zap.String("latency-profile", name))
+	}
+
+	writeAdminJson(w, r, latency.List())
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/log"
+	"alfred/pkg/metrics"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// limits of the metrics rejected requests
+const (
+	LIMIT_BODY_SIZE           = "body-size"
+	LIMIT_CONCURRENT_REQUESTS = "concurrent-requests"
+	LIMIT_CONNECTIONS         = "connections"
+)
+
+// Response of the connections over the listener max connections
+const CONNECTIONS_LIMIT_RESPONSE = "HTTP/1.1 503 Service Unavailable\r\nConnection: close\r\nRetry-After: 1\r\nContent-Length: 0\r\n\r\n"
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// Requests served by all the listeners
+var concurrentRequests atomic.Int64
+
+// limitsMiddleware answers a 503 to the requests over the max concurrent
+// requests, admin and probes excepted, and a 413 to the bodies larger than
+// the max body size. Bodies are read before the mocks get them.
+func limitsMiddleware(limits conf.LimitsConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			if limits.MaxConcurrentRequests > 0 && !isAdminPath(r.URL.Path) {
+
+				n := concurrentRequests.Add(1)
+				defer concurrentRequests.Add(-1)
+
+				if n > int64(limits.MaxConcurrentRequests) {
+
+					log.Debug(r.Context(), "max concurrent requests exceeded", zap.String("request-path", r.RequestURI))
+					metrics.IncRejectedRequests(LIMIT_CONCURRENT_REQUESTS)
+
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "too many concurrent requests, max "+strconv.Itoa(limits.MaxConcurrentRequests), http.StatusServiceUnavailable)
+					return
+				}
+			}
+
+			if limits.MaxBodySize > 0 && r.Body != nil && r.Body != http.NoBody {
+
+				if r.ContentLength > limits.MaxBodySize {
+					serveBodyTooLarge(w, r, limits.MaxBodySize)
+					return
+				}
+
+				body, err := readLimited(r.Body, limits.MaxBodySize)
+				if errors.Is(err, errBodyTooLarge) {
+					serveBodyTooLarge(w, r, limits.MaxBodySize)
+					return
+				}
+				if err != nil {
+					log.Error(r.Context(), "failed to read request body", err, zap.String("request-path", r.RequestURI))
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func isAdminPath(path string) bool {
+
+	return strings.HasPrefix(path, ADMIN_PATH) || path == HEALTH_PATH || path == READY_PATH
+}
+
+func serveBodyTooLarge(w http.ResponseWriter, r *http.Request, maxBodySize int64) {
+
+	log.Debug(r.Context(), "max body size exceeded", zap.String("request-path", r.RequestURI))
+	metrics.IncRejectedRequests(LIMIT_BODY_SIZE)
+
+	// the rest of the body is not read
+	w.Header().Set("Connection", "close")
+	http.Error(w, "request body too large, max "+strconv.FormatInt(maxBodySize, 10)+" bytes", http.StatusRequestEntityTooLarge)
+}
+
+// readLimited reads a body up to the max size, errBodyTooLarge if larger.
+func readLimited(reader io.Reader, maxSize int64) ([]byte, error) {
+
+	if maxSize <= 0 {
+		return io.ReadAll(reader)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return data, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+
+	return data, nil
+}
+
+// limitListener closes the connections over the max connections, after a
+// 503 response when they are not TLS ones.
+type limitListener struct {
+	net.Listener
+	max   int
+	tls   bool
+	mutex sync.Mutex
+	count int
+}
+
+func newLimitListener(listener net.Listener, max int, tls bool) net.Listener {
+
+	return &limitListener{Listener: listener, max: max, tls: tls}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+
+	for {
+
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return conn, err
+		}
+
+		l.mutex.Lock()
+		allowed := l.count < l.max
+		if allowed {
+			l.count++
+		}
+		l.mutex.Unlock()
+
+		if allowed {
+			return &limitConn{Conn: conn, release: l.release}, nil
+		}
+
+		metrics.IncRejectedRequests(LIMIT_CONNECTIONS)
+		log.Debug(context.Background(), "max connections exceeded on "+l.Addr().String(), zap.String("remote-addr", conn.RemoteAddr().String()))
+
+		if !l.tls {
+			_, _ = conn.Write([]byte(CONNECTIONS_LIMIT_RESPONSE))
+		}
+		conn.Close()
+	}
+}
+
+func (l *limitListener) release() {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.count--
+}
+
+type limitConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitConn) Close() error {
+
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+
+	return err
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"encoding/json"
+	"net/http"
+)
+
+// MockList answers requests not handled by a mock with the mock list.
+func MockList(w http.ResponseWriter, r *http.Request, mockCollection mock.MockCollection) {
+
+	mockList, _ := json.MarshalIndent(mockCollection.GetMockInfoList(), "", "   ")
+	_, err := w.Write([]byte("Hello Sir ! I take care ot the following mocks:\n" + string(mockList) + "\n\n(Alfred)"))
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
@@ -18,18 +18,27 @@ package server
 
 import (
 	"alfred/internal/action"
+	"alfred/internal/chaos"
+	"alfred/internal/conf"
 	"alfred/internal/function"
+	"alfred/internal/graphql"
 	"alfred/internal/helper"
 	"alfred/internal/log"
+	"alfred/internal/messaging"
 	"alfred/internal/mock"
+	"alfred/internal/soap"
 	"alfred/internal/tracing"
 	"alfred/pkg/detachcontext"
+	"alfred/pkg/metrics"
 	"alfred/pkg/request"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -37,342 +46,661 @@ import (
 	"go.uber.org/zap"
 )
 
-func AddMocksRoutes(mux *http.ServeMux, mockCollection mock.MockCollection, functions function.FunctionCollection, alfredGlobalDelay *time.Duration) {
+func AddMocksRoutes(mux *http.ServeMux, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection, functions function.FunctionCollection, alfredGlobalDelay *time.Duration, compression conf.CompressionConfig, unmatched *unmatchedPolicy) {
 
 	ctx := context.Background()
-	for _, m := range mockCollection.Mocks {
+	routes := mockCollection.GetRoutes()
+
+	patterns := map[string]bool{}
+	for _, route := range routes {
+		patterns[route.Pattern] = true
+	}
 
-		m := m
+	// function files with their own VMs pool
+	for _, m := range mockCollection.Mocks {
+		if m.HasFunctionPool() {
+			function.SetFunctionPool(m.FunctionFile, m.FunctionPool.MinSize, m.FunctionPool.MaxSize, m.FunctionPool.GetAcquireTimeout())
+		}
+	}
 
-		log.Debug(ctx, "Creating route for mock '"+m.GetName()+"'", zap.String("mock-url", m.GetRequestUrl()), zap.String("mock-conf", string(m.GetJsonBytes())))
+	for _, route := range routes {
 
-		mux.HandleFunc("/"+m.GetRequestMethod()+m.GetRequestUrl(), func(w http.ResponseWriter, r *http.Request) {
+		route := route
 
-			requestRecover(w, r)
-			ctx := r.Context()
+		for _, m := range route.Mocks {
+			log.Debug(ctx, "Creating route for mock '"+m.GetName()+"'", zap.String("mock-url", m.GetRequestUrl()), zap.String("mock-conf", string(m.GetJsonBytes())))
+		}
 
-			span := tracing.GetSpanFromContext(ctx)
-			span.SetAttributes(attribute.String("mockUsed", m.GetName()))
-			tracer := span.TracerProvider().Tracer(tracing.TracerName, trace.WithInstrumentationVersion(tracing.TracerVersion))
+		handler := func(w http.ResponseWriter, r *http.Request) {
 
-			helpersPopulated := []helper.Helper{}
-			var req request.Req
-			var res request.Res
+			requestRecover(w, r)
 
-			ctxReqDetailsSpan, reqDetailsSpan := tracer.Start(ctx, "get request details")
 			data, err := io.ReadAll(r.Body)
 			if err != nil {
-				log.Error(ctxReqDetailsSpan, "failed to read request body", err,
-					zap.String("mock-name", m.GetName()),
+				log.Error(r.Context(), "failed to read request body", err,
 					zap.String("request-path", r.RequestURI),
 				)
 			}
 
-			//req
-			{
-				req.Body = string(data)
-				req.Method = r.Method
-				req.SetHeaders(r.Header)
-				req.Url = r.RequestURI
-				req.SetQuery(r.URL.Query())
-			}
+			m, err := route.FindMock(r, data)
+			if err != nil {
+				log.Debug(r.Context(), "no mock matching the request",
+					zap.String("request-path", r.RequestURI),
+					zap.String("mismatches", err.Error()),
+				)
+				metrics.IncUnmatchedRequests(r.Method)
 
-			reqDetailsStr, _ := json.Marshal(req)
-			span.SetAttributes(attribute.String("requestDetails", string(reqDetailsStr)))
+				// mocks rejecting failing credentials
+				var authErr *mock.AuthError
+				if errors.As(err, &authErr) && authErr.Reject {
+					if authErr.Challenge != "" {
+						w.Header().Set("WWW-Authenticate", authErr.Challenge)
+					}
+					http.Error(w, http.StatusText(authErr.Status)+": "+authErr.Error(), authErr.Status)
+					return
+				}
 
-			reqDetailsSpan.End()
+				unmatched.serve(w, r, data)
+				return
+			}
 
-			log.Debug(ctx, "received a mock request, gona use mock '"+m.GetName()+"'",
-				zap.String("request-details", string(reqDetailsStr)),
-				zap.String("mock-conf", string(m.GetJsonBytes())),
-			)
+			if entry := log.GetAccessEntry(r.Context()); entry != nil {
+				entry.Mock = m.GetName()
+				entry.LogLevel = m.LogLevel
+			}
 
-			res.Body = m.GetResponseBody()
+			// logs of the mock use its level
+			if m.LogLevel != "" {
+				ctx, _ := log.WithLevel(r.Context(), m.LogLevel)
+				r = r.WithContext(ctx)
+			}
 
-			if m.HasHelper() {
-				span.SetAttributes(attribute.Bool("useHelper", true))
-				ctxHelper, helperSpan := tracer.Start(ctx, "manage helper(s)")
+			// mock rules of the response headers middleware
+			if m.ResponseHeaders != nil {
+				setMockResponseHeaders(r.Context(), m)
+			}
 
-				if m.HasRequestHelper() {
+			// mock CORS replace the global one
+			if m.Cors != nil && r.Header.Get("Origin") != "" {
+				setCorsHeaders(w.Header(), *m.Cors, r.Header.Get("Origin"))
+			}
 
-					ctxReqHelperSpan, reqHelperSpan := tracer.Start(ctxHelper, "populate request helper(s)")
+			if m.Connection != nil {
+				w = newConnectionWriter(w, r, *m.Connection)
+			}
 
-					log.Debug(ctxReqHelperSpan, "start to populate request helper(s)",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-					)
+			// response compression negotiated with the client
+			if m.IsCompressed(compression.Enable) {
+				if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding")); encoding != "" {
+					cw := newCompressWriter(w, encoding, compression.MinSize)
+					defer cw.Close()
+					w = cw
+				}
+			}
 
-					if m.HasRegexUrl() {
-						// Populate mock request helpers
-						pathHelpersPopulated, err := helper.PathHelperWatcher(r, m.GetPathRegexHelpers())
-						if err != nil {
-							log.Warn(ctxReqHelperSpan, "helpers path watcher in error", err,
-								zap.String("mock-name", m.GetName()),
-								zap.String("request-details", string(reqDetailsStr)),
-								zap.String("mock-conf", string(m.GetJsonBytes())),
-							)
-						}
-
-						helpersPopulated = append(helpersPopulated, pathHelpersPopulated...)
-					}
+			start := time.Now()
+			sw := newStatusWriter(w)
 
-					// Populate mock request helpers
-					requestHelpersPopulated, err := helper.RequestHelperWatcher([]byte(req.Body), r, m.GetRequestHelpers())
-					if err != nil {
-						log.Warn(ctxReqHelperSpan, "helpers request watcher in error", err,
-							zap.String("mock-name", m.GetName()),
-							zap.String("request-details", string(reqDetailsStr)),
-							zap.String("mock-conf", string(m.GetJsonBytes())),
-						)
-					}
+			if fault := chaos.GetFault(); fault != (chaos.Fault{}) {
 
-					helpersPopulated = append(helpersPopulated, requestHelpersPopulated...)
+				log.Debug(r.Context(), "chaos fault injected", zap.String("mock-name", m.GetName()), zap.Any("fault", fault))
 
-					log.Debug(ctxReqHelperSpan, "request helper(s) populated",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-						zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
-					)
+				// the connection is closed without response, once recorded
+				if fault.Drop {
+					metrics.ObserveMockRequest(m.GetName(), r.Method, 0, time.Since(start))
+					panic(http.ErrAbortHandler)
+				}
+
+				time.Sleep(fault.Delay)
 
-					reqHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(requestHelpersPopulated)))
-					reqHelperSpan.End()
+				if fault.Status != 0 {
+					http.Error(sw, "chaos fault injected", fault.Status)
+					metrics.ObserveMockRequest(m.GetName(), r.Method, sw.Status(), time.Since(start))
+					return
 				}
+			}
 
-				if m.HasDatetHelper() {
+			if allowed, wait := m.TakeRateLimitToken(); !allowed {
+				serveRateLimited(sw, r, m, wait)
+				metrics.ObserveMockRequest(m.GetName(), r.Method, sw.Status(), time.Since(start))
+				return
+			}
 
-					ctxDateHelperSpan, dateHelperSpan := tracer.Start(ctxHelper, "populate date helper(s)")
+			if m.IsStatic() {
+				serveStatic(sw, r, m)
+			} else {
+				serveMock(sw, r, m, data, functions, asyncRunningJobsCount, alfredGlobalDelay)
+			}
 
-					log.Debug(ctxDateHelperSpan, "start to populate date helper(s)",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-					)
+			metrics.ObserveMockRequest(m.GetName(), r.Method, sw.Status(), time.Since(start))
+		}
 
-					// Populate mock date helpers
-					dateHelpersPopulated, err := helper.DateWatcher(m.GetDateHelpers())
-					if err != nil {
-						log.Warn(ctxDateHelperSpan, "helpers date watcher in error", err,
-							zap.String("mock-name", m.GetName()),
-							zap.String("request-details", string(reqDetailsStr)),
-							zap.String("mock-conf", string(m.GetJsonBytes())),
-						)
-					}
+		mux.HandleFunc(route.Pattern, handler)
 
-					helpersPopulated = append(helpersPopulated, dateHelpersPopulated...)
+		// static mocks url without trailing slash, not redirected by the mux
+		// to the method prefixed path
+		if pattern := strings.TrimSuffix(route.Pattern, "/"); route.HasStaticMock() && !patterns[pattern] {
+			mux.HandleFunc(pattern, handler)
+		}
+	}
+}
 
-					log.Debug(ctxDateHelperSpan, "date helper(s) populated",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-						zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
-					)
+func serveMock(w http.ResponseWriter, r *http.Request, m *mock.Mock, data []byte, functions function.FunctionCollection, asyncRunningJobsCount *sync.WaitGroup, alfredGlobalDelay *time.Duration) {
 
-					dateHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(dateHelpersPopulated)))
-					dateHelperSpan.End()
-				}
+	ctx := r.Context()
 
-				if m.HasRandomHelper() {
+	span := tracing.GetSpanFromContext(ctx)
+	span.SetAttributes(attribute.String("mockUsed", m.GetName()))
+	tracing.SetSpanRoute(span, r.Method, m.GetRequestRoute())
+	tracer := span.TracerProvider().Tracer(tracing.TracerName, trace.WithInstrumentationVersion(tracing.TracerVersion))
 
-					ctxRandomHelperSpan, randomHelperSpan := tracer.Start(ctxHelper, "populate random helper(s)")
+	helpersPopulated := []helper.Helper{}
+	var req request.Req
+	var res request.Res
 
-					log.Debug(ctxRandomHelperSpan, "start to populate random helper(s)",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-					)
+	var err error
 
-					// Populate mock random helpers
-					randomHelpersPopulated, err := helper.RandomWatcher(m.GetRandomHelpers())
-					if err != nil {
-						log.Warn(ctxRandomHelperSpan, "helpers random watcher in error", err,
-							zap.String("mock-name", m.GetName()),
-							zap.String("request-details", string(reqDetailsStr)),
-							zap.String("mock-conf", string(m.GetJsonBytes())),
-						)
-					}
+	_, reqDetailsSpan := tracer.Start(ctx, "get request details")
 
-					helpersPopulated = append(helpersPopulated, randomHelpersPopulated...)
+	//req
+	{
+		req.Body = string(data)
+		req.Method = r.Method
+		req.SetHeaders(r.Header)
+		req.Url = r.RequestURI
+		req.SetQuery(r.URL.Query())
+		req.ClientCert = request.GetClientCert(r.TLS)
+		req.Auth = request.GetAuth(r)
+		req.Connection = request.GetConnection(r)
+	}
 
-					log.Debug(ctxRandomHelperSpan, "random helper(s) populated",
-						zap.String("mock-name", m.GetName()),
-						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-						zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
-					)
+	if m.IsGraphql() {
+		op, _ := graphql.ParseOperation(data)
+		req.Graphql = &op
+	}
 
-					randomHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(randomHelpersPopulated)))
-					randomHelperSpan.End()
-				}
+	if soap.IsXml(r.Header.Get("Content-Type")) {
+		req.BodyXml, err = soap.XmlToObject(data)
+		if err != nil {
+			log.Debug(ctx, "failed to parse xml request body", zap.String("mock-name", m.GetName()), zap.String("error", err.Error()))
+		}
+	}
 
-				//function JS
-				if m.HasFunctionFile() {
-					span.SetAttributes(attribute.Bool("useJsFunction", true))
-
-					ctxFuncFileHelperSpan, funcFileHelperSpan := tracer.Start(ctxHelper, "helper updater javascript function")
-					funcFileHelperSpan.SetAttributes(attribute.String("helpersBefore", helper.StringifyHelpers(helpersPopulated)))
-
-					f, _ := functions.GetFunction(m.FunctionFile)
-					if f.HasFuncUpdateHelpers {
-
-						helpersPopulated, err = f.UpdateHelpersListener(helpersPopulated)
-						if err != nil {
-							log.Error(ctxFuncFileHelperSpan, "error using user js update helper function", err)
-						}
-						log.Debug(ctxFuncFileHelperSpan, "update helper(s) populated with user js function",
-							zap.String("mock-name", m.GetName()),
-							zap.String("request-details", string(reqDetailsStr)),
-							zap.String("mock-conf", string(m.GetJsonBytes())),
-							zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
-						)
-					}
-					funcFileHelperSpan.SetAttributes(attribute.String("helpersAfter", helper.StringifyHelpers(helpersPopulated)))
-					funcFileHelperSpan.End()
-				}
+	if request.IsMultipart(r.Header.Get("Content-Type")) {
+		req.Multipart, err = request.ParseMultipart(r.Header.Get("Content-Type"), data)
+		if err != nil {
+			log.Debug(ctx, "failed to parse multipart request body", zap.String("mock-name", m.GetName()), zap.String("error", err.Error()))
+		}
+	}
 
-				ctxReplaceHelperSpan, replaceHelperSpan := tracer.Start(ctxHelper, "build response with helper(s) value(s)")
+	reqDetailsStr, _ := json.Marshal(req)
+	span.SetAttributes(attribute.String("requestDetails", string(reqDetailsStr)))
 
-				// Replace helpers inside mock response body
-				res.Body, err = helper.HelperReplacement(res.Body, helpersPopulated)
+	reqDetailsSpan.End()
 
-				// Set helpers inside mock response headers and set
-				for k, v := range m.GetResponseHeaders() {
+	log.Debug(ctx, "received a mock request, gona use mock '"+m.GetName()+"'",
+		zap.String("request-details", string(reqDetailsStr)),
+		zap.String("mock-conf", string(m.GetJsonBytes())),
+	)
 
-					v, err = helper.HelperReplacement(v, helpersPopulated)
-					res.SetHeader(k, v)
-				}
+	//graphql schema validation
+	if m.HasGraphqlSchema() {
+
+		errs := graphql.Validate(m.Request.Graphql.Schema, *req.Graphql)
+		if errs != nil {
+			log.Warn(ctx, "graphql operation not valid against the mock schema", errs,
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+			)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, err = w.Write(graphql.ErrorsBody(errs))
+			if err != nil {
+				log.Error(ctx, "failed to write", err)
+			}
+			return
+		}
+	}
+
+	res.Body = m.GetResponseBody()
+
+	if m.HasHelper() {
+		span.SetAttributes(attribute.Bool("useHelper", true))
+		ctxHelper, helperSpan := tracer.Start(ctx, "manage helper(s)")
+
+		if m.HasRequestHelper() || m.HasPathRegexHelper() {
 
+			ctxReqHelperSpan, reqHelperSpan := tracer.Start(ctxHelper, "populate request helper(s)")
+
+			log.Debug(ctxReqHelperSpan, "start to populate request helper(s)",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+			)
+
+			if m.HasRegexUrl() {
+				// Populate mock request helpers
+				pathHelpersPopulated, err := helper.PathHelperWatcher(r, m.GetPathRegexHelpers())
 				if err != nil {
-					log.Warn(ctxReplaceHelperSpan, "error during helpers replacement", err,
+					log.Warn(ctxReqHelperSpan, "helpers path watcher in error", err,
+						zap.String("mock-name", m.GetName()),
 						zap.String("request-details", string(reqDetailsStr)),
 						zap.String("mock-conf", string(m.GetJsonBytes())),
-						zap.String("response-body", res.Body),
-						zap.String("helpers", helper.StringifyHelpers(helpersPopulated)))
+					)
 				}
 
-				replaceHelperSpan.End()
-				helperSpan.End()
+				helpersPopulated = append(helpersPopulated, pathHelpersPopulated...)
+			}
 
-			} else {
+			// Populate mock request helpers
+			requestHelpersPopulated, err := helper.RequestHelperWatcher([]byte(req.Body), r, m.GetRequestHelpers())
+			if err != nil {
+				log.Warn(ctxReqHelperSpan, "helpers request watcher in error", err,
+					zap.String("mock-name", m.GetName()),
+					zap.String("request-details", string(reqDetailsStr)),
+					zap.String("mock-conf", string(m.GetJsonBytes())),
+				)
+			}
+
+			helpersPopulated = append(helpersPopulated, requestHelpersPopulated...)
+
+			log.Debug(ctxReqHelperSpan, "request helper(s) populated",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+			)
+
+			reqHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(requestHelpersPopulated)))
+			reqHelperSpan.End()
+		}
+
+		if m.HasDatetHelper() {
+
+			ctxDateHelperSpan, dateHelperSpan := tracer.Start(ctxHelper, "populate date helper(s)")
+
+			log.Debug(ctxDateHelperSpan, "start to populate date helper(s)",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+			)
+
+			// Populate mock date helpers
+			dateHelpersPopulated, err := helper.DateWatcher(m.GetDateHelpers())
+			if err != nil {
+				log.Warn(ctxDateHelperSpan, "helpers date watcher in error", err,
+					zap.String("mock-name", m.GetName()),
+					zap.String("request-details", string(reqDetailsStr)),
+					zap.String("mock-conf", string(m.GetJsonBytes())),
+				)
+			}
+
+			helpersPopulated = append(helpersPopulated, dateHelpersPopulated...)
+
+			log.Debug(ctxDateHelperSpan, "date helper(s) populated",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+			)
+
+			dateHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(dateHelpersPopulated)))
+			dateHelperSpan.End()
+		}
+
+		if m.HasRandomHelper() {
 
-				//set headers
-				res.Headers = m.GetResponseHeaders()
+			ctxRandomHelperSpan, randomHelperSpan := tracer.Start(ctxHelper, "populate random helper(s)")
+
+			log.Debug(ctxRandomHelperSpan, "start to populate random helper(s)",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+			)
+
+			// Populate mock random helpers
+			randomHelpersPopulated, err := helper.RandomWatcher(m.GetRandomHelpers())
+			if err != nil {
+				log.Warn(ctxRandomHelperSpan, "helpers random watcher in error", err,
+					zap.String("mock-name", m.GetName()),
+					zap.String("request-details", string(reqDetailsStr)),
+					zap.String("mock-conf", string(m.GetJsonBytes())),
+				)
 			}
 
-			res.Status = m.GetResponseStatus()
+			helpersPopulated = append(helpersPopulated, randomHelpersPopulated...)
+
+			log.Debug(ctxRandomHelperSpan, "random helper(s) populated",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+			)
+
+			randomHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(randomHelpersPopulated)))
+			randomHelperSpan.End()
+		}
 
-			//delay the request
-			ctxDelaySpan, delaySpan := tracer.Start(ctx, "delay response")
-			{
-				delay := m.GetDelay() + *alfredGlobalDelay
+		if m.HasGeneratorHelper() {
 
-				log.Debug(ctxDelaySpan, "request delayed with an offset of "+fmt.Sprint(int64(delay/time.Millisecond))+" millisecond(s)",
+			ctxGeneratorHelperSpan, generatorHelperSpan := tracer.Start(ctxHelper, "populate generated helper(s)")
+
+			// Populate mock uuid, counter, regex and custom helpers
+			generatorHelpersPopulated, err := helper.GeneratorWatcher(req, m.GetGeneratorHelpers())
+			if err != nil {
+				log.Warn(ctxGeneratorHelperSpan, "helpers generator watcher in error", err,
 					zap.String("mock-name", m.GetName()),
 					zap.String("request-details", string(reqDetailsStr)),
 					zap.String("mock-conf", string(m.GetJsonBytes())),
 				)
-				time.Sleep(delay)
 			}
-			delaySpan.End()
 
-			//function JS
-			if m.HasFunctionFile() {
+			helpersPopulated = append(helpersPopulated, generatorHelpersPopulated...)
 
-				span.SetAttributes(attribute.Bool("useJsFunction", true))
-				ctxAlfredJsFuncSpan, alfredJsFuncSpan := tracer.Start(ctx, "alfred javascript function")
+			log.Debug(ctxGeneratorHelperSpan, "generated helper(s) populated",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+			)
 
-				f, _ := functions.GetFunction(m.FunctionFile)
-				if f.HasFuncAlfred {
+			generatorHelperSpan.SetAttributes(attribute.String("helpers", helper.StringifyHelpers(generatorHelpersPopulated)))
+			generatorHelperSpan.End()
+		}
+
+		//function JS
+		if m.HasFunctionFile() {
+			span.SetAttributes(attribute.Bool("useJsFunction", true))
+
+			ctxFuncFileHelperSpan, funcFileHelperSpan := tracer.Start(ctxHelper, "helper updater javascript function")
+			funcFileHelperSpan.SetAttributes(attribute.String("function-file", m.FunctionFile))
+			funcFileHelperSpan.SetAttributes(attribute.String("helpersBefore", helper.StringifyHelpers(helpersPopulated)))
+
+			f, _ := functions.GetFunction(m.FunctionFile)
+			if f.HasFuncUpdateHelpers {
+
+				helpersPopulated, err = f.UpdateHelpersListener(helpersPopulated)
+				if err != nil {
+					tracing.SetSpanStatusError(&funcFileHelperSpan, err)
+					log.Error(ctxFuncFileHelperSpan, "error using user js update helper function", err)
+				}
+				log.Debug(ctxFuncFileHelperSpan, "update helper(s) populated with user js function",
+					zap.String("mock-name", m.GetName()),
+					zap.String("request-details", string(reqDetailsStr)),
+					zap.String("mock-conf", string(m.GetJsonBytes())),
+					zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+				)
+			}
+			funcFileHelperSpan.SetAttributes(attribute.String("helpersAfter", helper.StringifyHelpers(helpersPopulated)))
+			funcFileHelperSpan.End()
+		}
+
+		ctxReplaceHelperSpan, replaceHelperSpan := tracer.Start(ctxHelper, "build response with helper(s) value(s)")
+
+		// Replace helpers inside mock response body
+		if soap.IsXml(m.GetResponseHeader("Content-Type")) {
+			res.Body, err = helper.XmlHelperReplacement(res.Body, helpersPopulated)
+		} else {
+			res.Body, err = helper.HelperReplacement(res.Body, helpersPopulated)
+		}
+
+		// Set helpers inside mock response headers and set
+		for k, v := range m.GetResponseHeaders() {
+
+			v, err = helper.HelperReplacement(v, helpersPopulated)
+			res.SetHeader(k, v)
+		}
+
+		if err != nil {
+			log.Warn(ctxReplaceHelperSpan, "error during helpers replacement", err,
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("response-body", res.Body),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)))
+		}
+
+		replaceHelperSpan.End()
+		helperSpan.End()
+
+	} else {
+
+		//set headers
+		res.Headers = m.GetResponseHeaders()
+	}
+
+	res.Status = m.GetResponseStatus()
+
+	//delay the request
+	ctxDelaySpan, delaySpan := tracer.Start(ctx, "delay response")
+	{
+		delay := m.GetDelay() + *alfredGlobalDelay
+
+		log.Debug(ctxDelaySpan, "request delayed with an offset of "+fmt.Sprint(int64(delay/time.Millisecond))+" millisecond(s)",
+			zap.String("mock-name", m.GetName()),
+			zap.String("request-details", string(reqDetailsStr)),
+			zap.String("mock-conf", string(m.GetJsonBytes())),
+		)
+		time.Sleep(delay)
+	}
+	delaySpan.End()
+
+	//function JS
+	if m.HasFunctionFile() {
+
+		span.SetAttributes(attribute.Bool("useJsFunction", true))
+		ctxAlfredJsFuncSpan, alfredJsFuncSpan := tracer.Start(ctx, "alfred javascript function")
+		alfredJsFuncSpan.SetAttributes(attribute.String("function-file", m.FunctionFile))
+
+		f, _ := functions.GetFunction(m.FunctionFile)
+		if f.HasFuncAlfred {
+
+			res, err = f.AlfredFunc(*m, helpersPopulated, req, res)
+			if err != nil {
+				tracing.SetSpanStatusError(&alfredJsFuncSpan, err)
+				log.Error(ctx, "error using user js alfred function", err)
+			}
+			log.Debug(ctxAlfredJsFuncSpan, "use user js alfred function",
+				zap.String("mock-name", m.GetName()),
+				zap.String("request-details", string(reqDetailsStr)),
+				zap.String("mock-conf", string(m.GetJsonBytes())),
+				zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
+				zap.String("response", res.Stringify()),
+			)
+		}
+		alfredJsFuncSpan.End()
+	}
+
+	//set response headers
+	{
+		for k, v := range res.Headers {
+			w.Header().Set(k, v)
+		}
+	}
+
+	//req context end with c.String call, so save it for actions
+	detachedCtx := detachcontext.Detach(ctx)
+
+	//set status and body to end response
+	if m.IsStream() {
+
+		err = serveStream(w, r, m, req, res, helpersPopulated, functions)
+		if errors.Is(err, context.Canceled) {
+			log.Debug(ctx, "stream ended by the client", zap.String("mock-name", m.GetName()))
+			err = nil
+		}
+
+	} else if m.GetBodyFilePath() != "" && res.Body == "" {
+
+		err = serveBodyFile(w, r, m.GetBodyFilePath(), res.Status)
+
+	} else {
+
+		if res.Status != 0 {
+			w.WriteHeader(res.Status)
+		}
+
+		_, err = w.Write([]byte(res.Body))
+	}
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
 
-					res, err = f.AlfredFunc(*m, helpersPopulated, req, res)
+	//handle actions
+	{
+
+		for _, act := range m.GetActions() {
+
+			//gourtouine, waited at shutdown
+			asyncRunningJobsCount.Add(1)
+			go func(act mock.MockAction) {
+				defer asyncRunningJobsCount.Done()
+
+				ctx, alfredActionsSpan := tracer.Start(detachedCtx, "action")
+
+				if act.Type == action.SEND_REQUEST_TYPE {
+
+					_, alfredDelayActionsSpan := tracer.Start(ctx, "delay action")
+
+					delay := action.GetDelayDuration(act)
+					log.Debug(ctx, "action delayed for "+fmt.Sprint(delay),
+						zap.String("mock-name", m.GetName()),
+						zap.String("request-details", string(reqDetailsStr)),
+						zap.String("action-type", act.Type),
+					)
+					time.Sleep(delay)
+					alfredDelayActionsSpan.End()
+
+					req, err := action.CreateRequestFromMockAction(act, helpersPopulated)
 					if err != nil {
-						log.Error(ctx, "error using user js alfred function", err)
+						log.Error(ctx, "create request from mock action failed", err)
 					}
-					log.Debug(ctxAlfredJsFuncSpan, "use user js alfred function",
+
+					resp, err := req.Send(ctx)
+					if err != nil {
+						log.Error(ctx, "", err)
+					}
+
+					log.Debug(ctx, "action ended",
 						zap.String("mock-name", m.GetName()),
 						zap.String("request-details", string(reqDetailsStr)),
-						zap.String("mock-conf", string(m.GetJsonBytes())),
-						zap.String("helpers", helper.StringifyHelpers(helpersPopulated)),
-						zap.String("response", res.Stringify()),
+						zap.String("action-type", act.Type),
+						zap.String("action-reqMethod", req.GetMethod()),
+						zap.String("action-reqHeaders", fmt.Sprint(req.Headers)),
+						zap.String("action-reqTargeturl", req.GetBaseUrl()),
+						zap.String("action-reqBody", string(req.Body)),
+						zap.String("action-responseStatus", resp.Status),
+						zap.String("action-responseBody", resp.Body),
+						zap.String("action-responseHeaders", fmt.Sprint(resp.Headers)),
 					)
 				}
-				alfredJsFuncSpan.End()
-			}
 
-			//set response headers
-			{
-				for k, v := range res.Headers {
-					w.Header().Set(k, v)
-				}
-			}
+				alfredActionsSpan.End()
 
-			//req context end with c.String call, so save it for actions
-			detachedCtx := detachcontext.Detach(ctx)
+			}(act)
 
-			//set status and body to end response
-			if res.Status != 0 {
-				w.WriteHeader(res.Status)
-			}
+		}
+	}
+
+	//handle callbacks
+	for _, cb := range m.GetCallbacks() {
+
+		asyncRunningJobsCount.Add(1)
+		go func(cb mock.MockCallback) {
+			defer asyncRunningJobsCount.Done()
+
+			ctx, callbackSpan := tracer.Start(detachedCtx, "callback")
+			defer callbackSpan.End()
 
-			_, err = w.Write([]byte(res.Body))
+			callback, err := action.CreateCallback(cb, helpersPopulated)
 			if err != nil {
-				log.Error(r.Context(), "failed to write", err)
+				tracing.SetSpanStatusError(&callbackSpan, err)
+				log.Error(ctx, "create callback failed", err, zap.String("mock-name", m.GetName()))
+				return
 			}
 
-			//handle actions
-			{
+			//function JS
+			if m.HasFunctionFile() {
+
+				f, _ := functions.GetFunction(m.FunctionFile)
+				if f.HasFuncCallback {
 
-				for _, act := range m.GetActions() {
+					callback, err = f.CallbackFunc(*m, helpersPopulated, req, res, callback)
+					if err != nil {
+						tracing.SetSpanStatusError(&callbackSpan, err)
+						log.Error(ctx, "error using user js callback function", err, zap.String("mock-name", m.GetName()))
+						return
+					}
+				}
+			}
 
-					//gourtouine
-					go func(act mock.MockAction) {
+			_, callbackDelaySpan := tracer.Start(ctx, "delay callback")
+			log.Debug(ctx, "callback delayed for "+fmt.Sprint(callback.GetDelayDuration()),
+				zap.String("mock-name", m.GetName()),
+				zap.String("callback-url", callback.Url),
+			)
+			time.Sleep(callback.GetDelayDuration())
+			callbackDelaySpan.End()
 
-						ctx, alfredActionsSpan := tracer.Start(detachedCtx, "action")
+			cbReq, err := callback.CreateRequest()
+			if err != nil {
+				tracing.SetSpanStatusError(&callbackSpan, err)
+				log.Error(ctx, "create callback request failed", err, zap.String("mock-name", m.GetName()))
+				return
+			}
 
-						if act.Type == action.SEND_REQUEST_TYPE {
+			resp, err := cbReq.Send(ctx)
+			if err != nil {
+				tracing.SetSpanStatusError(&callbackSpan, err)
+				log.Error(ctx, "callback failed", err, zap.String("mock-name", m.GetName()), zap.String("callback-url", callback.Url))
+				return
+			}
 
-							_, alfredDelayActionsSpan := tracer.Start(ctx, "delay action")
+			log.Debug(ctx, "callback sent",
+				zap.String("mock-name", m.GetName()),
+				zap.String("callback-method", cbReq.GetMethod()),
+				zap.String("callback-url", cbReq.GetBaseUrl()),
+				zap.String("callback-body", string(cbReq.Body)),
+				zap.String("callback-responseStatus", resp.Status),
+			)
 
-							delay := action.GetDelayDuration(act)
-							log.Debug(ctx, "action delayed for "+fmt.Sprint(delay),
-								zap.String("mock-name", m.GetName()),
-								zap.String("request-details", string(reqDetailsStr)),
-								zap.String("action-type", act.Type),
-							)
-							time.Sleep(delay)
-							alfredDelayActionsSpan.End()
+		}(cb)
+	}
 
-							req, err := action.CreateRequestFromMockAction(act, helpersPopulated)
-							if err != nil {
-								log.Error(ctx, "create request from mock action failed", err)
-							}
+	//handle messages
+	for _, mm := range m.GetMessages() {
 
-							resp, err := req.Send(ctx)
-							if err != nil {
-								log.Error(ctx, "", err)
-							}
+		asyncRunningJobsCount.Add(1)
+		go func(mm mock.MockMessage) {
+			defer asyncRunningJobsCount.Done()
 
-							log.Debug(ctx, "action ended",
-								zap.String("mock-name", m.GetName()),
-								zap.String("request-details", string(reqDetailsStr)),
-								zap.String("action-type", act.Type),
-								zap.String("action-reqMethod", req.GetMethod()),
-								zap.String("action-reqHeaders", fmt.Sprint(req.Headers)),
-								zap.String("action-reqTargeturl", req.GetBaseUrl()),
-								zap.String("action-reqBody", string(req.Body)),
-								zap.String("action-responseStatus", resp.Status),
-								zap.String("action-responseBody", resp.Body),
-								zap.String("action-responseHeaders", fmt.Sprint(resp.Headers)),
-							)
-						}
+			ctx, messageSpan := tracer.Start(detachedCtx, "message")
+			defer messageSpan.End()
 
-						alfredActionsSpan.End()
+			msg, err := action.CreateMessage(mm, helpersPopulated)
+			if err != nil {
+				tracing.SetSpanStatusError(&messageSpan, err)
+				log.Error(ctx, "create message failed", err, zap.String("mock-name", m.GetName()))
+				return
+			}
 
-					}(act)
+			time.Sleep(action.GetMessageDelayDuration(mm))
 
-				}
+			err = messaging.Publish(ctx, msg)
+			if err != nil {
+				tracing.SetSpanStatusError(&messageSpan, err)
+				log.Error(ctx, "message publication failed", err, zap.String("mock-name", m.GetName()), zap.String("topic", msg.Topic))
+				return
 			}
-		})
+
+			log.Debug(ctx, "message published",
+				zap.String("mock-name", m.GetName()),
+				zap.String("broker", msg.Broker),
+				zap.String("topic", msg.Topic),
+				zap.String("message-key", msg.Key),
+				zap.String("message-body", msg.Body),
+			)
+
+		}(mm)
 	}
 }
@@ -0,0 +1,37 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/oidc"
+	"net/http"
+)
+
+// Identity provider endpoints, under the oidc path.
+func addOidcRoutes(mux *http.ServeMux) {
+
+	path := oidc.GetPath()
+
+	mux.HandleFunc("/"+http.MethodGet+path+oidc.DISCOVERY_PATH, oidc.Discovery)
+	mux.HandleFunc("/"+http.MethodGet+path+oidc.JWKS_PATH, oidc.Jwks)
+	mux.HandleFunc("/"+http.MethodPost+path+oidc.TOKEN_PATH, oidc.Token)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		mux.HandleFunc("/"+method+path+oidc.AUTHORIZE_PATH, oidc.Authorize)
+		mux.HandleFunc("/"+method+path+oidc.USERINFO_PATH, oidc.Userinfo)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/openapi"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Largest response body kept to be validated, larger bodies are sent as
+// they are written and not validated.
+const MAX_VALIDATED_BODY_SIZE = 1 << 20
+
+// Response kept in memory to be validated before being sent. Once the mock
+// flushes it, streams and throttled responses, or once its body is larger
+// than MAX_VALIDATED_BODY_SIZE, it's passed through as it's written: it's
+// still validated but can't be replaced by an error anymore.
+type responseRecorder struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+
+	// writes are passed through to w
+	passThrough bool
+
+	// body over the max size, not validated
+	tooLarge bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{w: w, header: http.Header{}, status: http.StatusOK}
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+
+	if !rec.tooLarge && rec.body.Len()+len(data) > MAX_VALIDATED_BODY_SIZE {
+
+		if err := rec.startPassThrough(); err != nil {
+			return 0, err
+		}
+
+		rec.tooLarge = true
+		rec.body = bytes.Buffer{}
+	}
+
+	if !rec.passThrough {
+		return rec.body.Write(data)
+	}
+
+	if !rec.tooLarge {
+		rec.body.Write(data)
+	}
+
+	return rec.w.Write(data)
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *responseRecorder) Flush() {
+
+	if err := rec.startPassThrough(); err != nil {
+		return
+	}
+
+	if flusher, ok := rec.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// startPassThrough sends the response kept so far, the next writes are
+// passed through.
+func (rec *responseRecorder) startPassThrough() error {
+
+	if rec.passThrough {
+		return nil
+	}
+
+	rec.passThrough = true
+
+	for k, v := range rec.header {
+		rec.w.Header()[k] = v
+	}
+	rec.w.WriteHeader(rec.status)
+
+	_, err := rec.w.Write(rec.body.Bytes())
+
+	return err
+}
+
+// decodedResponse returns the headers and the body of the response before
+// it was compressed by the mock.
+func (rec *responseRecorder) decodedResponse() (http.Header, []byte, error) {
+
+	encoding := strings.ToLower(strings.TrimSpace(rec.header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return rec.header, rec.body.Bytes(), nil
+	}
+
+	body, err := decompressBody(encoding, bytes.NewReader(rec.body.Bytes()), MAX_VALIDATED_BODY_SIZE)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := rec.header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	return header, body, nil
+}
+
+// Middleware validating requests and mocks responses against the openapi
+// spec. Violations are logged, or answered with an error status in strict mode.
+func openapiValidationMiddleware(validator *openapi.Validator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			input := validator.FindRoute(r)
+			if input == nil {
+				log.Debug(r.Context(), "request not described by the openapi spec, not validated",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Error(r.Context(), "failed to read request body", err)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			err = validator.ValidateRequest(r.Context(), input, body)
+			if err != nil {
+
+				log.Warn(r.Context(), "request does not comply with the openapi spec", err,
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+				)
+
+				if validator.Strict {
+					http.Error(w, "request does not comply with the openapi spec: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r)
+
+			if recorder.tooLarge {
+				log.Debug(r.Context(), "mock response larger than the validated responses, not validated",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+				)
+				return
+			}
+
+			header, body, err := recorder.decodedResponse()
+			if err != nil {
+				log.Warn(r.Context(), "compressed mock response not decoded, not validated", err,
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+				)
+
+				err = recorder.startPassThrough()
+				if err != nil {
+					log.Error(r.Context(), "failed to write", err)
+				}
+				return
+			}
+
+			err = validator.ValidateResponse(r.Context(), input, recorder.status, header, body)
+			if err != nil {
+
+				log.Warn(r.Context(), "mock response does not comply with the openapi spec", err,
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Int("status", recorder.status),
+				)
+
+				// a response already sent is not replaced
+				if validator.Strict && !recorder.passThrough {
+					http.Error(w, "mock response does not comply with the openapi spec: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+
+			err = recorder.startPassThrough()
+			if err != nil {
+				log.Error(r.Context(), "failed to write", err)
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_PROFILES_PATH = ADMIN_PATH + "/profiles"
+
+// Active profiles and tags of the served mocks
+type profilesResponse struct {
+	Profiles []string `json:"profiles"`
+	Tags     []string `json:"tags"`
+}
+
+// Profiles admin endpoint: GET returns the active profiles and the mocks tags,
+// PUT replaces the active profiles and DELETE deactivates them, only the
+// untagged mocks are then served.
+func ProfilesAdmin(w http.ResponseWriter, r *http.Request, mockCollection mock.MockCollection) {
+
+	switch r.Method {
+	case http.MethodPut:
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Error(r.Context(), "failed to read request body", err)
+		}
+
+		var profiles profilesResponse
+		err = json.Unmarshal(data, &profiles)
+		if err != nil {
+			http.Error(w, "profiles error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mock.SetProfiles(profiles.Profiles)
+		log.Info(r.Context(), "profiles set", zap.String("profiles", strings.Join(mock.GetProfiles(), ",")))
+
+	case http.MethodDelete:
+
+		mock.SetProfiles(nil)
+		log.Info(r.Context(), "profiles deactivated")
+	}
+
+	writeAdminJson(w, r, profilesResponse{Profiles: mock.GetProfiles(), Tags: mockCollection.GetTags()})
+}
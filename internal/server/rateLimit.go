@@ -0,0 +1,90 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/helper"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"alfred/internal/ratelimit"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const RATE_LIMIT_BODY = "rate limit exceeded"
+
+// Global rate limit, admin endpoints and probes are not limited
+func rateLimitMiddleware(limiter *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			if !isAdminPath(r.URL.Path) {
+
+				if allowed, wait := limiter.Allow(); !allowed {
+
+					log.Debug(r.Context(), "global rate limit exceeded", zap.String("request-path", r.RequestURI))
+
+					w.Header().Set("Retry-After", ratelimit.RetryAfterSeconds(wait))
+					http.Error(w, RATE_LIMIT_BODY, http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Answer a request over the mock rate limit, with the mock rate limit
+// response if set.
+func serveRateLimited(w http.ResponseWriter, r *http.Request, m *mock.Mock, wait time.Duration) {
+
+	log.Debug(r.Context(), "mock rate limit exceeded", zap.String("mock-name", m.GetName()))
+
+	retryAfter := ratelimit.RetryAfterSeconds(wait)
+
+	response := m.RateLimit.Response
+	if response == nil {
+		w.Header().Set("Retry-After", retryAfter)
+		http.Error(w, RATE_LIMIT_BODY, http.StatusTooManyRequests)
+		return
+	}
+
+	helpers := helper.RateLimitWatcher(m.GetRateLimitHelpers(), retryAfter, strconv.Itoa(m.RateLimit.Requests))
+
+	w.Header().Set("Retry-After", retryAfter)
+	for k, v := range response.Headers {
+		v, _ = helper.HelperReplacement(v, helpers)
+		w.Header().Set(k, v)
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+	w.WriteHeader(status)
+
+	body, _ := helper.HelperReplacement(response.GetBody(), helpers)
+	_, err := w.Write([]byte(body))
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
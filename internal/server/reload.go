@@ -0,0 +1,84 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/mock"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Server handler, swapped atomically when mocks are reloaded: requests in
+// progress end with the previous mocks.
+type reloadableHandler struct {
+	handler atomic.Value
+}
+
+func (h *reloadableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+func (h *reloadableHandler) load(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection) error {
+
+	handler, err := buildMocksHandler(conf, asyncRunningJobsCount, mockCollection)
+	if err != nil {
+		return err
+	}
+
+	h.handler.Store(handler)
+
+	return nil
+}
+
+// Reload serves new mock collections, function files are reloaded too. The
+// previous mocks are kept if one of the new handlers can't be built.
+func Reload(servers []*http.Server, conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection, services []Service) error {
+
+	mockCollections := map[string]mock.MockCollection{"": mockCollection}
+	for _, service := range services {
+		mockCollections[service.Conf.Name] = service.MockCollection
+	}
+
+	handlers := map[*reloadableHandler]http.Handler{}
+
+	for _, server := range servers {
+
+		listener, ok := server.Handler.(*virtualHosts)
+		if !ok {
+			return errors.New("server handler is not reloadable")
+		}
+
+		for name, h := range listener.handlers {
+
+			handler, err := buildMocksHandler(conf, asyncRunningJobsCount, mockCollections[name])
+			if err != nil {
+				return err
+			}
+
+			handlers[h] = handler
+		}
+	}
+
+	for h, handler := range handlers {
+		h.handler.Store(handler)
+	}
+
+	return nil
+}
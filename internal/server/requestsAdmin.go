@@ -0,0 +1,213 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/store"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const ADMIN_REQUESTS_PATH = ADMIN_PATH + "/requests"
+
+// Number of requests kept for the admin ui
+const MAX_RECENT_REQUESTS = 200
+
+// Shared list of the requests served by the replicas of a cluster
+const RECENT_REQUESTS_LIST = "requests"
+
+// Request served, without mock when no mock matched it. The listener is the
+// local address the request was received on, the replica is the host name of
+// the instance which served it when the journal is shared.
+type RecentRequest struct {
+	log.AccessEntry
+	Listener string `json:"listener"`
+	Matched  bool   `json:"matched"`
+	Replica  string `json:"replica,omitempty"`
+}
+
+var (
+	recentRequests      []RecentRequest
+	recentRequestsMutex sync.RWMutex
+
+	replica, _ = os.Hostname()
+)
+
+// recordRequest keeps the last requests served, admin requests and probes
+// are skipped
+func recordRequest(entry log.AccessEntry, listener string) {
+
+	if strings.HasPrefix(entry.Path, ADMIN_PATH) || entry.Path == HEALTH_PATH || entry.Path == READY_PATH {
+		return
+	}
+
+	request := RecentRequest{AccessEntry: entry, Listener: listener, Matched: entry.Mock != ""}
+
+	if lists, shared := store.Shared(); shared {
+
+		request.Replica = replica
+		pushSharedRequests(lists, []RecentRequest{request})
+		return
+	}
+
+	recentRequestsMutex.Lock()
+	defer recentRequestsMutex.Unlock()
+
+	recentRequests = append(recentRequests, request)
+	if len(recentRequests) > MAX_RECENT_REQUESTS {
+		recentRequests = recentRequests[len(recentRequests)-MAX_RECENT_REQUESTS:]
+	}
+}
+
+// GetRecentRequests returns the last requests served, oldest first
+func GetRecentRequests() []RecentRequest {
+
+	if lists, shared := store.Shared(); shared {
+		return getSharedRequests(lists)
+	}
+
+	recentRequestsMutex.RLock()
+	defer recentRequestsMutex.RUnlock()
+
+	return append([]RecentRequest{}, recentRequests...)
+}
+
+// SetRecentRequests replaces the last requests served, a snapshot import for
+// instance.
+func SetRecentRequests(requests []RecentRequest) {
+
+	if len(requests) > MAX_RECENT_REQUESTS {
+		requests = requests[len(requests)-MAX_RECENT_REQUESTS:]
+	}
+
+	if lists, shared := store.Shared(); shared {
+
+		clearSharedRequests(lists)
+		pushSharedRequests(lists, requests)
+		return
+	}
+
+	recentRequestsMutex.Lock()
+	defer recentRequestsMutex.Unlock()
+
+	recentRequests = append([]RecentRequest{}, requests...)
+}
+
+// ClearRecentRequests forgets the requests received on the listener, or all
+// of them without listener.
+func ClearRecentRequests(listener string) {
+
+	if lists, shared := store.Shared(); shared {
+
+		kept := []RecentRequest{}
+		if listener != "" {
+			for _, request := range getSharedRequests(lists) {
+				if request.Listener != listener {
+					kept = append(kept, request)
+				}
+			}
+		}
+
+		clearSharedRequests(lists)
+		pushSharedRequests(lists, kept)
+		return
+	}
+
+	recentRequestsMutex.Lock()
+	defer recentRequestsMutex.Unlock()
+
+	if listener == "" {
+		recentRequests = nil
+		return
+	}
+
+	kept := recentRequests[:0]
+	for _, request := range recentRequests {
+		if request.Listener != listener {
+			kept = append(kept, request)
+		}
+	}
+	recentRequests = kept
+}
+
+func pushSharedRequests(lists store.Lists, requests []RecentRequest) {
+
+	for _, request := range requests {
+
+		data, err := json.Marshal(request)
+		if err == nil {
+			err = lists.Push(RECENT_REQUESTS_LIST, string(data), MAX_RECENT_REQUESTS)
+		}
+		if err != nil {
+			log.Warn(context.Background(), "fail to record request in the shared journal", err)
+			return
+		}
+	}
+}
+
+func getSharedRequests(lists store.Lists) []RecentRequest {
+
+	values, err := lists.Range(RECENT_REQUESTS_LIST)
+	if err != nil {
+		log.Warn(context.Background(), "fail to read the shared journal", err)
+		return []RecentRequest{}
+	}
+
+	requests := make([]RecentRequest, 0, len(values))
+	for _, value := range values {
+
+		var request RecentRequest
+		if json.Unmarshal([]byte(value), &request) == nil {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests
+}
+
+func clearSharedRequests(lists store.Lists) {
+
+	err := lists.ClearList(RECENT_REQUESTS_LIST)
+	if err != nil {
+		log.Warn(context.Background(), "fail to clear the shared journal", err)
+	}
+}
+
+// Requests admin endpoint: GET returns the last requests served, newest
+// first, DELETE clears them.
+func RequestsAdmin(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method == http.MethodDelete {
+
+		ClearRecentRequests("")
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	all := GetRecentRequests()
+	requests := make([]RecentRequest, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		requests = append(requests, all[i])
+	}
+
+	writeAdminJson(w, r, requests)
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/helper"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Header set when a response doesn't have it, its value helpers are
+// populated for each request.
+type defaultHeader struct {
+	name    string
+	value   string
+	helpers []helper.Helper
+}
+
+// Global rules of the response headers
+type responseHeaders struct {
+	defaults []defaultHeader
+	remove   []string
+}
+
+type responseHeadersKey struct{}
+
+func buildResponseHeaders(config conf.ResponseHeadersConfig) (*responseHeaders, error) {
+
+	rules := &responseHeaders{}
+
+	for name, value := range config.Defaults {
+
+		helpers, err := helper.HelpersBuilder([]byte(value))
+		if err != nil {
+			return nil, errors.New("response header " + name + ": " + err.Error())
+		}
+
+		for _, h := range helpers {
+			if h.Type != helper.REQUEST && h.Type != helper.DATE && h.Type != helper.RANDOM && h.Type != helper.FAKER {
+				return nil, errors.New("response header " + name + ": helper type '" + h.Type + "' not available in the response headers")
+			}
+		}
+
+		rules.defaults = append(rules.defaults, defaultHeader{name: http.CanonicalHeaderKey(name), value: value, helpers: helpers})
+	}
+
+	sort.Slice(rules.defaults, func(i, j int) bool {
+		return rules.defaults[i].name < rules.defaults[j].name
+	})
+
+	for _, name := range config.Remove {
+		rules.remove = append(rules.remove, http.CanonicalHeaderKey(name))
+	}
+
+	return rules, nil
+}
+
+func (rules *responseHeaders) isEmpty() bool {
+
+	return rules == nil || (len(rules.defaults) == 0 && len(rules.remove) == 0)
+}
+
+// responseHeadersMiddleware applies the global and the mock headers rules
+// when the response headers are written.
+func responseHeadersMiddleware(rules *responseHeaders) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+
+			hw := &headersWriter{ResponseWriter: w, r: r, rules: rules}
+
+			next.ServeHTTP(hw, r.WithContext(context.WithValue(r.Context(), responseHeadersKey{}, hw)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// setMockResponseHeaders gives the rules of the mock serving the request to
+// the response headers middleware.
+func setMockResponseHeaders(ctx context.Context, m *mock.Mock) {
+
+	if hw, ok := ctx.Value(responseHeadersKey{}).(*headersWriter); ok {
+		hw.mock = m.ResponseHeaders
+	}
+}
+
+type headersWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	rules   *responseHeaders
+	mock    *mock.MockResponseHeaders
+	applied bool
+}
+
+func (hw *headersWriter) WriteHeader(status int) {
+
+	hw.apply()
+	hw.ResponseWriter.WriteHeader(status)
+}
+
+func (hw *headersWriter) Write(data []byte) (int, error) {
+
+	hw.apply()
+	return hw.ResponseWriter.Write(data)
+}
+
+func (hw *headersWriter) Flush() {
+
+	hw.apply()
+	if flusher, ok := hw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (hw *headersWriter) apply() {
+
+	if hw.applied {
+		return
+	}
+	hw.applied = true
+
+	header := hw.Header()
+
+	var skip, remove []string
+	if hw.mock != nil {
+		skip, remove = hw.mock.Skip, hw.mock.Remove
+	}
+
+	if hw.rules != nil {
+
+		for _, d := range hw.rules.defaults {
+
+			if header.Get(d.name) != "" || containsHeader(skip, d.name) {
+				continue
+			}
+
+			value, ok := d.populate(hw.r)
+			if ok {
+				header.Set(d.name, value)
+			}
+		}
+
+		for _, name := range hw.rules.remove {
+			if !containsHeader(skip, name) {
+				header.Del(name)
+			}
+		}
+	}
+
+	for _, name := range remove {
+		header.Del(name)
+	}
+}
+
+// populate returns the header value for the request, false when a request
+// value is missing.
+func (d defaultHeader) populate(r *http.Request) (string, bool) {
+
+	if len(d.helpers) == 0 {
+		return d.value, true
+	}
+
+	// the watchers populate all the helpers they are given
+	var request, date, random []helper.Helper
+	for _, h := range d.helpers {
+		switch h.Type {
+		case helper.REQUEST:
+			request = append(request, h)
+		case helper.DATE:
+			date = append(date, h)
+		default:
+			random = append(random, h)
+		}
+	}
+
+	date, _ = helper.DateWatcher(date)
+	random, err := helper.RandomWatcher(random)
+	if err != nil {
+		log.Warn(r.Context(), "response header "+d.name+" helpers in error", err)
+		return "", false
+	}
+
+	helpers := append(helper.RequestHeadersWatcher(r, request), date...)
+	helpers = append(helpers, random...)
+
+	value, err := helper.HelperReplacement(d.value, helpers)
+	if err != nil {
+		log.Debug(r.Context(), "response header "+d.name+" not set: "+err.Error())
+		return "", false
+	}
+
+	return value, true
+}
+
+func containsHeader(names []string, name string) bool {
+
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -19,14 +19,16 @@ package server
 import (
 	"alfred/internal/conf"
 	"alfred/internal/function"
+	"alfred/internal/health"
 	"alfred/internal/helper"
 	"alfred/internal/log"
 	"alfred/internal/mock"
+	"alfred/internal/openapi"
+	"alfred/internal/ratelimit"
 	"alfred/internal/tracing"
 	"alfred/pkg/metrics"
 	"context"
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -122,8 +124,20 @@ func logRequestMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// Completed with the mock used while the request is served
+		entry := &log.AccessEntry{Method: r.Method, RemoteAddr: r.RemoteAddr}
+		sw := newStatusWriter(w)
+
+		// A connection dropped by the chaos faults is logged, then aborted
+		aborted := false
+		defer func() {
+			if aborted {
+				panic(http.ErrAbortHandler)
+			}
+		}()
+
 		// Serve the request
-		next.ServeHTTP(w, r)
+		aborted = serveAbortable(next, sw, r.WithContext(log.WithAccessEntry(r.Context(), entry)))
 
 		var path string
 		values := r.Context().Value(helper.PathHelperKey("pathHelperValues"))
@@ -135,23 +149,70 @@ func logRequestMiddleware(next http.Handler) http.Handler {
 			path = r.URL.Path
 		}
 
+		// Mock log level applies
+		ctx := r.Context()
+		if entry.LogLevel != "" {
+			ctx, _ = log.WithLevel(ctx, entry.LogLevel)
+		}
+
+		entry.Path = removeFirstFolder(path)
+		entry.Status = sw.Status()
+		if aborted {
+			entry.Status = 0
+		}
+		entry.BodySize = sw.size
+		latency := time.Since(start)
+		entry.Time = start.UTC().Format(time.RFC3339Nano)
+		entry.LatencyMs = float64(latency.Microseconds()) / 1000
+
+		// Requests of the admin ui
+		var listener string
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			listener = addr.String()
+		}
+		recordRequest(*entry, listener)
+
 		// Log the request
-		msg := " " + r.Method + " " + removeFirstFolder(path) + " " + r.RemoteAddr + " " + fmt.Sprint(time.Since(start))
+		if log.AccessLogEnabled() {
+
+			log.WriteAccessEntry(ctx, entry)
+
+			return
+		}
+
+		msg := " " + r.Method + " " + removeFirstFolder(path) + " " + r.RemoteAddr + " " + fmt.Sprint(latency)
 
-		log.Info(r.Context(), msg)
+		log.Info(ctx, msg)
 
 		if log.GetLevel() == "debug" {
 
 			reqBodyBytes, _ := io.ReadAll(r.Body)
 
-			log.Debug(r.Context(), msg,
+			log.Debug(ctx, msg,
 				zap.String("request-body", string(reqBodyBytes)))
 		}
 	})
 }
 
-// Build the service
-func BuildServer(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection) (*http.Server, error) {
+// serveAbortable serves the request, true if the handler aborted it
+func serveAbortable(next http.Handler, w http.ResponseWriter, r *http.Request) (aborted bool) {
+
+	defer func() {
+		if err := recover(); err != nil {
+			if err != http.ErrAbortHandler {
+				panic(err)
+			}
+			aborted = true
+		}
+	}()
+
+	next.ServeHTTP(w, r)
+
+	return false
+}
+
+// Build the mocks handler with its middlewares
+func buildMocksHandler(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection) (http.Handler, error) {
 	//Build all endpoints handler
 	handler, err := BuildHandler(conf, asyncRunningJobsCount, mockCollection)
 	if err != nil {
@@ -161,6 +222,49 @@ func BuildServer(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockC
 	//Router
 	handler = routerMiddleware(handler)
 
+	//openapi validation, rejected requests are logged and journaled
+	if conf.Alfred.Openapi.SpecFile != "" {
+
+		doc, err := openapi.LoadSpec(conf.Alfred.Openapi.SpecFile)
+		if err != nil {
+			return nil, err
+		}
+
+		validator, err := openapi.NewValidator(doc, conf.Alfred.Openapi.ValidationMode)
+		if err != nil {
+			return nil, err
+		}
+
+		handler = openapiValidationMiddleware(validator)(handler)
+		log.Info(context.Background(), "openapi validation enabled with spec "+conf.Alfred.Openapi.SpecFile+" in '"+conf.Alfred.Openapi.ValidationMode+"' mode")
+	}
+
+	//CORS headers and preflight requests
+	if conf.Alfred.Cors.Enable || mockCollection.HasCorsMock() {
+		handler = corsMiddleware(buildGlobalCors(conf.Alfred.Cors), mockCollection)(handler)
+	}
+
+	//global rate limit
+	if conf.Alfred.RateLimit.Enable {
+
+		limiter, err := ratelimit.NewLimiter(conf.Alfred.RateLimit.Requests, conf.Alfred.RateLimit.Period, conf.Alfred.RateLimit.Burst)
+		if err != nil {
+			return nil, err
+		}
+
+		handler = rateLimitMiddleware(limiter)(handler)
+		log.Info(context.Background(), "global rate limit of "+limiter.String()+" enabled")
+	}
+
+	//default and removed response headers
+	rules, err := buildResponseHeaders(conf.Alfred.ResponseHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.isEmpty() || mockCollection.HasResponseHeadersMock() {
+		handler = responseHeadersMiddleware(rules)(handler)
+	}
+
 	//logger
 	handler = logRequestMiddleware(handler)
 
@@ -174,41 +278,101 @@ func BuildServer(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockC
 		handler = middlewarePathHelper(handler)
 	}
 
-	var tlsConfig *tls.Config
+	//compressed request bodies
+	handler = decompressRequestMiddleware(conf.Alfred.Limits.MaxBodySize)(handler)
+
+	//body size and concurrent requests limits
+	if conf.Alfred.Limits.MaxBodySize > 0 || conf.Alfred.Limits.MaxConcurrentRequests > 0 {
+		handler = limitsMiddleware(conf.Alfred.Limits)(handler)
+	}
 
-	if conf.Alfred.Core.Listen.TlsEnabled {
+	return handler, nil
+}
 
-		// Load server certificate and private key
-		certFile := conf.Alfred.Core.Listen.TlsCertPath // Path to the certificate file
-		keyFile := conf.Alfred.Core.Listen.TlsKeyPath   // Path to the private key file
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// Build the core server, and the servers of the services having their own
+// listener. Services without port are served by the core server.
+func BuildServers(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mockCollection mock.MockCollection, services []Service) ([]*http.Server, error) {
+
+	core := newVirtualHosts(conf.Alfred.Core.Listen)
+
+	err := core.addService(conf, asyncRunningJobsCount, "", nil, mockCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := []*virtualHosts{core}
+	names := map[string]bool{}
+
+	for _, service := range services {
+
+		if service.Conf.Name == "" || names[service.Conf.Name] {
+			return nil, errors.New("service name '" + service.Conf.Name + "' is empty or not unique")
+		}
+		names[service.Conf.Name] = true
+
+		// find the service listener
+		var listener *virtualHosts
+		for _, l := range listeners {
+			if service.Conf.Listen.Port == "" || l.listen.Ip+":"+l.listen.Port == service.Conf.Listen.Ip+":"+service.Conf.Listen.Port {
+				listener = l
+				break
+			}
+		}
+
+		if listener == nil {
+			listener = newVirtualHosts(service.Conf.Listen)
+			listeners = append(listeners, listener)
+		}
+
+		err = listener.addService(conf, asyncRunningJobsCount, service.Conf.Name, service.Conf.Hosts, service.MockCollection)
 		if err != nil {
-			log.Error(context.Background(), "error Server TLS", err)
-			return nil, fmt.Errorf("failed to load TLS certificate and key: %v", err)
+			return nil, errors.New("service " + service.Conf.Name + ": " + err.Error())
 		}
+	}
+
+	var servers []*http.Server
 
-		// Create a TLS configuration
-		tlsConfig = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: true,
-			ClientAuth:         tls.NoClientCert,
-			// Prefer server cipher suites
-			PreferServerCipherSuites: true,
-			// Use only modern, secure protocols
-			//MinVersion: tls.VersionTLS12,
-			// ... other TLS options as needed
+	for _, listener := range listeners {
+
+		server, err := buildServer(conf, listener)
+		if err != nil {
+			return nil, err
 		}
 
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// Build the server of a listener
+func buildServer(conf *conf.Config, listener *virtualHosts) (*http.Server, error) {
+
+	var tlsConfig *tls.Config
+	var err error
+
+	if listener.listen.TlsEnabled {
+
+		tlsConfig, err = buildTlsConfig(conf.Alfred.Name, listener.listen)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	//Associate the handler to a server (-> contains listening interface(s))
 	//Here, the server is listening on ALL interfaces and binding on 'conf.Port' port
-	return &http.Server{
-		Handler:   handler,
-		Addr:      fmt.Sprintf("%s:%s", conf.Alfred.Core.Listen.Ip, conf.Alfred.Core.Listen.Port),
+	server := &http.Server{
+		Handler:   listener,
+		Addr:      fmt.Sprintf("%s:%s", listener.listen.Ip, listener.listen.Port),
 		TLSConfig: tlsConfig,
-	}, nil
+	}
+
+	//HTTP/1.1 only, h2 is not negotiated with TLS
+	if listener.listen.Http2Disabled {
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	return server, nil
 }
 
 // Create Handler with endpoints to serve: used from service or from tests
@@ -246,14 +410,28 @@ func BuildHandler(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mock
 		{
 			mux.HandleFunc("/POST"+"/logger", ChangingLoggingLevelRuntime)
 
+			//Requests no mock matched
+			unmatched, err := newUnmatchedPolicy(conf.Alfred.Unmatched, mocks)
+			if err != nil {
+				return nil, err
+			}
+
 			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 
-				mockList, _ := json.MarshalIndent(mocks.GetMockInfoList(), "", "   ")
-				_, err := w.Write([]byte("Hello Sir ! I take care ot the following mocks:\n" + string(mockList) + "\n\n(Alfred)"))
+				// the mocks list is the home page of the default policy
+				if r.URL.Path == "/"+http.MethodGet+"/" && unmatched.policy == UNMATCHED_MOCK_LIST {
+					MockList(w, r, mocks)
+					return
+				}
+
+				metrics.IncUnmatchedRequests(r.Method)
+
+				body, err := io.ReadAll(r.Body)
 				if err != nil {
-					log.Error(r.Context(), "failed to write", err)
+					log.Error(r.Context(), "failed to read request body", err)
 				}
 
+				unmatched.serve(w, r, body)
 			})
 
 			mux.HandleFunc("/PATCH"+"/alfred", func(w http.ResponseWriter, r *http.Request) {
@@ -264,14 +442,76 @@ func BuildHandler(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mock
 				DelayMocks(&alfredGlobalDelay, w, r)
 			})
 
+			for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+				mux.HandleFunc("/"+method+ADMIN_CHAOS_PATH, ChaosAdmin)
+			}
+
+			for _, method := range []string{http.MethodGet, http.MethodDelete} {
+				mux.HandleFunc("/"+method+ADMIN_STORE_PATH, StoreAdmin)
+				mux.HandleFunc("/"+method+ADMIN_STORE_PATH+"/", StoreAdmin)
+			}
+
+			for _, method := range []string{http.MethodGet, http.MethodDelete} {
+				mux.HandleFunc("/"+method+ADMIN_REQUESTS_PATH, RequestsAdmin)
+				mux.HandleFunc("/"+method+ADMIN_UNMATCHED_PATH, UnmatchedAdmin)
+			}
+
+			for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+				mux.HandleFunc("/"+method+ADMIN_PROFILES_PATH, func(w http.ResponseWriter, r *http.Request) {
+					ProfilesAdmin(w, r, mocks)
+				})
+			}
+
+			for _, method := range []string{http.MethodGet, http.MethodPut} {
+				mux.HandleFunc("/"+method+ADMIN_SNAPSHOT_PATH, func(w http.ResponseWriter, r *http.Request) {
+					SnapshotAdmin(w, r, mocks)
+				})
+			}
+
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_LATENCY_PATH, LatencyAdmin)
+			for _, method := range []string{http.MethodPut, http.MethodDelete} {
+				mux.HandleFunc("/"+method+ADMIN_LATENCY_PATH+"/", LatencyAdmin)
+			}
+
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_JOBS_PATH, JobsAdmin)
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_JOBS_PATH+"/", JobsAdmin)
+			mux.HandleFunc("/"+http.MethodPost+ADMIN_JOBS_PATH+"/", JobsAdmin)
+
+			mux.HandleFunc("/"+http.MethodGet+HEALTH_PATH, Health)
+			mux.HandleFunc("/"+http.MethodGet+READY_PATH, Ready)
+
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_UI_PATH, AdminUi)
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_UI_PATH+"/", AdminUi)
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_POOLS_PATH, PoolsAdmin)
+			mux.HandleFunc("/"+http.MethodGet+ADMIN_MOCKS_PATH, func(w http.ResponseWriter, r *http.Request) {
+				MocksAdmin(w, r, mocks)
+			})
+
+			if conf.Alfred.Smtp.Enable {
+				mux.HandleFunc("/"+http.MethodGet+ADMIN_EMAILS_PATH, EmailsAdmin)
+				mux.HandleFunc("/"+http.MethodGet+ADMIN_EMAILS_PATH+"/", EmailsAdmin)
+				mux.HandleFunc("/"+http.MethodDelete+ADMIN_EMAILS_PATH, EmailsAdmin)
+			}
+
+			//Identity provider
+			if conf.Alfred.Oidc.Enable {
+				addOidcRoutes(mux)
+			}
+
 			//Load JS functions
 			functionCollection, err := function.CreateFunctionCollectionFromFolder(conf.Alfred.Core.FunctionsDir)
 			if err != nil {
 				log.Debug(context.Background(), "function files loader error: "+err.Error())
 			}
 
+			if err != nil && !errors.Is(err, function.ErrNoFunctionFile) {
+				health.Set(health.CHECK_FUNCTIONS, err, "")
+			} else {
+				health.Set(health.CHECK_FUNCTIONS, nil, strconv.Itoa(len(functionCollection))+" function file(s)")
+			}
+
 			// Create mocks routes
-			AddMocksRoutes(mux, mocks, functionCollection, &alfredGlobalDelay)
+			AddMocksRoutes(mux, asyncRunningJobsCount, mocks, functionCollection, &alfredGlobalDelay, conf.Alfred.Compression, unmatched)
 		}
 	}
 
@@ -279,66 +519,85 @@ func BuildHandler(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, mock
 }
 
 // Serve will bind the port(s) and launch serve in a separated goroutine
-func Serve(main_ctx context.Context, conf *conf.Config, server *http.Server) {
-
-	//Bind
-	listener, err := net.Listen("tcp", server.Addr)
-	if err != nil {
-		log.Error(main_ctx, "error Server Binding", err)
-		panic("error Server Binding on " + server.Addr)
-	}
-
-	//Log that's the bind is ok
-	fmt.Println("{ \"alfred-speaking\" : \"Started to serve on host " + conf.Alfred.Core.Listen.Ip + " and listening at port " + conf.Alfred.Core.Listen.Port + ", with " + main_ctx.Value(Key("mocksNb")).(string) + " mocks, Sir.\"}")
-	log.Info(main_ctx, "alfred started to serve on host "+conf.Alfred.Core.Listen.Ip+" and is listening at port "+conf.Alfred.Core.Listen.Port)
+func Serve(main_ctx context.Context, conf *conf.Config, servers []*http.Server) {
 
-	//Start to serve
-	go func() {
+	for i, server := range servers {
 
-		//tracing
-		cleanup, err := tracing.Init(main_ctx, tracing.OtelConfig{
-			ServiceName:           conf.Alfred.Name,
-			ServiceNamespace:      conf.Alfred.Namespace,
-			DeploymentEnvironment: conf.Alfred.Environment,
-			ExporterInsecure:      conf.Alfred.Tracing.Insecure,
-			TracesSampler:         conf.Alfred.Tracing.Sampler,
-			TracesSamplerArg:      conf.Alfred.Tracing.SamplerArgs,
-			ExporterOtlpEndpoint:  conf.Alfred.Tracing.OtlpEndpoint,
-		})
+		//Bind
+		listener, err := net.Listen("tcp", server.Addr)
 		if err != nil {
-			log.Error(main_ctx, "server panic", errors.New("error during preparing tracer..."+err.Error()))
-			panic("error during preparing tracer..." + err.Error())
+			log.Error(main_ctx, "error Server Binding", err)
+			panic("error Server Binding on " + server.Addr)
 		}
-		defer func() {
-			err := cleanup(main_ctx)
-			if err != nil {
-				log.Error(main_ctx, "error during tracing cleanup", err)
-			}
-		}()
 
-		if conf.Alfred.Core.Listen.TlsEnabled {
-			err = server.ServeTLS(listener, "", "")
-		} else {
-			err = server.Serve(listener)
+		//Connections over the limit are refused
+		if conf.Alfred.Limits.MaxConnections > 0 {
+			listener = newLimitListener(listener, conf.Alfred.Limits.MaxConnections, server.TLSConfig != nil)
 		}
 
-		if err != nil {
-			log.Error(main_ctx, "error at server start", err)
+		//Log that's the bind is ok
+		if i == 0 {
+			fmt.Println("{ \"alfred-speaking\" : \"Started to serve on host " + conf.Alfred.Core.Listen.Ip + " and listening at port " + conf.Alfred.Core.Listen.Port + ", with " + main_ctx.Value(Key("mocksNb")).(string) + " mocks, Sir.\"}")
 		}
-	}()
+		log.Info(main_ctx, "alfred started to serve on "+server.Addr, zap.String("services", server.Handler.(*virtualHosts).String()))
+
+		//Start to serve, the core server manages the tracing
+		go func(server *http.Server, withTracing bool) {
+
+			if withTracing {
+
+				//tracing
+				cleanup, err := tracing.Init(main_ctx, tracing.OtelConfig{
+					ServiceName:           conf.Alfred.Name,
+					ServiceVersion:        conf.Alfred.Version,
+					ServiceNamespace:      conf.Alfred.Namespace,
+					DeploymentEnvironment: conf.Alfred.Environment,
+					ExporterInsecure:      conf.Alfred.Tracing.Insecure,
+					TracesSampler:         conf.Alfred.Tracing.Sampler,
+					TracesSamplerArg:      conf.Alfred.Tracing.SamplerArgs,
+					ExporterOtlpEndpoint:  conf.Alfred.Tracing.OtlpEndpoint,
+					ExporterOtlpHeaders:   conf.Alfred.Tracing.OtlpHeaders,
+				})
+				if err != nil {
+					log.Error(main_ctx, "server panic", errors.New("error during preparing tracer..."+err.Error()))
+					panic("error during preparing tracer..." + err.Error())
+				}
+				defer func() {
+					err := cleanup(main_ctx)
+					if err != nil {
+						log.Error(main_ctx, "error during tracing cleanup", err)
+					}
+				}()
+			}
+
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+
+			if err != nil && err != http.ErrServerClosed {
+				log.Error(main_ctx, "error at server start", err)
+			}
+		}(server, i == 0)
+	}
 }
 
-// Return necessaries to stop serve
-func Stop(ctx context.Context, server *http.Server, asyncRunningJobsCount *sync.WaitGroup) {
+// Stop serving, the requests in progress and the async jobs have the timeout
+// to end.
+func Stop(ctx context.Context, servers []*http.Server, asyncRunningJobsCount *sync.WaitGroup, timeout time.Duration) {
 	log.Info(ctx, "Server is stopping")
 
 	//Let's some few seconds to shutdown gracefully
-	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(5*time.Second))
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(timeout))
 	defer cancel()
 
-	//Shutdown the http server
-	if err := server.Shutdown(ctx); err != nil {
-		log.Error(ctx, "Error While stopping Server: ", err)
+	//Shutdown the http servers
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error(ctx, "Error While stopping Server: ", err)
+		}
 	}
 
 	// Wait that all async jobs are done (timeboxed)
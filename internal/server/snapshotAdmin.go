@@ -0,0 +1,234 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/chaos"
+	"alfred/internal/latency"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"alfred/internal/store"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_SNAPSHOT_PATH = ADMIN_PATH + "/snapshot"
+
+// Version of the snapshot archive format
+const SNAPSHOT_VERSION = 1
+
+// Runtime state of Alfred: the mocks patched at runtime, the stored state by
+// namespace, the last requests served and the runtime settings. Missing
+// sections are left untouched by an import.
+type Snapshot struct {
+	Version         int                                   `json:"version"`
+	Created         time.Time                             `json:"created"`
+	Mocks           []json.RawMessage                     `json:"mocks,omitempty"`
+	Store           map[string]map[string]json.RawMessage `json:"store,omitempty"`
+	Requests        []RecentRequest                       `json:"requests,omitempty"`
+	Chaos           *chaos.Profile                        `json:"chaos,omitempty"`
+	Profiles        *[]string                             `json:"profiles,omitempty"`
+	LatencyProfiles []latency.Profile                     `json:"latency-profiles,omitempty"`
+}
+
+// What an import restored, the mocks not served are skipped
+type snapshotImport struct {
+	Mocks        int      `json:"mocks"`
+	SkippedMocks []string `json:"skippedMocks,omitempty"`
+	Namespaces   int      `json:"namespaces"`
+	Requests     int      `json:"requests"`
+}
+
+// Snapshot admin endpoint: GET exports the runtime state as a json archive,
+// PUT imports an archive, to reproduce a bug report or to seed a test
+// environment.
+func SnapshotAdmin(w http.ResponseWriter, r *http.Request, mockCollection mock.MockCollection) {
+
+	if r.Method == http.MethodPut {
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Error(r.Context(), "failed to read request body", err)
+		}
+
+		var snapshot Snapshot
+		err = json.Unmarshal(data, &snapshot)
+		if err == nil && snapshot.Version != SNAPSHOT_VERSION {
+			err = errors.New("snapshot version " + strconv.Itoa(snapshot.Version) + " not supported, use " + strconv.Itoa(SNAPSHOT_VERSION))
+		}
+		if err != nil {
+			http.Error(w, "snapshot error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := importSnapshot(snapshot, mockCollection)
+		if err != nil {
+			http.Error(w, "snapshot import error: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Info(r.Context(), "snapshot imported",
+			zap.Time("snapshot-created", snapshot.Created),
+			zap.Int("mocks", report.Mocks),
+			zap.Int("namespaces", report.Namespaces),
+			zap.Int("requests", report.Requests))
+
+		writeAdminJson(w, r, report)
+		return
+	}
+
+	snapshot, err := exportSnapshot(mockCollection)
+	if err != nil {
+		http.Error(w, "snapshot error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=\"alfred-snapshot-"+snapshot.Created.Format("20060102-150405")+".json\"")
+	writeAdminJson(w, r, snapshot)
+}
+
+func exportSnapshot(mockCollection mock.MockCollection) (Snapshot, error) {
+
+	snapshot := Snapshot{
+		Version:         SNAPSHOT_VERSION,
+		Created:         time.Now().UTC(),
+		Store:           map[string]map[string]json.RawMessage{},
+		Requests:        GetRecentRequests(),
+		LatencyProfiles: latency.List(),
+	}
+
+	for _, m := range mockCollection.Mocks {
+		if m.IsPatched() {
+			snapshot.Mocks = append(snapshot.Mocks, json.RawMessage(m.GetJsonBytes()))
+		}
+	}
+
+	s := store.Get()
+
+	namespaces, err := s.Namespaces()
+	if err != nil {
+		return snapshot, err
+	}
+
+	for _, ns := range namespaces {
+
+		entries, err := s.List(ns)
+		if err != nil {
+			return snapshot, err
+		}
+
+		snapshot.Store[ns] = map[string]json.RawMessage{}
+		for key, value := range entries {
+			snapshot.Store[ns][key] = json.RawMessage(value)
+		}
+	}
+
+	chaosProfile := chaos.GetProfile()
+	snapshot.Chaos = &chaosProfile
+
+	profiles := mock.GetProfiles()
+	snapshot.Profiles = &profiles
+
+	return snapshot, nil
+}
+
+// importSnapshot applies the settings first, an invalid setting stops the
+// import before the mocks and the state are changed.
+func importSnapshot(snapshot Snapshot, mockCollection mock.MockCollection) (snapshotImport, error) {
+
+	var report snapshotImport
+
+	if snapshot.Chaos != nil {
+		err := chaos.SetProfile(*snapshot.Chaos)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for _, profile := range snapshot.LatencyProfiles {
+		err := latency.Set(profile)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if snapshot.Profiles != nil {
+		mock.SetProfiles(*snapshot.Profiles)
+	}
+
+	for _, data := range snapshot.Mocks {
+
+		var named struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(data, &named)
+
+		m := findMockByName(mockCollection, named.Name)
+		if m == nil {
+			report.SkippedMocks = append(report.SkippedMocks, named.Name)
+			continue
+		}
+
+		err := mock.MockPatch(m, data)
+		if err != nil {
+			return report, errors.New("mock " + named.Name + ": " + err.Error())
+		}
+		report.Mocks++
+	}
+
+	s := store.Get()
+
+	for ns, entries := range snapshot.Store {
+
+		err := s.Wipe(ns)
+		if err != nil {
+			return report, err
+		}
+
+		for key, value := range entries {
+
+			err = s.Set(ns, key, string(value))
+			if err != nil {
+				return report, err
+			}
+		}
+		report.Namespaces++
+	}
+
+	if snapshot.Requests != nil {
+		SetRecentRequests(snapshot.Requests)
+		report.Requests = len(snapshot.Requests)
+	}
+
+	return report, nil
+}
+
+func findMockByName(mockCollection mock.MockCollection, name string) *mock.Mock {
+
+	for _, m := range mockCollection.Mocks {
+		if m.Name == name {
+			return m
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/mock"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// serveStatic serves a file of the static mock directory. Directories are
+// answered with their index file, ETag, conditional and range requests are
+// handled.
+func serveStatic(w http.ResponseWriter, r *http.Request, m *mock.Mock) {
+
+	prefix := strings.TrimSuffix("/"+m.GetRequestMethod()+m.GetRequestUrl(), "/")
+	name := strings.TrimPrefix(r.URL.Path, prefix)
+
+	root := http.Dir(m.GetStaticRoot())
+
+	file, info, err := openStaticFile(root, name)
+	if err == nil && info.IsDir() {
+
+		file.Close()
+
+		// relative links of the index need the trailing slash
+		if !strings.HasSuffix(name, "/") {
+			location := strings.TrimPrefix(r.URL.Path, "/"+r.Method) + "/"
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, location, http.StatusMovedPermanently)
+			return
+		}
+
+		file, info, err = openStaticFile(root, path.Join(name, m.Static.Index))
+	}
+
+	if os.IsNotExist(err) && m.Static.Fallback {
+		file, info, err = openStaticFile(root, "/"+m.Static.Index)
+	}
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	time.Sleep(m.GetDelay())
+
+	for k, v := range m.GetResponseHeaders() {
+		w.Header().Set(k, v)
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+func openStaticFile(root http.FileSystem, name string) (http.File, os.FileInfo, error) {
+
+	file, err := root.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return file, info, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+)
+
+// Response writer keeping the status and body size sent, for metrics and logs
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{ResponseWriter: w}
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(data)
+	sw.size += n
+
+	return n, err
+}
+
+func (sw *statusWriter) Flush() {
+
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Status sent, 200 if nothing has been written
+func (sw *statusWriter) Status() int {
+
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+	return sw.status
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/store"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_STORE_PATH = ADMIN_PATH + "/store"
+
+// Store admin endpoint: GET returns the stored state by namespace, DELETE
+// wipes it. /__admin/store/{namespace} targets a single namespace.
+func StoreAdmin(w http.ResponseWriter, r *http.Request) {
+
+	s := store.Get()
+
+	namespaces, err := s.Namespaces()
+	if err != nil {
+		http.Error(w, "store error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	namespace := strings.Trim(strings.TrimPrefix(r.URL.Path, "/"+r.Method+ADMIN_STORE_PATH), "/")
+	if namespace != "" {
+		namespaces = []string{namespace}
+	}
+
+	if r.Method == http.MethodDelete {
+
+		for _, ns := range namespaces {
+
+			err = s.Wipe(ns)
+			if err != nil {
+				http.Error(w, "store error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			log.Info(r.Context(), "store namespace wiped", zap.String("namespace", ns))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data := map[string]map[string]json.RawMessage{}
+
+	for _, ns := range namespaces {
+
+		entries, err := s.List(ns)
+		if err != nil {
+			http.Error(w, "store error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data[ns] = map[string]json.RawMessage{}
+		for key, value := range entries {
+			data[ns][key] = json.RawMessage(value)
+		}
+	}
+
+	body, _ := json.Marshal(data)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(body)
+	if err != nil {
+		log.Error(r.Context(), "failed to write", err)
+	}
+}
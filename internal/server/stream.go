@@ -0,0 +1,97 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/function"
+	"alfred/internal/helper"
+	"alfred/internal/mock"
+	"alfred/pkg/request"
+	"net/http"
+	"time"
+)
+
+// serveStream sends the chunks of a stream mock, produced by the stream
+// function of its function file or listed in the mock file. Each chunk is
+// flushed after its delay, the stream ends when the client is gone.
+func serveStream(w http.ResponseWriter, r *http.Request, m *mock.Mock, req request.Req, res request.Res, helpers []helper.Helper, functions function.FunctionCollection) error {
+
+	stream := *m.Response.Stream
+
+	if contentType := stream.GetContentType(); contentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if stream.Format == mock.STREAM_FORMAT_SSE {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Del("Content-Length")
+
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	w.WriteHeader(res.Status)
+	flush(w)
+
+	index := 0
+	emit := func(chunk mock.StreamChunk) error {
+
+		if delay := stream.GetDelay(chunk, index); delay > 0 {
+
+			select {
+			case <-r.Context().Done():
+				return r.Context().Err()
+			case <-time.After(time.Duration(delay) * time.Millisecond):
+			}
+		}
+		index++
+
+		_, err := w.Write(stream.Encode(chunk))
+		if err != nil {
+			return err
+		}
+		flush(w)
+
+		return r.Context().Err()
+	}
+
+	if m.HasFunctionFile() {
+
+		f, _ := functions.GetFunction(m.FunctionFile)
+		if f.HasFuncStream {
+			return f.StreamFunc(*m, helpers, req, res, emit)
+		}
+	}
+
+	for _, chunk := range stream.GetChunks() {
+
+		// helpers without value are already logged with the headers
+		chunk.Body, _ = helper.HelperReplacement(chunk.Body, helpers)
+
+		err := emit(chunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func flush(w http.ResponseWriter) {
+
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/certs"
+	"alfred/internal/conf"
+	"alfred/internal/log"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// buildTlsConfig loads (or generates) the listener certificate and sets the
+// client certificate policy.
+func buildTlsConfig(name string, listen conf.ListenConfig) (*tls.Config, error) {
+
+	ctx := context.Background()
+
+	var cert tls.Certificate
+	var err error
+
+	if listen.TlsSelfSigned {
+
+		hosts := []string{listen.Ip}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+
+		cert, err = certs.GenerateSelfSigned(name, hosts)
+		if err != nil {
+			log.Error(ctx, "error Server TLS", err)
+			return nil, err
+		}
+
+		names := cert.Leaf.DNSNames
+		for _, ip := range cert.Leaf.IPAddresses {
+			names = append(names, ip.String())
+		}
+		log.Info(ctx, "self-signed certificate generated for "+strings.Join(names, ", "))
+
+	} else {
+
+		// Load server certificate and private key
+		certFile := listen.TlsCertPath // Path to the certificate file
+		keyFile := listen.TlsKeyPath   // Path to the private key file
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Error(ctx, "error Server TLS", err)
+			return nil, fmt.Errorf("failed to load TLS certificate and key: %v", err)
+		}
+	}
+
+	clientAuth, err := certs.ParseClientAuth(listen.TlsClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a TLS configuration
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true,
+		ClientAuth:         clientAuth,
+		// Prefer server cipher suites
+		PreferServerCipherSuites: true,
+		// Use only modern, secure protocols
+		//MinVersion: tls.VersionTLS12,
+		// ... other TLS options as needed
+	}
+
+	// Client certificates are verified against the given authorities
+	if listen.TlsClientCaPath != "" {
+
+		tlsConfig.ClientCAs, err = certs.LoadCertPool(listen.TlsClientCaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client CA: %v", err)
+		}
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+
+		return nil, fmt.Errorf("tls client auth '%s' needs the client CA path", listen.TlsClientAuth)
+	}
+
+	return tlsConfig, nil
+}
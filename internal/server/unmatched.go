@@ -0,0 +1,284 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/health"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const ADMIN_UNMATCHED_PATH = ADMIN_PATH + "/unmatched"
+
+// Policies of the requests no mock matched
+const (
+	UNMATCHED_MOCK_LIST = "mock-list"
+	UNMATCHED_NOT_FOUND = "not-found"
+	UNMATCHED_PROXY     = "proxy"
+	UNMATCHED_STRICT    = "strict"
+)
+
+// Number of unmatched requests kept for the admin api
+const MAX_UNMATCHED_REQUESTS = 100
+
+// Bytes of an unmatched request body kept for the admin api
+const MAX_UNMATCHED_BODY_SIZE = 1 << 10
+
+// Request no mock matched, with the mocks closest to it
+type UnmatchedRequest struct {
+	DateTime   string          `json:"dateTime"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Query      string          `json:"query,omitempty"`
+	Headers    http.Header     `json:"headers"`
+	Body       string          `json:"body,omitempty"`
+	Listener   string          `json:"listener"`
+	NearMisses []mock.NearMiss `json:"near-misses"`
+}
+
+// Diagnostic body of the not-found and strict policies
+type unmatchedResponse struct {
+	Error      string          `json:"error"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	NearMisses []mock.NearMiss `json:"near-misses"`
+}
+
+type unmatchedPolicy struct {
+	policy string
+	mocks  mock.MockCollection
+	proxy  *httputil.ReverseProxy
+}
+
+var (
+	unmatchedRequests      []UnmatchedRequest
+	unmatchedCount         int
+	unmatchedStrict        bool
+	unmatchedRequestsMutex sync.RWMutex
+)
+
+func newUnmatchedPolicy(config conf.UnmatchedConfig, mocks mock.MockCollection) (*unmatchedPolicy, error) {
+
+	u := &unmatchedPolicy{policy: config.Policy, mocks: mocks}
+
+	switch config.Policy {
+	case "":
+		u.policy = UNMATCHED_MOCK_LIST
+	case UNMATCHED_MOCK_LIST, UNMATCHED_NOT_FOUND, UNMATCHED_STRICT:
+	case UNMATCHED_PROXY:
+
+		target, err := url.Parse(config.ProxyUrl)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, errors.New("unmatched proxy url '" + config.ProxyUrl + "' is not valid")
+		}
+
+		u.proxy = newUnmatchedProxy(target)
+	default:
+		return nil, errors.New("unmatched policy '" + config.Policy + "' not supported, use " + UNMATCHED_MOCK_LIST + ", " + UNMATCHED_NOT_FOUND + ", " + UNMATCHED_PROXY + " or " + UNMATCHED_STRICT)
+	}
+
+	unmatchedRequestsMutex.Lock()
+	defer unmatchedRequestsMutex.Unlock()
+
+	// the unmatched check is kept up to date when leaving the strict policy
+	if u.policy == UNMATCHED_STRICT || unmatchedStrict {
+		unmatchedStrict = u.policy == UNMATCHED_STRICT
+		setUnmatchedCheck()
+	}
+
+	return u, nil
+}
+
+// newUnmatchedProxy forwards the requests to the target, with their original
+// url as the mux path is prefixed by the method
+func newUnmatchedProxy(target *url.URL) *httputil.ReverseProxy {
+
+	return &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+
+			path, query, _ := strings.Cut(r.RequestURI, "?")
+
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			r.URL.Path = strings.TrimSuffix(target.Path, "/") + path
+			r.URL.RawPath = ""
+			r.URL.RawQuery = query
+			r.Host = target.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+
+			log.Warn(r.Context(), "unmatched request proxy failed", err, zap.String("proxy-url", target.String()))
+			http.Error(w, "unmatched request proxy failed: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+}
+
+// serve answers a request no mock matched, the body is already read
+func (u *unmatchedPolicy) serve(w http.ResponseWriter, r *http.Request, body []byte) {
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+
+	var listener string
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		listener = addr.String()
+	}
+
+	// only the start of the body is kept
+	keptBody := body
+	if len(keptBody) > MAX_UNMATCHED_BODY_SIZE {
+		keptBody = keptBody[:MAX_UNMATCHED_BODY_SIZE]
+	}
+
+	// the near misses are computed before the request is recorded, out of
+	// the unmatched requests lock
+	nearMisses := u.nearMisses(r, body)
+	if nearMisses == nil {
+		nearMisses = []mock.NearMiss{}
+	}
+
+	recordUnmatchedRequest(UnmatchedRequest{
+		DateTime:   time.Now().UTC().Format(time.RFC3339Nano),
+		Method:     r.Method,
+		Path:       path,
+		Query:      query,
+		Headers:    r.Header.Clone(),
+		Body:       string(keptBody),
+		Listener:   listener,
+		NearMisses: nearMisses,
+	})
+
+	switch u.policy {
+	case UNMATCHED_MOCK_LIST:
+
+		MockList(w, r, u.mocks)
+
+	case UNMATCHED_PROXY:
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		u.proxy.ServeHTTP(w, r)
+
+	default:
+
+		if u.policy == UNMATCHED_STRICT {
+			log.Warn(r.Context(), "unmatched request in strict mode, the health and readiness fail", nil, zap.String("request-path", path))
+		}
+
+		response, _ := json.MarshalIndent(unmatchedResponse{Error: "no mock matched the request", Method: r.Method, Path: path, NearMisses: nearMisses}, "", "  ")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, err := w.Write(response)
+		if err != nil {
+			log.Error(r.Context(), "failed to write", err)
+		}
+	}
+}
+
+func (u *unmatchedPolicy) nearMisses(r *http.Request, body []byte) []mock.NearMiss {
+
+	return u.mocks.NearMisses(r, r.Method, strings.TrimPrefix(r.URL.Path, "/"+r.Method), body)
+}
+
+func recordUnmatchedRequest(request UnmatchedRequest) {
+
+	unmatchedRequestsMutex.Lock()
+	defer unmatchedRequestsMutex.Unlock()
+
+	unmatchedCount++
+	unmatchedRequests = append(unmatchedRequests, request)
+	if len(unmatchedRequests) > MAX_UNMATCHED_REQUESTS {
+		unmatchedRequests = unmatchedRequests[len(unmatchedRequests)-MAX_UNMATCHED_REQUESTS:]
+	}
+
+	if unmatchedStrict {
+		setUnmatchedCheck()
+	}
+}
+
+// setUnmatchedCheck fails the health and readiness in strict mode once a
+// request is unmatched, unmatchedRequestsMutex is held
+func setUnmatchedCheck() {
+
+	if !unmatchedStrict || unmatchedCount == 0 {
+		health.Set(health.CHECK_UNMATCHED, nil, "")
+		return
+	}
+
+	last := unmatchedRequests[len(unmatchedRequests)-1]
+	health.Set(health.CHECK_UNMATCHED, errors.New("unmatched request "+last.Method+" "+last.Path), strconv.Itoa(unmatchedCount)+" unmatched request(s)")
+}
+
+// GetUnmatchedRequests returns the last requests no mock matched, oldest
+// first, with their near misses
+func GetUnmatchedRequests() []UnmatchedRequest {
+
+	unmatchedRequestsMutex.RLock()
+	defer unmatchedRequestsMutex.RUnlock()
+
+	return append([]UnmatchedRequest{}, unmatchedRequests...)
+}
+
+// ClearUnmatchedRequests forgets the unmatched requests, the strict mode
+// health and readiness are restored
+func ClearUnmatchedRequests() {
+
+	unmatchedRequestsMutex.Lock()
+	defer unmatchedRequestsMutex.Unlock()
+
+	unmatchedRequests = nil
+	unmatchedCount = 0
+
+	if unmatchedStrict {
+		setUnmatchedCheck()
+	}
+}
+
+// Unmatched requests admin endpoint: GET returns the last requests no mock
+// matched with their near miss mocks, newest first, DELETE clears them.
+func UnmatchedAdmin(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method == http.MethodDelete {
+
+		ClearUnmatchedRequests()
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	all := GetUnmatchedRequests()
+	requests := make([]UnmatchedRequest, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		requests = append(requests, all[i])
+	}
+
+	writeAdminJson(w, r, requests)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/mock"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// A mock collection served as a distinct backend service
+type Service struct {
+	Conf           conf.ServiceConfig
+	MockCollection mock.MockCollection
+}
+
+// Services of a listener, the request Host header selects the service; the
+// service without hosts, if any, serves the other requests.
+type virtualHosts struct {
+	listen   conf.ListenConfig
+	handlers map[string]*reloadableHandler
+	hosts    map[string]*reloadableHandler
+	fallback *reloadableHandler
+
+	//serves the hosts, through h2c if enabled
+	handler http.Handler
+}
+
+func newVirtualHosts(listen conf.ListenConfig) *virtualHosts {
+
+	v := &virtualHosts{
+		listen:   listen,
+		handlers: map[string]*reloadableHandler{},
+		hosts:    map[string]*reloadableHandler{},
+	}
+
+	v.handler = http.HandlerFunc(v.serveHost)
+
+	//HTTP/2 without TLS
+	if listen.H2cEnabled && !listen.TlsEnabled && !listen.Http2Disabled {
+		v.handler = h2c.NewHandler(v.handler, &http2.Server{})
+	}
+
+	return v
+}
+
+func (v *virtualHosts) addService(conf *conf.Config, asyncRunningJobsCount *sync.WaitGroup, name string, hosts []string, mockCollection mock.MockCollection) error {
+
+	handler := &reloadableHandler{}
+
+	err := handler.load(conf, asyncRunningJobsCount, mockCollection)
+	if err != nil {
+		return err
+	}
+
+	v.handlers[name] = handler
+
+	for _, host := range hosts {
+		v.hosts[strings.ToLower(host)] = handler
+	}
+
+	if len(hosts) == 0 && v.fallback == nil {
+		v.fallback = handler
+	}
+
+	return nil
+}
+
+func (v *virtualHosts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	v.handler.ServeHTTP(w, r)
+}
+
+func (v *virtualHosts) serveHost(w http.ResponseWriter, r *http.Request) {
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if handler, exists := v.hosts[strings.ToLower(host)]; exists {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	if v.fallback == nil {
+		http.Error(w, "no service for host '"+host+"'", http.StatusNotFound)
+		return
+	}
+
+	v.fallback.ServeHTTP(w, r)
+}
+
+// Names of the listener services, the core mocks are named 'core'.
+func (v *virtualHosts) String() string {
+
+	var names []string
+
+	for name := range v.handlers {
+
+		if name == "" {
+			name = "core"
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package smtp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DEFAULT_MAX_EMAILS = 1000
+
+// Email captured by the smtp server, recipients are the envelope ones so
+// bcc recipients are listed too.
+type Email struct {
+	Id          string            `json:"id"`
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Subject     string            `json:"subject"`
+	Headers     map[string]string `json:"headers"`
+	Text        string            `json:"text,omitempty"`
+	Html        string            `json:"html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Size        int               `json:"size"`
+	ReceivedAt  time.Time         `json:"receivedAt"`
+}
+
+// Attachment content is base64 encoded
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	Content     string `json:"content"`
+}
+
+// Filter of the captured emails, empty fields match any email
+type Filter struct {
+	To      string
+	From    string
+	Subject string
+}
+
+var (
+	emails      []Email
+	emailsMutex sync.RWMutex
+	maxEmails   = DEFAULT_MAX_EMAILS
+	lastId      int64
+)
+
+// SetMaxEmails caps the mailbox, the oldest emails are dropped
+func SetMaxEmails(max int) {
+
+	emailsMutex.Lock()
+	defer emailsMutex.Unlock()
+
+	if max <= 0 {
+		max = DEFAULT_MAX_EMAILS
+	}
+
+	maxEmails = max
+}
+
+func add(e Email) Email {
+
+	e.Id = strconv.FormatInt(atomic.AddInt64(&lastId, 1), 10)
+
+	emailsMutex.Lock()
+	defer emailsMutex.Unlock()
+
+	emails = append(emails, e)
+	if len(emails) > maxEmails {
+		emails = emails[len(emails)-maxEmails:]
+	}
+
+	return e
+}
+
+// List returns the captured emails matching the filter, oldest first
+func List(f Filter) []Email {
+
+	emailsMutex.RLock()
+	defer emailsMutex.RUnlock()
+
+	list := []Email{}
+	for _, e := range emails {
+		if f.match(e) {
+			list = append(list, e)
+		}
+	}
+
+	return list
+}
+
+func Get(id string) (Email, bool) {
+
+	emailsMutex.RLock()
+	defer emailsMutex.RUnlock()
+
+	for _, e := range emails {
+		if e.Id == id {
+			return e, true
+		}
+	}
+
+	return Email{}, false
+}
+
+func Clear() {
+
+	emailsMutex.Lock()
+	defer emailsMutex.Unlock()
+
+	emails = nil
+}
+
+func (f Filter) match(e Email) bool {
+
+	if f.From != "" && !strings.Contains(strings.ToLower(e.From), strings.ToLower(f.From)) {
+		return false
+	}
+
+	if f.Subject != "" && !strings.Contains(strings.ToLower(e.Subject), strings.ToLower(f.Subject)) {
+		return false
+	}
+
+	if f.To == "" {
+		return true
+	}
+
+	for _, to := range e.To {
+		if strings.Contains(strings.ToLower(to), strings.ToLower(f.To)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package smtp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var wordDecoder = mime.WordDecoder{}
+
+// parse reads the message text and html bodies, and its attachments. A
+// message that can't be parsed is kept as a text body.
+func parse(from string, to []string, data []byte) Email {
+
+	e := Email{From: from, To: to, Headers: map[string]string{}, Size: len(data), ReceivedAt: time.Now()}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		e.Text = string(data)
+		return e
+	}
+
+	for name := range msg.Header {
+		e.Headers[name] = decodeHeader(msg.Header.Get(name))
+	}
+
+	e.Subject = e.Headers["Subject"]
+
+	parsePart(&e, msg.Header, msg.Body)
+
+	return e
+}
+
+func decodeHeader(value string) string {
+
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+func getHeader(h map[string][]string, name string) string {
+
+	if values := h[name]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func parsePart(e *Email, header map[string][]string, body io.Reader) {
+
+	mediaType, params, err := mime.ParseMediaType(getHeader(header, "Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				return
+			}
+			parsePart(e, part.Header, part)
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(getHeader(header, "Content-Transfer-Encoding"), body))
+	if err != nil {
+		return
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(getHeader(header, "Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	switch {
+	case disposition == "attachment" || filename != "":
+		e.Attachments = append(e.Attachments, Attachment{
+			Filename:    decodeHeader(filename),
+			ContentType: mediaType,
+			Size:        len(content),
+			Content:     base64.StdEncoding.EncodeToString(content),
+		})
+	case mediaType == "text/html":
+		e.Html += string(content)
+	default:
+		e.Text += string(content)
+	}
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+
+	switch strings.ToLower(encoding) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineSkipper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+
+	return body
+}
+
+// base64 bodies are split in lines
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+
+	n, err := s.r.Read(p)
+
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+
+	return j, err
+}
@@ -0,0 +1,358 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package smtp
+
+import (
+	"alfred/internal/log"
+	"context"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	HOSTNAME           = "alfred"
+	MAX_MESSAGE_SIZE   = 25 * 1024 * 1024
+	CONNECTION_IDLE    = 5 * time.Minute
+	MAX_RECIPIENTS     = 100
+	RCPT_PREFIX        = "TO:"
+	MAIL_PREFIX        = "FROM:"
+	AUTH_PLAIN         = "PLAIN"
+	AUTH_LOGIN         = "LOGIN"
+	REPLY_READY        = 220
+	REPLY_BYE          = 221
+	REPLY_AUTH_OK      = 235
+	REPLY_OK           = 250
+	REPLY_AUTH_INPUT   = 334
+	REPLY_DATA_INPUT   = 354
+	REPLY_SYNTAX       = 501
+	REPLY_UNKNOWN      = 502
+	REPLY_BAD_SEQUENCE = 503
+	REPLY_TOO_MANY     = 452
+	REPLY_TOO_BIG      = 552
+)
+
+// Server accepting any mail, the mails are captured in the mailbox
+type Server struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    map[net.Conn]bool
+	stopped  bool
+	wg       sync.WaitGroup
+}
+
+// session of an smtp connection
+type session struct {
+	conn *textproto.Conn
+	from string
+	to   []string
+	mail bool
+}
+
+// Start listens on the address, connections are served in goroutines
+func Start(address string) (*Server, error) {
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{listener: listener, conns: map[net.Conn]bool{}}
+
+	s.wg.Add(1)
+	go s.accept()
+
+	log.Info(context.Background(), "smtp mock listening on "+listener.Addr().String())
+
+	return s, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Stop closes the listener and the open connections
+func (s *Server) Stop() {
+
+	s.mutex.Lock()
+	s.stopped = true
+	s.listener.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) track(c net.Conn, tracked bool) bool {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if tracked && s.stopped {
+		c.Close()
+		return false
+	} else if tracked {
+		s.conns[c] = true
+	} else {
+		delete(s.conns, c)
+	}
+
+	return true
+}
+
+func (s *Server) accept() {
+
+	defer s.wg.Done()
+
+	for {
+
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if !s.track(conn, true) {
+			continue
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+
+			defer s.wg.Done()
+			defer s.track(conn, false)
+			defer conn.Close()
+
+			err := serve(conn)
+			if err != nil {
+				log.Debug(context.Background(), "smtp connection closed", zap.String("error", err.Error()))
+			}
+		}()
+	}
+}
+
+func serve(c net.Conn) error {
+
+	ss := &session{conn: textproto.NewConn(c)}
+
+	err := ss.reply(REPLY_READY, HOSTNAME+" ESMTP ready")
+	if err != nil {
+		return err
+	}
+
+	for {
+
+		c.SetDeadline(time.Now().Add(CONNECTION_IDLE))
+
+		line, err := ss.conn.ReadLine()
+		if err != nil {
+			return err
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+
+		switch verb {
+		case "HELO":
+			err = ss.reply(REPLY_OK, HOSTNAME)
+		case "EHLO":
+			err = ss.reply(REPLY_OK, HOSTNAME, "8BITMIME", "AUTH PLAIN LOGIN", "SIZE "+strconv.Itoa(MAX_MESSAGE_SIZE))
+		case "MAIL":
+			err = ss.mailFrom(arg)
+		case "RCPT":
+			err = ss.rcptTo(arg)
+		case "DATA":
+			err = ss.data()
+		case "AUTH":
+			err = ss.auth(arg)
+		case "RSET":
+			ss.reset()
+			err = ss.reply(REPLY_OK, "OK")
+		case "NOOP":
+			err = ss.reply(REPLY_OK, "OK")
+		case "QUIT":
+			ss.reply(REPLY_BYE, "bye")
+			return nil
+		default:
+			err = ss.reply(REPLY_UNKNOWN, "command not implemented")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (ss *session) reply(code int, lines ...string) error {
+
+	for i, line := range lines {
+
+		separator := " "
+		if i < len(lines)-1 {
+			separator = "-"
+		}
+
+		err := ss.conn.PrintfLine("%d%s%s", code, separator, line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ss *session) reset() {
+
+	ss.from = ""
+	ss.to = nil
+	ss.mail = false
+}
+
+func (ss *session) mailFrom(arg string) error {
+
+	address, ok := getPathArg(arg, MAIL_PREFIX)
+	if !ok {
+		return ss.reply(REPLY_SYNTAX, "syntax: MAIL FROM:<address>")
+	}
+
+	ss.reset()
+	ss.from = address
+	ss.mail = true
+
+	return ss.reply(REPLY_OK, "OK")
+}
+
+func (ss *session) rcptTo(arg string) error {
+
+	if !ss.mail {
+		return ss.reply(REPLY_BAD_SEQUENCE, "MAIL first")
+	}
+
+	address, ok := getPathArg(arg, RCPT_PREFIX)
+	if !ok || address == "" {
+		return ss.reply(REPLY_SYNTAX, "syntax: RCPT TO:<address>")
+	}
+
+	if len(ss.to) >= MAX_RECIPIENTS {
+		return ss.reply(REPLY_TOO_MANY, "too many recipients")
+	}
+
+	ss.to = append(ss.to, address)
+
+	return ss.reply(REPLY_OK, "OK")
+}
+
+func (ss *session) data() error {
+
+	if len(ss.to) == 0 {
+		return ss.reply(REPLY_BAD_SEQUENCE, "RCPT first")
+	}
+
+	err := ss.reply(REPLY_DATA_INPUT, "end data with <CR><LF>.<CR><LF>")
+	if err != nil {
+		return err
+	}
+
+	reader := ss.conn.DotReader()
+
+	data, err := io.ReadAll(io.LimitReader(reader, MAX_MESSAGE_SIZE+1))
+	if err != nil {
+		return err
+	}
+
+	// read the rest of a message too big
+	_, err = io.Copy(io.Discard, reader)
+	if err != nil {
+		return err
+	}
+
+	defer ss.reset()
+
+	if len(data) > MAX_MESSAGE_SIZE {
+		return ss.reply(REPLY_TOO_BIG, "message too big")
+	}
+
+	e := add(parse(ss.from, ss.to, data))
+
+	log.Debug(context.Background(), "email received", zap.String("id", e.Id), zap.String("from", e.From), zap.Strings("to", e.To), zap.String("subject", e.Subject))
+
+	return ss.reply(REPLY_OK, "OK queued as "+e.Id)
+}
+
+// auth accepts any credentials
+func (ss *session) auth(arg string) error {
+
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	switch strings.ToUpper(mechanism) {
+	case AUTH_PLAIN:
+		if initial == "" {
+			err := ss.reply(REPLY_AUTH_INPUT, "")
+			if err != nil {
+				return err
+			}
+			_, err = ss.conn.ReadLine()
+			if err != nil {
+				return err
+			}
+		}
+	case AUTH_LOGIN:
+		prompts := []string{"VXNlcm5hbWU6", "UGFzc3dvcmQ6"}
+		if initial != "" {
+			prompts = prompts[1:]
+		}
+		for _, prompt := range prompts {
+			err := ss.reply(REPLY_AUTH_INPUT, prompt)
+			if err != nil {
+				return err
+			}
+			_, err = ss.conn.ReadLine()
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return ss.reply(REPLY_UNKNOWN, "unsupported authentication mechanism")
+	}
+
+	return ss.reply(REPLY_AUTH_OK, "authentication succeeded")
+}
+
+// getPathArg returns the address of "FROM:<address> [params]"
+func getPathArg(arg string, prefix string) (string, bool) {
+
+	arg = strings.TrimSpace(arg)
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	path := strings.TrimSpace(arg[len(prefix):])
+	path, _, _ = strings.Cut(path, " ")
+
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+
+	return path[1 : len(path)-1], true
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package smtp
+
+import (
+	"alfred/internal/log"
+	"encoding/base64"
+	netsmtp "net/smtp"
+	"strings"
+	"testing"
+)
+
+const multipartMail = "From: Alfred <alfred@example.com>\r\n" +
+	"To: bruce@example.com\r\n" +
+	"Subject: =?UTF-8?B?V2VsY29tZSDwn5GL?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Hello =3D Bruce\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Hello Bruce</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain; name=\"invoice.txt\"\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.txt\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"dG90YWw6IDQy\r\n" +
+	"--outer--\r\n"
+
+func TestSmtpServer(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+
+	s, err := Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	Clear()
+
+	// any credentials are accepted
+	auth := netsmtp.PlainAuth("", "user", "password", "127.0.0.1")
+	err = netsmtp.SendMail(s.Addr().String(), auth, "alfred@example.com", []string{"bruce@example.com", "lucius@example.com"}, []byte(multipartMail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emails := List(Filter{To: "lucius"})
+	if len(emails) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(emails))
+	}
+
+	e := emails[0]
+
+	if e.From != "alfred@example.com" || len(e.To) != 2 {
+		t.Errorf("unexpected envelope %s %v", e.From, e.To)
+	}
+
+	if e.Subject != "Welcome 👋" {
+		t.Errorf("unexpected subject %q", e.Subject)
+	}
+
+	if strings.TrimSpace(e.Text) != "Hello = Bruce" {
+		t.Errorf("unexpected text %q", e.Text)
+	}
+
+	if strings.TrimSpace(e.Html) != "<p>Hello Bruce</p>" {
+		t.Errorf("unexpected html %q", e.Html)
+	}
+
+	if len(e.Attachments) != 1 || e.Attachments[0].Filename != "invoice.txt" {
+		t.Fatalf("unexpected attachments %v", e.Attachments)
+	}
+
+	content, _ := base64.StdEncoding.DecodeString(e.Attachments[0].Content)
+	if string(content) != "total: 42" {
+		t.Errorf("unexpected attachment content %q", content)
+	}
+
+	if _, ok := Get(e.Id); !ok {
+		t.Errorf("email %s not found", e.Id)
+	}
+
+	if len(List(Filter{Subject: "invoice"})) != 0 {
+		t.Error("subject filter should not match")
+	}
+
+	Clear()
+
+	if len(List(Filter{})) != 0 {
+		t.Error("mailbox should be empty")
+	}
+}
+
+func TestMaxEmails(t *testing.T) {
+
+	Clear()
+	SetMaxEmails(2)
+	defer SetMaxEmails(DEFAULT_MAX_EMAILS)
+
+	for _, subject := range []string{"one", "two", "three"} {
+		add(Email{Subject: subject})
+	}
+
+	emails := List(Filter{})
+	if len(emails) != 2 || emails[0].Subject != "two" {
+		t.Errorf("unexpected emails %v", emails)
+	}
+
+	Clear()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package soap
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	xj "github.com/basgys/goxml2json"
+)
+
+const SOAP_ACTION_HEADER = "SOAPAction"
+
+// GetAction returns the request SOAP action, from the SOAPAction header
+// (SOAP 1.1) or from the content type action parameter (SOAP 1.2).
+func GetAction(r *http.Request) string {
+
+	action := r.Header.Get(SOAP_ACTION_HEADER)
+	if action != "" {
+		return strings.Trim(action, "\"")
+	}
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return params["action"]
+}
+
+// GetOperation returns the local name of the first element found in the
+// envelope body.
+func GetOperation(body []byte) (string, error) {
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	inBody := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", errors.New("soap envelope has no body operation")
+		}
+		if err != nil {
+			return "", errors.New("soap envelope parsing failed: " + err.Error())
+		}
+
+		element, isStartElement := token.(xml.StartElement)
+		if !isStartElement {
+			continue
+		}
+
+		if inBody {
+			return element.Name.Local, nil
+		}
+
+		inBody = element.Name.Local == "Body"
+	}
+}
+
+// IsXml returns true if the content type is a xml one (text/xml,
+// application/soap+xml ...).
+func IsXml(contentType string) bool {
+
+	return strings.Contains(contentType, "xml")
+}
+
+// XmlToObject converts a xml document into an object, attributes are
+// prefixed by '-' and element text is set in '#content' when the element
+// has attributes.
+func XmlToObject(body []byte) (map[string]interface{}, error) {
+
+	jsonBuffer, err := xj.Convert(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	err = json.Unmarshal(jsonBuffer.Bytes(), &object)
+	if err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
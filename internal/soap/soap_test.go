@@ -0,0 +1,77 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package soap
+
+import (
+	"net/http"
+	"testing"
+)
+
+const testEnvelope = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Header><Token>abc</Token></soap:Header>
+	<soap:Body>
+		<m:GetUser xmlns:m="urn:users"><m:UserId>42</m:UserId></m:GetUser>
+	</soap:Body>
+</soap:Envelope>`
+
+func TestGetAction(t *testing.T) {
+
+	r, _ := http.NewRequest(http.MethodPost, "/soap", nil)
+	r.Header.Set(SOAP_ACTION_HEADER, "\"urn:users/GetUser\"")
+
+	if GetAction(r) != "urn:users/GetUser" {
+		t.Errorf("soap 1.1 action is: '%s', want: 'urn:users/GetUser'", GetAction(r))
+	}
+
+	r.Header.Del(SOAP_ACTION_HEADER)
+	r.Header.Set("Content-Type", "application/soap+xml; charset=utf-8; action=\"urn:users/GetUser\"")
+
+	if GetAction(r) != "urn:users/GetUser" {
+		t.Errorf("soap 1.2 action is: '%s', want: 'urn:users/GetUser'", GetAction(r))
+	}
+}
+
+func TestGetOperation(t *testing.T) {
+
+	operation, err := GetOperation([]byte(testEnvelope))
+	if err != nil {
+		t.Errorf("get operation failed with error: %v", err)
+	}
+
+	if operation != "GetUser" {
+		t.Errorf("operation is: '%s', want: 'GetUser'", operation)
+	}
+
+	_, err = GetOperation([]byte("<a><b/></a>"))
+	if err == nil {
+		t.Errorf("get operation without envelope body should fail")
+	}
+}
+
+func TestXmlToObject(t *testing.T) {
+
+	object, err := XmlToObject([]byte(testEnvelope))
+	if err != nil {
+		t.Errorf("xml to object failed with error: %v", err)
+	}
+
+	userId := object["Envelope"].(map[string]interface{})["Body"].(map[string]interface{})["GetUser"].(map[string]interface{})["UserId"]
+	if userId != "42" {
+		t.Errorf("user id is: '%v', want: '42'", userId)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package socket
+
+import (
+	"alfred/internal/function"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const READ_BUFFER_SIZE = 64 * 1024
+
+// Listeners of the socket mocks
+type Listeners struct {
+	mutex   sync.Mutex
+	closers map[io.Closer]bool
+	stopped bool
+	wg      sync.WaitGroup
+
+	// addresses listened
+	addresses []string
+}
+
+// Start listens on the socket mocks ports, a listener error stops the ones
+// already started.
+func Start(mocks []*mock.Mock, functions function.FunctionCollection) (*Listeners, error) {
+
+	l := &Listeners{closers: map[io.Closer]bool{}}
+
+	for _, m := range mocks {
+
+		var err error
+
+		if m.Socket.Protocol == mock.SOCKET_UDP {
+			err = l.listenUdp(m, functions)
+		} else {
+			err = l.listenTcp(m, functions)
+		}
+
+		if err != nil {
+			l.Stop()
+			return nil, errors.New("socket mock " + m.GetName() + ": " + err.Error())
+		}
+
+		log.Info(context.Background(), m.Socket.Protocol+" mock "+m.GetName()+" listening on "+m.Socket.GetAddress())
+		l.addresses = append(l.addresses, m.Socket.Protocol+"://"+m.Socket.GetAddress())
+	}
+
+	return l, nil
+}
+
+func (l *Listeners) String() string {
+
+	return strconv.Itoa(len(l.addresses)) + " socket mock(s)"
+}
+
+// Stop closes the listeners and their connections
+func (l *Listeners) Stop() {
+
+	l.mutex.Lock()
+	l.stopped = true
+	for c := range l.closers {
+		c.Close()
+	}
+	l.mutex.Unlock()
+
+	l.wg.Wait()
+}
+
+func (l *Listeners) track(c io.Closer, tracked bool) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if tracked && l.stopped {
+		// accepted while stopping
+		c.Close()
+	} else if tracked {
+		l.closers[c] = true
+	} else {
+		delete(l.closers, c)
+	}
+}
+
+func (l *Listeners) listenTcp(m *mock.Mock, functions function.FunctionCollection) error {
+
+	listener, err := net.Listen("tcp", m.Socket.GetAddress())
+	if err != nil {
+		return err
+	}
+
+	l.track(listener, true)
+	l.wg.Add(1)
+
+	go func() {
+
+		defer l.wg.Done()
+
+		for {
+
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			l.track(conn, true)
+			l.wg.Add(1)
+
+			go func() {
+
+				defer l.wg.Done()
+				defer l.track(conn, false)
+				defer conn.Close()
+
+				buffer := make([]byte, READ_BUFFER_SIZE)
+
+				for {
+
+					n, err := conn.Read(buffer)
+					if err != nil {
+						return
+					}
+
+					reply, close := answer(m, functions, conn.RemoteAddr().String(), buffer[:n])
+
+					if len(reply) > 0 {
+						_, err = conn.Write(reply)
+						if err != nil {
+							log.Error(context.Background(), "failed to write", err, zap.String("mock-name", m.GetName()))
+							return
+						}
+					}
+
+					if close || m.Socket.CloseAfterReply {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return nil
+}
+
+func (l *Listeners) listenUdp(m *mock.Mock, functions function.FunctionCollection) error {
+
+	conn, err := net.ListenPacket("udp", m.Socket.GetAddress())
+	if err != nil {
+		return err
+	}
+
+	l.track(conn, true)
+	l.wg.Add(1)
+
+	go func() {
+
+		defer l.wg.Done()
+
+		buffer := make([]byte, READ_BUFFER_SIZE)
+
+		for {
+
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			reply, _ := answer(m, functions, addr.String(), buffer[:n])
+
+			if len(reply) > 0 {
+				_, err = conn.WriteTo(reply, addr)
+				if err != nil {
+					log.Error(context.Background(), "failed to write", err, zap.String("mock-name", m.GetName()))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// answer returns the reply to the data, from the onData function of the mock
+// function file, or from the mock replies. Replies are delayed here.
+func answer(m *mock.Mock, functions function.FunctionCollection, remoteAddr string, data []byte) ([]byte, bool) {
+
+	ctx := context.Background()
+
+	log.Debug(ctx, m.Socket.Protocol+" data received",
+		zap.String("mock-name", m.GetName()),
+		zap.String("remote-addr", remoteAddr),
+		zap.Int("data-size", len(data)),
+	)
+
+	if m.HasFunctionFile() {
+
+		f, err := functions.GetFunction(m.FunctionFile)
+		if err == nil && f.HasFuncOnData {
+
+			reply, err := f.OnDataFunc(*m, function.NewSocketData(m.Socket.Protocol, remoteAddr, data))
+			if err != nil {
+				log.Error(ctx, "error using user js onData function", err, zap.String("mock-name", m.GetName()))
+				return nil, false
+			}
+
+			if reply == nil {
+				return nil, false
+			}
+
+			b, err := reply.Bytes()
+			if err != nil {
+				log.Error(ctx, "js onData reply decoding failed", err, zap.String("mock-name", m.GetName()))
+				return nil, reply.Close
+			}
+
+			time.Sleep(time.Duration(reply.Delay) * time.Millisecond)
+
+			return b, reply.Close
+		}
+	}
+
+	reply, found := m.Socket.FindReply(data)
+	if !found {
+		return nil, false
+	}
+
+	time.Sleep(reply.GetDelay())
+
+	return reply.GetBytes(), false
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package socket
+
+import (
+	"alfred/internal/function"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func buildMock(t *testing.T, json string) *mock.Mock {
+
+	m, err := mock.BuildMockFromJson([]byte(json))
+	if err != nil {
+		t.Fatalf("mock build failed with error: %v", err)
+	}
+
+	return &m
+}
+
+func TestTcpAndUdpMocks(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+
+	tcpPort, udpPort := freePort(t), freePort(t)
+
+	tcpMock := buildMock(t, `{"name": "tcp", "socket": {"protocol": "tcp", "ip": "127.0.0.1", "port": "`+tcpPort+`",
+		"replies": [{"match": "PING", "reply": "PONG\n"}, {"match-hex": "0102", "reply-hex": "0a0b0c"}]}}`)
+	udpMock := buildMock(t, `{"name": "udp", "function-file": "udp.js", "socket": {"protocol": "udp", "ip": "127.0.0.1", "port": "`+udpPort+`"}}`)
+
+	f, err := function.CreateFunction("udp.js", []byte(`
+		function onData(mock, data) {
+			return { text: mock.name + " got " + data.hex };
+		}`))
+	if err != nil {
+		t.Fatalf("function creation failed with error: %v", err)
+	}
+
+	listeners, err := Start([]*mock.Mock{tcpMock, udpMock}, function.FunctionCollection{f})
+	if err != nil {
+		t.Fatalf("start failed with error: %v", err)
+	}
+	defer listeners.Stop()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+tcpPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	conn.Write([]byte("PING"))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "PONG\n" {
+		t.Errorf("tcp reply is: %q, %v", line, err)
+	}
+
+	conn.Write([]byte{0x00, 0x01, 0x02})
+	reply := make([]byte, 3)
+	_, err = conn.Read(reply)
+	if err != nil || string(reply) != "\x0a\x0b\x0c" {
+		t.Errorf("tcp hex reply is: %x, %v", reply, err)
+	}
+
+	udp, err := net.Dial("udp", "127.0.0.1:"+udpPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	udp.SetDeadline(time.Now().Add(time.Second))
+
+	udp.Write([]byte("hi"))
+	datagram := make([]byte, 64)
+	n, err := udp.Read(datagram)
+	if err != nil || string(datagram[:n]) != "udp got 6869" {
+		t.Errorf("udp reply is: %q, %v", datagram[:n], err)
+	}
+
+	_, err = mock.BuildMockFromJson([]byte(`{"name": "bad", "socket": {"protocol": "sctp", "port": "1"}}`))
+	if err == nil {
+		t.Errorf("unknown socket protocol should fail")
+	}
+}
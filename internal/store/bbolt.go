@@ -0,0 +1,158 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const BBOLT_OPEN_TIMEOUT = 5 * time.Second
+
+// One bucket per namespace
+type bboltStore struct {
+	db *bolt.DB
+}
+
+func newBboltStore(path string) (*bboltStore, error) {
+
+	if path == "" {
+		return nil, errors.New("bbolt store path is empty")
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// a second alfred instance on the same file fails after the timeout
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: BBOLT_OPEN_TIMEOUT})
+	if err != nil {
+		return nil, errors.New("bbolt store " + path + ": " + err.Error())
+	}
+
+	return &bboltStore{db: db}, nil
+}
+
+func (s *bboltStore) Get(namespace string, key string) (string, bool, error) {
+
+	var value []byte
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(namespace)); b != nil {
+			if v := b.Get([]byte(key)); v != nil {
+				value = append([]byte{}, v...)
+			}
+		}
+
+		return nil
+	})
+
+	return string(value), value != nil, err
+}
+
+func (s *bboltStore) Set(namespace string, key string, value string) error {
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+
+		b, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), []byte(value))
+	})
+}
+
+func (s *bboltStore) Delete(namespace string, key string) error {
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+
+		err := b.Delete([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		if k, _ := b.Cursor().First(); k == nil {
+			return tx.DeleteBucket([]byte(namespace))
+		}
+
+		return nil
+	})
+}
+
+func (s *bboltStore) List(namespace string) (map[string]string, error) {
+
+	entries := map[string]string{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			entries[string(k)] = string(v)
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+func (s *bboltStore) Namespaces() ([]string, error) {
+
+	namespaces := []string{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			namespaces = append(namespaces, string(name))
+			return nil
+		})
+	})
+
+	return namespaces, err
+}
+
+func (s *bboltStore) Wipe(namespace string) error {
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+
+		err := tx.DeleteBucket([]byte(namespace))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+func (s *bboltStore) Close() error {
+
+	return s.db.Close()
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"sort"
+	"sync"
+)
+
+type memoryStore struct {
+	mutex sync.RWMutex
+	data  map[string]map[string]string
+}
+
+func newMemoryStore() *memoryStore {
+
+	return &memoryStore{data: map[string]map[string]string{}}
+}
+
+func (s *memoryStore) Get(namespace string, key string) (string, bool, error) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, exists := s.data[namespace][key]
+
+	return value, exists, nil
+}
+
+func (s *memoryStore) Set(namespace string, key string, value string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.data[namespace] == nil {
+		s.data[namespace] = map[string]string{}
+	}
+	s.data[namespace][key] = value
+
+	return nil
+}
+
+func (s *memoryStore) Delete(namespace string, key string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data[namespace], key)
+	if len(s.data[namespace]) == 0 {
+		delete(s.data, namespace)
+	}
+
+	return nil
+}
+
+func (s *memoryStore) List(namespace string) (map[string]string, error) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := map[string]string{}
+	for key, value := range s.data[namespace] {
+		entries[key] = value
+	}
+
+	return entries, nil
+}
+
+func (s *memoryStore) Namespaces() ([]string, error) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	namespaces := []string{}
+	for namespace := range s.data {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces, nil
+}
+
+func (s *memoryStore) Wipe(namespace string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data, namespace)
+
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+
+	return nil
+}
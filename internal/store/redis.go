@@ -0,0 +1,191 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	REDIS_POOL_SIZE = 16
+	REDIS_TIMEOUT   = 5 * time.Second
+
+	DEFAULT_REDIS_PREFIX = "alfred"
+)
+
+// Deletes a key of a namespace hash, and the namespace once its hash is
+// empty, in one step so a replica setting a key meanwhile keeps it listed.
+var redisDelete = redis.NewScript(`
+redis.call('HDEL', KEYS[1], ARGV[1])
+if redis.call('HLEN', KEYS[1]) == 0 then
+	redis.call('SREM', KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// Replicas sharing the redis server and prefix share their state: one hash per
+// namespace, a set of the namespaces, and the lists of the request journal.
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisStore(c RedisConfig) (*redisStore, error) {
+
+	if c.Address == "" {
+		return nil, errors.New("redis store address is empty")
+	}
+
+	prefix := c.Prefix
+	if prefix == "" {
+		prefix = DEFAULT_REDIS_PREFIX
+	}
+
+	s := &redisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:         c.Address,
+			Password:     c.Password,
+			DB:           c.Db,
+			PoolSize:     REDIS_POOL_SIZE,
+			DialTimeout:  REDIS_TIMEOUT,
+			ReadTimeout:  REDIS_TIMEOUT,
+			WriteTimeout: REDIS_TIMEOUT,
+		}),
+		prefix: prefix,
+	}
+
+	// fail at startup on a wrong address or password
+	err := s.client.Ping(context.Background()).Err()
+	if err != nil {
+		s.client.Close()
+		return nil, errors.New("redis store " + c.Address + ": " + err.Error())
+	}
+
+	return s, nil
+}
+
+func (s *redisStore) namespaceKey(namespace string) string {
+
+	return s.prefix + ":store:" + namespace
+}
+
+func (s *redisStore) namespacesKey() string {
+
+	return s.prefix + ":namespaces"
+}
+
+func (s *redisStore) listKey(list string) string {
+
+	return s.prefix + ":list:" + list
+}
+
+func (s *redisStore) Get(namespace string, key string) (string, bool, error) {
+
+	value, err := s.client.HGet(context.Background(), s.namespaceKey(namespace), key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+func (s *redisStore) Set(namespace string, key string, value string) error {
+
+	ctx := context.Background()
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.namespaceKey(namespace), key, value)
+		pipe.SAdd(ctx, s.namespacesKey(), namespace)
+		return nil
+	})
+
+	return err
+}
+
+func (s *redisStore) Delete(namespace string, key string) error {
+
+	keys := []string{s.namespaceKey(namespace), s.namespacesKey()}
+
+	return redisDelete.Run(context.Background(), s.client, keys, key, namespace).Err()
+}
+
+func (s *redisStore) List(namespace string) (map[string]string, error) {
+
+	return s.client.HGetAll(context.Background(), s.namespaceKey(namespace)).Result()
+}
+
+func (s *redisStore) Namespaces() ([]string, error) {
+
+	namespaces, err := s.client.SMembers(context.Background(), s.namespacesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(namespaces)
+
+	return namespaces, nil
+}
+
+func (s *redisStore) Wipe(namespace string) error {
+
+	ctx := context.Background()
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.namespaceKey(namespace))
+		pipe.SRem(ctx, s.namespacesKey(), namespace)
+		return nil
+	})
+
+	return err
+}
+
+// Push appends a value to a shared list, only its last max values are kept
+func (s *redisStore) Push(list string, value string, max int) error {
+
+	ctx := context.Background()
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, s.listKey(list), value)
+		pipe.LTrim(ctx, s.listKey(list), int64(-max), -1)
+		return nil
+	})
+
+	return err
+}
+
+// Range returns the values of a shared list, oldest first
+func (s *redisStore) Range(list string) ([]string, error) {
+
+	return s.client.LRange(context.Background(), s.listKey(list), 0, -1).Result()
+}
+
+func (s *redisStore) ClearList(list string) error {
+
+	return s.client.Del(context.Background(), s.listKey(list)).Err()
+}
+
+func (s *redisStore) Close() error {
+
+	return s.client.Close()
+}
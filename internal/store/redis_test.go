@@ -0,0 +1,157 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func startFakeRedis(t *testing.T, password string) string {
+
+	server := miniredis.RunT(t)
+	if password != "" {
+		server.RequireAuth(password)
+	}
+
+	return server.Addr()
+}
+
+func TestRedisStore(t *testing.T) {
+
+	address := startFakeRedis(t, "secret")
+
+	err := Configure(Config{Type: TYPE_REDIS, Redis: RedisConfig{Address: address, Password: "secret", Db: 2}})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	testStore(t, Get())
+
+	// a second replica shares the state
+	replica, err := newRedisStore(RedisConfig{Address: address, Password: "secret", Db: 2})
+	if err != nil {
+		t.Fatalf("replica failed with error: %v", err)
+	}
+	defer replica.Close()
+
+	err = Get().Set("core", "counter", "42")
+	if err != nil {
+		t.Fatalf("set failed with error: %v", err)
+	}
+
+	value, exists, _ := replica.Get("core", "counter")
+	if !exists || value != "42" {
+		t.Errorf("replica counter is: %s", value)
+	}
+
+	// another prefix is another cluster
+	other, err := newRedisStore(RedisConfig{Address: address, Password: "secret", Db: 2, Prefix: "staging"})
+	if err != nil {
+		t.Fatalf("other cluster failed with error: %v", err)
+	}
+	defer other.Close()
+
+	_, exists, _ = other.Get("core", "counter")
+	if exists {
+		t.Errorf("counter should not be shared between prefixes")
+	}
+
+	// a key set by a replica while another deletes the last key keeps its
+	// namespace listed
+	for i := 0; i < 50; i++ {
+		Get().Set("race", "a", "1")
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			replica.Set("race", "b", "1")
+		}()
+		go func() {
+			defer wg.Done()
+			Get().Delete("race", "a")
+		}()
+		wg.Wait()
+
+		namespaces, _ := replica.Namespaces()
+		if !reflect.DeepEqual(namespaces, []string{"core", "race"}) {
+			t.Fatalf("namespaces are: %v", namespaces)
+		}
+		replica.Delete("race", "b")
+	}
+
+	err = Configure(Config{Type: TYPE_REDIS, Redis: RedisConfig{Address: address, Password: "wrong"}})
+	if err == nil {
+		t.Errorf("wrong password should fail")
+	}
+
+	err = Configure(Config{Type: TYPE_REDIS})
+	if err == nil {
+		t.Errorf("empty address should fail")
+	}
+
+	Close()
+}
+
+func TestRedisLists(t *testing.T) {
+
+	address := startFakeRedis(t, "")
+
+	err := Configure(Config{Type: TYPE_REDIS, Redis: RedisConfig{Address: address}})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+	defer Configure(Config{})
+
+	lists, ok := Shared()
+	if !ok {
+		t.Fatalf("redis store should be shared")
+	}
+
+	for i := 1; i <= 5; i++ {
+		err = lists.Push("requests", strconv.Itoa(i), 3)
+		if err != nil {
+			t.Fatalf("push failed with error: %v", err)
+		}
+	}
+
+	values, _ := lists.Range("requests")
+	if !reflect.DeepEqual(values, []string{"3", "4", "5"}) {
+		t.Errorf("values are: %v", values)
+	}
+
+	err = lists.ClearList("requests")
+	if err != nil {
+		t.Fatalf("clear failed with error: %v", err)
+	}
+
+	values, _ = lists.Range("requests")
+	if len(values) != 0 {
+		t.Errorf("values should be cleared: %v", values)
+	}
+
+	Configure(Config{})
+
+	_, ok = Shared()
+	if ok {
+		t.Errorf("memory store should not be shared")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	TYPE_MEMORY = "memory"
+	TYPE_BBOLT  = "bbolt"
+	TYPE_REDIS  = "redis"
+
+	// Namespace of the core mocks, services use their name
+	CORE_NAMESPACE = "core"
+)
+
+// Data kept by stateful mocks, the memory store is lost at exit while the
+// bbolt store persists in its file. The redis store is shared by the replicas
+// of a cluster.
+type Config struct {
+	Type  string      `json:"type"`
+	Path  string      `json:"path"`
+	Redis RedisConfig `json:"redis"`
+}
+
+// Replicas using the same prefix share their state
+type RedisConfig struct {
+	Address  string `json:"address"`
+	Password string `json:"password"`
+	Db       int    `json:"db"`
+	Prefix   string `json:"prefix"`
+}
+
+// Key/value store, values are json documents. Each mock collection has its
+// own namespace.
+type Store interface {
+	Get(namespace string, key string) (string, bool, error)
+	Set(namespace string, key string, value string) error
+	Delete(namespace string, key string) error
+	List(namespace string) (map[string]string, error)
+	Namespaces() ([]string, error)
+	Wipe(namespace string) error
+	Close() error
+}
+
+// Capped lists shared between replicas, implemented by the redis store for
+// the request journal.
+type Lists interface {
+	Push(list string, value string, max int) error
+	Range(list string) ([]string, error)
+	ClearList(list string) error
+}
+
+var (
+	current      Store = newMemoryStore()
+	currentMutex sync.RWMutex
+)
+
+// Configure opens the configured store. The previous one is closed first, a
+// bbolt file can't be opened twice; the memory store is used on errors.
+func Configure(c Config) error {
+
+	currentMutex.Lock()
+	defer currentMutex.Unlock()
+
+	err := current.Close()
+	if err != nil {
+		return err
+	}
+
+	current = newMemoryStore()
+
+	switch c.Type {
+	case "", TYPE_MEMORY:
+		return nil
+	case TYPE_BBOLT:
+		s, err := newBboltStore(c.Path)
+		if err != nil {
+			return err
+		}
+		current = s
+		return nil
+	case TYPE_REDIS:
+		s, err := newRedisStore(c.Redis)
+		if err != nil {
+			return err
+		}
+		current = s
+		return nil
+	}
+
+	return errors.New("store type '" + c.Type + "' not supported, use " + TYPE_MEMORY + ", " + TYPE_BBOLT + " or " + TYPE_REDIS)
+}
+
+func Get() Store {
+
+	currentMutex.RLock()
+	defer currentMutex.RUnlock()
+
+	return current
+}
+
+// Shared returns the lists of the store when it is shared between replicas
+func Shared() (Lists, bool) {
+
+	lists, ok := Get().(Lists)
+
+	return lists, ok
+}
+
+func Close() error {
+
+	return Get().Close()
+}
+
+// Namespace of a mock collection, from its service name
+func Namespace(service string) string {
+
+	if service == "" {
+		return CORE_NAMESPACE
+	}
+
+	return service
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+
+	err := s.Set("core", "cart", `{"items":2}`)
+	if err == nil {
+		err = s.Set("payments", "cart", `{"items":5}`)
+	}
+	if err != nil {
+		t.Fatalf("set failed with error: %v", err)
+	}
+
+	value, exists, err := s.Get("core", "cart")
+	if err != nil || !exists || value != `{"items":2}` {
+		t.Errorf("core cart is: %s, %v, %v", value, exists, err)
+	}
+
+	_, exists, _ = s.Get("core", "missing")
+	if exists {
+		t.Errorf("missing key should not exist")
+	}
+
+	namespaces, _ := s.Namespaces()
+	if !reflect.DeepEqual(namespaces, []string{"core", "payments"}) {
+		t.Errorf("namespaces are: %v", namespaces)
+	}
+
+	err = s.Wipe("payments")
+	if err != nil {
+		t.Fatalf("wipe failed with error: %v", err)
+	}
+
+	entries, _ := s.List("payments")
+	if len(entries) != 0 {
+		t.Errorf("payments should be wiped: %v", entries)
+	}
+
+	err = s.Delete("core", "cart")
+	if err != nil {
+		t.Fatalf("delete failed with error: %v", err)
+	}
+
+	namespaces, _ = s.Namespaces()
+	if len(namespaces) != 0 {
+		t.Errorf("namespaces are: %v", namespaces)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+
+	testStore(t, newMemoryStore())
+}
+
+func TestBboltStore(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "store", "alfred.db")
+
+	err := Configure(Config{Type: TYPE_BBOLT, Path: path})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	testStore(t, Get())
+
+	err = Get().Set("core", "counter", "42")
+	if err != nil {
+		t.Fatalf("set failed with error: %v", err)
+	}
+
+	// data survives a restart
+	err = Configure(Config{Type: TYPE_BBOLT, Path: path})
+	if err != nil {
+		t.Fatalf("configure failed with error: %v", err)
+	}
+
+	value, _, _ := Get().Get("core", "counter")
+	if value != "42" {
+		t.Errorf("counter is: %s", value)
+	}
+
+	err = Configure(Config{Type: "etcd"})
+	if err == nil {
+		t.Errorf("unknown store type should fail")
+	}
+
+	Close()
+}
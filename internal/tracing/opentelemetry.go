@@ -49,6 +49,7 @@ type OtelConfig struct {
 	ServiceNamespace      string
 	DeploymentEnvironment string
 	ExporterOtlpEndpoint  string
+	ExporterOtlpHeaders   string
 	ExporterInsecure      bool
 	TracesSampler         string
 	TracesSamplerArg      string
@@ -72,19 +73,20 @@ func initTracer(ctx context.Context, config OtelConfig) (func(context.Context) e
 	//exporter
 	if config.ExporterOtlpEndpoint != "" {
 
-		client := otlptracehttp.NewClient(
-
+		clientOpts := []otlptracehttp.Option{
 			otlptracehttp.WithEndpoint(config.ExporterOtlpEndpoint),
-		)
+		}
 
 		if config.ExporterInsecure {
+			clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+		}
 
-			client = otlptracehttp.NewClient(
-				otlptracehttp.WithInsecure(),
-				otlptracehttp.WithEndpoint(config.ExporterOtlpEndpoint),
-			)
+		if config.ExporterOtlpHeaders != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithHeaders(parseHeaders(config.ExporterOtlpHeaders)))
 		}
 
+		client := otlptracehttp.NewClient(clientOpts...)
+
 		exporter, err := otlptrace.New(
 			ctx,
 			client,
@@ -151,8 +153,38 @@ func Init(ctx context.Context, otelConfig OtelConfig) (func(context.Context) err
 func AddTracingMiddlware(handler http.Handler) http.Handler {
 
 	//mux.Use(otelmux.Middleware("alfred-server-name"))
-	return otelhttp.NewHandler(handler, "alfred-server-name")
+	//span is named with the method, then with the mock route when a mock is used
+	return otelhttp.NewHandler(handler, "alfred-server-name", otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+		return r.Method
+	}))
+
+}
+
+// SetSpanRoute names the request span with the route of the mock used,
+// following the http server spans conventions.
+func SetSpanRoute(span trace.Span, method string, route string) {
+
+	span.SetName(method + " " + route)
+	span.SetAttributes(semconv.HTTPRouteKey.String(route))
+}
+
+// Parse exporter headers, given as a 'key1=value1,key2=value2' list like the
+// OTEL_EXPORTER_OTLP_HEADERS variable.
+func parseHeaders(headersStr string) map[string]string {
+
+	headers := map[string]string{}
+
+	for _, header := range strings.Split(headersStr, ",") {
+
+		kv := strings.SplitN(header, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
 
+	return headers
 }
 
 func GetSpanContext(span trace.Span) (string, string, string) {
@@ -0,0 +1,66 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestParseHeaders(t *testing.T) {
+
+	headers := parseHeaders("Authorization=Basic abc=, x-scope = tenant ,broken")
+
+	if len(headers) != 2 || headers["Authorization"] != "Basic abc=" || headers["x-scope"] != "tenant" {
+		t.Errorf("parsed headers are: %v", headers)
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	handler := AddTracingMiddlware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetSpanRoute(GetSpanFromContext(r.Context()), r.Method, "/users/(.*)")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil).WithContext(context.Background())
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans count is: %d, want: 1", len(spans))
+	}
+
+	if spans[0].Name() != "GET /users/(.*)" {
+		t.Errorf("span name is: '%s', want: 'GET /users/(.*)'", spans[0].Name())
+	}
+
+	if spans[0].SpanContext().TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("span trace id is: '%s', want the incoming traceparent one", spans[0].SpanContext().TraceID().String())
+	}
+}
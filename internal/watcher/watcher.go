@@ -0,0 +1,125 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watcher
+
+import (
+	"alfred/internal/log"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// Watch calls onChange when files of the folders, sub folders included, are
+// created, written, removed or renamed. Events are debounced as editors often
+// write a file in several steps. Watching stops with the context.
+func Watch(ctx context.Context, dirs []string, debounce time.Duration, onChange func()) error {
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+
+		if dir == "" {
+			continue
+		}
+
+		err = addRecursive(fsWatcher, dir)
+		if err != nil {
+			log.Debug(ctx, "folder not watched: "+err.Error(), zap.String("folder", dir))
+		}
+	}
+
+	// changes are handled one at a time
+	var mutex sync.Mutex
+	handleChange := func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		onChange()
+	}
+
+	go func() {
+
+		defer fsWatcher.Close()
+
+		var timer *time.Timer
+
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				log.Debug(ctx, "file change detected", zap.String("file", event.Name), zap.String("operation", event.Op.String()))
+
+				// new folders are watched too
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = addRecursive(fsWatcher, event.Name)
+					}
+				}
+
+				if timer == nil {
+					timer = time.AfterFunc(debounce, handleChange)
+				} else {
+					timer.Reset(debounce)
+				}
+
+			case err, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warn(ctx, "files watcher error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func addRecursive(fsWatcher *fsnotify.Watcher, dir string) error {
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return fsWatcher.Add(path)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watcher
+
+import (
+	"alfred/internal/log"
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+
+	dir := t.TempDir()
+	subDir := filepath.Join(dir, "sub")
+	err := os.Mkdir(subDir, 0755)
+	if err != nil {
+		t.Fatalf("sub folder creation failed with error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var changes int32
+	err = Watch(ctx, []string{dir, "", filepath.Join(dir, "missing")}, 50*time.Millisecond, func() {
+		atomic.AddInt32(&changes, 1)
+	})
+	if err != nil {
+		t.Fatalf("watch failed with error: %v", err)
+	}
+
+	// several writes, a single change
+	for i := 0; i < 3; i++ {
+		err = os.WriteFile(filepath.Join(subDir, "mock.json"), []byte("{}"), 0644)
+		if err != nil {
+			t.Fatalf("file write failed with error: %v", err)
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	if atomic.LoadInt32(&changes) != 1 {
+		t.Errorf("changes count is: %d, want: 1", atomic.LoadInt32(&changes))
+	}
+}
@@ -0,0 +1,434 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wiremock
+
+import (
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"alfred/pkg/files"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// WireMock root folder layout
+	MAPPINGS_DIR = "mappings"
+	FILES_DIR    = "__files"
+)
+
+// Methods mocked for stubs matching any method
+var anyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+
+// StubMapping is a WireMock stub, the request url, method, headers, query
+// parameters and body patterns are used as matchers.
+type StubMapping struct {
+	Id       string       `json:"id"`
+	Name     string       `json:"name"`
+	Priority int          `json:"priority"`
+	Request  StubRequest  `json:"request"`
+	Response StubResponse `json:"response"`
+}
+
+type StubRequest struct {
+	Method          string                 `json:"method"`
+	Url             string                 `json:"url"`
+	UrlPath         string                 `json:"urlPath"`
+	UrlPattern      string                 `json:"urlPattern"`
+	UrlPathPattern  string                 `json:"urlPathPattern"`
+	Headers         map[string]interface{} `json:"headers"`
+	QueryParameters map[string]interface{} `json:"queryParameters"`
+	Cookies         map[string]interface{} `json:"cookies"`
+	BodyPatterns    []interface{}          `json:"bodyPatterns"`
+}
+
+type StubResponse struct {
+	Status                 int                    `json:"status"`
+	Body                   string                 `json:"body"`
+	JsonBody               json.RawMessage        `json:"jsonBody"`
+	Base64Body             string                 `json:"base64Body"`
+	BodyFileName           string                 `json:"bodyFileName"`
+	Headers                map[string]interface{} `json:"headers"`
+	FixedDelayMilliseconds int                    `json:"fixedDelayMilliseconds"`
+	DelayDistribution      *struct {
+		Type  string `json:"type"`
+		Lower int    `json:"lower"`
+		Upper int    `json:"upper"`
+	} `json:"delayDistribution"`
+	Fault string `json:"fault"`
+}
+
+// A mapping file contains one stub, or a stubs list
+type mappingFile struct {
+	StubMapping
+	Mappings []StubMapping `json:"mappings"`
+}
+
+// CreateMocksFromFolder converts the stub mappings of a WireMock root folder
+// (mappings and __files sub folders) into Alfred mocks.
+func CreateMocksFromFolder(path string) ([]*mock.Mock, error) {
+
+	matches, err := files.FindAllFiles(filepath.Join(path, MAPPINGS_DIR), "*.json")
+	if err != nil {
+		return nil, errors.New("wiremock mappings load failed: " + err.Error())
+	}
+
+	var stubs []StubMapping
+
+	for _, filePath := range matches {
+
+		fileContent, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		fileStubs, err := ParseMappings(fileContent)
+		if err != nil {
+			return nil, errors.New("wiremock mapping " + filePath + ": " + err.Error())
+		}
+
+		stubs = append(stubs, fileStubs...)
+	}
+
+	// the lower WireMock priority number wins, like the first Alfred mock
+	sort.SliceStable(stubs, func(i, j int) bool {
+		return getPriority(stubs[i]) < getPriority(stubs[j])
+	})
+
+	var mocks []*mock.Mock
+
+	for _, stub := range stubs {
+
+		stubMocks, err := ConvertStub(stub, filepath.Join(path, FILES_DIR))
+		if err != nil {
+			return nil, err
+		}
+
+		mocks = append(mocks, stubMocks...)
+	}
+
+	return mocks, nil
+}
+
+func ParseMappings(data []byte) ([]StubMapping, error) {
+
+	var file mappingFile
+
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Mappings != nil {
+		return file.Mappings, nil
+	}
+
+	return []StubMapping{file.StubMapping}, nil
+}
+
+// ConvertStub creates the Alfred mock(s) of a stub, a mock per method when
+// the stub matches any method.
+func ConvertStub(stub StubMapping, filesDir string) ([]*mock.Mock, error) {
+
+	var m mock.Mock
+
+	m.Name = getMockName(stub)
+
+	err := setUrl(&m, stub.Request)
+	if err != nil {
+		return nil, errors.New("wiremock stub '" + m.Name + "': " + err.Error())
+	}
+
+	err = setValueMatchers(&m, stub.Request)
+	if err != nil {
+		return nil, errors.New("wiremock stub '" + m.Name + "': " + err.Error())
+	}
+
+	m.Response, err = convertResponse(stub.Response, filesDir)
+	if err != nil {
+		return nil, errors.New("wiremock stub '" + m.Name + "': " + err.Error())
+	}
+
+	warnUnsupported(m.Name, stub)
+
+	methods := []string{strings.ToUpper(stub.Request.Method)}
+	if methods[0] == "ANY" || methods[0] == "" {
+		methods = anyMethods
+	}
+
+	var mocks []*mock.Mock
+
+	for _, method := range methods {
+
+		m.Request.Method = method
+		if len(methods) > 1 {
+			m.Name = getMockName(stub) + "-" + strings.ToLower(method)
+		}
+
+		jsonData, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		built, err := mock.BuildMockFromJson(jsonData)
+		if err != nil {
+			return nil, errors.New("wiremock stub '" + m.Name + "': " + err.Error())
+		}
+
+		mocks = append(mocks, &built)
+	}
+
+	return mocks, nil
+}
+
+func setUrl(m *mock.Mock, request StubRequest) error {
+
+	switch {
+	case request.Url != "":
+		m.Request.Url = strings.SplitN(request.Url, "?", 2)[0]
+	case request.UrlPath != "":
+		m.Request.Url = request.UrlPath
+	case request.UrlPattern != "":
+		m.Request.UrlRegexStr = anchorRegex(strings.SplitN(request.UrlPattern, `\?`, 2)[0])
+	case request.UrlPathPattern != "":
+		m.Request.UrlRegexStr = anchorRegex(request.UrlPathPattern)
+	default:
+		return errors.New("no url matcher")
+	}
+
+	return nil
+}
+
+// WireMock patterns match the whole url
+func anchorRegex(pattern string) string {
+
+	if strings.HasSuffix(pattern, "$") {
+		return pattern
+	}
+
+	return pattern + "$"
+}
+
+// setValueMatchers converts the equalTo, contains and matches patterns of the
+// headers, query parameters and body, a stub with other patterns can't be
+// expressed by the mock.
+func setValueMatchers(m *mock.Mock, request StubRequest) error {
+
+	var err error
+
+	m.Request.Headers, err = convertValueMatchers("header", request.Headers)
+	if err != nil {
+		return err
+	}
+
+	m.Request.Query, err = convertValueMatchers("query parameter", request.QueryParameters)
+	if err != nil {
+		return err
+	}
+
+	// query parameters of the url are matched with their value
+	if _, query, found := strings.Cut(request.Url, "?"); found {
+
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return errors.New("url query '" + query + "' is not valid: " + err.Error())
+		}
+
+		for name := range values {
+
+			if m.Request.Query == nil {
+				m.Request.Query = map[string]mock.MockValueMatcher{}
+			}
+
+			value := values.Get(name)
+			m.Request.Query[name] = mock.MockValueMatcher{EqualTo: &value}
+		}
+	}
+
+	for _, pattern := range request.BodyPatterns {
+
+		matcher, err := convertValueMatcher("body", pattern)
+		if err != nil {
+			return err
+		}
+
+		m.Request.Body = append(m.Request.Body, matcher)
+	}
+
+	return nil
+}
+
+func convertValueMatchers(target string, patterns map[string]interface{}) (map[string]mock.MockValueMatcher, error) {
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	matchers := map[string]mock.MockValueMatcher{}
+
+	for name, pattern := range patterns {
+
+		matcher, err := convertValueMatcher(target+" '"+name+"'", pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		matchers[name] = matcher
+	}
+
+	return matchers, nil
+}
+
+func convertValueMatcher(target string, pattern interface{}) (mock.MockValueMatcher, error) {
+
+	var matcher mock.MockValueMatcher
+
+	fields, isObject := pattern.(map[string]interface{})
+	if !isObject || len(fields) == 0 {
+		return matcher, errors.New(target + " pattern is not an object")
+	}
+
+	for name, value := range fields {
+
+		if name == "caseInsensitive" && value == false {
+			continue
+		}
+
+		str, isString := value.(string)
+
+		switch {
+		case name == "equalTo" && isString:
+			matcher.EqualTo = &str
+		case name == "contains" && isString:
+			matcher.Contains = str
+		case name == "matches" && isString:
+			matcher.Matches = str
+		default:
+			return matcher, errors.New(target + " pattern '" + name + "' not supported, only equalTo, contains and matches are")
+		}
+	}
+
+	return matcher, nil
+}
+
+func convertResponse(stubResponse StubResponse, filesDir string) (mock.MockResponse, error) {
+
+	response := mock.MockResponse{Status: stubResponse.Status}
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+
+	for name, value := range stubResponse.Headers {
+
+		if response.Headers == nil {
+			response.Headers = map[string]string{}
+		}
+
+		// multiple values are given as array
+		if values, isArray := value.([]interface{}); isArray {
+			var strValues []string
+			for _, v := range values {
+				strValues = append(strValues, fmt.Sprint(v))
+			}
+			response.Headers[name] = strings.Join(strValues, ", ")
+		} else {
+			response.Headers[name] = fmt.Sprint(value)
+		}
+	}
+
+	switch {
+	case len(stubResponse.JsonBody) > 0:
+		response.Body = stubResponse.JsonBody
+	case stubResponse.Body != "":
+		response.Body, _ = json.Marshal(stubResponse.Body)
+	case stubResponse.Base64Body != "":
+		body, err := base64.StdEncoding.DecodeString(stubResponse.Base64Body)
+		if err != nil {
+			return response, errors.New("base64Body decoding failed: " + err.Error())
+		}
+		response.Body, _ = json.Marshal(string(body))
+	case stubResponse.BodyFileName != "":
+		body, err := os.ReadFile(filepath.Join(filesDir, stubResponse.BodyFileName))
+		if err != nil {
+			return response, err
+		}
+		response.Body, _ = json.Marshal(string(body))
+	}
+
+	if stubResponse.DelayDistribution != nil && stubResponse.DelayDistribution.Type == "uniform" {
+		response.MinResponseTime = stubResponse.DelayDistribution.Lower
+		response.MaxResponseTime = stubResponse.DelayDistribution.Upper
+	} else {
+		response.MinResponseTime = stubResponse.FixedDelayMilliseconds
+	}
+
+	return response, nil
+}
+
+func warnUnsupported(name string, stub StubMapping) {
+
+	var ignored []string
+
+	if strings.Contains(stub.Request.UrlPattern, `\?`) {
+		ignored = append(ignored, "urlPattern query")
+	}
+	if len(stub.Request.Cookies) > 0 {
+		ignored = append(ignored, "cookies")
+	}
+	if stub.Response.Fault != "" {
+		ignored = append(ignored, "fault")
+	}
+
+	if len(ignored) > 0 {
+		log.Warn(context.Background(), "wiremock stub features not supported, ignored", errors.New(strings.Join(ignored, ", ")+" ignored"), zap.String("mock-name", name))
+	}
+}
+
+func getMockName(stub StubMapping) string {
+
+	if stub.Name != "" {
+		return stub.Name
+	}
+
+	if stub.Id != "" {
+		return "wiremock-" + stub.Id
+	}
+
+	url := stub.Request.Url + stub.Request.UrlPath + stub.Request.UrlPattern + stub.Request.UrlPathPattern
+
+	return "wiremock-" + strings.ToLower(stub.Request.Method) + "-" + url
+}
+
+// Stubs without priority have the WireMock default one
+func getPriority(stub StubMapping) int {
+
+	if stub.Priority == 0 {
+		return 5
+	}
+
+	return stub.Priority
+}
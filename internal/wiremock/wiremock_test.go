@@ -0,0 +1,124 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wiremock
+
+import (
+	"alfred/internal/log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvertStub(t *testing.T) {
+
+	log.InitLogger("test", false, "1.0")
+
+	stubs, err := ParseMappings([]byte(`{
+		"request": {"method": "GET", "urlPattern": "/users/[0-9]+\\?page=.*"},
+		"response": {"status": 201, "body": "created", "headers": {"X-Values": ["a", "b"]}, "fixedDelayMilliseconds": 30}
+	}`))
+	if err != nil {
+		t.Fatalf("parse mappings failed with error: %v", err)
+	}
+
+	if len(stubs) != 1 {
+		t.Fatalf("stubs count is: %d, want: 1", len(stubs))
+	}
+
+	mocks, err := ConvertStub(stubs[0], "")
+	if err != nil {
+		t.Fatalf("convert stub failed with error: %v", err)
+	}
+
+	m := mocks[0]
+	if m.Request.UrlRegexStr != "/users/[0-9]+$" || m.Request.Method != "GET" {
+		t.Errorf("mock request is: %s %s, want: GET /users/[0-9]+$", m.Request.Method, m.Request.UrlRegexStr)
+	}
+
+	if m.Response.Status != 201 || m.GetResponseBody() != "created" || m.Response.MinResponseTime != 30 {
+		t.Errorf("mock response is: %d %s %dms, want: 201 created 30ms", m.Response.Status, m.GetResponseBody(), m.Response.MinResponseTime)
+	}
+
+	if m.Response.Headers["X-Values"] != "a, b" {
+		t.Errorf("mock header X-Values is: '%s', want: 'a, b'", m.Response.Headers["X-Values"])
+	}
+}
+
+func TestConvertStubAnyMethod(t *testing.T) {
+
+	stubs, err := ParseMappings([]byte(`{"mappings": [
+		{"name": "any", "request": {"method": "ANY", "url": "/any"}, "response": {"jsonBody": {"ok": true}}}
+	]}`))
+	if err != nil {
+		t.Fatalf("parse mappings failed with error: %v", err)
+	}
+
+	mocks, err := ConvertStub(stubs[0], "")
+	if err != nil {
+		t.Fatalf("convert stub failed with error: %v", err)
+	}
+
+	if len(mocks) != len(anyMethods) {
+		t.Fatalf("mocks count is: %d, want: %d", len(mocks), len(anyMethods))
+	}
+
+	if mocks[1].Name != "any-post" || mocks[1].Request.Method != "POST" || mocks[1].Request.Url != "/any" {
+		t.Errorf("mock is: '%s' %s %s, want: 'any-post' POST /any", mocks[1].Name, mocks[1].Request.Method, mocks[1].Request.Url)
+	}
+
+	if mocks[0].Response.Status != 200 || mocks[0].GetResponseBody() != `{"ok":true}` {
+		t.Errorf("mock response is: %d %s, want: 200 {\"ok\":true}", mocks[0].Response.Status, mocks[0].GetResponseBody())
+	}
+}
+
+func TestConvertStubMatchers(t *testing.T) {
+
+	stubs, err := ParseMappings([]byte(`{"mappings": [
+		{"name": "search", "request": {"method": "POST", "url": "/search?page=2",
+			"headers": {"Content-Type": {"equalTo": "application/json", "caseInsensitive": false}},
+			"queryParameters": {"lang": {"matches": "en|fr"}},
+			"bodyPatterns": [{"contains": "\"query\""}]},
+			"response": {"status": 200}},
+		{"name": "json", "request": {"method": "POST", "url": "/json", "bodyPatterns": [{"equalToJson": {"a": 1}}]}, "response": {"status": 200}}
+	]}`))
+	if err != nil {
+		t.Fatalf("parse mappings failed with error: %v", err)
+	}
+
+	mocks, err := ConvertStub(stubs[0], "")
+	if err != nil {
+		t.Fatalf("convert stub failed with error: %v", err)
+	}
+
+	m := mocks[0]
+	if m.Request.Url != "/search" || *m.Request.Headers["Content-Type"].EqualTo != "application/json" || m.Request.Query["lang"].Matches != "en|fr" || *m.Request.Query["page"].EqualTo != "2" || m.Request.Body[0].Contains != `"query"` {
+		t.Errorf("mock request matchers are: %+v", m.Request)
+	}
+
+	r := httptest.NewRequest("POST", "/search?page=2&lang=fr", strings.NewReader(`{"query": "alfred"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	err = m.Match(r, []byte(`{"query": "alfred"}`))
+	if err != nil {
+		t.Errorf("request should match, got error '%s'", err.Error())
+	}
+
+	_, err = ConvertStub(stubs[1], "")
+	if err == nil {
+		t.Errorf("stub with an equalToJson body pattern should be rejected")
+	}
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package client starts an Alfred server embedded in Go tests, mocks are
+// pushed programmatically and the requests it served can be verified.
+//
+//	s, err := client.Start(client.WithMocksDir("testdata/mocks"))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer s.Close()
+//
+//	err = s.AddMock(`{"name": "get-user", "request": {"method": "GET", "url": "/users/1"}, "response": {"status": 200}}`)
+//	...
+//	err = s.Verify(client.RequestPattern{Mock: "get-user"}, 1)
+package client
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/log"
+	"alfred/internal/mock"
+	"alfred/internal/server"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_LOG_LEVEL = log.LOG_LEVEL_ERROR
+	SHUTDOWN_TIMEOUT  = 5 * time.Second
+)
+
+// Server is an Alfred server listening on a random loopback port
+type Server struct {
+	// Base url of the server, like http://127.0.0.1:41234
+	URL string
+
+	config     conf.Config
+	listener   net.Listener
+	httpServer *http.Server
+	jobs       sync.WaitGroup
+
+	mutex      sync.Mutex
+	dirMocks   []*mock.Mock
+	addedMocks []*mock.Mock
+}
+
+type Option func(*conf.Config)
+
+// WithMocksDir loads the mock files of the directory at start
+func WithMocksDir(dir string) Option {
+	return func(c *conf.Config) {
+		c.Alfred.Core.MocksDir = dir
+	}
+}
+
+// WithFunctionsDir sets the directory of the mocks function files
+func WithFunctionsDir(dir string) Option {
+	return func(c *conf.Config) {
+		c.Alfred.Core.FunctionsDir = dir
+	}
+}
+
+// WithBodiesDir sets the directory of the mocks body files
+func WithBodiesDir(dir string) Option {
+	return func(c *conf.Config) {
+		c.Alfred.Core.BodiesDir = dir
+	}
+}
+
+// WithLogLevel sets the Alfred logs level, error by default
+func WithLogLevel(level string) Option {
+	return func(c *conf.Config) {
+		c.Alfred.LogLevel = level
+	}
+}
+
+var initLogger sync.Once
+
+// Start serves the mocks of the options directories, without mocks when no
+// mocks directory is set.
+func Start(options ...Option) (*Server, error) {
+
+	s := &Server{config: conf.DefaultConfig}
+	s.config.Alfred.LogLevel = DEFAULT_LOG_LEVEL
+	s.config.Alfred.Core.MocksDir = ""
+	s.config.Alfred.Core.HotReload = false
+
+	for _, option := range options {
+		option(&s.config)
+	}
+
+	initLogger.Do(func() {
+		log.InitLogger("alfred-client", false, s.config.Alfred.Version)
+	})
+
+	err := log.SetLevel(s.config.Alfred.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.Alfred.Core.MocksDir != "" {
+
+		if _, err := os.Stat(s.config.Alfred.Core.MocksDir); err != nil {
+			return nil, err
+		}
+
+		mockCollection, err := mock.LoadMockCollectionFromFolder(s.config.Alfred.Core.MocksDir)
+		if err != nil {
+			return nil, err
+		}
+		s.dirMocks = mockCollection.Mocks
+	}
+
+	servers, err := server.BuildServers(&s.config, &s.jobs, s.getMockCollection(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.httpServer = servers[0]
+
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s.URL = "http://" + s.listener.Addr().String()
+
+	go func() {
+		err := s.httpServer.Serve(s.listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Error(context.Background(), "embedded server stopped", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Close stops the server once the requests in progress are served
+func (s *Server) Close() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	err := s.httpServer.Shutdown(ctx)
+	s.jobs.Wait()
+
+	server.ClearRecentRequests(s.listener.Addr().String())
+
+	return err
+}
+
+// AddMock serves the json mock in addition to the current ones, body files
+// are relative to the bodies directory.
+func (s *Server) AddMock(jsonMock string) error {
+
+	m, err := mock.BuildMockFromJson([]byte(jsonMock))
+	if err != nil {
+		return err
+	}
+
+	return s.addMocks(&m)
+}
+
+// AddMockFile serves the mock file in addition to the current ones, body
+// files are relative to the mock file directory.
+func (s *Server) AddMockFile(path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	m, err := mock.BuildMockFromJsonFile(data, path)
+	if err != nil {
+		return err
+	}
+
+	return s.addMocks(&m)
+}
+
+// ResetMocks removes the added mocks, the mocks directory ones are kept
+func (s *Server) ResetMocks() error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	added := s.addedMocks
+	s.addedMocks = nil
+
+	err := s.reload()
+	if err != nil {
+		s.addedMocks = added
+	}
+
+	return err
+}
+
+func (s *Server) addMocks(mocks ...*mock.Mock) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, m := range mocks {
+		if m.IsSocket() {
+			return errors.New("socket mock " + m.GetName() + " is not supported by the embedded server")
+		}
+	}
+
+	s.addedMocks = append(s.addedMocks, mocks...)
+
+	err := s.reload()
+	if err != nil {
+		s.addedMocks = s.addedMocks[:len(s.addedMocks)-len(mocks)]
+	}
+
+	return err
+}
+
+func (s *Server) reload() error {
+
+	return server.Reload([]*http.Server{s.httpServer}, &s.config, &s.jobs, s.getMockCollection(), nil)
+}
+
+func (s *Server) getMockCollection() mock.MockCollection {
+
+	var mocks []*mock.Mock
+	mocks = append(mocks, s.dirMocks...)
+	mocks = append(mocks, s.addedMocks...)
+
+	return mock.MockCollection{Mocks: mocks}
+}
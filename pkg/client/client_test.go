@@ -0,0 +1,132 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, url string) (int, string) {
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+
+	return res.StatusCode, string(body)
+}
+
+func TestServer(t *testing.T) {
+
+	s, err := Start(WithMocksDir("testdata/mocks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	status, body := get(t, s.URL+"/users/1")
+	if status != http.StatusOK || !strings.Contains(body, "Bruce") {
+		t.Errorf("unexpected response %d %s", status, body)
+	}
+
+	err = s.AddMock(`{"name": "create-order", "request": {"method": "POST", "url": "/orders"}, "response": {"status": 201}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := http.Post(s.URL+"/orders", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", res.StatusCode)
+	}
+
+	if err = s.Verify(RequestPattern{Mock: "get-user"}, 1); err != nil {
+		t.Error(err)
+	}
+
+	if err = s.Verify(RequestPattern{Method: "POST", Path: "/orders"}, 1); err != nil {
+		t.Error(err)
+	}
+
+	if err = s.VerifyNoUnmatched(); err != nil {
+		t.Error(err)
+	}
+
+	get(t, s.URL+"/unknown")
+
+	if err = s.VerifyNoUnmatched(); err == nil || !strings.Contains(err.Error(), "GET /unknown") {
+		t.Errorf("unmatched request not reported: %v", err)
+	}
+
+	s.ResetRequests()
+	if len(s.Requests()) != 0 {
+		t.Errorf("requests should be reset")
+	}
+
+	err = s.ResetMocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = http.Post(s.URL+"/orders", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if err = s.Verify(RequestPattern{Unmatched: true}, 1); err != nil {
+		t.Error(err)
+	}
+
+	if err = s.Verify(RequestPattern{Mock: "get-user"}, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServersIsolation(t *testing.T) {
+
+	s1, err := Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+
+	s2, err := Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	get(t, s1.URL+"/one")
+
+	if len(s1.Requests()) != 1 || len(s2.Requests()) != 0 {
+		t.Errorf("requests of a server should not be seen by the other one")
+	}
+
+	if err = s1.AddMock(`{"request": `); err == nil {
+		t.Errorf("invalid mock should be rejected")
+	}
+}
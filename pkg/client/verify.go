@@ -0,0 +1,157 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package client
+
+import (
+	"alfred/internal/server"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Request served by the server, without mock when no mock matched it
+type Request struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	Mock      string
+	Status    int
+	LatencyMs float64
+	Matched   bool
+}
+
+// RequestPattern selects requests, empty fields match any request
+type RequestPattern struct {
+	Method string
+	Path   string
+	Mock   string
+
+	// only the requests no mock matched
+	Unmatched bool
+}
+
+// Requests returns the last requests served, oldest first. The journal is
+// shared by the servers of the process and keeps the last 200 requests.
+func (s *Server) Requests() []Request {
+
+	var requests []Request
+
+	for _, r := range server.GetRecentRequests() {
+
+		if r.Listener != s.listener.Addr().String() {
+			continue
+		}
+
+		t, _ := time.Parse(time.RFC3339Nano, r.Time)
+
+		requests = append(requests, Request{
+			Time:      t,
+			Method:    r.Method,
+			Path:      r.Path,
+			Mock:      r.Mock,
+			Status:    r.Status,
+			LatencyMs: r.LatencyMs,
+			Matched:   r.Matched,
+		})
+	}
+
+	return requests
+}
+
+// ResetRequests forgets the requests served so far
+func (s *Server) ResetRequests() {
+
+	server.ClearRecentRequests(s.listener.Addr().String())
+}
+
+// FindRequests returns the requests served matching the pattern
+func (s *Server) FindRequests(p RequestPattern) []Request {
+
+	var requests []Request
+
+	for _, r := range s.Requests() {
+		if p.match(r) {
+			requests = append(requests, r)
+		}
+	}
+
+	return requests
+}
+
+// Verify fails if the number of requests matching the pattern is not the
+// expected one.
+func (s *Server) Verify(p RequestPattern, times int) error {
+
+	count := len(s.FindRequests(p))
+	if count != times {
+		return fmt.Errorf("expected %d request(s) matching %s, got %d", times, p, count)
+	}
+
+	return nil
+}
+
+// VerifyNoUnmatched fails if a request was not matched by any mock
+func (s *Server) VerifyNoUnmatched() error {
+
+	unmatched := s.FindRequests(RequestPattern{Unmatched: true})
+	if len(unmatched) == 0 {
+		return nil
+	}
+
+	var requests []string
+	for _, r := range unmatched {
+		requests = append(requests, r.Method+" "+r.Path)
+	}
+
+	return fmt.Errorf("%d request(s) not matched by any mock: %s", len(unmatched), strings.Join(requests, ", "))
+}
+
+func (p RequestPattern) match(r Request) bool {
+
+	if p.Method != "" && !strings.EqualFold(p.Method, r.Method) {
+		return false
+	}
+
+	if p.Path != "" && p.Path != r.Path {
+		return false
+	}
+
+	if p.Mock != "" && p.Mock != r.Mock {
+		return false
+	}
+
+	return !p.Unmatched || !r.Matched
+}
+
+func (p RequestPattern) String() string {
+
+	var fields []string
+
+	if p.Method != "" {
+		fields = append(fields, "method="+p.Method)
+	}
+	if p.Path != "" {
+		fields = append(fields, "path="+p.Path)
+	}
+	if p.Mock != "" {
+		fields = append(fields, "mock="+p.Mock)
+	}
+	if p.Unmatched {
+		fields = append(fields, "unmatched")
+	}
+
+	return "{" + strings.Join(fields, " ") + "}"
+}
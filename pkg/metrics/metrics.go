@@ -20,6 +20,7 @@ import (
 	"alfred/internal/log"
 	"context"
 	"net/http"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
@@ -35,47 +36,60 @@ type MetricsConfig struct {
 	SlowTime       int32
 }
 
-func AddMetrics(mux *http.ServeMux, conf MetricsConfig) {
+var (
+	promHandler http.Handler
+	metricsOnce sync.Once
+)
 
-	var metricsMux *http.ServeMux
+// AddMetrics creates the metrics registry and the dedicated metrics server
+// once, the exporter route is added to the mux when the port is shared.
+func AddMetrics(mux *http.ServeMux, conf MetricsConfig) {
 
 	conf.SanitizeConfiguration()
 
-	// Create non-global registry.
-	reg := prometheus.NewRegistry()
+	//create server
+	dedicatedServer := conf.HttpServerIp != conf.MetricIp || conf.HttpServerPort != conf.MetricPort
 
-	// Add go runtime metrics and process collectors.
-	reg.MustRegister(
-		collectors.NewGoCollector(),
-		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
-	)
+	metricsOnce.Do(func() {
 
-	promHandler := promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+		// Create non-global registry.
+		reg := prometheus.NewRegistry()
 
-	//create server
-	dedicatedServer := conf.HttpServerIp != conf.MetricIp || conf.HttpServerPort != conf.MetricPort
+		// Add go runtime metrics and process collectors.
+		reg.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
 
-	if dedicatedServer {
-		metricsMux = http.NewServeMux()
+		// Add mocks, functions and VM pool metrics.
+		mockMetrics = newMockMetrics(conf.SlowTime)
+		mockMetrics.register(reg)
 
-		metricsMux.Handle(conf.MetricPath, promHandler)
+		promHandler = promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
 
-		// Create a new HTTP server
-		server := &http.Server{
-			Addr:    conf.MetricIp + ":" + conf.MetricPort, // Specify the port to listen on
-			Handler: metricsMux,                            // Use the ServeMux as the handler
-		}
+		if dedicatedServer {
+			metricsMux := http.NewServeMux()
+
+			metricsMux.Handle(conf.MetricPath, promHandler)
 
-		go func() {
-			err := server.ListenAndServe()
-			if err != nil {
-				log.Error(context.Background(), "serve metrics error", err)
+			// Create a new HTTP server
+			server := &http.Server{
+				Addr:    conf.MetricIp + ":" + conf.MetricPort, // Specify the port to listen on
+				Handler: metricsMux,                            // Use the ServeMux as the handler
 			}
-		}()
 
-	} else {
-		metricsMux = mux
-		metricsMux.Handle(conf.MetricPath, promHandler)
+			go func() {
+				err := server.ListenAndServe()
+				if err != nil {
+					log.Error(context.Background(), "serve metrics error", err)
+				}
+			}()
+		}
+	})
+
+	if !dedicatedServer {
+		// server routes are prefixed by the method
+		mux.Handle("/GET"+conf.MetricPath, promHandler)
 	}
 }
 
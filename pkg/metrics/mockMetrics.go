@@ -0,0 +1,189 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const NAMESPACE = "alfred"
+
+// Alfred collectors, nil until metrics are enabled
+var mockMetrics *MockMetrics
+
+type MockMetrics struct {
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	unmatchedRequests *prometheus.CounterVec
+	functionDuration  *prometheus.HistogramVec
+	vmPoolSize        *prometheus.GaugeVec
+	vmPoolInUse       *prometheus.GaugeVec
+	vmAcquireWait     *prometheus.HistogramVec
+	vmAcquireTimeouts *prometheus.CounterVec
+	rejectedRequests  *prometheus.CounterVec
+}
+
+// Latency buckets go up to the slow time, from 1 millisecond
+func newMockMetrics(slowTime int32) *MockMetrics {
+
+	if slowTime <= 0 {
+		slowTime = 1
+	}
+	latencyBuckets := prometheus.ExponentialBucketsRange(0.001, float64(slowTime), 16)
+
+	return &MockMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "mock_requests_total",
+			Help:      "Requests served by mock, method and response status.",
+		}, []string{"mock", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "mock_request_duration_seconds",
+			Help:      "Mock responses latency, delays included.",
+			Buckets:   latencyBuckets,
+		}, []string{"mock"}),
+		unmatchedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "unmatched_requests_total",
+			Help:      "Requests not matching any mock.",
+		}, []string{"method"}),
+		functionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "function_duration_seconds",
+			Help:      "Javascript functions execution time.",
+			Buckets:   latencyBuckets,
+		}, []string{"function", "call"}),
+		vmPoolSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "vm_pool_size",
+			Help:      "Javascript VMs created in the pool, shared or of a function file.",
+		}, []string{"pool"}),
+		vmPoolInUse: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "vm_pool_in_use",
+			Help:      "Javascript VMs of the pool running a function.",
+		}, []string{"pool"}),
+		vmAcquireWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "vm_pool_acquire_wait_seconds",
+			Help:      "Time waited to get a javascript VM from the pool.",
+			Buckets:   prometheus.ExponentialBucketsRange(0.00001, float64(slowTime), 16),
+		}, []string{"pool"}),
+		vmAcquireTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "vm_pool_acquire_timeouts_total",
+			Help:      "Javascript VMs not acquired before the pool acquire timeout.",
+		}, []string{"pool"}),
+		rejectedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "rejected_requests_total",
+			Help:      "Requests and connections rejected by the limits.",
+		}, []string{"limit"}),
+	}
+}
+
+func (m *MockMetrics) register(reg *prometheus.Registry) {
+
+	reg.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.unmatchedRequests,
+		m.functionDuration,
+		m.vmPoolSize,
+		m.vmPoolInUse,
+		m.vmAcquireWait,
+		m.vmAcquireTimeouts,
+		m.rejectedRequests,
+	)
+}
+
+func ObserveMockRequest(mockName string, method string, status int, duration time.Duration) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.requests.WithLabelValues(mockName, method, strconv.Itoa(status)).Inc()
+	mockMetrics.requestDuration.WithLabelValues(mockName).Observe(duration.Seconds())
+}
+
+func IncUnmatchedRequests(method string) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.unmatchedRequests.WithLabelValues(method).Inc()
+}
+
+func ObserveFunction(fileName string, call string, duration time.Duration) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.functionDuration.WithLabelValues(fileName, call).Observe(duration.Seconds())
+}
+
+func SetVmPoolSize(pool string, size int) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.vmPoolSize.WithLabelValues(pool).Set(float64(size))
+}
+
+func SetVmPoolInUse(pool string, inUse int) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.vmPoolInUse.WithLabelValues(pool).Set(float64(inUse))
+}
+
+func ObserveVmAcquireWait(pool string, duration time.Duration) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.vmAcquireWait.WithLabelValues(pool).Observe(duration.Seconds())
+}
+
+func IncVmAcquireTimeouts(pool string) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.vmAcquireTimeouts.WithLabelValues(pool).Inc()
+}
+
+func IncRejectedRequests(limit string) {
+
+	if mockMetrics == nil {
+		return
+	}
+
+	mockMetrics.rejectedRequests.WithLabelValues(limit).Inc()
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveMockRequest(t *testing.T) {
+
+	// disabled metrics are ignored
+	mockMetrics = nil
+	ObserveMockRequest("test", "GET", 200, time.Millisecond)
+
+	mockMetrics = newMockMetrics(5)
+	mockMetrics.register(prometheus.NewRegistry())
+
+	ObserveMockRequest("test", "GET", 200, time.Millisecond)
+	ObserveMockRequest("test", "GET", 200, time.Millisecond)
+	ObserveMockRequest("test", "GET", 404, time.Millisecond)
+	IncUnmatchedRequests("POST")
+	IncRejectedRequests("body-size")
+
+	count := testutil.ToFloat64(mockMetrics.requests.WithLabelValues("test", "GET", "200"))
+	if count != 2 {
+		t.Errorf("mock requests count is: %v, want: 2", count)
+	}
+
+	count = testutil.ToFloat64(mockMetrics.unmatchedRequests.WithLabelValues("POST"))
+	if count != 1 {
+		t.Errorf("unmatched requests count is: %v, want: 1", count)
+	}
+
+	count = testutil.ToFloat64(mockMetrics.rejectedRequests.WithLabelValues("body-size"))
+	if count != 1 {
+		t.Errorf("rejected requests count is: %v, want: 1", count)
+	}
+
+	if testutil.CollectAndCount(mockMetrics.requestDuration) != 1 {
+		t.Errorf("mock request duration should have one serie")
+	}
+
+	mockMetrics = nil
+}
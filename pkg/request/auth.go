@@ -0,0 +1,76 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const AUTH_SCHEME_BASIC = "basic"
+const AUTH_SCHEME_BEARER = "bearer"
+
+// Credentials of the Authorization header, JWT bearer tokens claims are
+// decoded without signature verification.
+type Auth struct {
+	Scheme   string                 `json:"scheme"`
+	Username string                 `json:"username,omitempty"`
+	Password string                 `json:"password,omitempty"`
+	Token    string                 `json:"token,omitempty"`
+	Claims   map[string]interface{} `json:"claims,omitempty"`
+}
+
+// GetAuth reads the basic or bearer credentials of the request, nil if none.
+func GetAuth(r *http.Request) *Auth {
+
+	if username, password, ok := r.BasicAuth(); ok {
+		return &Auth{Scheme: AUTH_SCHEME_BASIC, Username: username, Password: password}
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, AUTH_SCHEME_BEARER) {
+		return nil
+	}
+
+	token = strings.TrimSpace(token)
+
+	return &Auth{Scheme: AUTH_SCHEME_BEARER, Token: token, Claims: DecodeJwtClaims(token)}
+}
+
+// DecodeJwtClaims returns the payload of a JWT, nil if the token is not a JWT.
+func DecodeJwtClaims(token string) map[string]interface{} {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+
+	var claims map[string]interface{}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return nil
+	}
+
+	return claims
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package request
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"time"
+)
+
+// Client certificate details of a mTLS request
+type ClientCert struct {
+	Subject          string    `json:"subject"`
+	CommonName       string    `json:"commonName"`
+	Organization     []string  `json:"organization"`
+	Issuer           string    `json:"issuer"`
+	IssuerCommonName string    `json:"issuerCommonName"`
+	SerialNumber     string    `json:"serialNumber"`
+	DnsNames         []string  `json:"dnsNames"`
+	EmailAddresses   []string  `json:"emailAddresses"`
+	NotBefore        time.Time `json:"notBefore"`
+	NotAfter         time.Time `json:"notAfter"`
+	Fingerprint      string    `json:"fingerprint"`
+	Verified         bool      `json:"verified"`
+}
+
+// GetClientCert returns the leaf certificate presented by the client, nil if
+// the connection is not TLS or without client certificate.
+func GetClientCert(state *tls.ConnectionState) *ClientCert {
+
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	cert := state.PeerCertificates[0]
+	sum := sha256.Sum256(cert.Raw)
+
+	return &ClientCert{
+		Subject:          cert.Subject.String(),
+		CommonName:       cert.Subject.CommonName,
+		Organization:     cert.Subject.Organization,
+		Issuer:           cert.Issuer.String(),
+		IssuerCommonName: cert.Issuer.CommonName,
+		SerialNumber:     cert.SerialNumber.String(),
+		DnsNames:         cert.DNSNames,
+		EmailAddresses:   cert.EmailAddresses,
+		NotBefore:        cert.NotBefore,
+		NotAfter:         cert.NotAfter,
+		Fingerprint:      hex.EncodeToString(sum[:]),
+		Verified:         len(state.VerifiedChains) > 0,
+	}
+}
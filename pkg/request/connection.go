@@ -0,0 +1,172 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package request
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// Proxies trusted to forward the client ip
+var (
+	trustedProxies      []*net.IPNet
+	trustedProxiesMutex sync.RWMutex
+)
+
+// Connection details of a request. The client ip is the remote ip, or the
+// X-Forwarded-For or X-Real-Ip address given by a trusted proxy.
+type Connection struct {
+	RemoteIp    string `json:"remoteIp"`
+	RemotePort  string `json:"remotePort"`
+	ClientIp    string `json:"clientIp"`
+	Protocol    string `json:"protocol"`
+	Tls         bool   `json:"tls"`
+	TlsVersion  string `json:"tlsVersion,omitempty"`
+	CipherSuite string `json:"cipherSuite,omitempty"`
+	Sni         string `json:"sni,omitempty"`
+	Alpn        string `json:"alpn,omitempty"`
+}
+
+// GetConnection returns the connection details of the request
+func GetConnection(r *http.Request) *Connection {
+
+	connection := &Connection{Protocol: r.Proto}
+
+	connection.RemoteIp, connection.RemotePort, _ = net.SplitHostPort(r.RemoteAddr)
+	connection.ClientIp = getClientIp(r, connection.RemoteIp)
+
+	if r.TLS != nil {
+		connection.Tls = true
+		connection.TlsVersion = tlsVersions[r.TLS.Version]
+		connection.CipherSuite = tls.CipherSuiteName(r.TLS.CipherSuite)
+		connection.Sni = r.TLS.ServerName
+		connection.Alpn = r.TLS.NegotiatedProtocol
+	}
+
+	return connection
+}
+
+// SetTrustedProxies replaces the addresses and CIDR ranges of the proxies
+// trusted to forward the client ip
+func SetTrustedProxies(proxies []string) error {
+
+	networks, err := ParseIpNetworks(proxies)
+	if err != nil {
+		return err
+	}
+
+	trustedProxiesMutex.Lock()
+	defer trustedProxiesMutex.Unlock()
+
+	trustedProxies = networks
+
+	return nil
+}
+
+// getClientIp returns the remote ip, or for a trusted proxy the last
+// X-Forwarded-For address not added by a trusted proxy, then the X-Real-Ip
+// address. The first addresses are set by the client and can be spoofed.
+func getClientIp(r *http.Request, remoteIp string) string {
+
+	trustedProxiesMutex.RLock()
+	defer trustedProxiesMutex.RUnlock()
+
+	if !ContainsIp(trustedProxies, net.ParseIP(remoteIp)) {
+		return remoteIp
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+
+		if i == 0 || !ContainsIp(trustedProxies, ip) {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-Ip"))); ip != nil {
+		return ip.String()
+	}
+
+	return remoteIp
+}
+
+// ParseIpNetworks reads ip addresses and CIDR ranges, an address is a single
+// ip range
+func ParseIpNetworks(ips []string) ([]*net.IPNet, error) {
+
+	networks := make([]*net.IPNet, 0, len(ips))
+
+	for _, ip := range ips {
+
+		if !strings.Contains(ip, "/") {
+
+			address := net.ParseIP(ip)
+			if address == nil {
+				return nil, errors.New("'" + ip + "' is not an ip address or a CIDR range")
+			}
+
+			bits := 8 * net.IPv4len
+			if address.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+
+			networks = append(networks, &net.IPNet{IP: address, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(ip)
+		if err != nil {
+			return nil, errors.New("'" + ip + "' is not an ip address or a CIDR range")
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// ContainsIp returns true if one of the networks contains the ip
+func ContainsIp(networks []*net.IPNet, ip net.IP) bool {
+
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
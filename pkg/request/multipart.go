@@ -0,0 +1,116 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package request
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parts of a multipart/form-data body, files are indexed by field name in
+// their upload order.
+type Multipart struct {
+	Fields map[string]string          `json:"fields"`
+	Files  map[string][]MultipartFile `json:"files"`
+}
+
+// Binary file contents are base64 encoded in contentBase64.
+type MultipartFile struct {
+	Filename      string `json:"filename"`
+	ContentType   string `json:"contentType,omitempty"`
+	Size          int    `json:"size"`
+	Sha256        string `json:"sha256"`
+	Content       string `json:"content,omitempty"`
+	ContentBase64 string `json:"contentBase64,omitempty"`
+}
+
+func IsMultipart(contentType string) bool {
+
+	return strings.HasPrefix(strings.ToLower(contentType), "multipart/form-data")
+}
+
+// ParseMultipart reads the parts of a multipart/form-data body.
+func ParseMultipart(contentType string, body []byte) (*Multipart, error) {
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	parts := &Multipart{Fields: map[string]string{}, Files: map[string][]MultipartFile{}}
+
+	for {
+
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		name := part.FormName()
+
+		if part.FileName() == "" {
+			parts.Fields[name] = string(content)
+			continue
+		}
+
+		sum := sha256.Sum256(content)
+		file := MultipartFile{
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        len(content),
+			Sha256:      hex.EncodeToString(sum[:]),
+		}
+
+		if utf8.Valid(content) {
+			file.Content = string(content)
+		} else {
+			file.ContentBase64 = base64.StdEncoding.EncodeToString(content)
+		}
+
+		parts.Files[name] = append(parts.Files[name], file)
+	}
+
+	return parts, nil
+}
+
+// First returns the first file uploaded with the field name
+func (m *Multipart) First(name string) (MultipartFile, bool) {
+
+	files := m.Files[name]
+	if len(files) == 0 {
+		return MultipartFile{}, false
+	}
+
+	return files[0], true
+}
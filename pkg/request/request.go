@@ -20,7 +20,6 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -43,11 +42,25 @@ type Request struct {
 }
 
 type Req struct {
-	Method  string            `json:"method"`
-	Url     string            `json:"url"`
-	Body    string            `json:"body"`
-	Query   map[string]string `json:"query"`
-	Headers map[string]string `json:"headers"`
+	Method    string                 `json:"method"`
+	Url       string                 `json:"url"`
+	Body      string                 `json:"body"`
+	Query     map[string]string      `json:"query"`
+	Headers   map[string]string      `json:"headers"`
+	Graphql   *GraphqlOperation      `json:"graphql,omitempty"`
+	BodyXml   map[string]interface{} `json:"bodyXml,omitempty"`
+	Multipart *Multipart             `json:"multipart,omitempty"`
+
+	ClientCert *ClientCert `json:"clientCert,omitempty"`
+	Auth       *Auth       `json:"auth,omitempty"`
+	Connection *Connection `json:"connection,omitempty"`
+}
+
+// GraphQL over HTTP request body
+type GraphqlOperation struct {
+	OperationName string                 `json:"operationName"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
 }
 
 func (r *Req) SetHeaders(headers http.Header) {
@@ -97,7 +110,11 @@ func (r *Request) Send(ctx context.Context) (Response, error) {
 
 	req, err := http.NewRequestWithContext(ctx, r.GetMethod(), r.GetBaseUrl(), r.GetBodyBytesBuffer())
 	if err != nil {
-		log.Fatal(err)
+		return Response{}, err
+	}
+
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
 	}
 
 	// appending to existing query args
@@ -112,8 +129,7 @@ func (r *Request) Send(ctx context.Context) (Response, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println("errored when sending request to the server: " + err.Error())
-		return Response{}, nil
+		return Response{}, errors.New("errored when sending request to the server: " + err.Error())
 	}
 
 	defer resp.Body.Close()
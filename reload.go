@@ -0,0 +1,252 @@
+/*
+ * Copyright The Alfred.go Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"alfred/internal/conf"
+	"alfred/internal/cron"
+	"alfred/internal/database"
+	"alfred/internal/function"
+	"alfred/internal/health"
+	"alfred/internal/log"
+	"alfred/internal/messaging"
+	"alfred/internal/server"
+	"alfred/internal/socket"
+	"alfred/internal/store"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// mocksServer holds what is replaced when the mocks or the configuration are
+// reloaded, hot reload and SIGHUP reloads are serialized.
+type mocksServer struct {
+	mutex         sync.Mutex
+	configuration *conf.Config
+	servers       []*http.Server
+	jobs          *sync.WaitGroup
+	sockets       *socket.Listeners
+
+	// consumers of the onMessage functions, subscribed again with the
+	// reloaded function files
+	consumersCtx  context.Context
+	stopConsumers context.CancelFunc
+}
+
+// reload loads the mocks again, the previous mocks are kept on error.
+func (s *mocksServer) reload(ctx context.Context, reason string) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.reloadMocks(ctx, reason)
+}
+
+func (s *mocksServer) reloadMocks(ctx context.Context, reason string) error {
+
+	// the modules required by the function files may have changed
+	function.ReloadModules()
+
+	mockCollection, err := loadMockCollection(ctx, s.configuration)
+	if err != nil {
+		log.Error(ctx, reason+" failed, previous mocks kept", err)
+		health.Set(health.CHECK_MOCKS, errors.New(reason+" failed, previous mocks kept: "+err.Error()), "")
+		return err
+	}
+
+	services, err := loadServices(ctx, s.configuration)
+	if err == nil {
+		err = server.Reload(s.servers, s.configuration, s.jobs, mockCollection, services)
+	}
+	if err != nil {
+		log.Error(ctx, reason+" failed, previous mocks kept", err)
+		health.Set(health.CHECK_MOCKS, errors.New(reason+" failed, previous mocks kept: "+err.Error()), "")
+		return err
+	}
+	health.Set(health.CHECK_MOCKS, nil, getMocksCount(mockCollection, services))
+
+	// listeners ports are released before the new ones are opened
+	s.sockets.Stop()
+	s.sockets, err = startSocketMocks(s.configuration, mockCollection, services)
+	if err != nil {
+		log.Error(ctx, reason+" of the socket mocks failed", err)
+		s.sockets = &socket.Listeners{}
+	}
+	health.Set(health.CHECK_SOCKETS, err, s.sockets.String())
+
+	scheduleJobs(ctx, s.configuration)
+
+	err = s.subscribeConsumers()
+	if err != nil {
+		log.Error(ctx, reason+" of the consumers failed", err)
+	}
+
+	log.Info(ctx, reason+" done - "+strconv.Itoa(len(mockCollection.Mocks))+" mock(s) served")
+
+	return nil
+}
+
+// subscribeConsumers subscribes the consumers with the current function
+// files, the previous consumers are stopped.
+func (s *mocksServer) subscribeConsumers() error {
+
+	if s.consumersCtx == nil {
+		return nil
+	}
+
+	// the brokers subscriptions are released before the new ones
+	if s.stopConsumers != nil {
+		s.stopConsumers()
+		messaging.WaitConsumers()
+	}
+
+	var ctx context.Context
+	ctx, s.stopConsumers = context.WithCancel(s.consumersCtx)
+
+	return subscribeConsumers(ctx, s.configuration)
+}
+
+// reloadConfiguration reads the configuration again and reloads the mocks,
+// the requests in progress are served with the previous mocks. The sections
+// bound to the opened listeners and connections need a restart, their
+// changes are ignored.
+func (s *mocksServer) reloadConfiguration(ctx context.Context) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	log.Info(ctx, "SIGHUP received, reloading configuration")
+
+	configuration, err := conf.GetConfiguration()
+	if err == nil {
+		_, err = time.ParseDuration(configuration.Alfred.Core.ShutdownTimeout)
+	}
+	if err != nil {
+		log.Error(ctx, "configuration reload failed, previous configuration kept", err)
+		return err
+	}
+
+	previous := s.configuration.Alfred
+	keepRestartSections(ctx, &configuration.Alfred, previous)
+
+	err = configureModules(configuration, &previous)
+	if err != nil {
+		log.Error(ctx, "configuration reload failed, modules configuration is partially applied", err)
+		return err
+	}
+
+	if !reflect.DeepEqual(configuration.Alfred.Store, previous.Store) {
+
+		err = store.Configure(store.Config{
+			Type: configuration.Alfred.Store.Type,
+			Path: configuration.Alfred.Store.Path,
+			Redis: store.RedisConfig{
+				Address:  configuration.Alfred.Store.Redis.Address,
+				Password: configuration.Alfred.Store.Redis.Password,
+				Db:       configuration.Alfred.Store.Redis.Db,
+				Prefix:   configuration.Alfred.Store.Redis.Prefix,
+			},
+		})
+		if err != nil {
+			log.Error(ctx, "store reconfiguration failed, a memory store is used", err)
+		}
+	}
+
+	if !reflect.DeepEqual(configuration.Alfred.Database, previous.Database) {
+
+		err = database.Configure(database.Config{
+			Driver:       configuration.Alfred.Database.Driver,
+			Dsn:          configuration.Alfred.Database.Dsn,
+			MaxOpenConns: configuration.Alfred.Database.MaxOpenConns,
+			QueryTimeout: configuration.Alfred.Database.QueryTimeout,
+		})
+		if err != nil {
+			log.Error(ctx, "database reconfiguration failed", err)
+		}
+	}
+
+	// the handlers of the previous mocks keep the previous configuration
+	s.configuration = &configuration
+
+	return s.reloadMocks(ctx, "configuration reload")
+}
+
+// keepRestartSections restores the previous value of the sections which are
+// only applied at startup, with a warning when they changed.
+func keepRestartSections(ctx context.Context, alfred *conf.AlfredConfig, previous conf.AlfredConfig) {
+
+	keepSection(ctx, "name", &alfred.Name, previous.Name)
+	keepSection(ctx, "version", &alfred.Version, previous.Version)
+	keepSection(ctx, "namespace", &alfred.Namespace, previous.Namespace)
+	keepSection(ctx, "environment", &alfred.Environment, previous.Environment)
+	keepSection(ctx, "core.listen", &alfred.Core.Listen, previous.Core.Listen)
+	keepSection(ctx, "core.hot-reload", &alfred.Core.HotReload, previous.Core.HotReload)
+	keepSection(ctx, "access-log", &alfred.AccessLog, previous.AccessLog)
+	keepSection(ctx, "prometheus", &alfred.Prometheus, previous.Prometheus)
+	keepSection(ctx, "tracing", &alfred.Tracing, previous.Tracing)
+	keepSection(ctx, "services", &alfred.Services, previous.Services)
+	keepSection(ctx, "smtp", &alfred.Smtp, previous.Smtp)
+	keepSection(ctx, "kafka", &alfred.Kafka, previous.Kafka)
+	keepSection(ctx, "mqtt", &alfred.Mqtt, previous.Mqtt)
+	keepSection(ctx, "amqp", &alfred.Amqp, previous.Amqp)
+}
+
+func keepSection[T any](ctx context.Context, name string, current *T, previous T) {
+
+	if reflect.DeepEqual(*current, previous) {
+		return
+	}
+
+	log.Warn(ctx, "configuration "+name+" changed, a restart is needed to apply it", nil)
+	*current = previous
+}
+
+// runtimeSectionChanged tells if a section also changed with the admin api
+// changed in the configuration file. Its runtime changes are then replaced,
+// with a warning.
+func runtimeSectionChanged[T any](name string, current T, previous T) bool {
+
+	if reflect.DeepEqual(current, previous) {
+		return false
+	}
+
+	log.Warn(context.Background(), "configuration "+name+" changed, its changes made with the admin api are replaced", nil)
+
+	return true
+}
+
+// stop ends the http listeners, the requests in progress and the async jobs
+// have the shutdown timeout to end, then the socket listeners are closed and
+// the function files jobs unscheduled.
+func (s *mocksServer) stop(ctx context.Context) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	timeout, err := time.ParseDuration(s.configuration.Alfred.Core.ShutdownTimeout)
+	if err != nil {
+		timeout, _ = time.ParseDuration(conf.DEFAULT_SHUTDOWN_TIMEOUT)
+	}
+
+	server.Stop(ctx, s.servers, s.jobs, timeout)
+	s.sockets.Stop()
+	cron.Stop()
+}
@@ -0,0 +1,11 @@
+-- Fixtures of the database example, seed them with:
+-- sqlite3 user-files/database/fixtures.db < user-files/database/fixtures.sql
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL
+);
+
+INSERT INTO users (name, email) VALUES
+    ('Bruce Wayne', 'bruce@wayne-enterprises.com'),
+    ('Alfred Pennyworth', 'alfred@wayne-enterprises.com');
@@ -0,0 +1,9 @@
+// The callback function is called before each mock callback is sent, it can
+// update the callback method, url, headers, body, delay or timeout.
+function callback(mock, helpers, req, res, callback) {
+
+    // confirm the payment between 1 and 3 seconds
+    callback.delay = 1000 + Math.floor(Math.random() * 2000);
+
+    return callback;
+}
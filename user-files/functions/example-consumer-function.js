@@ -0,0 +1,22 @@
+// onMessage handles the messages of the consumers using this file, it
+// can update the state, publish messages with alfred.messaging.publish and
+// return callbacks sent by Alfred: [{ method, url, headers, body, delay }].
+function onMessage(message) {
+
+    var order = JSON.parse(message.body);
+
+    alfred.state.set("order-" + order.id, "shipped");
+
+    alfred.messaging.publish({
+        topic: "shipments",
+        key: message.key,
+        body: JSON.stringify({ order: order.id, status: "shipped" })
+    });
+
+    return [{
+        url: "http://localhost:8080/some/kafka/webhook",
+        delay: 1000,
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ order: order.id, status: "shipped" })
+    }];
+}
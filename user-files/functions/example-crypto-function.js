@@ -0,0 +1,22 @@
+// alfred.crypto hashes (md5, sha1, sha256, sha512), signs (hmac) and draws
+// random bytes, digests are hex encoded unless "base64" or "base64url" is set.
+// alfred.encoding converts base64, base64url, hex and url encoded strings.
+function alfred(mock, helpers, req, res) {
+
+    var expected = "sha256=" + alfred.crypto.hmac("sha256", "webhook-secret", req.body);
+
+    if (req.headers["X-Signature"] !== expected) {
+
+        res.status = 401;
+        res.body = JSON.stringify({ error: "invalid signature" });
+        return res;
+    }
+
+    res.body = JSON.stringify({
+        id: alfred.crypto.randomBytes(8),
+        checksum: alfred.crypto.md5(req.body),
+        payload: alfred.encoding.base64Encode(req.body)
+    });
+
+    return res;
+}
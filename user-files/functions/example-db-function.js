@@ -0,0 +1,17 @@
+// alfred.db.query(sql, ...args) returns the rows, alfred.db.exec(sql, ...args)
+// returns { rowsAffected, lastInsertId }. Placeholders are the driver ones:
+// ? for sqlite and mysql, $1 for postgres.
+function alfred(mock, helpers, req, res) {
+
+    var users = alfred.db.query("SELECT id, name, email FROM users WHERE id = ?", req.query.id);
+
+    if (users.length === 0) {
+        res.status = 404;
+        res.body = JSON.stringify({ error: "user not found" });
+        return res;
+    }
+
+    res.body = JSON.stringify(users[0]);
+
+    return res;
+}
@@ -0,0 +1,12 @@
+function alfred(mock, helpers, req, res) {
+
+    var user = {
+        id: String(Date.now()),
+        name: req.graphql.variables.name,
+        email: req.graphql.variables.email
+    };
+
+    res.body = JSON.stringify({ data: { createUser: user } });
+
+    return res;
+}
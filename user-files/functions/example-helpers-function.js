@@ -0,0 +1,16 @@
+// helpers() returns custom helpers, used in any mock with
+// {{ alfred.custom.<name> }}. Each function gets the request and returns the
+// helper value.
+function helpers() {
+
+    return {
+        tenant: function (req) {
+            return (req.headers["X-Tenant"] || "default").toLowerCase();
+        },
+        visits: function (req) {
+            var visits = (alfred.state.get("visits") || 0) + 1;
+            alfred.state.set("visits", visits);
+            return visits;
+        }
+    };
+}
@@ -0,0 +1,39 @@
+// exports.jobs run on their cron schedule ("minute hour day-of-month month
+// day-of-week", or @hourly, @daily, @every 30s...) in the function VMs. A
+// handler can use alfred.state and return callbacks sent by Alfred.
+exports.jobs = [
+    {
+        name: "expire-orders",
+        cron: "* * * * *",
+        handler: function () {
+
+            var orders = alfred.state.get("job-orders") || [];
+            var now = Date.now();
+
+            // orders are expired 2 minutes after their creation
+            orders.forEach(function (order) {
+                if (order.status === "pending" && now - order.created > 2 * 60 * 1000) {
+                    order.status = "expired";
+                }
+            });
+
+            alfred.state.set("job-orders", orders);
+        }
+    }
+];
+
+function alfred(mock, helpers, req, res) {
+
+    var orders = alfred.state.get("job-orders") || [];
+
+    if (req.method === "POST") {
+
+        orders.push({ id: orders.length + 1, status: "pending", created: Date.now() });
+        alfred.state.set("job-orders", orders);
+        res.status = 201;
+    }
+
+    res.body = JSON.stringify(orders);
+
+    return res;
+}
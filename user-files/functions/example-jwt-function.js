@@ -0,0 +1,26 @@
+// alfred.jwt signs, verifies and decodes tokens with the keys of the jwt
+// configuration section, options can override them:
+// { algorithm, secret, privateKey, publicKey, issuer, expiresIn, kid }
+function alfred(mock, helpers, req, res) {
+
+    if (mock.name === "jwt-token") {
+
+        const token = alfred.jwt.sign({ sub: req.query.username, scope: "profile" }, { expiresIn: "15m" });
+
+        res.body = JSON.stringify({ access_token: token, token_type: "Bearer", expires_in: 900 });
+        return res;
+    }
+
+    try {
+
+        const claims = alfred.jwt.verify(req.auth ? req.auth.token : "");
+        res.body = JSON.stringify({ sub: claims.sub, scope: claims.scope });
+
+    } catch (e) {
+
+        res.status = 401;
+        res.body = JSON.stringify({ error: "invalid_token", error_description: e.message });
+    }
+
+    return res;
+}
@@ -0,0 +1,11 @@
+// Modules are required relative to the modules-dir configuration, the
+// functions-dir by default, node_modules packages are resolved from it too.
+// Pool VMs run the file at each call: declare modules with var, not const.
+var greetings = require('./utils/greetings.js');
+
+function alfred(mock, helpers, req, res) {
+
+    res.body = JSON.stringify({ message: greetings.greet(req.query.name || "bruce") });
+
+    return res;
+}
@@ -0,0 +1,34 @@
+// The 'example-team' sandbox policy of the configuration applies to this
+// file: only the faker, state and console apis are allowed, the call stack
+// and run time are limited.
+function alfred(mock, helpers, req, res) {
+
+    switch (req.query.try) {
+
+        case "db":
+            // disabled apis fail when they are used
+            try {
+                alfred.db.query("SELECT 1");
+            } catch (e) {
+                res.body = JSON.stringify({ error: e.message });
+                return res;
+            }
+            break;
+
+        case "loop":
+            // stopped after the policy timeout, the mock response is sent
+            for (;;) {}
+
+        case "recursion":
+            return deeper(res);
+    }
+
+    alfred.state.set("last-customer", alfred.faker.name());
+    res.body = JSON.stringify({ customer: alfred.state.get("last-customer") });
+
+    return res;
+}
+
+function deeper(res) {
+    return deeper(res);
+}
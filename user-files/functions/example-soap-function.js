@@ -0,0 +1,20 @@
+function alfred(mock, helpers, req, res) {
+
+    var userId = req.bodyXml.Envelope.Body.DeleteUser.UserId;
+
+    if (userId === "1") {
+        res.status = 500;
+        res.body = '<?xml version="1.0" encoding="UTF-8"?>'
+            + '<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>'
+            + '<faultcode>soap:Client</faultcode><faultstring>User ' + userId + ' can not be deleted</faultstring>'
+            + '</soap:Fault></soap:Body></soap:Envelope>';
+        return res;
+    }
+
+    res.body = '<?xml version="1.0" encoding="UTF-8"?>'
+        + '<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>'
+        + '<DeleteUserResponse xmlns="urn:users"><Deleted>' + userId + '</Deleted></DeleteUserResponse>'
+        + '</soap:Body></soap:Envelope>';
+
+    return res;
+}
@@ -0,0 +1,13 @@
+// onData answers the data received by socket mocks: data has the protocol,
+// remoteAddr, and the payload as text, hex and base64. Return nothing, or a
+// reply { text | hex | base64, delay, close }.
+function onData(mock, data) {
+
+    var count = (alfred.state.get("syslog-count") || 0) + 1;
+    alfred.state.set("syslog-count", count);
+
+    console.log(mock.name + " received from " + data.remoteAddr + ": " + data.text.trim());
+
+    // syslog receivers don't reply
+    return;
+}
@@ -0,0 +1,23 @@
+// alfred.state keeps json values between requests: get(key), set(key, value),
+// delete(key), keys() and clear(). Each service has its own namespace, set the
+// store type to bbolt to keep them after a restart.
+function alfred(mock, helpers, req, res) {
+
+    var cart = alfred.state.get("cart") || { items: [] };
+
+    if (req.method === "POST") {
+
+        cart.items.push(JSON.parse(req.body));
+        alfred.state.set("cart", cart);
+        res.status = 201;
+
+    } else if (req.method === "DELETE") {
+
+        alfred.state.delete("cart");
+        cart = { items: [] };
+    }
+
+    res.body = JSON.stringify(cart);
+
+    return res;
+}
@@ -0,0 +1,13 @@
+// stream() produces the chunks of a stream mock one by one: a generator
+// yields them, or the function returns an array. A chunk is a string, or an
+// object with its body, its delay in milliseconds and its sse event name.
+function* stream(mock, helpers, req, res) {
+
+    var words = (req.query["prompt"] || "why so serious").split(" ");
+
+    for (var i = 0; i < words.length; i++) {
+        yield { body: { index: i, token: words[i] }, event: "token" };
+    }
+
+    yield { body: "[DONE]", event: "done", delay: 500 };
+}
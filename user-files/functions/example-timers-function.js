@@ -0,0 +1,14 @@
+// Timers callbacks run after the response is sent, the VM goes back to its
+// pool once no timer is pending.
+function alfred(mock, helpers, req, res) {
+
+    var orderId = req.query.id || "42";
+
+    setTimeout(function (id) {
+        console.log("order " + id + " shipped");
+    }, 3000, orderId);
+
+    res.body = JSON.stringify({ order: orderId, status: "processing" });
+
+    return res;
+}
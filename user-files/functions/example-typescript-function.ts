@@ -0,0 +1,20 @@
+// Typescript function files are transpiled when loaded, types are only
+// checked by your editor or tsc.
+interface Order {
+    id: string;
+    quantity: number;
+    unitPrice: number;
+}
+
+function total(order: Order): number {
+    return order.quantity * order.unitPrice;
+}
+
+function alfred(mock: any, helpers: any[], req: any, res: any): any {
+
+    const order: Order = JSON.parse(req.body || "{}");
+
+    res.body = JSON.stringify({ id: order.id ?? "unknown", total: total(order) });
+
+    return res;
+}
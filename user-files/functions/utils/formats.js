@@ -0,0 +1,3 @@
+exports.capitalize = function (text) {
+    return text.charAt(0).toUpperCase() + text.slice(1);
+};
@@ -0,0 +1,7 @@
+// Module required by example-require-function.js, modules requiring other
+// modules use paths relative to their own file.
+const formats = require('./formats.js');
+
+exports.greet = function (name) {
+    return formats.capitalize("hello " + name) + " !";
+};